@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/Lina3386/telegram-bot/internal/app"
 	"log"
+	_ "time/tzdata"
 )
 
 func main() {
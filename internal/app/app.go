@@ -2,20 +2,37 @@ package app
 
 import (
 	"context"
+	"errors"
+	"expvar"
 	"flag"
-	"github.com/Lina3386/telegram-bot/internal/services"
+	"fmt"
 	"log"
-	"os"
+	"net"
+	"net/http"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/Lina3386/telegram-bot/internal/closer"
 	"github.com/Lina3386/telegram-bot/internal/config"
+	"github.com/Lina3386/telegram-bot/internal/config/env"
+	"github.com/Lina3386/telegram-bot/internal/router"
+	"github.com/Lina3386/telegram-bot/internal/schedule"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 var configPath string
 
+const (
+	stateCleanupInterval   = 10 * time.Minute
+	deletedCleanupInterval = time.Hour
+
+	// сколько при остановке ждать обработки уже принятых обновлений
+	updatesDrainTimeout = time.Minute
+
+	metricsPath = "/debug/vars"
+)
+
 func init() {
 	flag.StringVar(&configPath, "config-path", ".env", "path to config file")
 }
@@ -49,7 +66,12 @@ func (a *App) initDeps(ctx context.Context) error {
 		a.initConfig,
 		a.initServiceProvider,
 		a.initTelegramBot,
+		a.initRates,
+		a.initCalendar,
 		a.initScheduler,
+		a.initStateCleanup,
+		a.initDeletedCleanup,
+		a.initMetrics,
 	}
 
 	for i, f := range inits {
@@ -91,13 +113,38 @@ func (a *App) initTelegramBot(ctx context.Context) error {
 	return nil
 }
 
-func (a *App) initScheduler(ctx context.Context) error {
-	bot := a.bot
-	financeService := a.serviceProvider.FinanceService(ctx)
-	userRepository := a.serviceProvider.UserRepository(ctx)
-	monthlyContribRepository := a.serviceProvider.MonthlyContributionsRepository(ctx)
+// загружает курсы валют из файла, если он задан
+func (a *App) initRates(ctx context.Context) error {
+	path := a.serviceProvider.RatesConfig().FilePath()
+	if path == "" {
+		return nil
+	}
+
+	loaded, err := a.serviceProvider.FinanceService(ctx).LoadExchangeRatesFromFile(ctx, path)
+	if err != nil {
+		return err
+	}
+	log.Printf("Loaded %d exchange rate(s) from %s", loaded, path)
+	return nil
+}
+
+// загружает переносы выходных в производственный календарь, если файл задан
+func (a *App) initCalendar(context.Context) error {
+	path := a.serviceProvider.CalendarConfig().FilePath()
+	if path == "" {
+		return nil
+	}
 
-	scheduler := services.NewScheduler(bot, financeService, userRepository, monthlyContribRepository)
+	loaded, err := schedule.DefaultCalendar.LoadFile(path)
+	if err != nil {
+		return err
+	}
+	log.Printf("Loaded %d calendar day(s) from %s", loaded, path)
+	return nil
+}
+
+func (a *App) initScheduler(ctx context.Context) error {
+	scheduler := a.serviceProvider.Scheduler(ctx)
 	go func() {
 		scheduler.Start(ctx)
 	}()
@@ -105,6 +152,89 @@ func (a *App) initScheduler(ctx context.Context) error {
 	return nil
 }
 
+// периодически удаляет истекшие диалоговые сессии
+func (a *App) initStateCleanup(ctx context.Context) error {
+	store := a.serviceProvider.StateStore(ctx)
+
+	go func() {
+		ticker := time.NewTicker(stateCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				purged, err := store.PurgeExpired(ctx)
+				if err != nil {
+					log.Printf("Failed to purge expired sessions: %v", err)
+				} else if purged > 0 {
+					log.Printf("Purged %d expired session(s)", purged)
+				}
+			}
+		}
+	}()
+	log.Println("State cleanup started in background")
+	return nil
+}
+
+// периодически удаляет насовсем доходы, расходы и цели, удаленные дольше срока хранения
+func (a *App) initDeletedCleanup(ctx context.Context) error {
+	financeService := a.serviceProvider.FinanceService(ctx)
+	retention := a.serviceProvider.DeletionConfig().Retention()
+
+	go func() {
+		ticker := time.NewTicker(deletedCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				purged, err := financeService.PurgeDeleted(ctx, retention)
+				if err != nil {
+					log.Printf("Failed to purge deleted records: %v", err)
+				} else if purged > 0 {
+					log.Printf("Purged %d deleted record(s)", purged)
+				}
+			}
+		}
+	}()
+	log.Println("Deleted records cleanup started in background")
+	return nil
+}
+
+// отдает счетчики expvar (в том числе исходящих сообщений) на внутреннем адресе, отдельно от webhook
+func (a *App) initMetrics(context.Context) error {
+	addr := a.serviceProvider.MetricsConfig().ListenAddr()
+	if addr == "" {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, expvar.Handler())
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on metrics address %s: %w", addr, err)
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Metrics server stopped: %v", err)
+		}
+	}()
+	closer.Add(server.Close)
+
+	log.Printf("Metrics are served on http://%s%s", addr, metricsPath)
+	return nil
+}
+
 func (a *App) runTelegramBot() error {
 	log.Println("Telegram bot is starting...")
 
@@ -115,49 +245,119 @@ func (a *App) runTelegramBot() error {
 	}
 	log.Println("Bot is accessible via Telegram API")
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+	var updates tgbotapi.UpdatesChannel
+	var serverErrs <-chan error
 
-	log.Println("📡 Setting up updates channel...")
-	updates := a.bot.GetUpdatesChan(u)
+	switch a.serviceProvider.BotConfig().Mode() {
+	case env.BotModeWebhook:
+		log.Println("📡 Starting webhook server...")
+		webhook, err := newWebhookServer(a.bot, a.serviceProvider.WebhookConfig())
+		if err != nil {
+			return err
+		}
+		if err := webhook.Start(); err != nil {
+			return err
+		}
+		defer webhook.Stop()
+
+		updates = webhook.Updates()
+		serverErrs = webhook.Errors()
+
+	default:
+		// после работы в режиме webhook getUpdates не работает, пока webhook не снят
+		if _, err := a.bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+			log.Printf("Failed to delete webhook before polling: %v", err)
+		}
+
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = 60
+
+		log.Println("📡 Setting up updates channel...")
+		updates = a.bot.GetUpdatesChan(u)
+		defer a.bot.StopReceivingUpdates()
+	}
 
 	log.Println("Bot is running and listening for updates... (Press Ctrl+C to stop)")
 	log.Println("Try sending /start to the bot to test")
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	// stop отменяется по сигналу и прекращает прием обновлений; ctx обработчиков живет, пока не дообработаны принятые
+	stop, stopReceiving := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopReceiving()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	routes := a.newRouter(ctx)
 
-	botHandler := a.serviceProvider.BotHandler(context.Background())
+	botConfig := a.serviceProvider.BotConfig()
+	pool := a.serviceProvider.WorkerPool()
+	log.Printf("Processing updates with %d workers, queue size %d", botConfig.Workers(), botConfig.QueueSize())
+
+	defer func() {
+		log.Println("Waiting for in-flight updates...")
+		drainCtx, cancelDrain := context.WithTimeout(context.Background(), updatesDrainTimeout)
+		defer cancelDrain()
+		if err := pool.Shutdown(drainCtx); err != nil {
+			log.Printf("Failed to drain updates: %v", err)
+		}
+	}()
 
 	for {
 		select {
-		case <-sigChan:
+		case <-stop.Done():
 			log.Println("\nShutting down gracefully...")
 			return nil
 
+		case err := <-serverErrs:
+			log.Printf("Webhook server stopped: %v", err)
+			return err
+
 		case update := <-updates:
-			if update.Message != nil {
-				log.Printf("Message from %d: %s", update.Message.From.ID, update.Message.Text)
-
-				if update.Message.IsCommand() {
-					switch update.Message.Command() {
-					case "start":
-						botHandler.HandleStart(update.Message)
-					case "help":
-						botHandler.HandleHelp(update.Message)
-					case "cancel":
-						botHandler.HandleCancel(update.Message)
-					default:
-						botHandler.HandleUnknownCommand(update.Message)
-					}
-				} else {
-					botHandler.HandleTextMessage(update.Message)
-				}
-			}
-			if update.CallbackQuery != nil {
-				log.Printf("Callback from %d: %s", update.CallbackQuery.From.ID, update.CallbackQuery.Data)
-				botHandler.HandleCallback(update.CallbackQuery)
+			// обновления одного пользователя обрабатываются по порядку, разных - параллельно
+			err := pool.Submit(stop, updateKey(update), func() {
+				routes.Dispatch(ctx, update)
+			})
+			if err != nil {
+				log.Printf("Update %d dropped: %v", update.UpdateID, err)
 			}
 		}
 	}
 }
+
+// updateKey - по чему упорядочивать обновления: отправитель, а если его нет - чат
+func updateKey(update tgbotapi.Update) int64 {
+	if from := update.SentFrom(); from != nil {
+		return from.ID
+	}
+	if chat := update.FromChat(); chat != nil {
+		return chat.ID
+	}
+	return 0
+}
+
+// newRouter собирает маршруты бота и middleware вокруг них; порядок важен:
+// panic recovery снаружи, чтобы поймать панику в любом слое, а пользователь загружается только после проверки доступа
+func (a *App) newRouter(ctx context.Context) *router.Router {
+	botConfig := a.serviceProvider.BotConfig()
+	botHandler := a.serviceProvider.BotHandler(ctx)
+
+	allowed := make(map[int64]bool, len(botConfig.AllowedUsers()))
+	for _, id := range botConfig.AllowedUsers() {
+		allowed[id] = true
+	}
+	if len(allowed) > 0 {
+		log.Printf("Bot access is limited to %d users", len(allowed))
+	}
+
+	routes := botHandler.Router()
+	routes.Use(
+		router.Recover(botHandler.HandlePanic),
+		router.Logging(),
+		router.Timeout(botConfig.UpdateTimeout()),
+		router.Authorize(func(c *router.Context) bool {
+			return len(allowed) == 0 || allowed[c.UserID()]
+		}, botHandler.HandleAccessDenied),
+		botHandler.LoadUser,
+	)
+	return routes
+}
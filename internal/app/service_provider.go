@@ -8,23 +8,34 @@ import (
 
 	"github.com/Lina3386/telegram-bot/internal/client/db"
 	"github.com/Lina3386/telegram-bot/internal/client/db/pg"
+	"github.com/Lina3386/telegram-bot/internal/client/db/transaction"
 	"github.com/Lina3386/telegram-bot/internal/closer"
 	"github.com/Lina3386/telegram-bot/internal/config"
 	"github.com/Lina3386/telegram-bot/internal/config/env"
 	"github.com/Lina3386/telegram-bot/internal/repository"
+	"github.com/Lina3386/telegram-bot/internal/sender"
 	"github.com/Lina3386/telegram-bot/internal/services"
 	"github.com/Lina3386/telegram-bot/internal/state"
+	"github.com/Lina3386/telegram-bot/internal/worker"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	_ "github.com/lib/pq"
 )
 
 type ServiceProvider struct {
-	pgConfig   config.PGConfig
-	botConfig  config.BotConfig
-	authConfig config.AuthConfig
-	chatConfig config.ChatConfig
+	pgConfig       config.PGConfig
+	botConfig      config.BotConfig
+	webhookConfig  config.WebhookConfig
+	authConfig     config.AuthConfig
+	chatConfig     config.ChatConfig
+	stateConfig    config.StateConfig
+	ratesConfig    config.RatesConfig
+	calendarConfig config.CalendarConfig
+	incomeConfig   config.IncomeConfig
+	deletionConfig config.DeletionConfig
+	metricsConfig  config.MetricsConfig
 
-	dbClient db.Client
+	dbClient  db.Client
+	txManager db.TxManager
 
 	userRepo                 *repository.UserRepository
 	incomeRepo               *repository.IncomeRepository
@@ -32,6 +43,10 @@ type ServiceProvider struct {
 	goalRepo                 *repository.GoalRepository
 	monthlyContributionsRepo *repository.MonthlyContributionsRepository
 	incomeProcessingLogRepo  *repository.IncomeProcessingLogRepository
+	goalTransactionRepo      *repository.GoalTransactionRepository
+	expenseCategoryRepo      *repository.ExpenseCategoryRepository
+	rateRepo                 *repository.RateRepository
+	notificationRepo         *repository.NotificationRepository
 
 	financeService *services.FinanceService
 	authService    *services.AuthService
@@ -39,9 +54,12 @@ type ServiceProvider struct {
 
 	botHandler *bot_handler.BotHandler
 
-	stateManager *state.StateManager
+	stateStore state.StateStore
 
-	bot *tgbotapi.BotAPI
+	bot    *tgbotapi.BotAPI
+	sender *sender.Sender
+
+	workerPool *worker.Pool
 }
 
 func NewServiceProvider() *ServiceProvider {
@@ -70,6 +88,26 @@ func (s *ServiceProvider) BotConfig() config.BotConfig {
 	return s.botConfig
 }
 
+// WorkerPool - пул, в котором обрабатываются обновления; сервисы ставят в него фоновые задачи пользователя
+func (s *ServiceProvider) WorkerPool() *worker.Pool {
+	if s.workerPool == nil {
+		botConfig := s.BotConfig()
+		s.workerPool = worker.NewPool(botConfig.Workers(), botConfig.QueueSize())
+	}
+	return s.workerPool
+}
+
+func (s *ServiceProvider) WebhookConfig() config.WebhookConfig {
+	if s.webhookConfig == nil {
+		webhookConfig, err := env.NewWebhookConfig()
+		if err != nil {
+			log.Fatalf("failed to get webhook config: %v", err)
+		}
+		s.webhookConfig = webhookConfig
+	}
+	return s.webhookConfig
+}
+
 func (s *ServiceProvider) AuthConfig() config.AuthConfig {
 	if s.authConfig == nil {
 		authConfig, err := env.NewAuthConfig()
@@ -92,6 +130,72 @@ func (s *ServiceProvider) ChatConfig() config.ChatConfig {
 	return s.chatConfig
 }
 
+func (s *ServiceProvider) StateConfig() config.StateConfig {
+	if s.stateConfig == nil {
+		stateConfig, err := env.NewStateConfig()
+		if err != nil {
+			log.Fatalf("failed to get state config: %v", err)
+		}
+		s.stateConfig = stateConfig
+	}
+	return s.stateConfig
+}
+
+func (s *ServiceProvider) RatesConfig() config.RatesConfig {
+	if s.ratesConfig == nil {
+		ratesConfig, err := env.NewRatesConfig()
+		if err != nil {
+			log.Fatalf("failed to get rates config: %v", err)
+		}
+		s.ratesConfig = ratesConfig
+	}
+	return s.ratesConfig
+}
+
+func (s *ServiceProvider) CalendarConfig() config.CalendarConfig {
+	if s.calendarConfig == nil {
+		calendarConfig, err := env.NewCalendarConfig()
+		if err != nil {
+			log.Fatalf("failed to get calendar config: %v", err)
+		}
+		s.calendarConfig = calendarConfig
+	}
+	return s.calendarConfig
+}
+
+func (s *ServiceProvider) IncomeConfig() config.IncomeConfig {
+	if s.incomeConfig == nil {
+		incomeConfig, err := env.NewIncomeConfig()
+		if err != nil {
+			log.Fatalf("failed to get income config: %v", err)
+		}
+		s.incomeConfig = incomeConfig
+	}
+	return s.incomeConfig
+}
+
+func (s *ServiceProvider) DeletionConfig() config.DeletionConfig {
+	if s.deletionConfig == nil {
+		deletionConfig, err := env.NewDeletionConfig()
+		if err != nil {
+			log.Fatalf("failed to get deletion config: %v", err)
+		}
+		s.deletionConfig = deletionConfig
+	}
+	return s.deletionConfig
+}
+
+func (s *ServiceProvider) MetricsConfig() config.MetricsConfig {
+	if s.metricsConfig == nil {
+		metricsConfig, err := env.NewMetricsConfig()
+		if err != nil {
+			log.Fatalf("failed to get metrics config: %v", err)
+		}
+		s.metricsConfig = metricsConfig
+	}
+	return s.metricsConfig
+}
+
 func (s *ServiceProvider) DBClient(ctx context.Context) db.Client {
 	if s.dbClient == nil {
 		log.Println("Connecting to database...")
@@ -113,52 +217,87 @@ func (s *ServiceProvider) DBClient(ctx context.Context) db.Client {
 	return s.dbClient
 }
 
+func (s *ServiceProvider) TxManager(ctx context.Context) db.TxManager {
+	if s.txManager == nil {
+		s.txManager = transaction.NewTransactionManager(s.DBClient(ctx))
+	}
+	return s.txManager
+}
+
 func (s *ServiceProvider) SQLDB(ctx context.Context) *sql.DB {
 	return s.DBClient(ctx).DB()
 }
 
 func (s *ServiceProvider) UserRepository(ctx context.Context) *repository.UserRepository {
 	if s.userRepo == nil {
-		s.userRepo = repository.NewUserRepository(s.SQLDB(ctx))
+		s.userRepo = repository.NewUserRepository(s.DBClient(ctx))
 	}
 	return s.userRepo
 }
 
 func (s *ServiceProvider) IncomeRepository(ctx context.Context) *repository.IncomeRepository {
 	if s.incomeRepo == nil {
-		s.incomeRepo = repository.NewIncomeRepository(s.SQLDB(ctx))
+		s.incomeRepo = repository.NewIncomeRepository(s.DBClient(ctx))
 	}
 	return s.incomeRepo
 }
 
 func (s *ServiceProvider) ExpenseRepository(ctx context.Context) *repository.ExpenseRepository {
 	if s.expenseRepo == nil {
-		s.expenseRepo = repository.NewExpenseRepository(s.SQLDB(ctx))
+		s.expenseRepo = repository.NewExpenseRepository(s.DBClient(ctx))
 	}
 	return s.expenseRepo
 }
 
 func (s *ServiceProvider) GoalRepository(ctx context.Context) *repository.GoalRepository {
 	if s.goalRepo == nil {
-		s.goalRepo = repository.NewGoalRepository(s.SQLDB(ctx))
+		s.goalRepo = repository.NewGoalRepository(s.DBClient(ctx))
 	}
 	return s.goalRepo
 }
 
 func (s *ServiceProvider) MonthlyContributionsRepository(ctx context.Context) *repository.MonthlyContributionsRepository {
 	if s.monthlyContributionsRepo == nil {
-		s.monthlyContributionsRepo = repository.NewMonthlyContributionsRepository(s.SQLDB(ctx))
+		s.monthlyContributionsRepo = repository.NewMonthlyContributionsRepository(s.DBClient(ctx))
 	}
 	return s.monthlyContributionsRepo
 }
 
 func (s *ServiceProvider) IncomeProcessingLogRepository(ctx context.Context) *repository.IncomeProcessingLogRepository {
 	if s.incomeProcessingLogRepo == nil {
-		s.incomeProcessingLogRepo = repository.NewIncomeProcessingLogRepository(s.SQLDB(ctx))
+		s.incomeProcessingLogRepo = repository.NewIncomeProcessingLogRepository(s.DBClient(ctx))
 	}
 	return s.incomeProcessingLogRepo
 }
 
+func (s *ServiceProvider) GoalTransactionRepository(ctx context.Context) *repository.GoalTransactionRepository {
+	if s.goalTransactionRepo == nil {
+		s.goalTransactionRepo = repository.NewGoalTransactionRepository(s.DBClient(ctx))
+	}
+	return s.goalTransactionRepo
+}
+
+func (s *ServiceProvider) ExpenseCategoryRepository(ctx context.Context) *repository.ExpenseCategoryRepository {
+	if s.expenseCategoryRepo == nil {
+		s.expenseCategoryRepo = repository.NewExpenseCategoryRepository(s.DBClient(ctx))
+	}
+	return s.expenseCategoryRepo
+}
+
+func (s *ServiceProvider) RateRepository(ctx context.Context) *repository.RateRepository {
+	if s.rateRepo == nil {
+		s.rateRepo = repository.NewRateRepository(s.DBClient(ctx))
+	}
+	return s.rateRepo
+}
+
+func (s *ServiceProvider) NotificationRepository(ctx context.Context) *repository.NotificationRepository {
+	if s.notificationRepo == nil {
+		s.notificationRepo = repository.NewNotificationRepository(s.DBClient(ctx))
+	}
+	return s.notificationRepo
+}
+
 func (s *ServiceProvider) FinanceService(ctx context.Context) *services.FinanceService {
 	if s.financeService == nil {
 		s.financeService = services.NewFinanceService(
@@ -168,6 +307,13 @@ func (s *ServiceProvider) FinanceService(ctx context.Context) *services.FinanceS
 			s.GoalRepository(ctx),
 			s.MonthlyContributionsRepository(ctx),
 			s.IncomeProcessingLogRepository(ctx),
+			s.GoalTransactionRepository(ctx),
+			s.ExpenseCategoryRepository(ctx),
+			s.RateRepository(ctx),
+			s.TxManager(ctx),
+			s.WorkerPool(),
+			s.IncomeConfig().IrregularWindow(),
+			s.DeletionConfig().UndoWindow(),
 		)
 	}
 	return s.financeService
@@ -181,11 +327,17 @@ func (s *ServiceProvider) AuthService(ctx context.Context) *services.AuthService
 	return s.authService
 }
 
-func (s *ServiceProvider) StateManager() *state.StateManager {
-	if s.stateManager == nil {
-		s.stateManager = state.NewStateManager()
+func (s *ServiceProvider) StateStore(ctx context.Context) state.StateStore {
+	if s.stateStore == nil {
+		switch s.StateConfig().Backend() {
+		case env.StateBackendPostgres:
+			s.stateStore = state.NewDBStateManager(s.DBClient(ctx), s.StateConfig().TTL())
+		default:
+			s.stateStore = state.NewStateManager(s.StateConfig().TTL())
+		}
+		log.Printf("State store: %s (ttl %s)", s.StateConfig().Backend(), s.StateConfig().TTL())
 	}
-	return s.stateManager
+	return s.stateStore
 }
 
 func (s *ServiceProvider) TelegramBot(ctx context.Context) (*tgbotapi.BotAPI, error) {
@@ -206,26 +358,42 @@ func (s *ServiceProvider) TelegramBot(ctx context.Context) (*tgbotapi.BotAPI, er
 	return s.bot, nil
 }
 
+// Sender - все исходящие сообщения идут через него, чтобы не упираться в лимиты Telegram
+func (s *ServiceProvider) Sender(ctx context.Context) *sender.Sender {
+	if s.sender == nil {
+		bot, err := s.TelegramBot(ctx)
+		if err != nil {
+			log.Printf("Warning: bot not initialized, sender may not work: %v", err)
+		}
+		s.sender = sender.New(bot)
+	}
+	return s.sender
+}
+
 func (s *ServiceProvider) Scheduler(ctx context.Context) *services.Scheduler {
-	bot, _ := s.TelegramBot(ctx)
-	financeService := s.FinanceService(ctx)
-	userRepository := s.UserRepository(ctx)
-	monthlyContribRepository := s.MonthlyContributionsRepository(ctx)
+	if s.scheduler == nil {
+		financeService := s.FinanceService(ctx)
+		userRepository := s.UserRepository(ctx)
+		monthlyContribRepository := s.MonthlyContributionsRepository(ctx)
+		notificationRepository := s.NotificationRepository(ctx)
 
-	return services.NewScheduler(bot, financeService, userRepository, monthlyContribRepository)
+		s.scheduler = services.NewScheduler(s.Sender(ctx), financeService, userRepository, monthlyContribRepository, notificationRepository)
+	}
+	return s.scheduler
 }
 
 func (s *ServiceProvider) BotHandler(ctx context.Context) *bot_handler.BotHandler {
 	if s.botHandler == nil {
-		bot, err := s.TelegramBot(ctx)
-		if err != nil {
-			log.Printf("Warning: bot not initialized, handler may not work: %v", err)
+		if len(s.BotConfig().Admins()) == 0 {
+			log.Println("No bot admins configured: exchange rates can only be loaded from file")
 		}
 		s.botHandler = bot_handler.NewBotHandler(
-			bot,
+			s.Sender(ctx),
 			s.FinanceService(ctx),
 			s.AuthService(ctx),
-			s.StateManager(),
+			s.StateStore(ctx),
+			s.Scheduler(ctx),
+			s.BotConfig().Admins(),
 		)
 		log.Println("Bot handler created")
 	}
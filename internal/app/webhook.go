@@ -0,0 +1,160 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/config"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// заголовок, в котором Telegram присылает secret_token из setWebhook
+	webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+	webhookHealthPath   = "/healthz"
+
+	webhookReadTimeout     = 10 * time.Second
+	webhookShutdownTimeout = 10 * time.Second
+)
+
+// webhookServer принимает обновления от Telegram по HTTP и складывает их в тот же канал, что и polling
+type webhookServer struct {
+	bot     *tgbotapi.BotAPI
+	cfg     config.WebhookConfig
+	server  *http.Server
+	updates chan tgbotapi.Update
+	errs    chan error
+}
+
+func newWebhookServer(bot *tgbotapi.BotAPI, cfg config.WebhookConfig) (*webhookServer, error) {
+	link, err := url.Parse(cfg.URL())
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook url: %w", err)
+	}
+	path := link.Path
+	if path == "" {
+		path = "/"
+	}
+
+	w := &webhookServer{
+		bot:     bot,
+		cfg:     cfg,
+		updates: make(chan tgbotapi.Update, bot.Buffer),
+		errs:    make(chan error, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(webhookHealthPath, func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc(path, w.handleUpdate)
+
+	w.server = &http.Server{
+		Handler:     mux,
+		ReadTimeout: webhookReadTimeout,
+	}
+	return w, nil
+}
+
+func (w *webhookServer) handleUpdate(rw http.ResponseWriter, r *http.Request) {
+	secret := r.Header.Get(webhookSecretHeader)
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(w.cfg.SecretToken())) != 1 {
+		log.Printf("[WEBHOOK] Rejected request from %s: bad secret token", r.RemoteAddr)
+		http.Error(rw, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	update, err := w.bot.HandleUpdate(r)
+	if err != nil {
+		log.Printf("[WEBHOOK] Bad update from %s: %v", r.RemoteAddr, err)
+		http.Error(rw, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case w.updates <- *update:
+		rw.WriteHeader(http.StatusOK)
+	case <-r.Context().Done():
+		// Telegram повторит доставку
+		http.Error(rw, "busy", http.StatusServiceUnavailable)
+	}
+}
+
+// Start поднимает HTTP-сервер и только потом регистрирует webhook, чтобы первая доставка не ушла в пустоту
+func (w *webhookServer) Start() error {
+	listener, err := net.Listen("tcp", w.cfg.ListenAddr())
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", w.cfg.ListenAddr(), err)
+	}
+
+	go func() {
+		var errServe error
+		if w.cfg.TLSCertFile() != "" {
+			errServe = w.server.ServeTLS(listener, w.cfg.TLSCertFile(), w.cfg.TLSKeyFile())
+		} else {
+			errServe = w.server.Serve(listener)
+		}
+		if !errors.Is(errServe, http.ErrServerClosed) {
+			w.errs <- errServe
+		}
+	}()
+
+	if err := w.register(); err != nil {
+		w.server.Close()
+		return err
+	}
+
+	log.Printf("[WEBHOOK] Listening on %s, webhook set to %s", w.cfg.ListenAddr(), w.cfg.URL())
+	return nil
+}
+
+// setWebhook с secret_token: в WebhookConfig библиотеки этого поля нет, поэтому параметры собираем сами
+func (w *webhookServer) register() error {
+	params := tgbotapi.Params{
+		"url":          w.cfg.URL(),
+		"secret_token": w.cfg.SecretToken(),
+	}
+
+	var err error
+	if w.cfg.UploadCert() {
+		files := []tgbotapi.RequestFile{{Name: "certificate", Data: tgbotapi.FilePath(w.cfg.TLSCertFile())}}
+		_, err = w.bot.UploadFiles("setWebhook", params, files)
+	} else {
+		_, err = w.bot.MakeRequest("setWebhook", params)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set webhook: %w", err)
+	}
+	return nil
+}
+
+// Stop дожидается обработки текущих запросов и снимает webhook
+func (w *webhookServer) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+	defer cancel()
+
+	if err := w.server.Shutdown(ctx); err != nil {
+		log.Printf("[WEBHOOK] Failed to shut down server: %v", err)
+	}
+	if _, err := w.bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Printf("[WEBHOOK] Failed to delete webhook: %v", err)
+		return
+	}
+	log.Println("[WEBHOOK] Webhook removed")
+}
+
+func (w *webhookServer) Updates() tgbotapi.UpdatesChannel {
+	return w.updates
+}
+
+// Errors - ошибка, с которой упал HTTP-сервер
+func (w *webhookServer) Errors() <-chan error {
+	return w.errs
+}
@@ -1,10 +1,32 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 )
 
+// Handler - функция, выполняемая в рамках транзакции
+type Handler func(ctx context.Context) error
+
 type Client interface {
 	DB() *sql.DB
+	QueryExecer
+	Transactor
 	Close() error
 }
+
+// QueryExecer выполняет запросы в транзакции из контекста, если она есть
+type QueryExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type Transactor interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// TxManager выполняет обработчик в одной транзакции
+type TxManager interface {
+	ReadCommitted(ctx context.Context, f Handler) error
+}
@@ -9,6 +9,10 @@ import (
 	_ "github.com/lib/pq"
 )
 
+type key string
+
+const TxKey key = "tx"
+
 type pgClient struct {
 	db *sql.DB
 }
@@ -32,6 +36,31 @@ func (c *pgClient) DB() *sql.DB {
 	return c.db
 }
 
+func (c *pgClient) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if tx, ok := ctx.Value(TxKey).(*sql.Tx); ok {
+		return tx.ExecContext(ctx, query, args...)
+	}
+	return c.db.ExecContext(ctx, query, args...)
+}
+
+func (c *pgClient) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if tx, ok := ctx.Value(TxKey).(*sql.Tx); ok {
+		return tx.QueryContext(ctx, query, args...)
+	}
+	return c.db.QueryContext(ctx, query, args...)
+}
+
+func (c *pgClient) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if tx, ok := ctx.Value(TxKey).(*sql.Tx); ok {
+		return tx.QueryRowContext(ctx, query, args...)
+	}
+	return c.db.QueryRowContext(ctx, query, args...)
+}
+
+func (c *pgClient) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return c.db.BeginTx(ctx, opts)
+}
+
 func (c *pgClient) Close() error {
 	if c.db != nil {
 		return c.db.Close()
@@ -39,4 +68,6 @@ func (c *pgClient) Close() error {
 	return nil
 }
 
-
+func MakeContextTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, TxKey, tx)
+}
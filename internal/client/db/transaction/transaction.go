@@ -0,0 +1,59 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Lina3386/telegram-bot/internal/client/db"
+	"github.com/Lina3386/telegram-bot/internal/client/db/pg"
+)
+
+type manager struct {
+	db db.Transactor
+}
+
+func NewTransactionManager(db db.Transactor) db.TxManager {
+	return &manager{
+		db: db,
+	}
+}
+
+func (m *manager) transaction(ctx context.Context, opts sql.TxOptions, fn db.Handler) (err error) {
+	// вложенный вызов выполняется в уже открытой транзакции
+	if _, ok := ctx.Value(pg.TxKey).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.BeginTx(ctx, &opts)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	ctx = pg.MakeContextTx(ctx, tx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic recovered: %v", r)
+		}
+
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				err = errors.Join(err, fmt.Errorf("failed to rollback transaction: %w", errRollback))
+			}
+			return
+		}
+
+		if errCommit := tx.Commit(); errCommit != nil {
+			err = fmt.Errorf("failed to commit transaction: %w", errCommit)
+		}
+	}()
+
+	return fn(ctx)
+}
+
+func (m *manager) ReadCommitted(ctx context.Context, f db.Handler) error {
+	txOpts := sql.TxOptions{Isolation: sql.LevelReadCommitted}
+	return m.transaction(ctx, txOpts, f)
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/joho/godotenv"
 )
 
@@ -11,6 +13,21 @@ type PGConfig interface {
 type BotConfig interface {
 	Token() string
 	Debug() bool
+	Mode() string
+	UpdateTimeout() time.Duration
+	AllowedUsers() []int64
+	Workers() int
+	QueueSize() int
+	Admins() []int64
+}
+
+type WebhookConfig interface {
+	URL() string
+	ListenAddr() string
+	SecretToken() string
+	TLSCertFile() string
+	TLSKeyFile() string
+	UploadCert() bool
 }
 
 type AuthConfig interface {
@@ -21,6 +38,32 @@ type ChatConfig interface {
 	Address() string
 }
 
+type StateConfig interface {
+	Backend() string
+	TTL() time.Duration
+}
+
+type RatesConfig interface {
+	FilePath() string
+}
+
+type CalendarConfig interface {
+	FilePath() string
+}
+
+type MetricsConfig interface {
+	ListenAddr() string
+}
+
+type IncomeConfig interface {
+	IrregularWindow() int
+}
+
+type DeletionConfig interface {
+	UndoWindow() time.Duration
+	Retention() time.Duration
+}
+
 func Load(path string) error {
 	err := godotenv.Load(path)
 	if err != nil {
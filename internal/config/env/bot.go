@@ -2,18 +2,46 @@ package env
 
 import (
 	"errors"
+	"fmt"
 	"github.com/Lina3386/telegram-bot/internal/config"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const (
 	botTokenEnvName = "TELEGRAM_BOT_TOKEN"
 	botDebugEnvName = "LOG_LEVEL"
+	botModeEnvName  = "BOT_MODE"
+
+	botUpdateTimeoutEnvName = "BOT_UPDATE_TIMEOUT"
+	botAllowedUsersEnvName  = "BOT_ALLOWED_USERS"
+	botWorkersEnvName       = "BOT_WORKERS"
+	botQueueSizeEnvName     = "BOT_QUEUE_SIZE"
+	botAdminsEnvName        = "BOT_ADMINS"
+
+	BotModePolling = "polling"
+	BotModeWebhook = "webhook"
+)
+
+// сколько может обрабатываться одно обновление, включая запросы к БД
+const defaultUpdateTimeout = 30 * time.Second
+
+const (
+	defaultWorkers   = 8
+	defaultQueueSize = 100
 )
 
 type botConfig struct {
-	token string
-	debug bool
+	token         string
+	debug         bool
+	mode          string
+	updateTimeout time.Duration
+	allowedUsers  []int64
+	workers       int
+	queueSize     int
+	admins        []int64
 }
 
 func NewBotConfig() (config.BotConfig, error) {
@@ -24,12 +52,79 @@ func NewBotConfig() (config.BotConfig, error) {
 
 	debug := os.Getenv(botDebugEnvName) == "debug"
 
+	mode := os.Getenv(botModeEnvName)
+	if mode == "" {
+		mode = BotModePolling
+	}
+	if mode != BotModePolling && mode != BotModeWebhook {
+		return nil, fmt.Errorf("%s must be %q or %q", botModeEnvName, BotModePolling, BotModeWebhook)
+	}
+
+	updateTimeout, err := durationFromEnv(botUpdateTimeoutEnvName, defaultUpdateTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	allowedUsers, err := parseTelegramIDs(os.Getenv(botAllowedUsersEnvName))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", botAllowedUsersEnvName, err)
+	}
+
+	workers, err := positiveIntFromEnv(botWorkersEnvName, defaultWorkers)
+	if err != nil {
+		return nil, err
+	}
+	queueSize, err := positiveIntFromEnv(botQueueSizeEnvName, defaultQueueSize)
+	if err != nil {
+		return nil, err
+	}
+
+	admins, err := parseTelegramIDs(os.Getenv(botAdminsEnvName))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", botAdminsEnvName, err)
+	}
+
 	return &botConfig{
-		token: token,
-		debug: debug,
+		token:         token,
+		debug:         debug,
+		mode:          mode,
+		updateTimeout: updateTimeout,
+		allowedUsers:  allowedUsers,
+		workers:       workers,
+		queueSize:     queueSize,
+		admins:        admins,
 	}, nil
 }
 
+func positiveIntFromEnv(name string, def int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 1 {
+		return 0, fmt.Errorf("invalid %s: must be a positive number", name)
+	}
+	return parsed, nil
+}
+
+// "123, 456" -> [123 456]
+func parseTelegramIDs(value string) ([]int64, error) {
+	var ids []int64
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad telegram id %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
 func (cfg *botConfig) Token() string {
 	return cfg.token
 }
@@ -37,3 +132,32 @@ func (cfg *botConfig) Token() string {
 func (cfg *botConfig) Debug() bool {
 	return cfg.debug
 }
+
+// Mode - как получать обновления: long polling или webhook
+func (cfg *botConfig) Mode() string {
+	return cfg.mode
+}
+
+func (cfg *botConfig) UpdateTimeout() time.Duration {
+	return cfg.updateTimeout
+}
+
+// AllowedUsers - telegram id, которым доступен бот; пустой список - доступен всем
+func (cfg *botConfig) AllowedUsers() []int64 {
+	return cfg.allowedUsers
+}
+
+// Workers - сколько обновлений обрабатывается параллельно; обновления одного пользователя - всегда по очереди
+func (cfg *botConfig) Workers() int {
+	return cfg.workers
+}
+
+// QueueSize - сколько обновлений может ждать обработки, дальше прием новых притормаживает
+func (cfg *botConfig) QueueSize() int {
+	return cfg.queueSize
+}
+
+// Admins - telegram id администраторов: только они меняют общие для всех настройки, например курсы валют
+func (cfg *botConfig) Admins() []int64 {
+	return cfg.admins
+}
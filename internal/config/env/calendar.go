@@ -0,0 +1,24 @@
+package env
+
+import (
+	"os"
+
+	"github.com/Lina3386/telegram-bot/internal/config"
+)
+
+const calendarFileEnvName = "CALENDAR_FILE"
+
+type calendarConfig struct {
+	filePath string
+}
+
+// файл переносов необязателен: без него работают встроенные праздники
+func NewCalendarConfig() (config.CalendarConfig, error) {
+	return &calendarConfig{
+		filePath: os.Getenv(calendarFileEnvName),
+	}, nil
+}
+
+func (cfg *calendarConfig) FilePath() string {
+	return cfg.filePath
+}
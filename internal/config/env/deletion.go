@@ -0,0 +1,59 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/config"
+)
+
+const (
+	undoWindowEnvName       = "UNDO_WINDOW"
+	deletedRetentionEnvName = "DELETED_RETENTION"
+)
+
+type deletionConfig struct {
+	undoWindow time.Duration
+	retention  time.Duration
+}
+
+// сколько удаление можно отменить (по умолчанию 15 минут) и сколько хранить удаленное (7 дней)
+func NewDeletionConfig() (config.DeletionConfig, error) {
+	undoWindow, err := durationFromEnv(undoWindowEnvName, 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	retention, err := durationFromEnv(deletedRetentionEnvName, 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if retention < undoWindow {
+		return nil, fmt.Errorf("%s must not be shorter than %s", deletedRetentionEnvName, undoWindowEnvName)
+	}
+
+	return &deletionConfig{
+		undoWindow: undoWindow,
+		retention:  retention,
+	}, nil
+}
+
+func durationFromEnv(name string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("invalid %s: must be a positive duration like 15m or 168h", name)
+	}
+	return parsed, nil
+}
+
+func (cfg *deletionConfig) UndoWindow() time.Duration {
+	return cfg.undoWindow
+}
+
+func (cfg *deletionConfig) Retention() time.Duration {
+	return cfg.retention
+}
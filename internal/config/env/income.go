@@ -0,0 +1,35 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Lina3386/telegram-bot/internal/config"
+)
+
+const irregularWindowEnvName = "IRREGULAR_INCOME_WINDOW"
+
+type incomeConfig struct {
+	irregularWindow int
+}
+
+// за сколько последних месяцев усреднять нерегулярный доход, по умолчанию 3
+func NewIncomeConfig() (config.IncomeConfig, error) {
+	window := 3
+	if windowStr := os.Getenv(irregularWindowEnvName); windowStr != "" {
+		parsed, err := strconv.Atoi(windowStr)
+		if err != nil || parsed < 1 || parsed > 24 {
+			return nil, fmt.Errorf("%s must be a number of months from 1 to 24", irregularWindowEnvName)
+		}
+		window = parsed
+	}
+
+	return &incomeConfig{
+		irregularWindow: window,
+	}, nil
+}
+
+func (cfg *incomeConfig) IrregularWindow() int {
+	return cfg.irregularWindow
+}
@@ -0,0 +1,24 @@
+package env
+
+import (
+	"os"
+
+	"github.com/Lina3386/telegram-bot/internal/config"
+)
+
+const metricsAddrEnvName = "METRICS_ADDR"
+
+type metricsConfig struct {
+	listenAddr string
+}
+
+// метрики отдаются на отдельном, внутреннем адресе (например 127.0.0.1:9090); не задан - не отдаются
+func NewMetricsConfig() (config.MetricsConfig, error) {
+	return &metricsConfig{
+		listenAddr: os.Getenv(metricsAddrEnvName),
+	}, nil
+}
+
+func (cfg *metricsConfig) ListenAddr() string {
+	return cfg.listenAddr
+}
@@ -0,0 +1,24 @@
+package env
+
+import (
+	"os"
+
+	"github.com/Lina3386/telegram-bot/internal/config"
+)
+
+const ratesFileEnvName = "RATES_FILE"
+
+type ratesConfig struct {
+	filePath string
+}
+
+// путь к файлу курсов необязателен: курсы можно задать командой /rate
+func NewRatesConfig() (config.RatesConfig, error) {
+	return &ratesConfig{
+		filePath: os.Getenv(ratesFileEnvName),
+	}, nil
+}
+
+func (cfg *ratesConfig) FilePath() string {
+	return cfg.filePath
+}
@@ -0,0 +1,54 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/config"
+)
+
+const (
+	stateBackendEnvName = "STATE_BACKEND"
+	stateTTLEnvName     = "STATE_TTL"
+
+	StateBackendMemory   = "memory"
+	StateBackendPostgres = "postgres"
+)
+
+type stateConfig struct {
+	backend string
+	ttl     time.Duration
+}
+
+func NewStateConfig() (config.StateConfig, error) {
+	backend := os.Getenv(stateBackendEnvName)
+	if backend == "" {
+		backend = StateBackendMemory
+	}
+	if backend != StateBackendMemory && backend != StateBackendPostgres {
+		return nil, fmt.Errorf("%s must be %q or %q", stateBackendEnvName, StateBackendMemory, StateBackendPostgres)
+	}
+
+	ttl := 24 * time.Hour
+	if ttlStr := os.Getenv(stateTTLEnvName); ttlStr != "" {
+		parsed, err := time.ParseDuration(ttlStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", stateTTLEnvName, err)
+		}
+		ttl = parsed
+	}
+
+	return &stateConfig{
+		backend: backend,
+		ttl:     ttl,
+	}, nil
+}
+
+func (cfg *stateConfig) Backend() string {
+	return cfg.backend
+}
+
+func (cfg *stateConfig) TTL() time.Duration {
+	return cfg.ttl
+}
@@ -0,0 +1,95 @@
+package env
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+
+	"github.com/Lina3386/telegram-bot/internal/config"
+)
+
+const (
+	webhookURLEnvName         = "WEBHOOK_URL"
+	webhookListenAddrEnvName  = "WEBHOOK_LISTEN_ADDR"
+	webhookSecretTokenEnvName = "WEBHOOK_SECRET_TOKEN"
+	webhookTLSCertEnvName     = "WEBHOOK_TLS_CERT"
+	webhookTLSKeyEnvName      = "WEBHOOK_TLS_KEY"
+	webhookUploadCertEnvName  = "WEBHOOK_UPLOAD_CERT"
+)
+
+// такие символы Telegram допускает в secret_token
+var secretTokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+type webhookConfig struct {
+	url         string
+	listenAddr  string
+	secretToken string
+	tlsCertFile string
+	tlsKeyFile  string
+	uploadCert  bool
+}
+
+// без сертификата сервер слушает обычный HTTP - TLS тогда завершает балансировщик или прокси
+func NewWebhookConfig() (config.WebhookConfig, error) {
+	rawURL := os.Getenv(webhookURLEnvName)
+	parsed, err := url.Parse(rawURL)
+	if rawURL == "" || err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return nil, fmt.Errorf("%s must be a public https URL, e.g. https://bot.example.com/telegram", webhookURLEnvName)
+	}
+
+	secretToken := os.Getenv(webhookSecretTokenEnvName)
+	if !secretTokenPattern.MatchString(secretToken) {
+		return nil, fmt.Errorf("%s must be 1-256 characters of A-Z, a-z, 0-9, _ and -", webhookSecretTokenEnvName)
+	}
+
+	listenAddr := os.Getenv(webhookListenAddrEnvName)
+	if listenAddr == "" {
+		listenAddr = ":8443"
+	}
+
+	certFile := os.Getenv(webhookTLSCertEnvName)
+	keyFile := os.Getenv(webhookTLSKeyEnvName)
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("%s and %s must be set together", webhookTLSCertEnvName, webhookTLSKeyEnvName)
+	}
+
+	uploadCert := os.Getenv(webhookUploadCertEnvName) == "true"
+	if uploadCert && certFile == "" {
+		return nil, fmt.Errorf("%s requires %s", webhookUploadCertEnvName, webhookTLSCertEnvName)
+	}
+
+	return &webhookConfig{
+		url:         rawURL,
+		listenAddr:  listenAddr,
+		secretToken: secretToken,
+		tlsCertFile: certFile,
+		tlsKeyFile:  keyFile,
+		uploadCert:  uploadCert,
+	}, nil
+}
+
+func (cfg *webhookConfig) URL() string {
+	return cfg.url
+}
+
+func (cfg *webhookConfig) ListenAddr() string {
+	return cfg.listenAddr
+}
+
+func (cfg *webhookConfig) SecretToken() string {
+	return cfg.secretToken
+}
+
+func (cfg *webhookConfig) TLSCertFile() string {
+	return cfg.tlsCertFile
+}
+
+func (cfg *webhookConfig) TLSKeyFile() string {
+	return cfg.tlsKeyFile
+}
+
+// UploadCert - отправить сертификат в Telegram (нужно для самоподписанного)
+func (cfg *webhookConfig) UploadCert() bool {
+	return cfg.uploadCert
+}
@@ -2,12 +2,17 @@ package bot_handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/router"
+	"github.com/Lina3386/telegram-bot/internal/schedule"
+	"github.com/Lina3386/telegram-bot/internal/sender"
 	"github.com/Lina3386/telegram-bot/internal/services"
 	"github.com/Lina3386/telegram-bot/internal/state"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -18,6 +23,12 @@ const helpText = `📖 Справка по командам:
 /start - Начать работу
 /help - Показать эту справку
 /cancel - Отменить текущее действие
+/currency - Базовая валюта (например /currency USD)
+/rate - Курсы валют (задает администратор: /rate USD 92.5)
+/settings - Настройки и распределение по целям
+/timezone - Часовой пояс (например /timezone Asia/Omsk)
+/report - Доходы: план и факт за месяц (например /report 01.2026)
+/got - Записать нерегулярное поступление (например /got 25000 Фриланс)
 
 📌 Как использовать:
 1️⃣ Нажмите 💳 чтобы добавить доход
@@ -27,84 +38,60 @@ const helpText = `📖 Справка по командам:
 
 💡 Совет: Все действия можно отменить командой /cancel`
 
+// сколько последних операций показывать в истории цели
+const goalHistoryLimit = 20
+
 type BotHandler struct {
-	bot            *tgbotapi.BotAPI
+	bot            *sender.Sender
 	financeService *services.FinanceService
 	authService    *services.AuthService
-	stateManager   *state.StateManager
+	stateManager   state.StateStore
+	scheduler      *services.Scheduler
+	admins         map[int64]bool
 }
 
 func NewBotHandler(
-	bot *tgbotapi.BotAPI,
+	bot *sender.Sender,
 	financeService *services.FinanceService,
 	authService *services.AuthService,
-	stateManager *state.StateManager,
+	stateManager state.StateStore,
+	scheduler *services.Scheduler,
+	admins []int64,
 ) *BotHandler {
+	adminSet := make(map[int64]bool, len(admins))
+	for _, id := range admins {
+		adminSet[id] = true
+	}
+
 	return &BotHandler{
 		bot:            bot,
 		financeService: financeService,
 		authService:    authService,
 		stateManager:   stateManager,
+		scheduler:      scheduler,
+		admins:         adminSet,
 	}
 }
 
-func (h *BotHandler) HandleStart(message *tgbotapi.Message) {
-	userID := message.From.ID
-	username := message.From.UserName
-	if username == "" {
-		username = message.From.FirstName
-	}
-	chatID := message.Chat.ID
+// HandleStart - пользователя к этому моменту уже загрузил или зарегистрировал LoadUser
+func (h *BotHandler) HandleStart(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+	username := displayName(c.From())
 
-	ctx := context.Background()
-	log.Printf("User %d (%s) started the bot", userID, username)
+	c.Logf("User %d (%s) started the bot", userID, username)
+	h.clearState(c)
 
-	// существует ли пользователь
-	existingUser, err := h.financeService.GetUserByTelegramID(ctx, userID)
-	if err == nil && existingUser != nil {
-		// уже существует - просто приветствие
-		log.Printf("User %d already exists, welcome back!", userID)
-		h.stateManager.ClearState(userID)
+	if !c.NewUser {
 		msg := fmt.Sprintf("👋 С возвращением, %s!\n\n"+
 			"Выберите действие:\n\n"+
 			helpText,
 			username,
 		)
-		h.sendMessageWithKeyboard(chatID, msg, h.mainMenu())
-		return
-	}
-
-	token, err := h.authService.RegisterTelegramUser(ctx, userID, username)
-	if err != nil {
-		log.Printf("Failed to register user: %v", err)
-		h.sendMessage(chatID, "Ошибка регистрации. Попробуйте позже.")
+		h.sendMessageWithKeyboard(c, chatID, msg, h.mainMenu())
 		return
 	}
 
-	fmt.Sprintf("User %s registered", username)
-
-	_, err = h.financeService.CreateUser(ctx, userID, username, token)
-	if err != nil {
-		log.Printf("Failed to create user in DB: %v", err)
-		existingUser, checkErr := h.financeService.GetUserByTelegramID(ctx, userID)
-		if checkErr == nil && existingUser != nil {
-			log.Printf("User already exists, continuing...")
-			h.stateManager.ClearState(userID)
-			msg := fmt.Sprintf("👋 Добро пожаловать, %s!\n\n"+
-				"Я помогу вам управлять финансами.\n\n"+
-				"Выберите действие:\n\n"+
-				helpText,
-				username,
-			)
-			h.sendMessageWithKeyboard(chatID, msg, h.mainMenu())
-			return
-		}
-		h.sendMessage(chatID, "Ошибка при сохранении данных.")
-		return
-	}
-
-	h.stateManager.ClearState(userID)
-
 	msg := fmt.Sprintf("👋 Добро пожаловать, %s!\n\n"+
 		"Я помогу вам управлять финансами.\n\n"+
 		"Выберите действие:\n\n"+
@@ -112,350 +99,492 @@ func (h *BotHandler) HandleStart(message *tgbotapi.Message) {
 		username,
 	)
 
-	h.sendMessageWithKeyboard(chatID, msg, h.mainMenu())
+	h.sendMessageWithKeyboard(c, chatID, msg, h.mainMenu())
+	h.showTimezonePicker(c)
 }
 
-func (h *BotHandler) HandleHelp(message *tgbotapi.Message) {
-	h.sendMessage(message.Chat.ID, helpText)
+func (h *BotHandler) HandleHelp(c *router.Context) {
+	h.sendMessage(c, c.ChatID(), helpText)
 }
 
-func (h *BotHandler) HandleCancel(message *tgbotapi.Message) {
-	userID := message.From.ID
-	currentState := h.stateManager.GetState(userID)
+func (h *BotHandler) HandleCancel(c *router.Context) {
+	currentState, err := h.stateManager.GetState(c, c.UserID())
+	if err != nil {
+		c.Logf("[STATE] Failed to get dialog state: %v", err)
+	}
 
-	if currentState == state.StateIdle {
-		h.sendMessage(message.Chat.ID, "ℹ️ Нет активного действия для отмены")
+	if err == nil && currentState == state.StateIdle {
+		h.sendMessage(c, c.ChatID(), "ℹ️ Нет активного действия для отмены")
 		return
 	}
 
-	h.stateManager.ClearState(userID)
-	h.sendMessageWithKeyboard(message.Chat.ID, "❌ Действие отменено. Вернулись в главное меню", h.mainMenu())
+	if err := h.stateManager.ClearState(c, c.UserID()); err != nil {
+		c.Logf("[STATE] Failed to clear dialog: %v", err)
+		h.sendMessage(c, c.ChatID(), "⚠️ Не удалось отменить действие, попробуйте еще раз")
+		return
+	}
+	h.sendMessageWithKeyboard(c, c.ChatID(), "❌ Действие отменено. Вернулись в главное меню", h.mainMenu())
 }
 
-func (h *BotHandler) HandleUnknownCommand(message *tgbotapi.Message) {
-	if strings.HasPrefix(message.Text, "/testpayday") {
-		h.handleTestPaydayCommand(message)
-		return
+func (h *BotHandler) HandleUnknownCommand(c *router.Context) {
+	h.sendMessage(c, c.ChatID(), "❓ Неизвестная команда.\n\nИспользуйте /help для справки")
+}
+
+// handleIdleText - текст, который не относится ни к кнопкам меню, ни к шагу диалога
+func (h *BotHandler) handleIdleText(c *router.Context) {
+	if current, err := h.stateManager.GetState(c, c.UserID()); err == nil && current == state.StateIdle {
+		h.sendMessageWithKeyboard(c, c.ChatID(), "Используйте меню ниже:", h.mainMenu())
 	}
+}
 
-	h.sendMessage(message.Chat.ID, "❓ Неизвестная команда.\n\nИспользуйте /help для справки")
+func (h *BotHandler) handleDone(c *router.Context) {
+	h.clearState(c)
+	h.sendMessageWithKeyboard(c, c.ChatID(), "Операция завершена!", h.mainMenu())
 }
 
-func (h *BotHandler) HandleTextMessage(message *tgbotapi.Message) {
-	userID := message.From.ID
-	chatID := message.Chat.ID
-	text := message.Text
-	ctx := context.Background()
+func (h *BotHandler) handleBack(c *router.Context) {
+	h.clearState(c)
+	h.sendMessageWithKeyboard(c, c.ChatID(), "Вернулись в главное меню", h.mainMenu())
+}
 
-	currentState := h.stateManager.GetState(userID)
+func (h *BotHandler) handleIncomeNameInput(c *router.Context) {
+	chatID := c.ChatID()
+	text := c.Text()
 
-	switch text {
-	case "💳 Мои доходы", "мои доходы", "доходы":
-		h.handleShowIncomes(message)
+	if !h.setTempData(c, "income_name", text) ||
+		!h.setState(c, state.StateAddingIncomeAmount) {
 		return
+	}
+	h.sendMessage(c, chatID, "Введите размер дохода (например 50000 или 1000 USD):")
+}
 
-	case "💰 Мои расходы", "мои расходы", "расходы":
-		h.handleShowExpenses(message)
-		return
+func (h *BotHandler) handleIncomeAmountInput(c *router.Context) {
+	chatID := c.ChatID()
+	text := c.Text()
 
-	case "🍀 Цели", "цели", "цель":
-		h.handleShowGoals(message)
+	amount, currency, ok := h.parseAmountInput(c, text)
+	if !ok {
 		return
-
-	case "📈 Статистика", "📊 Статистика", "статистика", "стата":
-		h.handleShowStats(message)
+	}
+	if !h.setTempData(c, "income_amount", strconv.FormatInt(int64(amount), 10)) ||
+		!h.setTempData(c, "income_currency", currency) ||
+		!h.setState(c, state.StateAddingIncomeFrequency) {
 		return
+	}
+	h.sendMessage(c, chatID, incomeFrequencyPrompt())
+}
 
-	case "✅ Готово", "готово":
-		h.stateManager.ClearState(userID)
-		h.sendMessageWithKeyboard(chatID, "Операция завершена!", h.mainMenu())
-		return
+func (h *BotHandler) handleIncomeFrequencyInput(c *router.Context) {
+	chatID := c.ChatID()
+	text := c.Text()
 
-	case "⬅️ Назад", "назад":
-		h.stateManager.ClearState(userID)
-		h.sendMessageWithKeyboard(chatID, "Вернулись в главное меню", h.mainMenu())
+	session, ok := h.dialogSession(c)
+	if !ok {
 		return
 	}
 
-	switch currentState {
-	case state.StateChangingGoalPriority:
-		h.handlePriorityInput(message)
+	freq, err := strconv.Atoi(text)
+	if err != nil || freq < 1 || freq > len(incomeFrequencyInfos) {
+		h.sendMessage(c, chatID, fmt.Sprintf("❌ Введите число от 1 до %d", len(incomeFrequencyInfos)))
 		return
-
-	case state.StateAddingIncome:
-		h.stateManager.SetTempData(userID, "income_name", text)
-		h.stateManager.SetState(userID, state.StateAddingIncomeAmount)
-		h.sendMessage(chatID, "Введите размер дохода (в рублях):")
-
-	case state.StateAddingIncomeAmount:
-		amount, err := strconv.ParseInt(text, 10, 64)
-		if err != nil || amount <= 0 {
-			h.sendMessage(chatID, "❌ Введите корректное число")
-			return
-		}
-		h.stateManager.SetTempData(userID, "income_amount", text)
-		h.stateManager.SetState(userID, state.StateAddingIncomeFrequency)
-		h.sendMessage(chatID, "Выберите частоту получения дохода:\n\n1️⃣ Ежемесячно (monthly)\n2️⃣ Еженедельно (weekly)\n3️⃣ Через неделю (biweekly)\n\nВведите число от 1 до 3:")
-
-	case state.StateAddingIncomeFrequency:
-		freq, _ := strconv.Atoi(text)
-		var frequency string
-		var prompt string
-		switch freq {
-		case 1:
-			frequency = "monthly"
-			prompt = "Введите день месяца для получения дохода (1-31):"
-		case 2:
-			frequency = "weekly"
-			prompt = "Введите день недели для получения дохода (0=воскресенье, 1=понедельник, ..., 6=суббота):"
-		case 3:
-			frequency = "biweekly"
-			prompt = "Введите день недели для получения дохода (0=воскресенье, 1=понедельник, ..., 6=суббота):"
-		default:
-			h.sendMessage(chatID, "❌ Введите число от 1 до 3")
+	}
+	info := incomeFrequencyInfos[freq-1]
+	editing := session.Get("edit_income_id") != ""
+	if info.frequency == models.IncomeFrequencyIrregular && !editing {
+		h.createIrregularIncome(c)
+		return
+	}
+	if !h.setTempData(c, "income_frequency", info.frequency) {
+		return
+	}
+	if info.prompt == "" && editing {
+		// ни дня, ни переноса спрашивать не нужно - сохраняем сразу
+		h.saveIncomeScheduleEdit(c)
+		return
+	}
+	if info.prompt == "" {
+		// последний рабочий день переносить некуда
+		if !h.setState(c, state.StateAddingIncomeHour) {
 			return
 		}
-		h.stateManager.SetTempData(userID, "income_frequency", frequency)
-		h.stateManager.SetState(userID, state.StateAddingIncomeDay)
-		h.sendMessage(chatID, prompt)
-
-	case state.StateAddingIncomeDay:
-		recurringDay, err := strconv.Atoi(text)
-		frequency := h.stateManager.GetTempData(userID, "income_frequency")
-		if frequency == "" {
-			frequency = "monthly"
-		}
+		h.sendMessage(c, chatID, "В каком часу получать уведомления? (0-23, например 9 для 9:00, 18 для 18:00)\n\nПо умолчанию: 18:00")
+		return
+	}
+	if !h.setState(c, state.StateAddingIncomeDay) {
+		return
+	}
+	h.sendMessage(c, chatID, info.prompt)
+}
+
+func (h *BotHandler) handleIncomeDayInput(c *router.Context) {
+	chatID := c.ChatID()
+	text := c.Text()
+
+	session, ok := h.dialogSession(c)
+	if !ok {
+		return
+	}
 
-		if frequency == "monthly" && (err != nil || recurringDay < 1 || recurringDay > 31) {
-			h.sendMessage(chatID, "❌ Введите число от 1 до 31")
+	if session.Get("income_frequency") == models.IncomeFrequencyInstallments {
+		totalMinor, _ := strconv.ParseInt(session.Get("income_amount"), 10, 64)
+		installments, errText, ok := parseInstallments(text, models.Money(totalMinor), session.Get("income_currency"))
+		if !ok {
+			h.sendMessage(c, chatID, errText)
 			return
 		}
-		if (frequency == "weekly" || frequency == "biweekly") && (err != nil || recurringDay < 0 || recurringDay > 6) {
-			h.sendMessage(chatID, "❌ Введите число от 0 до 6 (день недели)")
+		if !h.setTempData(c, "income_installments", encodeInstallments(installments)) ||
+			!h.setState(c, state.StateAddingIncomeShift) {
 			return
 		}
+		h.sendMessage(c, chatID, incomeShiftPrompt())
+		return
+	}
 
-		h.stateManager.SetTempData(userID, "income_recurring_day", text)
-		h.stateManager.SetState(userID, state.StateAddingIncomeHour)
-		h.sendMessage(chatID, "В каком часу получать уведомления? (0-23, например 9 для 9:00, 18 для 18:00)\n\nПо умолчанию: 18:00")
+	kind := schedule.Kind(session.Get("income_frequency"))
+	rule, errText, ok := parseIncomeScheduleDay(kind, text, c.User.Now())
+	if !ok {
+		h.sendMessage(c, chatID, errText)
+		return
+	}
 
-	case state.StateAddingIncomeHour:
-		notificationHour, err := strconv.Atoi(text)
-		if err != nil || notificationHour < 0 || notificationHour > 23 {
-			h.sendMessage(chatID, "❌ Введите число от 0 до 23")
-			return
-		}
+	if !h.saveIncomeRuleToDialog(c, rule) ||
+		!h.setState(c, state.StateAddingIncomeShift) {
+		return
+	}
+	h.sendMessage(c, chatID, incomeShiftPrompt())
+}
 
-		incomeName := h.stateManager.GetTempData(userID, "income_name")
-		incomeAmount, _ := strconv.ParseInt(h.stateManager.GetTempData(userID, "income_amount"), 10, 64)
-		frequency := h.stateManager.GetTempData(userID, "income_frequency")
-		recurringDay, _ := strconv.Atoi(h.stateManager.GetTempData(userID, "income_recurring_day"))
+func (h *BotHandler) handleIncomeShiftInput(c *router.Context) {
+	chatID := c.ChatID()
+	text := c.Text()
 
-		nextPayDate := h.calculateNextPayDate(frequency, recurringDay)
+	session, ok := h.dialogSession(c)
+	if !ok {
+		return
+	}
 
-		_, err = h.financeService.CreateIncomeWithFrequencyAndHour(ctx, userID, incomeName, incomeAmount, frequency, recurringDay, notificationHour, nextPayDate)
-		if err != nil {
-			log.Printf("Failed to create income: %v", err)
-			h.sendMessage(chatID, "❌ Ошибка при сохранении дохода")
-			return
-		}
+	choice, err := strconv.Atoi(text)
+	if err != nil || choice < 1 || choice > len(incomeShiftInfos) {
+		h.sendMessage(c, chatID, fmt.Sprintf("❌ Введите число от 1 до %d", len(incomeShiftInfos)))
+		return
+	}
+	if !h.setTempData(c, "income_shift", string(incomeShiftInfos[choice-1].shift)) {
+		return
+	}
+	if session.Get("edit_income_id") != "" {
+		// у существующего дохода час уведомлений не меняется
+		h.saveIncomeScheduleEdit(c)
+		return
+	}
+	if !h.setState(c, state.StateAddingIncomeHour) {
+		return
+	}
+	h.sendMessage(c, chatID, "В каком часу получать уведомления? (0-23, например 9 для 9:00, 18 для 18:00)\n\nПо умолчанию: 18:00")
+}
 
-		fmt.Sprintf("%s: %d₽ (%s day %d, notify at %d:00)", incomeName, incomeAmount, frequency, recurringDay, notificationHour)
+func (h *BotHandler) handleIncomeHourInput(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+	text := c.Text()
 
-		h.stateManager.ClearState(userID)
+	session, ok := h.dialogSession(c)
+	if !ok {
+		return
+	}
 
-		dayDesc := fmt.Sprintf("число %d", recurringDay)
-		if frequency == "weekly" || frequency == "biweekly" {
-			weeks := map[int]string{0: "воскресенье", 1: "понедельник", 2: "вторник", 3: "среда", 4: "четверг", 5: "пятница", 6: "суббота"}
-			dayDesc = weeks[recurringDay]
-		}
+	notificationHour, err := strconv.Atoi(text)
+	if err != nil || notificationHour < 0 || notificationHour > 23 {
+		h.sendMessage(c, chatID, "❌ Введите число от 0 до 23")
+		return
+	}
 
-		freqDesc := map[string]string{
-			"monthly":  "ежемесячно",
-			"weekly":   "еженедельно",
-			"biweekly": "через неделю",
-		}[frequency]
+	incomeName := session.Get("income_name")
+	incomeAmountMinor, _ := strconv.ParseInt(session.Get("income_amount"), 10, 64)
+	incomeAmount := models.Money(incomeAmountMinor)
+	incomeCurrency := session.Get("income_currency")
+	rule := incomeRuleFromDialog(session)
+
+	var income *models.Income
+	if string(rule.Kind) == models.IncomeFrequencyInstallments {
+		installments := decodeInstallments(session.Get("income_installments"))
+		income, err = h.financeService.CreateInstallmentIncome(c, userID, incomeName, incomeAmount, incomeCurrency, installments, rule.Shift, notificationHour)
+	} else {
+		income, err = h.financeService.CreateScheduledIncome(c, userID, incomeName, incomeAmount, incomeCurrency, rule, notificationHour)
+	}
+	if errors.Is(err, services.ErrNoExchangeRate) {
+		h.sendMessage(c, chatID, fmt.Sprintf("❌ Для валюты %s не задан курс. Курсы задает администратор бота (/rate %s 92.5), после этого добавьте доход заново", incomeCurrency, incomeCurrency))
+		h.clearState(c)
+		return
+	}
+	if err != nil {
+		c.Logf("Failed to create income: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при сохранении дохода")
+		return
+	}
 
-		h.sendMessageWithKeyboard(
-			chatID,
-			fmt.Sprintf("✅ Доход добавлен:\n%s: %d₽ (%s, %s)\n🔔 Уведомления в %d:00", incomeName, incomeAmount, freqDesc, dayDesc, notificationHour),
-			h.mainMenu(),
-		)
+	h.clearState(c)
 
-	case state.StateAddingExpense:
-		h.stateManager.SetTempData(userID, "expense_name", text)
-		h.stateManager.SetState(userID, state.StateAddingExpenseAmount)
-		h.sendMessage(chatID, "Введите размер расхода (в рублях):")
+	h.sendMessageWithKeyboard(c,
+		chatID,
+		fmt.Sprintf("✅ Доход добавлен:\n%s: %s (%s)\n📅 Ближайшая выплата: %s\n🔔 Уведомления в %d:00",
+			incomeName, models.FormatAmount(income.Amount, income.Currency), incomeScheduleText(*income), income.NextPayDate.Format("02.01.2006"), notificationHour),
+		h.mainMenu(),
+	)
+}
 
-	case state.StateAddingExpenseAmount:
-		amount, err := strconv.ParseInt(text, 10, 64)
-		if err != nil || amount <= 0 {
-			h.sendMessage(chatID, "❌ Введите корректное число")
-			return
-		}
+func (h *BotHandler) handleExpenseNameInput(c *router.Context) {
+	chatID := c.ChatID()
+	text := c.Text()
 
-		expenseName := h.stateManager.GetTempData(userID, "expense_name")
+	if !h.setTempData(c, "expense_name", text) ||
+		!h.setState(c, state.StateAddingExpenseAmount) {
+		return
+	}
+	h.sendMessage(c, chatID, "Введите размер расхода (например 1500 или 20 EUR):")
+}
 
-		_, err = h.financeService.CreateExpense(ctx, userID, expenseName, amount)
-		if err != nil {
-			log.Printf("Failed to create expense: %v", err)
-			h.sendMessage(chatID, "❌ Ошибка при сохранении расхода")
-			return
-		}
+func (h *BotHandler) handleExpenseAmountInput(c *router.Context) {
+	chatID := c.ChatID()
+	text := c.Text()
 
-		fmt.Sprintf("%s: %d₽", expenseName, amount)
+	amount, currency, ok := h.parseAmountInput(c, text)
+	if !ok {
+		return
+	}
 
-		h.stateManager.ClearState(userID)
-		h.sendMessageWithKeyboard(
-			chatID,
-			fmt.Sprintf("✅ Расход добавлен:\n%s: %d₽", expenseName, amount),
-			h.mainMenu(),
-		)
+	if !h.setTempData(c, "expense_amount", strconv.FormatInt(int64(amount), 10)) ||
+		!h.setTempData(c, "expense_currency", currency) ||
+		!h.setState(c, state.StateAddingExpenseKind) {
+		return
+	}
+	h.sendMessage(c, chatID, "Какой это расход?\n\n1️⃣ Регулярный (аренда, подписки, проезд)\n2️⃣ Разовый (покупка)\n\nВведите 1 или 2:")
+}
 
-	case state.StateCreatingGoal:
-		h.stateManager.SetTempData(userID, "goal_name", text)
-		h.stateManager.SetState(userID, state.StateCreatingGoalTarget)
-		h.sendMessage(chatID, "Введите целевую сумму (в рублях):")
+func (h *BotHandler) handleExpenseKindInput(c *router.Context) {
+	chatID := c.ChatID()
+	text := c.Text()
 
-	case state.StateCreatingGoalTarget:
-		targetAmount, err := strconv.ParseInt(text, 10, 64)
-		if err != nil || targetAmount <= 0 {
-			h.sendMessage(chatID, "❌ Введите корректную сумму")
+	switch text {
+	case "1":
+		if !h.setTempData(c, "expense_kind", models.ExpenseKindRecurring) ||
+			!h.setState(c, state.StateAddingExpenseFrequency) {
 			return
 		}
-		h.stateManager.SetTempData(userID, "goal:target", text)
-
-		allGoals, err := h.financeService.GetUserGoals(ctx, userID)
-		if err != nil {
-			h.sendMessage(chatID, "❌ Ошибка при получении списка целей")
-			h.stateManager.ClearState(userID)
+		h.sendMessage(c, chatID, expenseFrequencyPrompt)
+	case "2":
+		if !h.setTempData(c, "expense_kind", models.ExpenseKindOneOff) ||
+			!h.setState(c, state.StateAddingExpenseDate) {
 			return
 		}
+		h.sendMessage(c, chatID, "Когда была покупка? Введите дату в формате ДД.ММ.ГГГГ или \"сегодня\":")
+	default:
+		h.sendMessage(c, chatID, "❌ Введите 1 или 2")
+	}
+}
 
-		maxPriority := 0
-		for _, g := range allGoals {
-			if g.Status == "active" && g.Priority > maxPriority {
-				maxPriority = int(g.Priority)
-			}
-		}
-		newPriority := maxPriority + 1
+func (h *BotHandler) handleExpenseFrequencyInput(c *router.Context) {
+	chatID := c.ChatID()
+	text := c.Text()
 
-		goalName := h.stateManager.GetTempData(userID, "goal_name")
-		goal, err := h.financeService.CreateGoal(ctx, userID, goalName, targetAmount, newPriority)
-		if err != nil {
-			log.Printf("Failed to create goal: %v", err)
-			h.sendMessage(chatID, "❌ Ошибка создания цели")
-			return
-		}
+	var frequency, prompt string
+	switch text {
+	case "1":
+		frequency = "monthly"
+		prompt = "Введите день месяца для списания (1-31):"
+	case "2":
+		frequency = "weekly"
+		prompt = "Введите день недели для списания (0=воскресенье, 1=понедельник, ..., 6=суббота):"
+	case "3":
+		frequency = "biweekly"
+		prompt = "Введите день недели для списания (0=воскресенье, 1=понедельник, ..., 6=суббота):"
+	default:
+		h.sendMessage(c, chatID, "❌ Введите число от 1 до 3")
+		return
+	}
+	if !h.setTempData(c, "expense_frequency", frequency) ||
+		!h.setState(c, state.StateAddingExpenseDay) {
+		return
+	}
+	h.sendMessage(c, chatID, prompt)
+}
 
-		var priorityText string
-		if newPriority == 1 {
-			priorityText = "Наивысший"
-		} else if newPriority == 2 {
-			priorityText = "Высокий"
-		} else if newPriority == 3 {
-			priorityText = "Низкий"
-		} else {
-			priorityText = fmt.Sprintf("Приоритет %d", newPriority)
-		}
+func (h *BotHandler) handleExpenseDayInput(c *router.Context) {
+	chatID := c.ChatID()
+	text := c.Text()
 
-		fmt.Sprintf("%s (цель: %d, приоритет: %s)", goalName, targetAmount, priorityText)
+	session, ok := h.dialogSession(c)
+	if !ok {
+		return
+	}
 
-		timeToGoal := h.calculateTimeToGoal(targetAmount, goal.MonthlyContrib, 0)
-		h.stateManager.ClearState(userID)
-		h.sendMessageWithKeyboard(chatID, fmt.Sprintf("✅ Цель создана:\n📌 %s\n💰 Сумма: %d₽\n📅 Ежемесячно: %d₽\n⚡ Приоритет: %s (%d)\n⏱ Время до цели: %s\n📆 Дата достижения: %s", goalName, targetAmount, goal.MonthlyContrib, priorityText, newPriority, timeToGoal, goal.TargetDate.Format("02.01.2006")), h.mainMenu())
+	recurringDay, err := strconv.Atoi(text)
+	frequency := session.Get("expense_frequency")
+
+	if frequency == "monthly" && (err != nil || recurringDay < 1 || recurringDay > 31) {
+		h.sendMessage(c, chatID, "❌ Введите число от 1 до 31")
+		return
+	}
+	if frequency != "monthly" && (err != nil || recurringDay < 0 || recurringDay > 6) {
+		h.sendMessage(c, chatID, "❌ Введите число от 0 до 6 (день недели)")
 		return
+	}
 
-	case state.StateWithdrawingFromGoal:
-		amount, err := strconv.ParseInt(text, 10, 64)
-		if err != nil || amount <= 0 {
-			h.sendMessage(chatID, "❌ Введите корректное число")
-			return
-		}
+	if session.Get("edit_expense_id") != "" {
+		h.saveExpenseScheduleEdit(c, recurringDay)
+		return
+	}
 
-		goalIDStr := h.stateManager.GetTempData(userID, "withdraw_goal_id")
-		goalID, err := strconv.ParseInt(goalIDStr, 10, 64)
-		if err != nil {
-			h.sendMessage(chatID, "❌ Ошибка")
-			h.stateManager.ClearState(userID)
-			return
-		}
+	if !h.setTempData(c, "expense_recurring_day", text) {
+		return
+	}
+	h.askExpenseCategory(c)
+}
 
-		goal, err := h.financeService.WithdrawFromGoal(ctx, goalID, amount)
-		if err != nil {
-			log.Printf("Failed to withdraw from goal: %v", err)
-			h.sendMessage(chatID, "❌ Ошибка при вычитании")
-			h.stateManager.ClearState(userID)
-			return
-		}
+func (h *BotHandler) handleExpenseDateInput(c *router.Context) {
+	chatID := c.ChatID()
+	text := c.Text()
 
-		fmt.Sprintf("%s: -%d₽ (remaining: %d₽)", goal.GoalName, amount, goal.CurrentAmount)
+	spentAt, ok := parseExpenseDate(text, c.User.Now())
+	if !ok {
+		h.sendMessage(c, chatID, "❌ Введите дату в формате ДД.ММ.ГГГГ или \"сегодня\"")
+		return
+	}
 
-		progress := int64(0)
-		if goal.TargetAmount > 0 {
-			progress = (goal.CurrentAmount * 100) / goal.TargetAmount
-		}
+	if !h.setTempData(c, "expense_spent_at", spentAt.Format("2006-01-02")) {
+		return
+	}
+	h.askExpenseCategory(c)
+}
 
-		h.stateManager.ClearState(userID)
+func (h *BotHandler) handleGoalNameInput(c *router.Context) {
+	chatID := c.ChatID()
+	text := c.Text()
 
-		// Кнопка вернуться к цели
-		backToGoalBtn := tgbotapi.NewInlineKeyboardButtonData("🔙 Вернуться к цели", fmt.Sprintf("select_goal_%d", goal.ID))
+	if !h.setTempData(c, "goal_name", text) ||
+		!h.setState(c, state.StateCreatingGoalTarget) {
+		return
+	}
+	h.sendMessage(c, chatID, "Введите целевую сумму (например 300000 или 5000 USD):")
+}
 
-		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
-			"✅ Вычтено %d₽\n\n🎯 %s\nОсталось: %d₽ / %d₽ (%d%%)",
-			amount, goal.GoalName, goal.CurrentAmount, goal.TargetAmount, progress))
+func (h *BotHandler) handleGoalTargetInput(c *router.Context) {
+	chatID := c.ChatID()
+	text := c.Text()
 
-		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup([]tgbotapi.InlineKeyboardButton{backToGoalBtn})
-		h.bot.Send(msg)
-	case state.StateAddingContribution:
-		amount, err := strconv.ParseInt(text, 10, 64)
-		if err != nil || amount <= 0 {
-			h.sendMessage(chatID, "❌ Введите корректное число")
-			return
-		}
+	targetAmount, currency, ok := h.parseAmountInput(c, text)
+	if !ok {
+		return
+	}
+	if !h.setTempData(c, "goal:target", strconv.FormatInt(int64(targetAmount), 10)) ||
+		!h.setTempData(c, "goal:currency", currency) ||
+		!h.setState(c, state.StateCreatingGoalDeadline) {
+		return
+	}
+	h.sendMessage(c, chatID, "К какому сроку нужно накопить? Введите дату (например 01.06.2026 или 06.2026) или \"нет\", если срока нет:")
+}
 
-		goalIDStr := h.stateManager.GetTempData(userID, "contribute_goal_id")
-		goalID, _ := strconv.ParseInt(goalIDStr, 10, 64)
+func (h *BotHandler) handleGoalDeadlineInput(c *router.Context) {
+	chatID := c.ChatID()
+	text := c.Text()
 
-		goal, err := h.financeService.ContributeToGoal(ctx, goalID, amount)
-		if err != nil {
-			log.Printf("Failed to contribute to goal: %v", err)
-			h.sendMessage(chatID, "❌ Ошибка при добавлении")
-			return
-		}
+	deadline, ok := parseGoalDeadline(text, c.User.Now())
+	if !ok {
+		h.sendMessage(c, chatID, "❌ Введите будущую дату в формате ДД.ММ.ГГГГ или ММ.ГГГГ, либо \"нет\"")
+		return
+	}
+	h.createGoalFromDialog(c, deadline)
+}
 
-		progress := int64(0)
-		if goal.TargetAmount > 0 {
-			progress = (goal.CurrentAmount * 100) / goal.TargetAmount
-		}
+func (h *BotHandler) handleWithdrawInput(c *router.Context) {
+	chatID := c.ChatID()
+	text := c.Text()
 
-		statusText := "✅ Добавлено!"
-		if goal.Status == "completed" {
-			statusText = "🎉 Цель достигнута!"
-		}
+	session, ok := h.dialogSession(c)
+	if !ok {
+		return
+	}
 
-		fmt.Sprintf("%s: +%d₽ (total: %d₽)", goal.GoalName, amount, goal.CurrentAmount)
+	amount, err := models.ParseMoney(text)
+	if err != nil || amount <= 0 {
+		h.sendMessage(c, chatID, "❌ Введите корректную сумму, например 1500 или 1 499,90")
+		return
+	}
+
+	goalIDStr := session.Get("withdraw_goal_id")
+	goalID, err := strconv.ParseInt(goalIDStr, 10, 64)
+	if err != nil {
+		h.sendMessage(c, chatID, "❌ Ошибка")
+		h.clearState(c)
+		return
+	}
+
+	goal, err := h.financeService.WithdrawFromGoal(c, goalID, amount)
+	if err != nil {
+		c.Logf("Failed to withdraw from goal: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при вычитании")
+		h.clearState(c)
+		return
+	}
 
-		h.stateManager.ClearState(userID)
+	progress := goal.CurrentAmount.Percent(goal.TargetAmount)
 
-		backToGoalBtn := tgbotapi.NewInlineKeyboardButtonData("🔙 Вернуться к цели", fmt.Sprintf("select_goal_%d", goal.ID))
+	h.clearState(c)
 
-		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
-			"%s\n\n🎯 %s\nСобрано: %d₽ / %d₽ (%d%%)",
-			statusText, goal.GoalName, goal.CurrentAmount, goal.TargetAmount, progress))
+	// Кнопка вернуться к цели
+	backToGoalBtn := tgbotapi.NewInlineKeyboardButtonData("🔙 Вернуться к цели", fmt.Sprintf("goal/%d", goal.ID))
 
-		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup([]tgbotapi.InlineKeyboardButton{backToGoalBtn})
-		h.bot.Send(msg)
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"✅ Вычтено %s\n\n🎯 %s\nОсталось: %s / %s (%d%%)",
+		models.FormatAmount(amount, goal.Currency), goal.GoalName,
+		models.FormatAmount(goal.CurrentAmount, goal.Currency), models.FormatAmount(goal.TargetAmount, goal.Currency), progress))
 
-	case state.StatePaydayEnteringAmount:
-		h.handlePaydayAmountInput(message)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup([]tgbotapi.InlineKeyboardButton{backToGoalBtn})
+	h.bot.Send(c, msg)
+}
+
+func (h *BotHandler) handleContributionInput(c *router.Context) {
+	chatID := c.ChatID()
+	text := c.Text()
+
+	session, ok := h.dialogSession(c)
+	if !ok {
 		return
+	}
 
-	default:
-		if currentState == state.StateIdle {
-			h.sendMessageWithKeyboard(chatID, "Используйте меню ниже:", h.mainMenu())
-		}
+	amount, err := models.ParseMoney(text)
+	if err != nil || amount <= 0 {
+		h.sendMessage(c, chatID, "❌ Введите корректную сумму, например 1500 или 1 499,90")
+		return
 	}
+
+	goalIDStr := session.Get("contribute_goal_id")
+	goalID, _ := strconv.ParseInt(goalIDStr, 10, 64)
+
+	goal, err := h.financeService.ContributeToGoal(c, goalID, amount)
+	if err != nil {
+		c.Logf("Failed to contribute to goal: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при добавлении")
+		return
+	}
+
+	progress := goal.CurrentAmount.Percent(goal.TargetAmount)
+
+	statusText := "✅ Добавлено!"
+	if goal.Status == "completed" {
+		statusText = "🎉 Цель достигнута!"
+	}
+
+	h.clearState(c)
+
+	backToGoalBtn := tgbotapi.NewInlineKeyboardButtonData("🔙 Вернуться к цели", fmt.Sprintf("goal/%d", goal.ID))
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"%s\n\n🎯 %s\nСобрано: %s / %s (%d%%)",
+		statusText, goal.GoalName,
+		models.FormatAmount(goal.CurrentAmount, goal.Currency), models.FormatAmount(goal.TargetAmount, goal.Currency), progress))
+
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup([]tgbotapi.InlineKeyboardButton{backToGoalBtn})
+	h.bot.Send(c, msg)
 }
 
 func (h *BotHandler) mainMenu() tgbotapi.ReplyKeyboardMarkup {
@@ -468,12 +597,15 @@ func (h *BotHandler) mainMenu() tgbotapi.ReplyKeyboardMarkup {
 			tgbotapi.NewKeyboardButton("🍀 Цели"),
 			tgbotapi.NewKeyboardButton("📈 Статистика"),
 		),
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("⚙️ Настройки"),
+		),
 	)
 }
 
-func (h *BotHandler) sendMessage(chatID int64, text string) error {
+func (h *BotHandler) sendMessage(ctx context.Context, chatID int64, text string) error {
 	msg := tgbotapi.NewMessage(chatID, text)
-	_, err := h.bot.Send(msg)
+	_, err := h.bot.Send(ctx, msg)
 	if err != nil {
 		log.Printf("Failed to send message to %d: %v", chatID, err)
 		return err
@@ -482,13 +614,14 @@ func (h *BotHandler) sendMessage(chatID int64, text string) error {
 }
 
 func (h *BotHandler) sendMessageWithKeyboard(
+	ctx context.Context,
 	chatID int64,
 	text string,
 	keyboard tgbotapi.ReplyKeyboardMarkup,
 ) error {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = keyboard
-	_, err := h.bot.Send(msg)
+	_, err := h.bot.Send(ctx, msg)
 	if err != nil {
 		log.Printf("Failed to send message with keyboard to %d: %v", chatID, err)
 		return err
@@ -496,12 +629,56 @@ func (h *BotHandler) sendMessageWithKeyboard(
 	return nil
 }
 
-func (h *BotHandler) answerCallback(callbackQueryID, text string) {
+func (h *BotHandler) answerCallback(ctx context.Context, callbackQueryID, text string) {
 	callback := tgbotapi.NewCallback(callbackQueryID, text)
-	h.bot.Request(callback)
+	h.bot.Request(ctx, callback)
+}
+
+// setState и setTempData сохраняют шаг диалога. Если сохранить не удалось, диалог прерывается
+// с сообщением пользователю и возвращается false - обработчик должен сразу вернуться
+func (h *BotHandler) setState(c *router.Context, s state.DialogState) bool {
+	return h.dialogOK(c, h.stateManager.SetState(c, c.UserID(), s))
+}
+
+func (h *BotHandler) setTempData(c *router.Context, key, value string) bool {
+	return h.dialogOK(c, h.stateManager.SetTempData(c, c.UserID(), key, value))
+}
+
+// dialogSession - сессия с ответами на предыдущие шаги; без нее продолжать диалог нельзя
+func (h *BotHandler) dialogSession(c *router.Context) (*state.UserSession, bool) {
+	session, err := h.stateManager.GetSession(c, c.UserID())
+	if !h.dialogOK(c, err) {
+		return nil, false
+	}
+	return session, true
+}
+
+func (h *BotHandler) dialogOK(c *router.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	c.Logf("[STATE] Dialog interrupted: %v", err)
+	if err := h.stateManager.ClearState(c, c.UserID()); err != nil {
+		c.Logf("[STATE] Failed to clear dialog: %v", err)
+	}
+	h.sendMessageWithKeyboard(c, c.ChatID(), "⚠️ Не удалось сохранить ответ, действие прервано. Начните его заново из меню", h.mainMenu())
+	return false
 }
 
-func (h *BotHandler) calculateTimeToGoal(targetAmount, monthlyContrib, currentAmount int64) string {
+// clearState завершает диалог; если сброс не записался, следующий текст попадет в старый шаг
+func (h *BotHandler) clearState(c *router.Context) {
+	if err := h.stateManager.ClearState(c, c.UserID()); err != nil {
+		c.Logf("[STATE] Failed to clear dialog: %v", err)
+		h.sendMessage(c, c.ChatID(), "⚠️ Не удалось завершить действие. Если бот ответит невпопад, отправьте /cancel")
+	}
+}
+
+// handleStateError - текст пришел, но шаг диалога прочитать не удалось
+func (h *BotHandler) handleStateError(c *router.Context) {
+	h.sendMessage(c, c.ChatID(), "⚠️ Не удалось обработать сообщение, попробуйте отправить его еще раз")
+}
+
+func (h *BotHandler) calculateTimeToGoal(targetAmount, monthlyContrib, currentAmount models.Money) string {
 	remaining := targetAmount - currentAmount
 	if remaining <= 0 {
 		return "Цель достигнута! 🎉"
@@ -511,14 +688,14 @@ func (h *BotHandler) calculateTimeToGoal(targetAmount, monthlyContrib, currentAm
 		return "Недостаточно средств для накопления"
 	}
 
-	months := remaining / monthlyContrib
+	months := int64(remaining / monthlyContrib)
 	if remaining%monthlyContrib > 0 {
 		months++
 	}
 
 	years := months / 12
 	months = months % 12
-	days := (remaining % monthlyContrib) * 30 / monthlyContrib
+	days := (remaining % monthlyContrib).MulDiv(30, int64(monthlyContrib))
 
 	var parts []string
 	if years > 0 {
@@ -546,7 +723,7 @@ func (h *BotHandler) calculateTimeToGoal(targetAmount, monthlyContrib, currentAm
 		} else if days >= 2 && days <= 4 {
 			dayWord = "дня"
 		}
-		parts = append(parts, fmt.Sprintf("%d %s", days, dayWord))
+		parts = append(parts, fmt.Sprintf("%d %s", int64(days), dayWord))
 	}
 
 	if len(parts) == 0 {
@@ -557,29 +734,32 @@ func (h *BotHandler) calculateTimeToGoal(targetAmount, monthlyContrib, currentAm
 }
 
 // изменение приоритета
-func (h *BotHandler) handleChangePriority(userID int64, chatID int64, goalID int64) {
-	ctx := context.Background()
+func (h *BotHandler) handleChangePriority(c *router.Context, goalID int64) {
+	userID := c.UserID()
+	chatID := c.ChatID()
 
-	goals, err := h.financeService.GetUserGoals(ctx, userID)
+	goals, err := h.financeService.GetUserGoals(c, userID)
 	if err != nil {
-		h.sendMessage(chatID, "❌ Ошибка при загрузке целей")
+		h.sendMessage(c, chatID, "❌ Ошибка при загрузке целей")
 		return
 	}
 
 	activeGoalsCount := 0
 	for _, goal := range goals {
-		if goal.Status == "active" {
+		if goal.HoldsPriority() {
 			activeGoalsCount++
 		}
 	}
 
 	if activeGoalsCount <= 1 {
-		h.sendMessage(chatID, "ℹ️ Невозможно изменить приоритет: требуется минимум 2 активные цели")
+		h.sendMessage(c, chatID, "ℹ️ Невозможно изменить приоритет: требуется минимум 2 активные цели")
 		return
 	}
 
-	h.stateManager.SetTempData(userID, "change_priority_goal_id", fmt.Sprintf("%d", goalID))
-	h.stateManager.SetState(userID, state.StateChangingGoalPriority)
+	if !h.setTempData(c, "change_priority_goal_id", fmt.Sprintf("%d", goalID)) ||
+		!h.setState(c, state.StateChangingGoalPriority) {
+		return
+	}
 
 	text := fmt.Sprintf(
 		"🔀 <b>Изменение приоритета</b>\n\n"+
@@ -593,59 +773,63 @@ func (h *BotHandler) handleChangePriority(userID int64, chatID int64, goalID int
 
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "HTML"
-	h.bot.Send(msg)
+	h.bot.Send(c, msg)
 }
 
-func (h *BotHandler) handlePriorityInput(message *tgbotapi.Message) {
-	userID := message.From.ID
-	chatID := message.Chat.ID
-	ctx := context.Background()
+func (h *BotHandler) handlePriorityInput(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	session, ok := h.dialogSession(c)
+	if !ok {
+		return
+	}
 
-	newPriority, err := strconv.Atoi(message.Text)
+	newPriority, err := strconv.Atoi(c.Text())
 	if err != nil {
-		h.sendMessage(chatID, "❌ Введите корректное число")
+		h.sendMessage(c, chatID, "❌ Введите корректное число")
 		return
 	}
 
-	goalIDStr := h.stateManager.GetTempData(userID, "change_priority_goal_id")
+	goalIDStr := session.Get("change_priority_goal_id")
 	goalID, _ := strconv.ParseInt(goalIDStr, 10, 64)
 
-	goals, err := h.financeService.GetUserGoals(ctx, userID)
+	goals, err := h.financeService.GetUserGoals(c, userID)
 	if err != nil {
-		h.sendMessage(chatID, "❌ Ошибка при загрузке целей")
-		h.stateManager.ClearState(userID)
+		h.sendMessage(c, chatID, "❌ Ошибка при загрузке целей")
+		h.clearState(c)
 		return
 	}
 
 	activeGoalsCount := 0
 	for _, goal := range goals {
-		if goal.Status == "active" {
+		if goal.HoldsPriority() {
 			activeGoalsCount++
 		}
 	}
 
 	if newPriority < 1 || newPriority > activeGoalsCount {
-		h.sendMessage(chatID, fmt.Sprintf("❌ Введите число от 1 до %d", activeGoalsCount))
+		h.sendMessage(c, chatID, fmt.Sprintf("❌ Введите число от 1 до %d", activeGoalsCount))
 		return
 	}
 
-	err = h.financeService.SwapGoalPriorities(ctx, userID, goalID, newPriority)
+	err = h.financeService.SwapGoalPriorities(c, userID, goalID, newPriority)
 	if err != nil {
-		log.Printf("Failed to swap priorities: %v", err)
-		h.sendMessage(chatID, "❌ Ошибка при изменении приоритета")
-		h.stateManager.ClearState(userID)
+		c.Logf("Failed to swap priorities: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при изменении приоритета")
+		h.clearState(c)
 		return
 	}
 
-	_, err = h.financeService.DistributeFundsToGoals(ctx, userID)
+	_, err = h.financeService.DistributeFundsToGoals(c, userID)
 	if err != nil {
-		log.Printf("Failed to redistribute funds: %v", err)
+		c.Logf("Failed to redistribute funds: %v", err)
 	}
 
-	h.stateManager.ClearState(userID)
+	h.clearState(c)
 
-	h.sendMessage(chatID, fmt.Sprintf("✅ Приоритет изменен на %d\n\nБюджет пересчитан в соответствии с новыми приоритетами", newPriority))
+	h.sendMessage(c, chatID, fmt.Sprintf("✅ Приоритет изменен на %d\n\nБюджет пересчитан в соответствии с новыми приоритетами", newPriority))
 
 	time.Sleep(500 * time.Millisecond)
-	h.showGoalDetailsV2(userID, chatID, goalID)
+	h.showGoalDetailsV2(c, goalID)
 }
@@ -1,403 +1,281 @@
 package bot_handler
 
 import (
-	"context"
 	"fmt"
-	"github.com/Lina3386/telegram-bot/internal/state"
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"log"
 	"strconv"
-	"strings"
-)
-
-func (h *BotHandler) HandleCallback(query *tgbotapi.CallbackQuery) {
-	userID := query.From.ID
-	chatID := query.Message.Chat.ID
-	callbackData := query.Data
-	ctx := context.Background()
+	"time"
 
-	log.Printf("Callback from user %d: %s", userID, callbackData)
+	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/router"
+	"github.com/Lina3386/telegram-bot/internal/state"
+)
 
-	if strings.HasPrefix(callbackData, "payday_") {
-		h.handlePaydayCallbacks(query)
+func (h *BotHandler) handleAddIncomeCallback(c *router.Context) {
+	if !h.dialogOK(c, h.stateManager.ClearState(c, c.UserID())) ||
+		!h.setState(c, state.StateAddingIncome) {
 		return
 	}
-	if strings.HasPrefix(callbackData, "test_payday_") {
-		h.handleTestPaydayCallbacks(query)
+	h.sendMessage(c, c.ChatID(), "Введите название дохода:")
+	h.answerCallback(c, c.Callback.ID, "✅ Введите данные")
+}
+
+func (h *BotHandler) handleAddExpenseCallback(c *router.Context) {
+	if !h.dialogOK(c, h.stateManager.ClearState(c, c.UserID())) ||
+		!h.setState(c, state.StateAddingExpense) {
 		return
 	}
+	h.sendMessage(c, c.ChatID(), "Введите название расхода:")
+	h.answerCallback(c, c.Callback.ID, "✅ Введите данные")
+}
 
-	shouldDeleteMessage := true
-	switch callbackData {
-	case "contrib", "contribute", "withdraw", "changepriority":
-		// Эти кнопки ведут к вводу текста, не меняют экран - не удаляем
-		shouldDeleteMessage = false
+func (h *BotHandler) handleCreateGoalCallback(c *router.Context) {
+	if !h.setState(c, state.StateCreatingGoal) {
+		return
 	}
+	h.sendMessage(c, c.ChatID(), "Введите название цели:")
+	h.answerCallback(c, c.Callback.ID, "✅ Введите данные")
+}
 
-	if shouldDeleteMessage {
-		deleteMsg := tgbotapi.NewDeleteMessage(query.Message.Chat.ID, query.Message.MessageID)
-		_, err := h.bot.Request(deleteMsg)
-		if err != nil {
-			log.Printf("Failed to delete original message: %v", err)
-		}
-	}
+func (h *BotHandler) handleShowIncomesCallback(c *router.Context) {
+	h.answerCallback(c, c.Callback.ID, "✅")
+	h.handleShowIncomes(c)
+}
 
-	switch callbackData {
-	case "add_income":
-		h.stateManager.SetState(userID, state.StateAddingIncome)
-		h.sendMessage(chatID, "Введите название дохода:")
-		h.answerCallback(query.ID, "✅ Введите данные")
-		return
+func (h *BotHandler) handleShowExpensesCallback(c *router.Context) {
+	h.answerCallback(c, c.Callback.ID, "✅")
+	h.handleShowExpenses(c)
+}
 
-	case "add_expense":
-		h.stateManager.SetState(userID, state.StateAddingExpense)
-		h.sendMessage(chatID, "Введите название расхода:")
-		h.answerCallback(query.ID, "✅ Введите данные")
-		return
+func (h *BotHandler) handleShowGoalsCallback(c *router.Context) {
+	h.answerCallback(c, c.Callback.ID, "✅")
+	h.handleShowGoals(c)
+}
 
-	case "create_goal":
-		h.stateManager.SetState(userID, state.StateCreatingGoal)
-		h.sendMessage(chatID, "Введите название цели:")
-		h.answerCallback(query.ID, "✅ Введите данные")
-		return
+func (h *BotHandler) handleExpenseCategoriesCallback(c *router.Context) {
+	h.answerCallback(c, c.Callback.ID, "✅")
+	h.showExpenseCategories(c)
+}
 
-	case "back_to_goals":
-		h.answerCallback(query.ID, "✅")
-		h.handleShowGoals(&tgbotapi.Message{
-			From: &tgbotapi.User{ID: userID},
-			Chat: &tgbotapi.Chat{ID: chatID},
-		})
-		return
-	}
+func (h *BotHandler) handleGoalArchiveCallback(c *router.Context) {
+	h.answerCallback(c, c.Callback.ID, "✅")
+	h.showGoalArchive(c)
+}
 
-	if strings.HasPrefix(callbackData, "select_goal_") {
-		goalIDStr := strings.TrimPrefix(callbackData, "select_goal_")
-		goalID, err := strconv.ParseInt(goalIDStr, 10, 64)
-		if err != nil {
-			h.answerCallback(query.ID, "❌ Ошибка")
-			return
-		}
-
-		deleteMsg := tgbotapi.NewDeleteMessage(query.Message.Chat.ID, query.Message.MessageID)
-		_, err = h.bot.Request(deleteMsg)
-		if err != nil {
-			log.Printf("Failed to delete original message: %v", err)
-		}
-
-		h.showGoalDetailsV2(userID, chatID, goalID)
-		return
-	}
+func (h *BotHandler) handleTimezoneMenuCallback(c *router.Context) {
+	h.answerCallback(c, c.Callback.ID, "✅")
+	h.showTimezonePicker(c)
+}
 
-	parts := strings.SplitN(callbackData, "_", 2)
-	if len(parts) < 1 {
-		h.answerCallback(query.ID, "❌ Неизвестное действие")
+func (h *BotHandler) handleCustomTimezoneCallback(c *router.Context) {
+	if !h.setState(c, state.StateSettingTimezone) {
 		return
 	}
+	h.answerCallback(c, c.Callback.ID, "✅")
+	h.sendMessage(c, c.ChatID(), "Введите часовой пояс, например Asia/Tomsk, или смещение от UTC, например +7:")
+}
 
-	action := parts[0]
-	params := ""
-	if len(parts) > 1 {
-		params = parts[1]
-	}
+// handleGoalCallback - goal/<id>: карточка цели
+func (h *BotHandler) handleGoalCallback(c *router.Context) {
+	h.answerCallback(c, c.Callback.ID, "✅")
+	h.showGoalDetailsV2(c, c.Params.Int64("id"))
+}
 
-	switch action {
-
-	case "delete":
-		if params == "" {
-			h.answerCallback(query.ID, "❌ Ошибка формата")
-			return
-		}
-
-		resourceParts := strings.SplitN(params, "_", 2)
-		if len(resourceParts) < 2 {
-			h.answerCallback(query.ID, "❌ Ошибка формата")
-			return
-		}
-
-		resourceType := resourceParts[0]
-		resourceID := resourceParts[1]
-
-		switch resourceType {
-		case "income":
-			incomeID, err := strconv.ParseInt(resourceID, 10, 64)
-			if err != nil {
-				h.answerCallback(query.ID, "❌ Ошибка")
-				return
-			}
-			err = h.financeService.DeleteIncome(ctx, userID, incomeID)
-			if err != nil {
-				log.Printf("Failed to delete income: %v", err)
-				h.answerCallback(query.ID, "❌ Ошибка при удалении")
-				return
-			}
-			fmt.Sprintf("income_id: %d", incomeID)
-			h.answerCallback(query.ID, "✅ Доход удален")
-			h.handleShowIncomes(&tgbotapi.Message{From: &tgbotapi.User{ID: userID}, Chat: &tgbotapi.Chat{ID: chatID}})
-
-		case "expense":
-			expenseID, err := strconv.ParseInt(resourceID, 10, 64)
-			if err != nil {
-				h.answerCallback(query.ID, "❌ Ошибка")
-				return
-			}
-			err = h.financeService.DeleteExpense(ctx, userID, expenseID)
-			if err != nil {
-				log.Printf("Failed to delete expense: %v", err)
-				h.answerCallback(query.ID, "❌ Ошибка при удалении")
-				return
-			}
-			fmt.Sprintf("expense_id: %d", expenseID)
-			h.answerCallback(query.ID, "✅ Расход удален")
-			h.handleShowExpenses(&tgbotapi.Message{From: &tgbotapi.User{ID: userID}, Chat: &tgbotapi.Chat{ID: chatID}})
-
-		case "goal":
-			goalID, err := strconv.ParseInt(resourceID, 10, 64)
-			if err != nil {
-				h.answerCallback(query.ID, "❌ Ошибка")
-				return
-			}
-			err = h.financeService.DeleteGoal(ctx, userID, goalID)
-			if err != nil {
-				log.Printf("Failed to delete goal: %v", err)
-				h.answerCallback(query.ID, "❌ Ошибка при удалении")
-				return
-			}
-			fmt.Sprintf("goal_id: %d", goalID)
-			h.answerCallback(query.ID, "✅ Цель удалена")
-			h.handleShowGoals(&tgbotapi.Message{From: &tgbotapi.User{ID: userID}, Chat: &tgbotapi.Chat{ID: chatID}})
-		}
-		return
+func (h *BotHandler) handleGoalHistoryCallback(c *router.Context) {
+	h.answerCallback(c, c.Callback.ID, "✅")
+	h.showGoalHistory(c, c.Params.Int64("id"))
+}
 
-	case "select_goal":
-		if params == "" {
-			h.answerCallback(query.ID, "❌ Ошибка формата")
-			return
-		}
+func (h *BotHandler) handleGoalPriorityCallback(c *router.Context) {
+	h.answerCallback(c, c.Callback.ID, "✅")
+	h.handleChangePriority(c, c.Params.Int64("id"))
+}
 
-		goalID, err := strconv.ParseInt(params, 10, 64)
-		if err != nil {
-			h.answerCallback(query.ID, "❌ Ошибка")
-			return
-		}
+func (h *BotHandler) handleGoalPercentCallback(c *router.Context) {
+	h.answerCallback(c, c.Callback.ID, "✅")
+	h.handleChangeGoalPercent(c, c.Params.Int64("id"))
+}
 
-		h.answerCallback(query.ID, "✅")
-		h.showGoalDetails(userID, chatID, goalID)
+func (h *BotHandler) handleContributeCallback(c *router.Context) {
+	if !h.setTempData(c, "contribute_goal_id", c.Params.String("id")) ||
+		!h.setState(c, state.StateAddingContribution) {
 		return
+	}
+	h.answerCallback(c, c.Callback.ID, "✅ Введите сумму")
+	h.sendMessage(c, c.ChatID(), "Введите сумму для добавления к цели:")
+}
 
-	case "contrib":
-		if params == "" {
-			h.answerCallback(query.ID, "❌ Ошибка формата")
-			return
-		}
-		h.stateManager.SetTempData(userID, "contribute_goal_id", params)
-		h.stateManager.SetState(userID, state.StateAddingContribution)
-		h.answerCallback(query.ID, "✅ Введите сумму")
-		h.sendMessage(chatID, "Введите сумму для добавления к цели:")
-		return
+func (h *BotHandler) handleWithdrawCallback(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
 
-	case "contribute":
-		if params == "" {
-			h.answerCallback(query.ID, "❌ Ошибка формата")
-			return
-		}
-		h.stateManager.SetTempData(userID, "contribute_goal_id", params)
-		h.stateManager.SetState(userID, state.StateAddingContribution)
-		h.answerCallback(query.ID, "✅ Введите сумму")
-		h.sendMessage(chatID, "Введите сумму для добавления к цели:")
+	goal, err := h.financeService.GetUserGoalByID(c, userID, c.Params.Int64("id"))
+	if err != nil {
+		c.Logf("Failed to get goal: %v", err)
+		h.answerCallback(c, c.Callback.ID, "❌ Ошибка")
 		return
-
-	case "withdraw":
-		if params == "" {
-			h.answerCallback(query.ID, "❌ Ошибка формата")
-			return
-		}
-		goalID, err := strconv.ParseInt(params, 10, 64)
-		if err != nil {
-			h.answerCallback(query.ID, "❌ Ошибка")
-			return
-		}
-		goal, err := h.financeService.GetUserGoalByID(ctx, userID, goalID)
-		if err != nil {
-			log.Printf("Failed to get goal: %v", err)
-			h.answerCallback(query.ID, "❌ Ошибка")
-			return
-		}
-		if goal.CurrentAmount == 0 {
-			h.answerCallback(query.ID, "ℹ️ На цели нет средств")
-			return
-		}
-		h.stateManager.SetTempData(userID, "withdraw_goal_id", params)
-		h.stateManager.SetState(userID, state.StateWithdrawingFromGoal)
-		h.answerCallback(query.ID, "✅ Введите сумму для вычета")
-		h.sendMessage(chatID, fmt.Sprintf(
-			"💸 Вычитание из цели: %s\nТекущая сумма: %d₽\n\nВведите сумму для вычета:",
-			goal.GoalName, goal.CurrentAmount,
-		))
+	}
+	if goal.CurrentAmount == 0 {
+		h.answerCallback(c, c.Callback.ID, "ℹ️ На цели нет средств")
 		return
-
-	case "changepriority":
-		if params == "" {
-			h.answerCallback(query.ID, "❌ Ошибка формата")
-			return
-		}
-
-		goalID, err := strconv.ParseInt(params, 10, 64)
-		if err != nil {
-			h.answerCallback(query.ID, "❌ Ошибка")
-			return
-		}
-
-		h.answerCallback(query.ID, "✅")
-		h.handleChangePriority(userID, chatID, goalID)
+	}
+	if !h.setTempData(c, "withdraw_goal_id", c.Params.String("id")) ||
+		!h.setState(c, state.StateWithdrawingFromGoal) {
 		return
-
-	default:
-		log.Printf("⚠️ DEBUG: Неизвестный callback: '%s', action: '%s', params: '%s'", callbackData, action, params)
-		h.answerCallback(query.ID, fmt.Sprintf("❓ Неизвестное действие: %s", callbackData))
 	}
+	h.answerCallback(c, c.Callback.ID, "✅ Введите сумму для вычета")
+	h.sendMessage(c, chatID, fmt.Sprintf(
+		"💸 Вычитание из цели: %s\nТекущая сумма: %s\n\nВведите сумму для вычета:",
+		goal.GoalName, models.FormatAmount(goal.CurrentAmount, goal.Currency),
+	))
 }
 
-func (h *BotHandler) handlePaydayCallbacks(query *tgbotapi.CallbackQuery) {
-	userID := query.From.ID
-	chatID := query.Message.Chat.ID
-	callbackData := query.Data
-	ctx := context.Background()
+// handleCategoryLimitCallback - category/<id>/limit: ввод месячного лимита категории
+func (h *BotHandler) handleCategoryLimitCallback(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
 
-	parts := strings.Split(callbackData, "_")
-	if len(parts) < 2 {
-		h.answerCallback(query.ID, "❌ Ошибка формата")
+	category, err := h.financeService.GetUserCategoryByID(c, userID, c.Params.Int64("id"))
+	if err != nil {
+		c.Logf("Failed to get category: %v", err)
+		h.answerCallback(c, c.Callback.ID, "❌ Категория не найдена")
 		return
 	}
 
-	action := parts[1]
-
-	switch action {
-	case "goal":
-		if len(parts) < 4 {
-			h.answerCallback(query.ID, "❌ Ошибка формата")
-			return
-		}
-
-		incomeID, _ := strconv.ParseInt(parts[2], 10, 64)
-		goalID, _ := strconv.ParseInt(parts[3], 10, 64)
-
-		h.showGoalDetailsV2WithBack(userID, chatID, goalID, fmt.Sprintf("payday_back_%d", incomeID), "⬅️ Назад к получке")
-		h.answerCallback(query.ID, "✅")
-
-	case "add":
-		if len(parts) < 4 {
-			h.answerCallback(query.ID, "❌ Ошибка формата")
-			return
-		}
+	if !h.setTempData(c, "limit_category_id", c.Params.String("id")) ||
+		!h.setState(c, state.StateSettingCategoryLimit) {
+		return
+	}
+	h.answerCallback(c, c.Callback.ID, "✅ Введите лимит")
 
-		goalID, _ := strconv.ParseInt(parts[3], 10, 64)
-		incomeID, _ := strconv.ParseInt(parts[2], 10, 64)
+	current := "не задан"
+	if category.MonthlyLimit > 0 {
+		current = models.FormatAmount(category.MonthlyLimit, c.User.BaseCurrency)
+	}
+	h.sendMessage(c, chatID, fmt.Sprintf("🏷 %s\nТекущий лимит: %s\n\nВведите месячный лимит (0 - без лимита):", category.Name, current))
+}
 
-		h.stateManager.SetTempData(userID, "payday_contributing_goal_id", fmt.Sprintf("%d", goalID))
-		h.stateManager.SetTempData(userID, "payday_contributing_income_id", fmt.Sprintf("%d", incomeID))
-		h.stateManager.SetState(userID, state.StatePaydayEnteringAmount)
+func (h *BotHandler) handleUnknownCallback(c *router.Context) {
+	h.answerCallback(c, c.Callback.ID, "❓ Кнопка устарела, откройте меню заново")
+}
 
-		h.sendMessage(chatID, "Введите сумму для отложения:")
-		h.answerCallback(query.ID, "✅ Введите сумму")
+// handlePaydayGoalCallback - payday/<доход>/goal/<цель>: цель из уведомления о получке
+func (h *BotHandler) handlePaydayGoalCallback(c *router.Context) {
+	h.showPaydayGoalDetails(c, c.Params.Int64("goal"), c.Params.Int64("income"))
+	h.answerCallback(c, c.Callback.ID, "✅")
+}
 
-	case "back":
-		if len(parts) < 3 {
-			h.answerCallback(query.ID, "❌ Ошибка формата")
-			return
-		}
+func (h *BotHandler) handlePaydayAddCallback(c *router.Context) {
+	if !h.setTempData(c, "payday_contributing_goal_id", c.Params.String("goal")) ||
+		!h.setTempData(c, "payday_contributing_income_id", c.Params.String("income")) ||
+		!h.setState(c, state.StatePaydayEnteringAmount) {
+		return
+	}
 
-		incomeID, _ := strconv.ParseInt(parts[2], 10, 64)
+	h.sendMessage(c, c.ChatID(), "Введите сумму для отложения:")
+	h.answerCallback(c, c.Callback.ID, "✅ Введите сумму")
+}
 
-		income, err := h.financeService.GetUserIncomeByID(ctx, userID, incomeID)
-		if err != nil {
-			log.Printf("Failed to get income by ID: %v", err)
-			h.answerCallback(query.ID, "❌ Ошибка")
-			return
-		}
+// handlePaydayBackCallback - payday/<доход>: снова меню получки
+func (h *BotHandler) handlePaydayBackCallback(c *router.Context) {
+	userID := c.UserID()
 
-		if income == nil {
-			h.answerCallback(query.ID, "❌ Доход не найден")
-			return
-		}
+	income, err := h.financeService.GetUserIncomeByID(c, userID, c.Params.Int64("income"))
+	if err != nil {
+		c.Logf("Failed to get income by ID: %v", err)
+		h.answerCallback(c, c.Callback.ID, "❌ Ошибка")
+		return
+	}
 
-		h.showPaydayMenu(userID, chatID, income.ID, income.Name, income.Amount, ctx)
-		h.answerCallback(query.ID, "✅")
+	if income == nil {
+		h.answerCallback(c, c.Callback.ID, "❌ Доход не найден")
+		return
+	}
 
-	case "complete":
-		// payday_complete_
-		h.stateManager.ClearState(userID)
-		h.sendMessageWithKeyboard(chatID, "😊 Взносы завершены! Спасибо!", h.mainMenu())
-		h.answerCallback(query.ID, "✅ Готово")
+	h.showPaydayMenu(c, income.ID, income.Name, h.financeService.LastPaydayAmount(c, *income), income.Currency)
+	h.answerCallback(c, c.Callback.ID, "✅")
+}
 
-	default:
-		h.answerCallback(query.ID, "❓ Неизвестное действие")
+// handlePaydayConfirmCallback - payday/<доход>/confirm/<ГГГГММДД>: пришло по плану
+func (h *BotHandler) handlePaydayConfirmCallback(c *router.Context) {
+	income, payDate, ok := h.paydayFromCallback(c)
+	if !ok {
+		return
 	}
+	planned := income.PaydayAmount(payDate)
+	h.confirmPaydayReceived(c, income, payDate, planned, payDate)
+	h.answerCallback(c, c.Callback.ID, "✅ Записано")
 }
 
-func (h *BotHandler) handleTestPaydayCallbacks(query *tgbotapi.CallbackQuery) {
-	userID := query.From.ID
-	chatID := query.Message.Chat.ID
-	callbackData := query.Data
-	ctx := context.Background()
-
-	parts := strings.Split(callbackData, "_")
-	if len(parts) < 3 {
-		h.answerCallback(query.ID, "❌ Ошибка формата теста")
+// handlePaydayCorrectCallback - payday/<доход>/correct/<ГГГГММДД>: пришло иначе, спрашиваем сумму и дату
+func (h *BotHandler) handlePaydayCorrectCallback(c *router.Context) {
+	income, payDate, ok := h.paydayFromCallback(c)
+	if !ok {
 		return
 	}
+	planned := income.PaydayAmount(payDate)
 
-	action := parts[2]
-
-	switch action {
-	case "goal":
-		if len(parts) < 5 {
-			h.answerCallback(query.ID, "❌ Ошибка формата теста")
-			return
-		}
-
-		incomeID, _ := strconv.ParseInt(parts[3], 10, 64)
-		goalID, _ := strconv.ParseInt(parts[4], 10, 64)
-
-		// детальную информацию цели с кнопкой назад к тестовому меню
-		h.showTestGoalDetailsV2WithBack(userID, chatID, goalID, incomeID)
-		h.answerCallback(query.ID, "✅ (Тест)")
-
-	case "back":
-		if len(parts) < 4 {
-			h.answerCallback(query.ID, "❌ Ошибка формата теста")
-			return
-		}
+	if !h.setTempData(c, "payday_actual_income_id", strconv.FormatInt(income.ID, 10)) ||
+		!h.setTempData(c, "payday_actual_date", c.Params.String("date")) ||
+		!h.setState(c, state.StatePaydayEnteringActual) {
+		return
+	}
+	h.sendMessage(c, c.ChatID(), fmt.Sprintf(
+		"По плану: %s, %s\n\n"+
+			"Сколько пришло на самом деле? Если деньги пришли в другой день, добавьте дату, например:\n52000 12.01.2026",
+		models.FormatAmount(planned, income.Currency), payDate.Format("02.01.2006"),
+	))
+	h.answerCallback(c, c.Callback.ID, "✅ Введите сумму")
+}
 
-		incomeID, _ := strconv.ParseInt(parts[3], 10, 64)
+func (h *BotHandler) paydayFromCallback(c *router.Context) (*models.Income, time.Time, bool) {
+	payDate, err := time.Parse("20060102", c.Params.String("date"))
+	if err != nil {
+		h.answerCallback(c, c.Callback.ID, "❌ Ошибка формата")
+		return nil, time.Time{}, false
+	}
 
-		err := h.financeService.TestPaydayNotification(h.bot, ctx, userID, incomeID)
-		if err != nil {
-			h.answerCallback(query.ID, "❌ Ошибка теста")
-		}
-		h.answerCallback(query.ID, "✅ (Тест)")
+	income, err := h.financeService.GetUserIncomeByID(c, c.UserID(), c.Params.Int64("income"))
+	if err != nil {
+		c.Logf("Failed to get income by ID: %v", err)
+		h.answerCallback(c, c.Callback.ID, "❌ Доход не найден")
+		return nil, time.Time{}, false
+	}
+	return income, payDate, true
+}
 
-	case "add":
-		if len(parts) < 5 {
-			h.answerCallback(query.ID, "❌ Ошибка формата теста")
-			return
-		}
+func (h *BotHandler) handlePaydayCompleteCallback(c *router.Context) {
+	h.clearState(c)
+	h.sendMessageWithKeyboard(c, c.ChatID(), "😊 Взносы завершены! Спасибо!", h.mainMenu())
+	h.answerCallback(c, c.Callback.ID, "✅ Готово")
+}
 
-		incomeID, _ := strconv.ParseInt(parts[3], 10, 64)
-		goalID, _ := strconv.ParseInt(parts[4], 10, 64)
+func (h *BotHandler) handleTestPaydayGoalCallback(c *router.Context) {
+	// детальную информацию цели с кнопкой назад к тестовому меню
+	h.showTestGoalDetailsV2WithBack(c, c.Params.Int64("goal"), c.Params.Int64("income"))
+	h.answerCallback(c, c.Callback.ID, "✅ (Тест)")
+}
 
-		h.stateManager.SetTempData(userID, "payday_contributing_goal_id", fmt.Sprintf("%d", goalID))
-		h.stateManager.SetTempData(userID, "payday_contributing_income_id", fmt.Sprintf("%d", incomeID))
-		h.stateManager.SetState(userID, state.StatePaydayEnteringAmount)
+func (h *BotHandler) handleTestPaydayBackCallback(c *router.Context) {
+	err := h.financeService.TestPaydayNotification(h.bot, c, c.UserID(), c.Params.Int64("income"))
+	if err != nil {
+		h.answerCallback(c, c.Callback.ID, "❌ Ошибка теста")
+	}
+	h.answerCallback(c, c.Callback.ID, "✅ (Тест)")
+}
 
-		h.sendMessage(chatID, "🧪 Введите сумму для тестового вклада:")
-		h.answerCallback(query.ID, "✅ Тест: Введите сумму")
+func (h *BotHandler) handleTestPaydayAddCallback(c *router.Context) {
+	if !h.setTempData(c, "payday_contributing_goal_id", c.Params.String("goal")) ||
+		!h.setTempData(c, "payday_contributing_income_id", c.Params.String("income")) ||
+		!h.setState(c, state.StatePaydayEnteringAmount) {
+		return
+	}
 
-	case "complete":
-		h.stateManager.ClearState(userID)
-		h.sendMessageWithKeyboard(chatID, "🧪 Тест завершен! Уведомления работают правильно.", h.mainMenu())
-		h.answerCallback(query.ID, "✅ Тест завершен")
+	h.sendMessage(c, c.ChatID(), "🧪 Введите сумму для тестового вклада:")
+	h.answerCallback(c, c.Callback.ID, "✅ Тест: Введите сумму")
+}
 
-	default:
-		h.answerCallback(query.ID, "❓ Неизвестное действие теста")
-	}
+func (h *BotHandler) handleTestPaydayCompleteCallback(c *router.Context) {
+	h.clearState(c)
+	h.sendMessageWithKeyboard(c, c.ChatID(), "🧪 Тест завершен! Уведомления работают правильно.", h.mainMenu())
+	h.answerCallback(c, c.Callback.ID, "✅ Тест завершен")
 }
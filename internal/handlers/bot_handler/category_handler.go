@@ -0,0 +1,255 @@
+package bot_handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/router"
+	"github.com/Lina3386/telegram-bot/internal/services"
+	"github.com/Lina3386/telegram-bot/internal/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func (h *BotHandler) askExpenseCategory(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	categories, err := h.financeService.GetUserCategories(c, userID)
+	if err != nil {
+		log.Printf("Failed to get categories: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при получении категорий")
+		h.clearState(c)
+		return
+	}
+
+	if !h.setState(c, state.StateAddingExpenseCategory) {
+		return
+	}
+
+	prompt := "Выберите категорию расхода:\n\n"
+	for i, category := range categories {
+		prompt += fmt.Sprintf("%d. %s\n", i+1, category.Name)
+	}
+	prompt += "\nВведите номер категории или название новой:"
+	h.sendMessage(c, chatID, prompt)
+}
+
+func (h *BotHandler) handleExpenseCategoryInput(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+	text := strings.TrimSpace(c.Text())
+
+	session, ok := h.dialogSession(c)
+	if !ok {
+		return
+	}
+
+	categories, err := h.financeService.GetUserCategories(c, userID)
+	if err != nil {
+		c.Logf("Failed to get categories: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при получении категорий")
+		return
+	}
+
+	var categoryID int64
+	var categoryName string
+
+	// номер из списка, иначе - название (существующей или новой категории)
+	if index, err := strconv.Atoi(text); err == nil {
+		if index < 1 || index > len(categories) {
+			h.sendMessage(c, chatID, fmt.Sprintf("❌ Введите номер от 1 до %d или название новой категории", len(categories)))
+			return
+		}
+		categoryID = categories[index-1].ID
+		categoryName = categories[index-1].Name
+	} else {
+		if text == "" || len([]rune(text)) > 100 {
+			h.sendMessage(c, chatID, "❌ Название категории должно быть от 1 до 100 символов")
+			return
+		}
+
+		category, err := h.financeService.CreateCategory(c, userID, text)
+		if err != nil {
+			c.Logf("Failed to create category: %v", err)
+			h.sendMessage(c, chatID, "❌ Ошибка при создании категории")
+			return
+		}
+		categoryID = category.ID
+		categoryName = category.Name
+	}
+
+	expenseName := session.Get("expense_name")
+	amountMinor, _ := strconv.ParseInt(session.Get("expense_amount"), 10, 64)
+	amount := models.Money(amountMinor)
+
+	currency := session.Get("expense_currency")
+
+	var expense *models.Expense
+	if session.Get("expense_kind") == models.ExpenseKindOneOff {
+		spentAt, _ := time.Parse("2006-01-02", session.Get("expense_spent_at"))
+		expense, err = h.financeService.CreateOneOffExpense(c, userID, expenseName, amount, currency, spentAt, categoryID)
+	} else {
+		frequency := session.Get("expense_frequency")
+		recurringDay, _ := strconv.Atoi(session.Get("expense_recurring_day"))
+		expense, err = h.financeService.CreateRecurringExpense(c, userID, expenseName, amount, currency, frequency, recurringDay, categoryID)
+	}
+	if errors.Is(err, services.ErrNoExchangeRate) {
+		h.sendMessage(c, chatID, fmt.Sprintf("❌ Для валюты %s не задан курс. Курсы задает администратор бота (/rate %s 92.5), после этого добавьте расход заново", currency, currency))
+		h.clearState(c)
+		return
+	}
+	if err != nil {
+		c.Logf("Failed to create expense: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при сохранении расхода")
+		return
+	}
+
+	h.clearState(c)
+	h.sendMessageWithKeyboard(c,
+		chatID,
+		fmt.Sprintf("✅ Расход добавлен:\n%s: %s (%s)\n🏷 %s", expenseName, models.FormatAmount(expense.Amount, expense.Currency), expenseScheduleText(*expense), categoryName),
+		h.mainMenu(),
+	)
+}
+
+func (h *BotHandler) showExpenseCategories(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	spending, err := h.financeService.GetCategorySpending(c, userID)
+	if errors.Is(err, services.ErrNoExchangeRate) {
+		h.sendMessage(c, chatID, "❌ Не для всех валют расходов задан курс."+noRateHint(err))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get category spending: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при загрузке категорий")
+		return
+	}
+
+	baseCurrency := c.User.BaseCurrency
+
+	text := fmt.Sprintf("🏷 Категории расходов (лимиты в %s):\n\n", baseCurrency)
+	var inlineButtons [][]tgbotapi.InlineKeyboardButton
+
+	for _, cs := range spending {
+		text += formatCategorySpending(cs, baseCurrency)
+
+		if cs.Category.ID == 0 {
+			continue
+		}
+		btn := tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("✏️ Лимит: %s", cs.Category.Name),
+			fmt.Sprintf("category/%d/limit", cs.Category.ID),
+		)
+		inlineButtons = append(inlineButtons, []tgbotapi.InlineKeyboardButton{btn})
+	}
+
+	text += "\n💡 Новую категорию можно указать при добавлении расхода"
+
+	backBtn := tgbotapi.NewInlineKeyboardButtonData("⬅️ К расходам", "expenses")
+	inlineButtons = append(inlineButtons, []tgbotapi.InlineKeyboardButton{backBtn})
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(inlineButtons...)
+
+	_, err = h.bot.Send(c, msg)
+	if err != nil {
+		log.Printf("Failed to send categories: %v", err)
+	}
+}
+
+func (h *BotHandler) handleCategoryLimitInput(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	session, ok := h.dialogSession(c)
+	if !ok {
+		return
+	}
+
+	limit, err := models.ParseMoney(c.Text())
+	if err != nil || limit < 0 {
+		h.sendMessage(c, chatID, "❌ Введите сумму лимита (0 - без лимита)")
+		return
+	}
+
+	categoryID, _ := strconv.ParseInt(session.Get("limit_category_id"), 10, 64)
+
+	err = h.financeService.SetCategoryLimit(c, userID, categoryID, limit)
+	if err != nil {
+		c.Logf("Failed to set category limit: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при сохранении лимита")
+		h.clearState(c)
+		return
+	}
+
+	h.clearState(c)
+	if limit == 0 {
+		h.sendMessage(c, chatID, "✅ Лимит снят")
+	} else {
+		h.sendMessage(c, chatID, fmt.Sprintf("✅ Месячный лимит: %s", models.FormatAmount(limit, c.User.BaseCurrency)))
+	}
+	h.showExpenseCategories(c)
+}
+
+func formatCategorySpending(cs services.CategorySpending, currency string) string {
+	if cs.Category.MonthlyLimit <= 0 {
+		return fmt.Sprintf("• %s: %s\n", cs.Category.Name, models.FormatAmount(cs.Spent, currency))
+	}
+
+	mark := ""
+	percent := cs.Percent()
+	if percent >= 100 {
+		mark = " 🚨"
+	} else if percent >= 80 {
+		mark = " ⚠️"
+	}
+
+	return fmt.Sprintf("• %s: %s / %s (%d%%)%s\n", cs.Category.Name,
+		models.FormatAmount(cs.Spent, currency), models.FormatAmount(cs.Category.MonthlyLimit, currency), percent, mark)
+}
+
+// "сегодня", "вчера", ДД.ММ.ГГГГ или ДД.ММ (текущий год)
+// now - время в зоне пользователя
+func parseExpenseDate(text string, now time.Time) (time.Time, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "сегодня":
+		return today, true
+	case "вчера":
+		return today.AddDate(0, 0, -1), true
+	}
+
+	if date, err := time.Parse("02.01.2006", strings.TrimSpace(text)); err == nil {
+		return date, true
+	}
+	if date, err := time.Parse("02.01", strings.TrimSpace(text)); err == nil {
+		return time.Date(now.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), true
+	}
+	return time.Time{}, false
+}
+
+func expenseScheduleText(expense models.Expense) string {
+	if expense.Kind == models.ExpenseKindOneOff {
+		return "разово, " + expense.SpentAt.Time.Format("02.01.2006")
+	}
+
+	switch expense.Frequency {
+	case "weekly", "biweekly":
+		weeks := map[int]string{0: "вс", 1: "пн", 2: "вт", 3: "ср", 4: "чт", 5: "пт", 6: "сб"}
+		freqText := "еженедельно"
+		if expense.Frequency == "biweekly" {
+			freqText = "через неделю"
+		}
+		return fmt.Sprintf("%s, %s", freqText, weeks[expense.RecurringDay])
+	default:
+		return fmt.Sprintf("ежемесячно, %d число", expense.RecurringDay)
+	}
+}
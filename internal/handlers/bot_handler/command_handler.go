@@ -1,23 +1,23 @@
 package bot_handler
 
 import (
-	"context"
 	"fmt"
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"log"
 	"strconv"
 	"strings"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/router"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-func (h *BotHandler) handleShowIncomes(message *tgbotapi.Message) {
-	userID := message.From.ID
-	chatID := message.Chat.ID
-	ctx := context.Background()
+func (h *BotHandler) handleShowIncomes(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
 
-	incomes, err := h.financeService.GetUserIncomes(ctx, userID)
+	incomes, err := h.financeService.GetUserIncomes(c, userID)
 	if err != nil {
-		log.Printf("Failed to get incomes: %v", err)
-		h.sendMessage(chatID, "❌ Ошибка при загрузке доходов")
+		c.Logf("Failed to get incomes: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при загрузке доходов")
 		return
 	}
 
@@ -25,40 +25,29 @@ func (h *BotHandler) handleShowIncomes(message *tgbotapi.Message) {
 	var inlineButtons [][]tgbotapi.InlineKeyboardButton
 
 	if len(incomes) > 0 {
-		totalIncome, err := h.financeService.CalculateTotalIncome(ctx, userID)
+		totalIncome, err := h.financeService.CalculateTotalIncome(c, userID)
 		if err != nil {
-			log.Printf("Failed to calculate total income: %v", err)
+			c.Logf("Failed to calculate total income: %v", err)
 			totalIncome = 0
 		}
+		rateHint := noRateHint(err)
 
-		for i, income := range incomes {
-			freqText := income.Frequency
-			if freqText == "monthly" {
-				freqText = "ежемесячно"
-			} else if freqText == "weekly" {
-				freqText = "еженедельно"
-			} else if freqText == "biweekly" {
-				freqText = "через неделю"
-			}
+		baseCurrency := c.User.BaseCurrency
 
-			dayDesc := fmt.Sprintf("%d число", income.RecurringDay)
-			if income.Frequency == "weekly" || income.Frequency == "biweekly" {
-				weeks := map[int]string{0: "вс", 1: "пн", 2: "вт", 3: "ср", 4: "чт", 5: "пт", 6: "сб"}
-				dayDesc = weeks[income.RecurringDay]
-			}
-
-			text += fmt.Sprintf("%d\n💰 %s: %d₽ (%s, %s)\n\n", i+1, income.Name, income.Amount, freqText, dayDesc)
+		for i, income := range incomes {
+			text += fmt.Sprintf("%d\n💰 %s: %s (%s)\n\n", i+1, income.Name, models.FormatAmount(income.Amount, income.Currency), incomeScheduleText(income))
 
-			button := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑️ Удалить %d", i+1), fmt.Sprintf("delete_income_%d", income.ID))
-			inlineButtons = append(inlineButtons, []tgbotapi.InlineKeyboardButton{button})
+			editButton := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✏️ Изменить %d", i+1), fmt.Sprintf("income/%d/edit", income.ID))
+			button := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑️ Удалить %d", i+1), fmt.Sprintf("income/%d/delete", income.ID))
+			inlineButtons = append(inlineButtons, []tgbotapi.InlineKeyboardButton{editButton, button})
 		}
 
-		text += fmt.Sprintf("\n📈 Общий доход: %d₽\n\n", totalIncome)
+		text += fmt.Sprintf("\n📈 Общий доход: %s\n%s\n", models.FormatAmount(totalIncome, baseCurrency), rateHint)
 	} else {
 		text += "У вас нет добавленных доходов\n\n"
 	}
 
-	addButton := tgbotapi.NewInlineKeyboardButtonData("➕ Добавить доход", "add_income")
+	addButton := tgbotapi.NewInlineKeyboardButtonData("➕ Добавить доход", "incomes/new")
 	inlineButtons = append(inlineButtons, []tgbotapi.InlineKeyboardButton{addButton})
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(inlineButtons...)
@@ -66,71 +55,110 @@ func (h *BotHandler) handleShowIncomes(message *tgbotapi.Message) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = keyboard
 
-	_, err = h.bot.Send(msg)
+	_, err = h.bot.Send(c, msg)
 	if err != nil {
-		log.Printf("Failed to send income list: %v", err)
+		c.Logf("Failed to send income list: %v", err)
 	}
 }
 
-func (h *BotHandler) handleShowExpenses(message *tgbotapi.Message) {
-	userID := message.From.ID
-	chatID := message.Chat.ID
-	ctx := context.Background()
+func (h *BotHandler) handleShowExpenses(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
 
-	expenses, err := h.financeService.GetUserExpenses(ctx, userID)
+	expenses, err := h.financeService.GetUserExpenses(c, userID)
 	if err != nil {
-		log.Printf("Failed to get expenses: %v", err)
-		h.sendMessage(chatID, "❌ Ошибка при загрузке расходов")
+		c.Logf("Failed to get expenses: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при загрузке расходов")
 		return
 	}
 
+	categories, err := h.financeService.GetUserCategories(c, userID)
+	if err != nil {
+		c.Logf("Failed to get categories: %v", err)
+	}
+	categoryNames := make(map[int64]string, len(categories))
+	for _, category := range categories {
+		categoryNames[category.ID] = category.Name
+	}
+
+	// разовые траты прошлых месяцев на бюджет уже не влияют - не показываем их
+	now := c.User.Now()
+	visible := make([]models.Expense, 0, len(expenses))
+	for _, expense := range expenses {
+		if expense.Kind == models.ExpenseKindOneOff {
+			spentYear, spentMonth, _ := expense.SpentAt.Time.Date()
+			if spentYear != now.Year() || spentMonth != now.Month() {
+				continue
+			}
+		}
+		visible = append(visible, expense)
+	}
+	expenses = visible
+
 	text := "💰 Ваши расходы:\n\n"
 	var inlineButtons [][]tgbotapi.InlineKeyboardButton
-	totalExpense := int64(0)
 
 	if len(expenses) > 0 {
+		totalExpense, err := h.financeService.CalculateTotalExpense(c, userID)
+		if err != nil {
+			c.Logf("Failed to calculate total expense: %v", err)
+		}
+		rateHint := noRateHint(err)
+
+		baseCurrency := c.User.BaseCurrency
+
 		for i, expense := range expenses {
-			text += fmt.Sprintf("%d. 📌 %s: %d₽\n", i+1, expense.Name, expense.Amount)
-			totalExpense += expense.Amount
+			icon := "🔁"
+			if expense.Kind == models.ExpenseKindOneOff {
+				icon = "🛒"
+			}
+			text += fmt.Sprintf("%d. %s %s: %s (%s)", i+1, icon, expense.Name, models.FormatAmount(expense.Amount, expense.Currency), expenseScheduleText(expense))
+			if name, ok := categoryNames[expense.CategoryID.Int64]; ok && expense.CategoryID.Valid {
+				text += fmt.Sprintf(" 🏷 %s", name)
+			}
+			text += "\n"
 
-			button := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑️ Удалить #%d", i+1), fmt.Sprintf("delete_expense_%d", expense.ID))
-			inlineButtons = append(inlineButtons, []tgbotapi.InlineKeyboardButton{button})
+			editButton := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✏️ Изменить #%d", i+1), fmt.Sprintf("expense/%d/edit", expense.ID))
+			button := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑️ Удалить #%d", i+1), fmt.Sprintf("expense/%d/delete", expense.ID))
+			inlineButtons = append(inlineButtons, []tgbotapi.InlineKeyboardButton{editButton, button})
 		}
 
-		text += fmt.Sprintf("\n📉 Общие расходы: %d₽\n\n", totalExpense)
+		text += fmt.Sprintf("\n📉 Расходы за месяц: %s\n%s\n", models.FormatAmount(totalExpense, baseCurrency), rateHint)
 	} else {
 		text += "У вас нет добавленных расходов\n\n"
 	}
 
-	addButton := tgbotapi.NewInlineKeyboardButtonData("➕ Добавить расход", "add_expense")
+	addButton := tgbotapi.NewInlineKeyboardButtonData("➕ Добавить расход", "expenses/new")
 	inlineButtons = append(inlineButtons, []tgbotapi.InlineKeyboardButton{addButton})
 
+	categoriesButton := tgbotapi.NewInlineKeyboardButtonData("🏷 Категории и лимиты", "expenses/categories")
+	inlineButtons = append(inlineButtons, []tgbotapi.InlineKeyboardButton{categoriesButton})
+
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(inlineButtons...)
 
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = keyboard
 
-	_, err = h.bot.Send(msg)
+	_, err = h.bot.Send(c, msg)
 	if err != nil {
-		log.Printf("Failed to send expense list: %v", err)
+		c.Logf("Failed to send expense list: %v", err)
 	}
 }
 
-func (h *BotHandler) handleShowGoals(message *tgbotapi.Message) {
-	userID := message.From.ID
-	chatID := message.Chat.ID
-	ctx := context.Background()
+func (h *BotHandler) handleShowGoals(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
 
-	_, err := h.financeService.DistributeFundsToGoals(ctx, userID)
+	_, err := h.financeService.DistributeFundsToGoals(c, userID)
 	if err != nil {
-		log.Printf("Failed to redistribute funds on goals view: %v", err)
+		c.Logf("Failed to redistribute funds on goals view: %v", err)
 		// Продолжаем показывать цели даже при ошибке перерасчета
 	}
 
-	goals, err := h.financeService.GetUserGoals(ctx, userID)
+	goals, err := h.financeService.GetUserGoals(c, userID)
 	if err != nil {
-		log.Printf("Failed to get goals: %v", err)
-		h.sendMessage(chatID, "❌ Ошибка при загрузке целей")
+		c.Logf("Failed to get goals: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при загрузке целей")
 		return
 	}
 
@@ -148,11 +176,8 @@ func (h *BotHandler) handleShowGoals(message *tgbotapi.Message) {
 
 	if len(goals) > 0 {
 		for _, goal := range goals {
-			if goal.Status == "active" {
-				progress := int64(0)
-				if goal.TargetAmount > 0 {
-					progress = (goal.CurrentAmount * 100) / goal.TargetAmount
-				}
+			if goal.HoldsPriority() {
+				progress := goal.CurrentAmount.Percent(goal.TargetAmount)
 
 				priorityStr := ""
 				if len(goals) > 1 {
@@ -172,14 +197,20 @@ func (h *BotHandler) handleShowGoals(message *tgbotapi.Message) {
 					monthlyContrib = goal.MonthlyBudgetLimit
 				}
 
+				pausedStr := ""
+				if goal.Status == models.GoalStatusPaused {
+					pausedStr = " ⏸️"
+				}
+
 				text += fmt.Sprintf(
-					"• %s%s: %d₽ / %d₽ (%d%%)\n",
-					goal.GoalName, priorityStr, goal.CurrentAmount, goal.TargetAmount, progress,
+					"• %s%s%s: %s / %s (%d%%)\n",
+					goal.GoalName, priorityStr, pausedStr,
+					models.FormatAmount(goal.CurrentAmount, goal.Currency), models.FormatAmount(goal.TargetAmount, goal.Currency), progress,
 				)
 
 				btn := tgbotapi.NewInlineKeyboardButtonData(
 					fmt.Sprintf("ꪜ %s", goal.GoalName),
-					fmt.Sprintf("select_goal_%d", goal.ID),
+					fmt.Sprintf("goal/%d", goal.ID),
 				)
 				inlineButtons = append(inlineButtons, []tgbotapi.InlineKeyboardButton{btn})
 			}
@@ -190,59 +221,93 @@ func (h *BotHandler) handleShowGoals(message *tgbotapi.Message) {
 		text += "У вас пока нет целей.\n\nНажмите ➕ чтобы создать цель"
 	}
 
-	createBtn := tgbotapi.NewInlineKeyboardButtonData("➕ Создать цель", "create_goal")
+	createBtn := tgbotapi.NewInlineKeyboardButtonData("➕ Создать цель", "goals/new")
 	inlineButtons = append(inlineButtons, []tgbotapi.InlineKeyboardButton{createBtn})
 
+	for _, goal := range goals {
+		if !goal.HoldsPriority() {
+			archiveBtn := tgbotapi.NewInlineKeyboardButtonData("🗄 Архив целей", "goals/archive")
+			inlineButtons = append(inlineButtons, []tgbotapi.InlineKeyboardButton{archiveBtn})
+			break
+		}
+	}
+
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(inlineButtons...)
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = keyboard
 
-	h.bot.Send(msg)
+	h.bot.Send(c, msg)
 }
 
-func (h *BotHandler) handleShowStats(message *tgbotapi.Message) {
-	userID := message.From.ID
-	chatID := message.Chat.ID
-	ctx := context.Background()
+func (h *BotHandler) handleShowStats(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	baseCurrency := c.User.BaseCurrency
 
-	totalIncome, err := h.financeService.CalculateTotalIncome(ctx, userID)
+	totalIncome, err := h.financeService.CalculateTotalIncome(c, userID)
 	if err != nil {
-		log.Printf("Failed to calculate total income: %v", err)
+		c.Logf("Failed to calculate total income: %v", err)
 	}
+	rateHint := noRateHint(err)
 
-	totalExpense, err := h.financeService.CalculateTotalExpense(ctx, userID)
+	totalExpense, err := h.financeService.CalculateTotalExpense(c, userID)
 	if err != nil {
-		log.Printf("Failed to calculate total expense: %v", err)
+		c.Logf("Failed to calculate total expense: %v", err)
+	}
+	if rateHint == "" {
+		rateHint = noRateHint(err)
 	}
 
-	availableForSavings, err := h.financeService.CalculateAvailableForSavings(ctx, userID)
+	availableForSavings, err := h.financeService.CalculateAvailableForSavings(c, userID)
 	if err != nil {
-		log.Printf("Failed to calculate available for savings: %v", err)
+		c.Logf("Failed to calculate available for savings: %v", err)
 	}
 
-	goals, err := h.financeService.GetUserActiveGoalsByTelegramID(ctx, userID)
+	goals, err := h.financeService.GetUserActiveGoalsByTelegramID(c, userID)
 	if err != nil {
-		log.Printf("Failed to get goals: %v", err)
+		c.Logf("Failed to get goals: %v", err)
 	}
 
 	text := fmt.Sprintf(
 		"📊 Ваша финансовая статистика:\n\n"+
-			"💰 Общий доход: %d₽\n"+
-			"💸 Общие расходы: %d₽\n"+
-			"🎯 Доступно для сбережений: %d₽\n",
-		totalIncome, totalExpense, availableForSavings,
+			"💰 Общий доход: %s\n"+
+			"💸 Общие расходы: %s\n"+
+			"🎯 Доступно для сбережений: %s\n",
+		models.FormatAmount(totalIncome, baseCurrency),
+		models.FormatAmount(totalExpense, baseCurrency),
+		models.FormatAmount(availableForSavings, baseCurrency),
 	)
+	text += rateHint
+	text += "📋 План и факт по доходам: /report\n"
+
+	spending, err := h.financeService.GetCategorySpending(c, userID)
+	if err != nil {
+		c.Logf("Failed to get category spending: %v", err)
+	}
+	if totalExpense > 0 && len(spending) > 0 {
+		text += "\n🏷 Расходы по категориям:\n"
+		for _, cs := range spending {
+			if cs.Spent == 0 && cs.Category.MonthlyLimit == 0 {
+				continue
+			}
+			text += formatCategorySpending(cs, baseCurrency)
+		}
+	}
 
 	if len(goals) > 0 {
 		text += "\n🎯 Цели накопления:\n\n"
-		totalSaved := int64(0)
-		totalMonthlyContrib := int64(0)
+		totalSaved := models.Money(0)
+		totalMonthlyContrib := models.Money(0)
+
+		// итоги по целям в разных валютах сводим к базовой
+		converter, err := h.financeService.CurrencyConverter(c)
+		if err != nil {
+			c.Logf("Failed to load rates: %v", err)
+		}
 
 		for _, goal := range goals {
-			progress := int64(0)
-			if goal.TargetAmount > 0 {
-				progress = (goal.CurrentAmount * 100) / goal.TargetAmount
-			}
+			progress := goal.CurrentAmount.Percent(goal.TargetAmount)
 			remaining := goal.TargetAmount - goal.CurrentAmount
 			if remaining < 0 {
 				remaining = 0
@@ -250,55 +315,68 @@ func (h *BotHandler) handleShowStats(message *tgbotapi.Message) {
 
 			text += fmt.Sprintf(
 				"🎯 %s\n"+
-					"   Накоплено: %d₽ / %d₽ (%d%%)\n"+
-					"   Копится в месяц: %d₽\n"+
-					"   Осталось: %d₽\n\n",
-				goal.GoalName, goal.CurrentAmount, goal.TargetAmount, progress,
-				goal.MonthlyContrib, remaining,
+					"   Накоплено: %s / %s (%d%%)\n"+
+					"   Копится в месяц: %s\n"+
+					"   Осталось: %s\n\n",
+				goal.GoalName,
+				models.FormatAmount(goal.CurrentAmount, goal.Currency), models.FormatAmount(goal.TargetAmount, goal.Currency), progress,
+				models.FormatAmount(goal.MonthlyContrib, goal.Currency), models.FormatAmount(remaining, goal.Currency),
 			)
 
-			totalSaved += goal.CurrentAmount
-			totalMonthlyContrib += goal.MonthlyContrib
+			if converter == nil {
+				continue
+			}
+			saved, err := converter.Convert(goal.CurrentAmount, goal.Currency, baseCurrency)
+			if err != nil {
+				c.Logf("Failed to convert goal %d: %v", goal.ID, err)
+				continue
+			}
+			monthly, err := converter.Convert(goal.MonthlyContrib, goal.Currency, baseCurrency)
+			if err != nil {
+				c.Logf("Failed to convert goal %d: %v", goal.ID, err)
+				continue
+			}
+			totalSaved += saved
+			totalMonthlyContrib += monthly
 		}
 
 		text += fmt.Sprintf(
 			"📊 Итого:\n"+
-				"   Всего накоплено: %d₽\n"+
-				"   Всего копится в месяц: %d₽\n",
-			totalSaved, totalMonthlyContrib,
+				"   Всего накоплено: %s\n"+
+				"   Всего копится в месяц: %s\n",
+			models.FormatAmount(totalSaved, baseCurrency), models.FormatAmount(totalMonthlyContrib, baseCurrency),
 		)
 	}
 
-	h.sendMessageWithKeyboard(chatID, text, h.mainMenu())
+	h.sendMessageWithKeyboard(c, chatID, text, h.mainMenu())
 }
 
-func (h *BotHandler) handleTestPaydayCommand(message *tgbotapi.Message) {
-	userID := message.From.ID
-	chatID := message.Chat.ID
-	ctx := context.Background()
+func (h *BotHandler) handleTestPaydayCommand(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
 
-	args := strings.Fields(message.Text)
+	args := strings.Fields(c.Text())
 	if len(args) < 2 {
-		h.sendMessage(chatID, "❌ Использование: /testpayday [порядковый_номер_дохода]\n\nСначала посмотрите список своих доходов (номер 1,2,3...)")
+		h.sendMessage(c, chatID, "❌ Использование: /testpayday [порядковый_номер_дохода]\n\nСначала посмотрите список своих доходов (номер 1,2,3...)")
 		return
 	}
 
 	incomeIndexStr := args[1]
 	incomeIndex, err := strconv.Atoi(incomeIndexStr)
 	if err != nil || incomeIndex < 1 {
-		h.sendMessage(chatID, "❌ Номер дохода должен быть числом от 1")
+		h.sendMessage(c, chatID, "❌ Номер дохода должен быть числом от 1")
 		return
 	}
 
 	// список доходов пользователя
-	incomes, err := h.financeService.GetUserIncomes(ctx, userID)
+	incomes, err := h.financeService.GetUserIncomes(c, userID)
 	if err != nil {
-		h.sendMessage(chatID, "❌ Ошибка при загрузке доходов")
+		h.sendMessage(c, chatID, "❌ Ошибка при загрузке доходов")
 		return
 	}
 
 	if len(incomes) < incomeIndex {
-		h.sendMessage(chatID, fmt.Sprintf("❌ Номер дохода должен быть от 1 до %d", len(incomes)))
+		h.sendMessage(c, chatID, fmt.Sprintf("❌ Номер дохода должен быть от 1 до %d", len(incomes)))
 		return
 	}
 
@@ -306,11 +384,11 @@ func (h *BotHandler) handleTestPaydayCommand(message *tgbotapi.Message) {
 	income := incomes[incomeIndex-1]
 	incomeID := income.ID
 
-	err = h.financeService.TestPaydayNotification(h.bot, ctx, userID, incomeID)
+	err = h.financeService.TestPaydayNotification(h.bot, c, userID, incomeID)
 	if err != nil {
-		h.sendMessage(chatID, fmt.Sprintf("❌ Ошибка: %v", err))
+		h.sendMessage(c, chatID, fmt.Sprintf("❌ Ошибка: %v", err))
 		return
 	}
 
-	h.sendMessage(chatID, "✅ Тестовое уведомление отправлено!")
+	h.sendMessage(c, chatID, "✅ Тестовое уведомление отправлено!")
 }
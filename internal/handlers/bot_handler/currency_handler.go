@@ -0,0 +1,119 @@
+package bot_handler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/router"
+	"github.com/Lina3386/telegram-bot/internal/services"
+)
+
+// /rate - список курсов, /rate USD 92.5 - задать курс в рублях за единицу.
+// Курсы общие для всех пользователей, поэтому менять их может только администратор
+func (h *BotHandler) HandleRate(c *router.Context) {
+	chatID := c.ChatID()
+	isAdmin := h.admins[c.UserID()]
+
+	args := strings.Fields(c.Message.CommandArguments())
+	if len(args) == 0 {
+		rates, err := h.financeService.GetExchangeRates(c)
+		if err != nil {
+			c.Logf("Failed to get rates: %v", err)
+			h.sendMessage(c, chatID, "❌ Ошибка при загрузке курсов")
+			return
+		}
+
+		text := "💱 Курсы валют (в рублях за единицу):\n\n"
+		if len(rates) == 0 {
+			text += "Курсы пока не заданы\n"
+		}
+		for _, rate := range rates {
+			text += fmt.Sprintf("%s: %.4f (обновлен %s)\n", rate.Currency, rate.Rate, rate.UpdatedAt.Format("02.01.2006"))
+		}
+		if isAdmin {
+			text += "\nЧтобы задать курс: /rate USD 92.5"
+		} else {
+			text += "\nКурсы задает администратор бота"
+		}
+		h.sendMessage(c, chatID, text)
+		return
+	}
+
+	if !isAdmin {
+		c.Logf("[RATE] User %d is not allowed to set exchange rates", c.UserID())
+		h.sendMessage(c, chatID, "⛔ Курсы валют общие для всех пользователей, их может менять только администратор бота")
+		return
+	}
+
+	if len(args) != 2 {
+		h.sendMessage(c, chatID, "❌ Использование: /rate USD 92.5")
+		return
+	}
+
+	rate, err := strconv.ParseFloat(strings.ReplaceAll(args[1], ",", "."), 64)
+	if err != nil || rate <= 0 {
+		h.sendMessage(c, chatID, "❌ Курс должен быть положительным числом")
+		return
+	}
+
+	currency, ok := models.NormalizeCurrency(args[0])
+	if !ok || currency == models.DefaultCurrency {
+		h.sendMessage(c, chatID, "❌ Укажите код валюты, например USD или EUR")
+		return
+	}
+
+	if err := h.financeService.SetExchangeRate(c, currency, rate); err != nil {
+		c.Logf("Failed to set rate: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при сохранении курса")
+		return
+	}
+
+	h.sendMessage(c, chatID, fmt.Sprintf("✅ Курс сохранен: 1 %s = %.4f₽", currency, rate))
+}
+
+// /currency - текущая базовая валюта, /currency USD - сменить её
+func (h *BotHandler) HandleCurrency(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	args := strings.Fields(c.Message.CommandArguments())
+	if len(args) == 0 {
+		h.sendMessage(c, chatID, fmt.Sprintf("💱 Базовая валюта: %s\n\nВсе итоги считаются в ней. Сменить: /currency USD", c.User.BaseCurrency))
+		return
+	}
+
+	err := h.financeService.SetBaseCurrency(c, userID, args[0])
+	if errors.Is(err, services.ErrNoExchangeRate) {
+		h.sendMessage(c, chatID, fmt.Sprintf("❌ Для этой валюты нет курса. Курсы задает администратор бота: /rate %s 92.5", strings.ToUpper(args[0])))
+		return
+	}
+	if err != nil {
+		c.Logf("Failed to set base currency: %v", err)
+		h.sendMessage(c, chatID, "❌ Укажите код валюты, например RUB, USD или EUR")
+		return
+	}
+
+	currency, _ := models.NormalizeCurrency(args[0])
+	h.sendMessage(c, chatID, fmt.Sprintf("✅ Базовая валюта: %s", currency))
+}
+
+// разбирает сумму с необязательной валютой; без валюты возвращает пустой код
+func (h *BotHandler) parseAmountInput(c *router.Context, text string) (models.Money, string, bool) {
+	amount, currency, err := models.ParseAmountWithCurrency(text)
+	if err != nil || amount <= 0 {
+		h.sendMessage(c, c.ChatID(), "❌ Введите корректную сумму, например 1500, 1 499,90, 15к или 100 USD")
+		return 0, "", false
+	}
+	return amount, currency, true
+}
+
+// подсказка о курсах, когда итоги нельзя пересчитать
+func noRateHint(err error) string {
+	if errors.Is(err, services.ErrNoExchangeRate) {
+		return "\n⚠️ Не для всех валют задан курс - итоги неполные. Курсы задает администратор бота: /rate USD 92.5\n"
+	}
+	return ""
+}
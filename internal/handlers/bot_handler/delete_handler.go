@@ -0,0 +1,190 @@
+package bot_handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/router"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// сколько действует кнопка подтверждения удаления
+const deleteConfirmTTL = 5 * time.Minute
+
+// askDeleteConfirmation - первое нажатие "Удалить": показываем, что пропадет, и просим подтвердить
+func (h *BotHandler) askDeleteConfirmation(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	resourceType, id := c.Params.String("type"), c.Params.Int64("id")
+
+	issued := time.Now().Unix()
+	confirmData := fmt.Sprintf("%s/%d/delete/confirm/%d", resourceType, id, issued)
+	cancelData := fmt.Sprintf("%s/%d/delete/cancel", resourceType, id)
+
+	var text string
+	var buttons [][]tgbotapi.InlineKeyboardButton
+	switch resourceType {
+	case "income":
+		income, err := h.financeService.GetUserIncomeByID(c, userID, id)
+		if err != nil {
+			c.Logf("Failed to get income: %v", err)
+			h.answerCallback(c, c.Callback.ID, "❌ Доход не найден")
+			return
+		}
+		text = fmt.Sprintf("❓ Удалить доход?\n\n💰 %s: %s (%s)\n\n"+
+			"Доход перестанет учитываться в бюджете и распределении по целям, напоминаний о выплатах больше не будет",
+			income.Name, models.FormatAmount(income.Amount, income.Currency), incomeScheduleText(*income))
+
+	case "expense":
+		expense, err := h.financeService.GetUserExpenseByID(c, userID, id)
+		if err != nil {
+			c.Logf("Failed to get expense: %v", err)
+			h.answerCallback(c, c.Callback.ID, "❌ Расход не найден")
+			return
+		}
+		text = fmt.Sprintf("❓ Удалить расход?\n\n%s: %s (%s)\n\nРасход перестанет учитываться в бюджете и лимитах категорий",
+			expense.Name, models.FormatAmount(expense.Amount, expense.Currency), expenseScheduleText(*expense))
+
+	case "goal":
+		info, err := h.financeService.GetGoalDeletionInfo(c, userID, id)
+		if err != nil {
+			c.Logf("Failed to get goal deletion info: %v", err)
+			h.answerCallback(c, c.Callback.ID, "❌ Цель не найдена")
+			return
+		}
+		goal := info.Goal
+		text = fmt.Sprintf("❓ Удалить цель «%s»?\n\nБудет потеряно:\n💰 Накоплено: %s из %s\n📅 Записей о месячных взносах: %d\n📜 История операций по цели",
+			goal.GoalName, models.FormatAmount(goal.CurrentAmount, goal.Currency), models.FormatAmount(goal.TargetAmount, goal.Currency), info.Contributions)
+
+		// с деньгами на цели сначала предлагаем их перенести
+		if len(info.Transfers) > 0 {
+			text += "\n\nНакопленное можно перенести на другую цель и удалить эту:"
+			for _, other := range info.Transfers {
+				buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData("➡️ Перенести на «"+other.GoalName+"»", fmt.Sprintf("goal/%d/delete/move/%d/%d", goal.ID, other.ID, issued)),
+				))
+			}
+		}
+
+	default:
+		h.answerCallback(c, c.Callback.ID, "❌ Неизвестное действие")
+		return
+	}
+
+	text += fmt.Sprintf("\n\n⏳ Подтверждение действует %s", undoWindowText(deleteConfirmTTL))
+	buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ Да, удалить", confirmData),
+		tgbotapi.NewInlineKeyboardButtonData("❌ Нет", cancelData),
+	))
+
+	h.answerCallback(c, c.Callback.ID, "✅")
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons...)
+	if _, err := h.bot.Send(c, msg); err != nil {
+		c.Logf("Failed to send delete confirmation: %v", err)
+	}
+}
+
+// handleDeleteConfirm - <тип>/<id>/delete/confirm/<время показа>
+func (h *BotHandler) handleDeleteConfirm(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	resourceType, id := c.Params.String("type"), c.Params.Int64("id")
+	if deleteConfirmExpired(c.Params.Int64("issued")) {
+		h.answerCallback(c, c.Callback.ID, "⌛ Подтверждение устарело")
+		h.sendMessage(c, chatID, "⌛ Подтверждение устарело - ничего не удалено. Нажмите «Удалить» еще раз")
+		h.showDeleteSource(c, resourceType, id)
+		return
+	}
+
+	switch resourceType {
+	case "income":
+		deleted, err := h.financeService.DeleteIncome(c, userID, id)
+		if err != nil {
+			c.Logf("Failed to delete income: %v", err)
+			h.answerCallback(c, c.Callback.ID, "❌ Ошибка при удалении")
+			return
+		}
+		h.answerCallback(c, c.Callback.ID, "✅ Доход удален")
+		h.handleShowIncomes(c)
+		h.sendUndoOffer(c, fmt.Sprintf("🗑️ Доход «%s» удален", deleted.Name), fmt.Sprintf("income/%d/undo", deleted.ID))
+
+	case "expense":
+		deleted, err := h.financeService.DeleteExpense(c, userID, id)
+		if err != nil {
+			c.Logf("Failed to delete expense: %v", err)
+			h.answerCallback(c, c.Callback.ID, "❌ Ошибка при удалении")
+			return
+		}
+		h.answerCallback(c, c.Callback.ID, "✅ Расход удален")
+		h.handleShowExpenses(c)
+		h.sendUndoOffer(c, fmt.Sprintf("🗑️ Расход «%s» удален", deleted.Name), fmt.Sprintf("expense/%d/undo", deleted.ID))
+
+	case "goal":
+		deleted, err := h.financeService.DeleteGoal(c, userID, id)
+		if err != nil {
+			c.Logf("Failed to delete goal: %v", err)
+			h.answerCallback(c, c.Callback.ID, "❌ Ошибка при удалении")
+			return
+		}
+		h.answerCallback(c, c.Callback.ID, "✅ Цель удалена")
+		h.handleShowGoals(c)
+		h.sendUndoOffer(c, fmt.Sprintf("🗑️ Цель «%s» удалена", deleted.GoalName), fmt.Sprintf("goal/%d/undo", deleted.ID))
+
+	default:
+		h.answerCallback(c, c.Callback.ID, "❌ Неизвестное действие")
+	}
+}
+
+// handleDeleteMove - goal/<цель>/delete/move/<куда перенести>/<время показа>
+func (h *BotHandler) handleDeleteMove(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	goalID, toGoalID := c.Params.Int64("id"), c.Params.Int64("to")
+	if deleteConfirmExpired(c.Params.Int64("issued")) {
+		h.answerCallback(c, c.Callback.ID, "⌛ Подтверждение устарело")
+		h.sendMessage(c, chatID, "⌛ Подтверждение устарело - ничего не удалено и не перенесено. Нажмите «Удалить» еще раз")
+		h.showGoalDetailsV2(c, goalID)
+		return
+	}
+
+	deleted, moved, to, err := h.financeService.DeleteGoalMovingBalance(c, userID, goalID, toGoalID)
+	if err != nil {
+		c.Logf("Failed to move balance and delete goal: %v", err)
+		h.answerCallback(c, c.Callback.ID, "❌ Ошибка при переносе")
+		h.sendMessage(c, chatID, "❌ Не удалось перенести накопленное"+noRateHint(err)+"\nЦель не удалена")
+		return
+	}
+
+	h.answerCallback(c, c.Callback.ID, "✅ Перенесено")
+	h.handleShowGoals(c)
+	h.sendUndoOffer(c,
+		fmt.Sprintf("➡️ %s перенесено на «%s»\n🗑️ Цель «%s» удалена\n\nОтмена вернет цель, но не перенос: его можно сделать снятием и пополнением",
+			models.FormatAmount(moved, to.Currency), to.GoalName, deleted.GoalName),
+		fmt.Sprintf("goal/%d/undo", deleted.ID))
+}
+
+func (h *BotHandler) handleDeleteCancel(c *router.Context) {
+	h.answerCallback(c, c.Callback.ID, "Удаление отменено")
+	h.showDeleteSource(c, c.Params.String("type"), c.Params.Int64("id"))
+}
+
+// возвращает на экран, с которого начали удаление
+func (h *BotHandler) showDeleteSource(c *router.Context, resourceType string, id int64) {
+	switch resourceType {
+	case "income":
+		h.handleShowIncomes(c)
+	case "expense":
+		h.handleShowExpenses(c)
+	case "goal":
+		h.showGoalDetailsV2(c, id)
+	}
+}
+
+func deleteConfirmExpired(issued int64) bool {
+	return time.Since(time.Unix(issued, 0)) > deleteConfirmTTL
+}
@@ -0,0 +1,409 @@
+package bot_handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/router"
+	"github.com/Lina3386/telegram-bot/internal/services"
+	"github.com/Lina3386/telegram-bot/internal/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const expenseFrequencyPrompt = "Как часто повторяется расход?\n\n1️⃣ Ежемесячно (monthly)\n2️⃣ Еженедельно (weekly)\n3️⃣ Через неделю (biweekly)\n\nВведите число от 1 до 3:"
+
+// handleEditMenuCallback - <тип>/<id>/edit: меню полей для изменения
+func (h *BotHandler) handleEditMenuCallback(c *router.Context) {
+	id := c.Params.Int64("id")
+
+	switch c.Params.String("type") {
+	case "income":
+		h.showIncomeEditMenu(c, id)
+	case "expense":
+		h.showExpenseEditMenu(c, id)
+	case "goal":
+		h.showGoalEditMenu(c, id)
+	default:
+		h.answerCallback(c, c.Callback.ID, "❌ Неизвестное действие")
+		return
+	}
+	h.answerCallback(c, c.Callback.ID, "✅")
+}
+
+// handleEditFieldCallback - <тип>/<id>/edit/<поле>: ввод нового значения
+func (h *BotHandler) handleEditFieldCallback(c *router.Context) {
+	h.answerCallback(c, c.Callback.ID, "✅ Введите данные")
+	h.startEdit(c, c.Params.String("type"), c.Params.String("field"), c.Params.Int64("id"))
+}
+
+func (h *BotHandler) showIncomeEditMenu(c *router.Context, incomeID int64) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	income, err := h.financeService.GetUserIncomeByID(c, userID, incomeID)
+	if err != nil {
+		log.Printf("Failed to get income: %v", err)
+		h.sendMessage(c, chatID, "❌ Доход не найден")
+		return
+	}
+
+	text := fmt.Sprintf("✏️ Изменение дохода\n\n💰 %s: %s\n📅 %s\n",
+		income.Name, models.FormatAmount(income.Amount, income.Currency), incomeScheduleText(*income))
+	buttons := [][]tgbotapi.InlineKeyboardButton{
+		{
+			tgbotapi.NewInlineKeyboardButtonData("📝 Название", fmt.Sprintf("income/%d/edit/name", income.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("💵 Сумма", fmt.Sprintf("income/%d/edit/amount", income.ID)),
+		},
+	}
+
+	scheduleRow := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("📅 Расписание", fmt.Sprintf("income/%d/edit/schedule", income.ID)),
+	}
+	if !income.IsIrregular() {
+		text += fmt.Sprintf("🔔 Уведомления в %d:00\n", income.NotificationHour)
+		scheduleRow = append(scheduleRow, tgbotapi.NewInlineKeyboardButtonData("🔔 Час уведомлений", fmt.Sprintf("income/%d/edit/hour", income.ID)))
+	}
+	buttons = append(buttons, scheduleRow)
+	buttons = append(buttons, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад к доходам", "incomes"),
+	})
+
+	msg := tgbotapi.NewMessage(chatID, text+"\nЧто изменить?")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons...)
+	if _, err := h.bot.Send(c, msg); err != nil {
+		log.Printf("Failed to send income edit menu: %v", err)
+	}
+}
+
+func (h *BotHandler) showExpenseEditMenu(c *router.Context, expenseID int64) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	expense, err := h.financeService.GetUserExpenseByID(c, userID, expenseID)
+	if err != nil {
+		log.Printf("Failed to get expense: %v", err)
+		h.sendMessage(c, chatID, "❌ Расход не найден")
+		return
+	}
+
+	text := fmt.Sprintf("✏️ Изменение расхода\n\n%s: %s\n📅 %s\n",
+		expense.Name, models.FormatAmount(expense.Amount, expense.Currency), expenseScheduleText(*expense))
+	row := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("📝 Название", fmt.Sprintf("expense/%d/edit/name", expense.ID)),
+		tgbotapi.NewInlineKeyboardButtonData("💵 Сумма", fmt.Sprintf("expense/%d/edit/amount", expense.ID)),
+	}
+	buttons := [][]tgbotapi.InlineKeyboardButton{row}
+	// у разовой траты периодичности нет
+	if expense.Kind != models.ExpenseKindOneOff {
+		buttons = append(buttons, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("📅 Периодичность", fmt.Sprintf("expense/%d/edit/schedule", expense.ID)),
+		})
+	}
+	buttons = append(buttons, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад к расходам", "expenses"),
+	})
+
+	msg := tgbotapi.NewMessage(chatID, text+"\nЧто изменить?")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons...)
+	if _, err := h.bot.Send(c, msg); err != nil {
+		log.Printf("Failed to send expense edit menu: %v", err)
+	}
+}
+
+func (h *BotHandler) showGoalEditMenu(c *router.Context, goalID int64) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	goal, err := h.financeService.GetUserGoalByID(c, userID, goalID)
+	if err != nil {
+		log.Printf("Failed to get goal: %v", err)
+		h.sendMessage(c, chatID, "❌ Цель не найдена")
+		return
+	}
+
+	text := fmt.Sprintf("✏️ Изменение цели\n\n🎯 %s\nЦель: %s\nСобрано: %s\n\nЧто изменить?",
+		goal.GoalName, models.FormatAmount(goal.TargetAmount, goal.Currency), models.FormatAmount(goal.CurrentAmount, goal.Currency))
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📝 Название", fmt.Sprintf("goal/%d/edit/name", goal.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("💵 Целевая сумма", fmt.Sprintf("goal/%d/edit/target", goal.ID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔙 Вернуться к цели", fmt.Sprintf("goal/%d", goal.ID)),
+		),
+	)
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = keyboard
+	if _, err := h.bot.Send(c, msg); err != nil {
+		log.Printf("Failed to send goal edit menu: %v", err)
+	}
+}
+
+// начинает ввод нового значения поля; расписания редактируются шагами диалогов добавления
+func (h *BotHandler) startEdit(c *router.Context, entity string, field string, id int64) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	if !h.dialogOK(c, h.stateManager.ClearState(c, c.UserID())) ||
+		!h.setTempData(c, "edit_type", entity) ||
+		!h.setTempData(c, "edit_id", strconv.FormatInt(id, 10)) {
+		return
+	}
+
+	switch entity + "_" + field {
+	case "income_name", "expense_name", "goal_name":
+		if !h.setState(c, state.StateEditingName) {
+			return
+		}
+		h.sendMessage(c, chatID, "Введите новое название:")
+
+	case "income_amount", "expense_amount":
+		if !h.setState(c, state.StateEditingAmount) {
+			return
+		}
+		h.sendMessage(c, chatID, "Введите новую сумму (например 50000 или 1 499,90):")
+
+	case "goal_target":
+		if !h.setState(c, state.StateEditingAmount) {
+			return
+		}
+		h.sendMessage(c, chatID, "Введите новую целевую сумму:")
+
+	case "income_hour":
+		if !h.setState(c, state.StateEditingIncomeHour) {
+			return
+		}
+		h.sendMessage(c, chatID, "В каком часу получать уведомления? (0-23, например 9 для 9:00, 18 для 18:00)")
+
+	case "income_schedule":
+		income, err := h.financeService.GetUserIncomeByID(c, userID, id)
+		if err != nil {
+			log.Printf("Failed to get income: %v", err)
+			h.clearState(c)
+			h.sendMessage(c, chatID, "❌ Доход не найден")
+			return
+		}
+		if !h.setTempData(c, "edit_income_id", strconv.FormatInt(income.ID, 10)) ||
+			!h.setTempData(c, "income_amount", strconv.FormatInt(int64(income.Amount), 10)) ||
+			!h.setTempData(c, "income_currency", income.Currency) ||
+			!h.setState(c, state.StateAddingIncomeFrequency) {
+			return
+		}
+		h.sendMessage(c, chatID, incomeFrequencyPrompt())
+
+	case "expense_schedule":
+		if !h.setTempData(c, "edit_expense_id", strconv.FormatInt(id, 10)) ||
+			!h.setState(c, state.StateAddingExpenseFrequency) {
+			return
+		}
+		h.sendMessage(c, chatID, expenseFrequencyPrompt)
+
+	default:
+		h.clearState(c)
+		h.sendMessage(c, chatID, "❌ Неизвестное действие")
+	}
+}
+
+func (h *BotHandler) editTarget(c *router.Context) (string, int64, bool) {
+	session, ok := h.dialogSession(c)
+	if !ok {
+		return "", 0, false
+	}
+
+	id, _ := strconv.ParseInt(session.Get("edit_id"), 10, 64)
+	return session.Get("edit_type"), id, true
+}
+
+func (h *BotHandler) handleEditNameInput(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	name := strings.TrimSpace(c.Text())
+	if name == "" {
+		h.sendMessage(c, chatID, "❌ Название не может быть пустым")
+		return
+	}
+
+	entity, id, ok := h.editTarget(c)
+	if !ok {
+		return
+	}
+	var err error
+	switch entity {
+	case "income":
+		err = h.financeService.RenameIncome(c, userID, id, name)
+	case "expense":
+		err = h.financeService.RenameExpense(c, userID, id, name)
+	case "goal":
+		err = h.financeService.RenameGoal(c, userID, id, name)
+	}
+	h.clearState(c)
+	if err != nil {
+		c.Logf("Failed to rename %s %d: %v", entity, id, err)
+		h.sendMessage(c, chatID, "❌ Ошибка при сохранении")
+		return
+	}
+
+	h.sendMessageWithKeyboard(c, chatID, "✅ Название изменено", h.mainMenu())
+	h.showEditResult(c, entity, id)
+}
+
+func (h *BotHandler) handleEditAmountInput(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	amount, err := models.ParseMoney(c.Text())
+	if err != nil || amount <= 0 {
+		h.sendMessage(c, chatID, "❌ Введите корректную сумму, например 1500 или 1 499,90")
+		return
+	}
+
+	entity, id, ok := h.editTarget(c)
+	if !ok {
+		return
+	}
+	switch entity {
+	case "income":
+		_, err = h.financeService.UpdateIncomeAmount(c, userID, id, amount)
+		if errors.Is(err, services.ErrInstallmentsTotal) {
+			// части заданы суммами и с новой суммой не сходятся - задаем их заново
+			if !h.setTempData(c, "edit_income_id", strconv.FormatInt(id, 10)) ||
+				!h.setTempData(c, "income_amount", strconv.FormatInt(int64(amount), 10)) {
+				return
+			}
+			if income, errGet := h.financeService.GetUserIncomeByID(c, userID, id); errGet == nil && !h.setTempData(c, "income_currency", income.Currency) {
+				return
+			}
+			if !h.setTempData(c, "income_frequency", models.IncomeFrequencyInstallments) ||
+				!h.setState(c, state.StateAddingIncomeDay) {
+				return
+			}
+			h.sendMessage(c, chatID, "Части дохода заданы суммами и не сходятся с новой суммой.\n\n"+incomeInstallmentsPrompt())
+			return
+		}
+	case "expense":
+		err = h.financeService.UpdateExpenseAmount(c, userID, id, amount)
+	case "goal":
+		var goal *models.SavingsGoal
+		goal, err = h.financeService.UpdateGoalTarget(c, userID, id, amount)
+		if errors.Is(err, services.ErrTargetBelowSaved) {
+			h.sendMessage(c, chatID, "❌ Целевая сумма должна быть больше уже накопленной. Введите другую сумму:")
+			return
+		}
+		if err == nil {
+			h.clearState(c)
+			h.sendMessageWithKeyboard(c, chatID, fmt.Sprintf("✅ Новая цель: %s", models.FormatAmount(goal.TargetAmount, goal.Currency)), h.mainMenu())
+			h.showGoalDetailsV2(c, goal.ID)
+			return
+		}
+	}
+	h.clearState(c)
+	if err != nil {
+		c.Logf("Failed to update %s %d amount: %v", entity, id, err)
+		h.sendMessage(c, chatID, "❌ Ошибка при сохранении")
+		return
+	}
+
+	h.sendMessageWithKeyboard(c, chatID, "✅ Сумма изменена, распределение по целям пересчитано", h.mainMenu())
+	h.showEditResult(c, entity, id)
+}
+
+func (h *BotHandler) handleEditHourInput(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	hour, err := strconv.Atoi(strings.TrimSpace(c.Text()))
+	if err != nil || hour < 0 || hour > 23 {
+		h.sendMessage(c, chatID, "❌ Введите число от 0 до 23")
+		return
+	}
+
+	_, id, ok := h.editTarget(c)
+	if !ok {
+		return
+	}
+	h.clearState(c)
+	if err := h.financeService.UpdateIncomeNotificationHour(c, userID, id, hour); err != nil {
+		c.Logf("Failed to update notification hour for income %d: %v", id, err)
+		h.sendMessage(c, chatID, "❌ Ошибка при сохранении")
+		return
+	}
+
+	h.sendMessageWithKeyboard(c, chatID, fmt.Sprintf("✅ Уведомления теперь в %d:00", hour), h.mainMenu())
+	h.showIncomeEditMenu(c, id)
+}
+
+// сохраняет расписание дохода, собранное шагами диалога добавления
+func (h *BotHandler) saveIncomeScheduleEdit(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	session, ok := h.dialogSession(c)
+	if !ok {
+		return
+	}
+
+	incomeID, _ := strconv.ParseInt(session.Get("edit_income_id"), 10, 64)
+	amountMinor, _ := strconv.ParseInt(session.Get("income_amount"), 10, 64)
+	rule := incomeRuleFromDialog(session)
+
+	var installments []models.IncomeInstallment
+	if string(rule.Kind) == models.IncomeFrequencyInstallments {
+		installments = decodeInstallments(session.Get("income_installments"))
+	}
+
+	income, err := h.financeService.UpdateIncomeSchedule(c, userID, incomeID, models.Money(amountMinor), rule, installments)
+	h.clearState(c)
+	if err != nil {
+		log.Printf("Failed to update income schedule: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при сохранении расписания")
+		return
+	}
+
+	text := fmt.Sprintf("✅ Расписание изменено:\n%s: %s (%s)", income.Name, models.FormatAmount(income.Amount, income.Currency), incomeScheduleText(*income))
+	if !income.IsIrregular() {
+		text += fmt.Sprintf("\n📅 Ближайшая выплата: %s", income.NextPayDate.Format("02.01.2006"))
+	}
+	h.sendMessageWithKeyboard(c, chatID, text, h.mainMenu())
+	h.showIncomeEditMenu(c, income.ID)
+}
+
+func (h *BotHandler) saveExpenseScheduleEdit(c *router.Context, recurringDay int) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	session, ok := h.dialogSession(c)
+	if !ok {
+		return
+	}
+
+	expenseID, _ := strconv.ParseInt(session.Get("edit_expense_id"), 10, 64)
+	frequency := session.Get("expense_frequency")
+
+	err := h.financeService.UpdateExpenseSchedule(c, userID, expenseID, frequency, recurringDay)
+	h.clearState(c)
+	if err != nil {
+		log.Printf("Failed to update expense schedule: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при сохранении")
+		return
+	}
+
+	h.sendMessageWithKeyboard(c, chatID, "✅ Периодичность изменена", h.mainMenu())
+	h.showExpenseEditMenu(c, expenseID)
+}
+
+func (h *BotHandler) showEditResult(c *router.Context, entity string, id int64) {
+	switch entity {
+	case "income":
+		h.showIncomeEditMenu(c, id)
+	case "expense":
+		h.showExpenseEditMenu(c, id)
+	case "goal":
+		h.showGoalDetailsV2(c, id)
+	}
+}
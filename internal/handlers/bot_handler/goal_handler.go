@@ -0,0 +1,250 @@
+package bot_handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/router"
+	"github.com/Lina3386/telegram-bot/internal/services"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// последний шаг диалога создания цели: сумма и валюта уже в temp data
+func (h *BotHandler) createGoalFromDialog(c *router.Context, deadline sql.NullTime) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	session, ok := h.dialogSession(c)
+	if !ok {
+		return
+	}
+
+	targetRaw, _ := strconv.ParseInt(session.Get("goal:target"), 10, 64)
+	targetAmount := models.Money(targetRaw)
+	currency := session.Get("goal:currency")
+
+	allGoals, err := h.financeService.GetUserGoals(c, userID)
+	if err != nil {
+		h.sendMessage(c, chatID, "❌ Ошибка при получении списка целей")
+		h.clearState(c)
+		return
+	}
+
+	maxPriority := 0
+	for _, g := range allGoals {
+		if g.HoldsPriority() && g.Priority > maxPriority {
+			maxPriority = int(g.Priority)
+		}
+	}
+	newPriority := maxPriority + 1
+
+	goalName := session.Get("goal_name")
+	goal, err := h.financeService.CreateGoal(c, userID, goalName, targetAmount, currency, deadline, newPriority)
+	if errors.Is(err, services.ErrNoExchangeRate) {
+		h.sendMessage(c, chatID, fmt.Sprintf("❌ Для валюты %s не задан курс. Курсы задает администратор бота (/rate %s 92.5), после этого создайте цель заново", currency, currency))
+		h.clearState(c)
+		return
+	}
+	if errors.Is(err, services.ErrDeadlineInPast) {
+		h.sendMessage(c, chatID, "❌ Срок должен быть в будущем. Введите другую дату или \"нет\":")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create goal: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка создания цели")
+		return
+	}
+
+	var priorityText string
+	if newPriority == 1 {
+		priorityText = "Наивысший"
+	} else if newPriority == 2 {
+		priorityText = "Высокий"
+	} else if newPriority == 3 {
+		priorityText = "Низкий"
+	} else {
+		priorityText = fmt.Sprintf("Приоритет %d", newPriority)
+	}
+
+	timeToGoal := h.calculateTimeToGoal(targetAmount, goal.MonthlyContrib, 0)
+	h.clearState(c)
+
+	text := fmt.Sprintf("✅ Цель создана:\n📌 %s\n💰 Сумма: %s\n📅 Ежемесячно: %s\n⚡ Приоритет: %s (%d)\n⏱ Время до цели: %s\n📆 Дата достижения: %s", goalName, models.FormatAmount(targetAmount, goal.Currency), models.FormatAmount(goal.MonthlyContrib, goal.Currency), priorityText, newPriority, timeToGoal, goal.TargetDate.Format("02.01.2006"))
+	text += goalDeadlineText(*goal, c.User.Now())
+	h.sendMessageWithKeyboard(c, chatID, text, h.mainMenu())
+}
+
+// "нет" - цель без срока; "06.2026" - к концу месяца; now - время в зоне пользователя
+func parseGoalDeadline(text string, now time.Time) (sql.NullTime, bool) {
+	text = strings.TrimSpace(text)
+	switch strings.ToLower(text) {
+	case "нет", "-", "пропустить", "без срока":
+		return sql.NullTime{}, true
+	}
+
+	var date time.Time
+	if d, err := time.Parse("02.01.2006", text); err == nil {
+		date = d
+	} else if d, err := time.Parse("01.2006", text); err == nil {
+		date = d.AddDate(0, 1, -1)
+	} else {
+		return sql.NullTime{}, false
+	}
+
+	if !date.After(models.DayStart(now)) {
+		return sql.NullTime{}, false
+	}
+	return sql.NullTime{Time: date, Valid: true}, true
+}
+
+// строка срока для карточки цели вместе с предупреждением; now - время в зоне пользователя
+func goalDeadlineText(goal models.SavingsGoal, now time.Time) string {
+	if !goal.Deadline.Valid {
+		return ""
+	}
+	return fmt.Sprintf("\n🗓 Срок: %s", goal.Deadline.Time.Format("02.01.2006")) + deadlineWarning(goal, now)
+}
+
+// предупреждение для карточки цели, если при текущем плане срок будет пропущен
+func deadlineWarning(goal models.SavingsGoal, now time.Time) string {
+	if !goal.Deadline.Valid || goal.Status != "active" {
+		return ""
+	}
+
+	shortfall := services.DeadlineShortfall(goal, now)
+	if shortfall <= 0 {
+		return ""
+	}
+
+	// срок - дата, как в parseGoalDeadline: в сам день срока он еще не прошел
+	if goal.Deadline.Time.Before(models.DayStart(now)) {
+		return fmt.Sprintf("\n\n⚠️ Срок %s прошел, до цели не хватает %s",
+			goal.Deadline.Time.Format("02.01.2006"), models.FormatAmount(shortfall, goal.Currency))
+	}
+
+	text := fmt.Sprintf("\n\n⚠️ При текущем плане к %s не хватит %s",
+		goal.Deadline.Time.Format("02.01.2006"), models.FormatAmount(shortfall, goal.Currency))
+	if goal.TargetDate.After(goal.Deadline.Time) {
+		text += fmt.Sprintf(", цель будет достигнута только %s", goal.TargetDate.Format("02.01.2006"))
+	}
+	text += fmt.Sprintf("\nНужно откладывать %s в месяц вместо %s",
+		models.FormatAmount(services.RequiredMonthlyAmount(goal, now), goal.Currency),
+		models.FormatAmount(goal.MonthlyContrib, goal.Currency))
+	return text
+}
+
+// handleGoalStatusCallback - goal/<id>/pause, goal/<id>/resume, goal/<id>/archive
+func (h *BotHandler) handleGoalStatusCallback(action string) router.Handler {
+	return func(c *router.Context) {
+		userID := c.UserID()
+		goalID := c.Params.Int64("id")
+
+		var err error
+		var done string
+		switch action {
+		case "pause":
+			err = h.financeService.PauseGoal(c, userID, goalID)
+			done = "⏸️ Цель на паузе"
+		case "resume":
+			err = h.financeService.ResumeGoal(c, userID, goalID)
+			done = "▶️ Цель возобновлена"
+		case "archive":
+			err = h.financeService.ArchiveGoal(c, userID, goalID)
+			done = "🗄 Цель в архиве"
+		}
+
+		if errors.Is(err, services.ErrGoalStatusTransition) {
+			h.answerCallback(c, c.Callback.ID, "ℹ️ Действие недоступно для этой цели")
+			h.showGoalDetailsV2(c, goalID)
+			return
+		}
+		if err != nil {
+			c.Logf("Failed to change goal status: %v", err)
+			h.answerCallback(c, c.Callback.ID, "❌ Ошибка")
+			return
+		}
+
+		h.answerCallback(c, c.Callback.ID, done)
+		h.showGoalDetailsV2(c, goalID)
+	}
+}
+
+// архив: достигнутые цели с датой и сроком накопления, а также убранные в архив
+func (h *BotHandler) showGoalArchive(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	goals, err := h.financeService.GetArchivedGoals(c, userID)
+	if err != nil {
+		log.Printf("Failed to get archived goals: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при загрузке архива")
+		return
+	}
+
+	text := "🗄 Архив целей:\n\n"
+	if len(goals) == 0 {
+		text += "Здесь пока пусто"
+	}
+
+	var buttons [][]tgbotapi.InlineKeyboardButton
+	for _, goal := range goals {
+		if goal.CompletedAt.Valid {
+			text += fmt.Sprintf("🏆 %s: %s\n   достигнута %s за %s\n",
+				goal.GoalName,
+				models.FormatAmount(goal.TargetAmount, goal.Currency),
+				goal.CompletedAt.Time.Format("02.01.2006"),
+				formatDuration(goal.CreatedAt, goal.CompletedAt.Time),
+			)
+		} else {
+			text += fmt.Sprintf("📦 %s: %s / %s\n   не завершена, создана %s\n",
+				goal.GoalName,
+				models.FormatAmount(goal.CurrentAmount, goal.Currency),
+				models.FormatAmount(goal.TargetAmount, goal.Currency),
+				goal.CreatedAt.Format("02.01.2006"),
+			)
+		}
+
+		btn := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("ꪜ %s", goal.GoalName), fmt.Sprintf("goal/%d", goal.ID))
+		buttons = append(buttons, []tgbotapi.InlineKeyboardButton{btn})
+	}
+
+	backBtn := tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад к целям", "goals")
+	buttons = append(buttons, []tgbotapi.InlineKeyboardButton{backBtn})
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons...)
+	h.bot.Send(c, msg)
+}
+
+// сколько времени заняло накопление: "1 г. 3 мес.", "2 мес. 5 дн.", "12 дн."
+func formatDuration(from, to time.Time) string {
+	months := (to.Year()-from.Year())*12 + int(to.Month()-from.Month())
+	if to.Day() < from.Day() {
+		months--
+	}
+	if months < 0 {
+		months = 0
+	}
+	days := int(to.Sub(from.AddDate(0, months, 0)).Hours() / 24)
+
+	var parts []string
+	if years := months / 12; years > 0 {
+		parts = append(parts, fmt.Sprintf("%d г.", years))
+	}
+	if months%12 > 0 {
+		parts = append(parts, fmt.Sprintf("%d мес.", months%12))
+	}
+	if days > 0 && months < 12 {
+		parts = append(parts, fmt.Sprintf("%d дн.", days))
+	}
+	if len(parts) == 0 {
+		return "меньше дня"
+	}
+	return strings.Join(parts, " ")
+}
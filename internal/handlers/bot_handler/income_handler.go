@@ -0,0 +1,404 @@
+package bot_handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/router"
+	"github.com/Lina3386/telegram-bot/internal/schedule"
+	"github.com/Lina3386/telegram-bot/internal/services"
+	"github.com/Lina3386/telegram-bot/internal/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+type incomeFrequencyInfo struct {
+	frequency string
+	title     string
+	prompt    string // пустой - день не спрашиваем
+}
+
+// порядок соответствует номерам в диалоге добавления дохода
+var incomeFrequencyInfos = []incomeFrequencyInfo{
+	{string(schedule.Monthly), "Ежемесячно", "Введите день месяца для получения дохода (1-31):"},
+	{string(schedule.Weekly), "Еженедельно", "Введите день недели для получения дохода (0=воскресенье, 1=понедельник, ..., 6=суббота):"},
+	{string(schedule.Biweekly), "Раз в две недели", "Введите дату любой выплаты, например ближайшей (ДД.ММ.ГГГГ) - от нее будем отсчитывать каждые две недели:"},
+	{string(schedule.SemiMonthly), "Два раза в месяц (аванс и зарплата)", "Введите два дня месяца через пробел, например 10 25:"},
+	{string(schedule.LastBusinessDay), "В последний рабочий день месяца", ""},
+	{string(schedule.Quarterly), "Раз в квартал", "Введите дату ближайшей выплаты (ДД.ММ.ГГГГ):"},
+	{models.IncomeFrequencyIrregular, "Нерегулярно (фриланс, подработки)", ""},
+	{models.IncomeFrequencyInstallments, "Частями: свой день и сумма у каждой части", "Введите части дохода, каждую с новой строки: день месяца и сумму или процент. Для последней части можно написать \"остаток\". Например:\n25 40%\n10 остаток"},
+}
+
+type incomeShiftInfo struct {
+	shift schedule.Shift
+	title string
+}
+
+var incomeShiftInfos = []incomeShiftInfo{
+	{schedule.ShiftNone, "Выплата в тот же день"},
+	{schedule.ShiftPrevious, "Переносится на предыдущий рабочий день"},
+	{schedule.ShiftNext, "Переносится на следующий рабочий день"},
+}
+
+var weekdayNames = map[int]string{0: "воскресенье", 1: "понедельник", 2: "вторник", 3: "среда", 4: "четверг", 5: "пятница", 6: "суббота"}
+
+func incomeFrequencyPrompt() string {
+	text := "Выберите частоту получения дохода:\n\n"
+	for i, info := range incomeFrequencyInfos {
+		text += fmt.Sprintf("%d. %s\n", i+1, info.title)
+	}
+	return text + fmt.Sprintf("\nВведите число от 1 до %d:", len(incomeFrequencyInfos))
+}
+
+func incomeShiftPrompt() string {
+	text := "Если день выплаты выпадает на выходной или праздник:\n\n"
+	for i, info := range incomeShiftInfos {
+		text += fmt.Sprintf("%d. %s\n", i+1, info.title)
+	}
+	return text + fmt.Sprintf("\nВведите число от 1 до %d:", len(incomeShiftInfos))
+}
+
+func incomeInstallmentsPrompt() string {
+	for _, info := range incomeFrequencyInfos {
+		if info.frequency == models.IncomeFrequencyInstallments {
+			return info.prompt
+		}
+	}
+	return ""
+}
+
+// разбирает ответ на вопрос о дне выплаты; now - время в зоне пользователя
+func parseIncomeScheduleDay(kind schedule.Kind, text string, now time.Time) (schedule.Rule, string, bool) {
+	rule := schedule.Rule{Kind: kind}
+	text = strings.TrimSpace(text)
+
+	switch kind {
+	case schedule.Weekly:
+		day, err := strconv.Atoi(text)
+		if err != nil || day < 0 || day > 6 {
+			return rule, "❌ Введите число от 0 до 6 (день недели)", false
+		}
+		rule.Day = day
+
+	case schedule.Biweekly, schedule.Quarterly:
+		date, err := time.Parse("02.01.2006", text)
+		if err != nil {
+			return rule, "❌ Введите дату в формате ДД.ММ.ГГГГ", false
+		}
+		if date.Before(now.AddDate(-1, 0, 0)) || date.After(now.AddDate(1, 0, 0)) {
+			return rule, "❌ Дата должна быть в пределах года от сегодняшнего дня", false
+		}
+		rule.Day = date.Day()
+		if kind == schedule.Biweekly {
+			rule.Day = int(date.Weekday())
+		}
+		rule.Anchor = date
+
+	case schedule.SemiMonthly:
+		parts := strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == ',' || r == ';' })
+		if len(parts) != 2 {
+			return rule, "❌ Введите два дня месяца через пробел, например 10 25", false
+		}
+		first, err1 := strconv.Atoi(parts[0])
+		second, err2 := strconv.Atoi(parts[1])
+		rule.Day, rule.SecondDay = first, second
+		if err1 != nil || err2 != nil || rule.Validate() != nil {
+			return rule, "❌ Введите два разных числа от 1 до 31", false
+		}
+
+	case schedule.LastBusinessDay:
+
+	default:
+		day, err := strconv.Atoi(text)
+		if err != nil || day < 1 || day > 31 {
+			return rule, "❌ Введите число от 1 до 31", false
+		}
+		rule.Day = day
+	}
+	return rule, "", true
+}
+
+// разбирает части дохода, по строке на часть: "25 40%", "10 30000", "10 остаток"
+func parseInstallments(text string, total models.Money, currency string) ([]models.IncomeInstallment, string, bool) {
+	var installments []models.IncomeInstallment
+	rest := -1
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Sprintf("❌ Не понял строку %q: нужен день и сумма, например 25 40%%", strings.TrimSpace(line)), false
+		}
+
+		day, err := strconv.Atoi(fields[0])
+		if err != nil || day < 1 || day > 31 {
+			return nil, "❌ День выплаты должен быть числом от 1 до 31", false
+		}
+		inst := models.IncomeInstallment{Day: day}
+
+		value := strings.Join(fields[1:], "")
+		switch {
+		case strings.EqualFold(value, "остаток"):
+			if rest >= 0 {
+				return nil, "❌ \"Остаток\" может быть только у одной части", false
+			}
+			rest = len(installments)
+		case strings.HasSuffix(value, "%"):
+			percent, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+			if err != nil || percent < 1 || percent > 100 {
+				return nil, "❌ Процент должен быть числом от 1 до 100", false
+			}
+			inst.Percent = percent
+		default:
+			amount, err := models.ParseMoney(value)
+			if err != nil || amount <= 0 {
+				return nil, fmt.Sprintf("❌ Не понял сумму %q", value), false
+			}
+			inst.Amount = amount
+		}
+		installments = append(installments, inst)
+	}
+
+	if rest >= 0 {
+		sum := models.Money(0)
+		for _, inst := range installments {
+			if inst.Percent > 0 {
+				sum += total.MulDiv(int64(inst.Percent), 100)
+			} else {
+				sum += inst.Amount
+			}
+		}
+		if sum >= total {
+			return nil, "❌ На остаток ничего не осталось: остальные части уже покрывают весь доход", false
+		}
+		installments[rest].Amount = total - sum
+	}
+
+	if err := services.ValidateInstallments(total, installments); err != nil {
+		if errors.Is(err, services.ErrInstallmentsTotal) {
+			return nil, fmt.Sprintf("❌ Части в сумме должны давать весь доход %s. Поправьте суммы или напишите \"остаток\" у одной из частей", models.FormatAmount(total, currency)), false
+		}
+		return nil, "❌ Нужно минимум две части, и у каждой свой день месяца", false
+	}
+	return installments, "", true
+}
+
+// части в temp data: "день:сумма:процент;..."
+func encodeInstallments(installments []models.IncomeInstallment) string {
+	parts := make([]string, len(installments))
+	for i, inst := range installments {
+		parts[i] = fmt.Sprintf("%d:%d:%d", inst.Day, int64(inst.Amount), inst.Percent)
+	}
+	return strings.Join(parts, ";")
+}
+
+func decodeInstallments(value string) []models.IncomeInstallment {
+	var installments []models.IncomeInstallment
+	for _, part := range strings.Split(value, ";") {
+		var inst models.IncomeInstallment
+		var amount int64
+		if _, err := fmt.Sscanf(part, "%d:%d:%d", &inst.Day, &amount, &inst.Percent); err != nil {
+			continue
+		}
+		inst.Amount = models.Money(amount)
+		installments = append(installments, inst)
+	}
+	return installments
+}
+
+// описание расписания дохода; у дохода частями - список частей
+func incomeScheduleText(income models.Income) string {
+	if income.IsIrregular() {
+		return "нерегулярно, оценка в месяц; поступления - командой /got"
+	}
+	if !income.HasInstallments() {
+		return scheduleText(income.Schedule())
+	}
+
+	amounts := income.InstallmentAmounts()
+	parts := make([]string, len(income.Installments))
+	for i, inst := range income.Installments {
+		parts[i] = fmt.Sprintf("%d число - %s", inst.Day, models.FormatAmount(amounts[i], income.Currency))
+	}
+	text := "частями: " + strings.Join(parts, ", ")
+	switch schedule.Shift(income.PayShift) {
+	case schedule.ShiftPrevious:
+		text += ", с выходных - раньше"
+	case schedule.ShiftNext:
+		text += ", с выходных - позже"
+	}
+	return text
+}
+
+// описание расписания для списка доходов: "ежемесячно, 10 число"
+func scheduleText(rule schedule.Rule) string {
+	switch rule.Shift {
+	case schedule.ShiftPrevious:
+		return scheduleKindText(rule) + ", с выходных - раньше"
+	case schedule.ShiftNext:
+		return scheduleKindText(rule) + ", с выходных - позже"
+	default:
+		return scheduleKindText(rule)
+	}
+}
+
+func scheduleKindText(rule schedule.Rule) string {
+	switch rule.Kind {
+	case schedule.Weekly:
+		return fmt.Sprintf("еженедельно, %s", weekdayNames[rule.Day])
+	case schedule.Biweekly:
+		if rule.Anchor.IsZero() {
+			return fmt.Sprintf("раз в две недели, %s", weekdayNames[rule.Day])
+		}
+		return fmt.Sprintf("раз в две недели, %s (от %s)", weekdayNames[int(rule.Anchor.Weekday())], rule.Anchor.Format("02.01.2006"))
+	case schedule.SemiMonthly:
+		return fmt.Sprintf("два раза в месяц, %d и %d число", min(rule.Day, rule.SecondDay), max(rule.Day, rule.SecondDay))
+	case schedule.LastBusinessDay:
+		return "в последний рабочий день месяца"
+	case schedule.Quarterly:
+		return fmt.Sprintf("раз в квартал, %d число", rule.Day)
+	default:
+		return fmt.Sprintf("ежемесячно, %d число", rule.Day)
+	}
+}
+
+// правило из temp data диалога добавления дохода
+func incomeRuleFromDialog(session *state.UserSession) schedule.Rule {
+	rule := schedule.Rule{
+		Kind:  schedule.Kind(session.Get("income_frequency")),
+		Shift: schedule.Shift(session.Get("income_shift")),
+	}
+	rule.Day, _ = strconv.Atoi(session.Get("income_day"))
+	rule.SecondDay, _ = strconv.Atoi(session.Get("income_second_day"))
+	if anchor := session.Get("income_anchor"); anchor != "" {
+		rule.Anchor, _ = time.Parse("2006-01-02", anchor)
+	}
+	return rule
+}
+
+func (h *BotHandler) saveIncomeRuleToDialog(c *router.Context, rule schedule.Rule) bool {
+	if !h.setTempData(c, "income_day", strconv.Itoa(rule.Day)) ||
+		!h.setTempData(c, "income_second_day", strconv.Itoa(rule.SecondDay)) {
+		return false
+	}
+	if !rule.Anchor.IsZero() {
+		return h.setTempData(c, "income_anchor", rule.Anchor.Format("2006-01-02"))
+	}
+	return true
+}
+
+// HandleGot записывает поступление по нерегулярному доходу: /got 25000 или /got 25000 Фриланс
+func (h *BotHandler) HandleGot(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	args := strings.Fields(c.Message.CommandArguments())
+	if len(args) == 0 {
+		h.sendMessage(c, chatID, "❌ Использование: /got [сумма] [название дохода]\n\nНапример: /got 25000 Фриланс")
+		return
+	}
+
+	amount, err := models.ParseMoney(args[0])
+	if err != nil || amount <= 0 {
+		h.sendMessage(c, chatID, "❌ Введите корректную сумму, например /got 25000")
+		return
+	}
+
+	incomes, err := h.financeService.GetIrregularIncomes(c, userID)
+	if err != nil {
+		c.Logf("Failed to get irregular incomes: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при загрузке доходов")
+		return
+	}
+	if len(incomes) == 0 {
+		h.sendMessage(c, chatID, "ℹ️ У вас нет нерегулярных доходов. Добавьте доход с частотой \"Нерегулярно\" в разделе 💳 Мои доходы")
+		return
+	}
+
+	if name := strings.Join(args[1:], " "); name != "" {
+		for _, income := range incomes {
+			if strings.EqualFold(income.Name, name) {
+				h.logIrregularIncome(c, income.ID, amount)
+				return
+			}
+		}
+	}
+	if len(incomes) == 1 {
+		h.logIrregularIncome(c, incomes[0].ID, amount)
+		return
+	}
+
+	var buttons [][]tgbotapi.InlineKeyboardButton
+	for _, income := range incomes {
+		btn := tgbotapi.NewInlineKeyboardButtonData(income.Name, fmt.Sprintf("income/%d/got/%d", income.ID, int64(amount)))
+		buttons = append(buttons, []tgbotapi.InlineKeyboardButton{btn})
+	}
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("К какому доходу отнести %s?", amount))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons...)
+	h.bot.Send(c, msg)
+}
+
+// irregular_got_<income>_<сумма в копейках>
+func (h *BotHandler) handleIrregularGotCallback(c *router.Context) {
+	h.answerCallback(c, c.Callback.ID, "✅")
+	h.logIrregularIncome(c, c.Params.Int64("id"), models.Money(c.Params.Int64("amount")))
+}
+
+// записывает поступление и показывает рекомендации, как в день выплаты
+func (h *BotHandler) logIrregularIncome(c *router.Context, incomeID int64, amount models.Money) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	income, _, err := h.financeService.LogIrregularIncome(c, userID, incomeID, amount)
+	if err != nil {
+		log.Printf("Failed to log irregular income: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при записи поступления")
+		return
+	}
+
+	h.sendMessage(c, chatID, fmt.Sprintf("✅ Записано поступление: %s - %s", income.Name, models.FormatAmount(amount, income.Currency)))
+	h.scheduler.NotifyIncomeReceived(c, *income, amount, userID)
+}
+
+// нерегулярный доход создается сразу после выбора частоты: ни дня, ни часа уведомлений у него нет
+func (h *BotHandler) createIrregularIncome(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	session, ok := h.dialogSession(c)
+	if !ok {
+		return
+	}
+
+	incomeName := session.Get("income_name")
+	incomeAmountMinor, _ := strconv.ParseInt(session.Get("income_amount"), 10, 64)
+	incomeCurrency := session.Get("income_currency")
+
+	income, err := h.financeService.CreateIrregularIncome(c, userID, incomeName, models.Money(incomeAmountMinor), incomeCurrency)
+	if errors.Is(err, services.ErrNoExchangeRate) {
+		h.sendMessage(c, chatID, fmt.Sprintf("❌ Для валюты %s не задан курс. Курсы задает администратор бота (/rate %s 92.5), после этого добавьте доход заново", incomeCurrency, incomeCurrency))
+		h.clearState(c)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create income: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при сохранении дохода")
+		return
+	}
+
+	h.clearState(c)
+	h.sendMessageWithKeyboard(c,
+		chatID,
+		fmt.Sprintf("✅ Доход добавлен:\n%s: нерегулярно, около %s в месяц\n\n"+
+			"Когда придут деньги, запишите их командой /got, например /got 25000 - и я подскажу, сколько отложить.\n"+
+			"Пока нет истории, месячный план считается по вашей оценке, потом - по среднему за последние месяцы",
+			income.Name, models.FormatAmount(income.Amount, income.Currency)),
+		h.mainMenu(),
+	)
+}
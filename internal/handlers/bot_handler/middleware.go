@@ -0,0 +1,51 @@
+package bot_handler
+
+import (
+	"github.com/Lina3386/telegram-bot/internal/router"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// LoadUser загружает пользователя в контекст, а того, кто пишет впервые, регистрирует
+func (h *BotHandler) LoadUser(next router.Handler) router.Handler {
+	return func(c *router.Context) {
+		user, created, err := h.authService.EnsureTelegramUser(c, c.UserID(), displayName(c.From()))
+		if err != nil {
+			c.Logf("Failed to load user: %v", err)
+			h.replyError(c, "❌ Сервис временно недоступен. Попробуйте позже")
+			return
+		}
+		c.User = user
+		c.NewUser = created
+		next(c)
+	}
+}
+
+// HandlePanic - ответ пользователю, если обработчик упал
+func (h *BotHandler) HandlePanic(c *router.Context) {
+	h.replyError(c, "❌ Что-то пошло не так. Попробуйте еще раз или отмените действие командой /cancel")
+}
+
+func (h *BotHandler) HandleAccessDenied(c *router.Context) {
+	h.replyError(c, "⛔ Доступ к боту ограничен")
+}
+
+// на кнопку нужно ответить, иначе у пользователя будут крутиться часики
+func (h *BotHandler) replyError(c *router.Context, text string) {
+	if c.Callback != nil {
+		h.answerCallback(c, c.Callback.ID, text)
+	}
+	if chatID := c.ChatID(); chatID != 0 {
+		h.sendMessage(c, chatID, text)
+	}
+}
+
+// имя для приветствия и регистрации: username, а если его нет - имя
+func displayName(from *tgbotapi.User) string {
+	if from == nil {
+		return ""
+	}
+	if from.UserName != "" {
+		return from.UserName
+	}
+	return from.FirstName
+}
@@ -1,111 +1,153 @@
 package bot_handler
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/router"
 )
 
-func (h *BotHandler) calculateNextPayDate(frequency string, recurringDay int) time.Time {
-	now := time.Now()
-	switch frequency {
-	case "monthly":
-		next := time.Date(now.Year(), now.Month(), recurringDay, 9, 0, 0, 0, now.Location())
-		if next.After(now) {
-			return next
-		}
-		return time.Date(now.Year(), now.Month()+1, recurringDay, 9, 0, 0, 0, now.Location())
-	case "weekly":
-		daysUntil := (recurringDay - int(now.Weekday()) + 7) % 7
-		if daysUntil == 0 && now.Hour() >= 9 {
-			daysUntil = 7
-		}
-		next := now.AddDate(0, 0, daysUntil).Truncate(24 * time.Hour)
-		return time.Date(next.Year(), next.Month(), next.Day(), 9, 0, 0, 0, next.Location())
-	case "biweekly":
-		daysUntil := (recurringDay - int(now.Weekday()) + 7) % 7
-		next := now.AddDate(0, 0, daysUntil).Truncate(24 * time.Hour)
-		if next.Before(now) || (next.Equal(now.Truncate(24*time.Hour)) && now.Hour() >= 9) {
-			next = next.AddDate(0, 0, 7)
-		}
-		return time.Date(next.Year(), next.Month(), next.Day(), 9, 0, 0, 0, next.Location())
-	default:
-		return time.Date(now.Year(), now.Month(), now.Day()+1, 9, 0, 0, 0, now.Location())
-	}
-}
+func (h *BotHandler) handlePaydayAmountInput(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+	text := c.Text()
 
-func (h *BotHandler) handlePaydayAmountInput(message *tgbotapi.Message) {
-	userID := message.From.ID
-	chatID := message.Chat.ID
-	text := message.Text
-	ctx := context.Background()
+	session, ok := h.dialogSession(c)
+	if !ok {
+		return
+	}
 
-	amount, err := strconv.ParseInt(text, 10, 64)
+	amount, err := models.ParseMoney(text)
 	if err != nil || amount <= 0 {
-		h.sendMessage(chatID, "❌ Введите корректное число")
+		h.sendMessage(c, chatID, "❌ Введите корректную сумму, например 1500 или 1 499,90")
 		return
 	}
 
-	goalIDStr := h.stateManager.GetTempData(userID, "payday_contributing_goal_id")
-	incomeIDStr := h.stateManager.GetTempData(userID, "payday_contributing_income_id")
+	goalIDStr := session.Get("payday_contributing_goal_id")
+	incomeIDStr := session.Get("payday_contributing_income_id")
 
 	if goalIDStr == "" || incomeIDStr == "" {
-		h.sendMessage(chatID, "❌ Ошибка: данные о цели не найдены")
+		h.sendMessage(c, chatID, "❌ Ошибка: данные о цели не найдены")
 		return
 	}
 
 	goalID, _ := strconv.ParseInt(goalIDStr, 10, 64)
 	incomeID, _ := strconv.ParseInt(incomeIDStr, 10, 64)
 
-	goal, err := h.financeService.ContributeToGoal(ctx, goalID, amount)
+	goal, err := h.financeService.ContributeToGoalFromPayday(c, goalID, incomeID, amount)
 	if err != nil {
-		log.Printf("Failed to contribute to goal: %v", err)
-		h.sendMessage(chatID, "❌ Ошибка при добавлении")
+		c.Logf("Failed to contribute to goal: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при добавлении")
 		return
 	}
+	c.Logf("%s: +%s (total: %s)", goal.GoalName, amount, goal.CurrentAmount)
 
-	currentMonth := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.Now().Location())
-	contrib, err := h.financeService.GetMonthlyContribution(ctx, userID, goalID, currentMonth)
-
-	if err != nil || contrib == nil {
-		_, err = h.financeService.CreateMonthlyContribution(ctx, userID, goalID, currentMonth, amount)
-	} else {
-		contrib.AmountContributed += amount
-		err = h.financeService.UpdateMonthlyContribution(ctx, contrib)
-	}
-
-	if err != nil {
-		log.Printf("Failed to save monthly contribution: %v", err)
-	}
-
-	fmt.Sprintf("%s: +%d₽ (total: %d₽)", goal.GoalName, amount, goal.CurrentAmount)
-
-	incomes, err := h.financeService.GetUserIncomes(ctx, userID)
+	incomes, err := h.financeService.GetUserIncomes(c, userID)
 	if err != nil {
-		log.Printf("Failed to get user incomes: %v", err)
-		h.sendMessage(chatID, "❌ Ошибка при возврате к меню дохода")
+		c.Logf("Failed to get user incomes: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при возврате к меню дохода")
 		return
 	}
 
 	incomeName := ""
-	incomeAmount := int64(0)
+	incomeAmount := models.Money(0)
+	incomeCurrency := models.DefaultCurrency
 	for _, income := range incomes {
 		if income.ID == incomeID {
 			incomeName = income.Name
-			incomeAmount = income.Amount
+			incomeAmount = h.financeService.LastPaydayAmount(c, income)
+			incomeCurrency = income.Currency
 			break
 		}
 	}
 
 	if incomeName == "" {
-		h.sendMessage(chatID, "❌ Не найден доход для меню дохода")
+		h.sendMessage(c, chatID, "❌ Не найден доход для меню дохода")
+		return
+	}
+
+	h.clearState(c)
+	h.showPaydayMenu(c, incomeID, incomeName, incomeAmount, incomeCurrency)
+}
+
+// фактическая сумма выплаты и, если деньги пришли не в плановый день, дата: "52000 12.01.2026"
+func (h *BotHandler) handlePaydayActualInput(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	session, ok := h.dialogSession(c)
+	if !ok {
+		return
+	}
+
+	incomeID, _ := strconv.ParseInt(session.Get("payday_actual_income_id"), 10, 64)
+	payDate, err := time.Parse("20060102", session.Get("payday_actual_date"))
+	if incomeID == 0 || err != nil {
+		h.clearState(c)
+		h.sendMessage(c, chatID, "❌ Ошибка: данные о выплате не найдены")
 		return
 	}
 
-	h.stateManager.ClearState(userID)
-	h.showPaydayMenu(userID, chatID, incomeID, incomeName, incomeAmount, ctx)
+	fields := strings.Fields(c.Text())
+	receivedDate := payDate
+	if len(fields) > 1 {
+		if date, ok := parseExpenseDate(fields[len(fields)-1], c.User.Now()); ok {
+			receivedDate = date
+			fields = fields[:len(fields)-1]
+		}
+	}
+
+	amount, err := models.ParseMoney(strings.Join(fields, " "))
+	if err != nil || amount < 0 {
+		h.sendMessage(c, chatID, "❌ Введите сумму и при необходимости дату, например 52000 или 52000 12.01.2026")
+		return
+	}
+	if receivedDate.Before(payDate.AddDate(0, -1, 0)) || receivedDate.After(payDate.AddDate(0, 2, 0)) {
+		h.sendMessage(c, chatID, "❌ Дата слишком далеко от плановой даты выплаты")
+		return
+	}
+
+	income, err := h.financeService.GetUserIncomeByID(c, userID, incomeID)
+	if err != nil {
+		c.Logf("Failed to get income by ID: %v", err)
+		h.clearState(c)
+		h.sendMessage(c, chatID, "❌ Доход не найден")
+		return
+	}
+
+	h.clearState(c)
+	h.confirmPaydayReceived(c, income, payDate, amount, receivedDate)
+}
+
+func (h *BotHandler) confirmPaydayReceived(c *router.Context, income *models.Income, payDate time.Time, amount models.Money, receivedDate time.Time) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	entry, err := h.financeService.ConfirmIncomeReceived(c, userID, income.ID, payDate, amount, receivedDate)
+	if err != nil {
+		log.Printf("Failed to confirm income %d: %v", income.ID, err)
+		h.sendMessage(c, chatID, "❌ Ошибка при сохранении поступления")
+		return
+	}
+
+	text := fmt.Sprintf("✅ Записали: %s - %s, %s", income.Name, models.FormatAmount(amount, income.Currency), receivedDate.Format("02.01.2006"))
+	if diff := entry.ActualAmount - entry.IncomeAmount; diff != 0 {
+		text += fmt.Sprintf("\n📊 Отличие от плана: %s", signedAmount(diff, income.Currency))
+	}
+	if entry.IsLate() {
+		text += fmt.Sprintf("\n⏰ Пришло позже плана (%s)", payDate.Format("02.01.2006"))
+	}
+	h.sendMessage(c, chatID, text)
+}
+
+// сумма со знаком: "+12 000 ₽", "-500 ₽"
+func signedAmount(amount models.Money, currency string) string {
+	if amount > 0 {
+		return "+" + models.FormatAmount(amount, currency)
+	}
+	return models.FormatAmount(amount, currency)
 }
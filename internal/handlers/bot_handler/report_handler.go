@@ -0,0 +1,100 @@
+package bot_handler
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/router"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var monthNames = []string{"", "Январь", "Февраль", "Март", "Апрель", "Май", "Июнь", "Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь"}
+
+// HandleReport - план и факт по доходам: /report или /report 01.2026
+func (h *BotHandler) HandleReport(c *router.Context) {
+	chatID := c.ChatID()
+
+	month := models.MonthStart(c.User.Now())
+	if args := strings.TrimSpace(c.Message.CommandArguments()); args != "" {
+		parsed, err := time.Parse("01.2006", args)
+		if err != nil {
+			h.sendMessage(c, chatID, "❌ Укажите месяц в формате ММ.ГГГГ, например /report 01.2026")
+			return
+		}
+		month = parsed
+	}
+
+	h.showIncomeReport(c, month.Year(), month.Month())
+}
+
+func (h *BotHandler) showIncomeReport(c *router.Context, year int, month time.Month) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	report, err := h.financeService.GetIncomePlanVsActual(c, userID, year, month)
+	if err != nil {
+		log.Printf("Failed to build income report: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при построении отчета"+noRateHint(err))
+		return
+	}
+
+	text := fmt.Sprintf("📋 Доходы: план и факт\n%s %d\n\n", monthNames[month], year)
+	if len(report.Incomes) == 0 {
+		text += "У вас нет добавленных доходов\n"
+	}
+
+	for _, row := range report.Incomes {
+		currency := row.Income.Currency
+		text += fmt.Sprintf("💰 %s\n   План: %s\n", row.Income.Name, models.FormatAmount(row.Planned, currency))
+		if row.Confirmed > 0 {
+			text += fmt.Sprintf("   Факт: %s", models.FormatAmount(row.Actual, currency))
+			if diff := row.Actual - row.Planned; diff != 0 {
+				text += fmt.Sprintf(" (%s)", signedAmount(diff, currency))
+			}
+			text += "\n"
+		}
+		for _, entry := range row.Late {
+			text += fmt.Sprintf("   ⏰ Выплата %s пришла %s\n", entry.ProcessedDate.Format("02.01"), entry.ReceivedDate.Time.Format("02.01"))
+		}
+		if row.Pending > 0 {
+			text += fmt.Sprintf("   ❔ Не подтверждено выплат: %d\n", row.Pending)
+		}
+		text += "\n"
+	}
+
+	text += fmt.Sprintf(
+		"📊 Итого:\n"+
+			"   План: %s\n"+
+			"   Факт: %s (%s)\n\n"+
+			"ℹ️ Где поступление не подтверждено, в факт идет плановая сумма",
+		models.FormatAmount(report.TotalPlanned, report.BaseCurrency),
+		models.FormatAmount(report.TotalActual, report.BaseCurrency),
+		signedAmount(report.TotalActual-report.TotalPlanned, report.BaseCurrency),
+	)
+
+	prev := report.Month.AddDate(0, -1, 0)
+	next := report.Month.AddDate(0, 1, 0)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("◀️ "+monthNames[prev.Month()], "report/"+prev.Format("200601")),
+		tgbotapi.NewInlineKeyboardButtonData(monthNames[next.Month()]+" ▶️", "report/"+next.Format("200601")),
+	))
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = keyboard
+	if _, err := h.bot.Send(c, msg); err != nil {
+		log.Printf("Failed to send income report: %v", err)
+	}
+}
+
+func (h *BotHandler) handleIncomeReportCallback(c *router.Context) {
+	month, err := time.Parse("200601", c.Params.String("month"))
+	if err != nil {
+		h.answerCallback(c, c.Callback.ID, "❌ Ошибка")
+		return
+	}
+	h.answerCallback(c, c.Callback.ID, "✅")
+	h.showIncomeReport(c, month.Year(), month.Month())
+}
@@ -0,0 +1,133 @@
+package bot_handler
+
+import (
+	"github.com/Lina3386/telegram-bot/internal/router"
+	"github.com/Lina3386/telegram-bot/internal/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Router - все команды, кнопки меню, шаги диалогов и callback-кнопки бота
+func (h *BotHandler) Router() *router.Router {
+	r := router.New(h.stateManager.GetState)
+
+	r.Command("start", h.HandleStart)
+	r.Command("help", h.HandleHelp)
+	r.Command("cancel", h.HandleCancel)
+	r.Command("rate", h.HandleRate)
+	r.Command("currency", h.HandleCurrency)
+	r.Command("settings", h.HandleSettings)
+	r.Command("timezone", h.HandleTimezone)
+	r.Command("report", h.HandleReport)
+	r.Command("got", h.HandleGot)
+	r.Command("testpayday", h.handleTestPaydayCommand)
+	r.UnknownCommand(h.HandleUnknownCommand)
+
+	// кнопки главного меню работают в любом состоянии диалога
+	r.Text(h.handleShowIncomes, "💳 Мои доходы", "мои доходы", "доходы")
+	r.Text(h.handleShowExpenses, "💰 Мои расходы", "мои расходы", "расходы")
+	r.Text(h.handleShowGoals, "🍀 Цели", "цели", "цель")
+	r.Text(h.handleShowStats, "📈 Статистика", "📊 Статистика", "статистика", "стата")
+	r.Text(h.HandleSettings, "⚙️ Настройки", "настройки")
+	r.Text(h.handleDone, "✅ Готово", "готово")
+	r.Text(h.handleBack, "⬅️ Назад", "назад")
+
+	r.State(state.StateAddingIncome, h.handleIncomeNameInput)
+	r.State(state.StateAddingIncomeAmount, h.handleIncomeAmountInput)
+	r.State(state.StateAddingIncomeFrequency, h.handleIncomeFrequencyInput)
+	r.State(state.StateAddingIncomeDay, h.handleIncomeDayInput)
+	r.State(state.StateAddingIncomeShift, h.handleIncomeShiftInput)
+	r.State(state.StateAddingIncomeHour, h.handleIncomeHourInput)
+
+	r.State(state.StateAddingExpense, h.handleExpenseNameInput)
+	r.State(state.StateAddingExpenseAmount, h.handleExpenseAmountInput)
+	r.State(state.StateAddingExpenseKind, h.handleExpenseKindInput)
+	r.State(state.StateAddingExpenseFrequency, h.handleExpenseFrequencyInput)
+	r.State(state.StateAddingExpenseDay, h.handleExpenseDayInput)
+	r.State(state.StateAddingExpenseDate, h.handleExpenseDateInput)
+	r.State(state.StateAddingExpenseCategory, h.handleExpenseCategoryInput)
+	r.State(state.StateSettingCategoryLimit, h.handleCategoryLimitInput)
+
+	r.State(state.StateCreatingGoal, h.handleGoalNameInput)
+	r.State(state.StateCreatingGoalTarget, h.handleGoalTargetInput)
+	r.State(state.StateCreatingGoalDeadline, h.handleGoalDeadlineInput)
+	r.State(state.StateAddingContribution, h.handleContributionInput)
+	r.State(state.StateWithdrawingFromGoal, h.handleWithdrawInput)
+	r.State(state.StateChangingGoalPriority, h.handlePriorityInput)
+	r.State(state.StateSettingGoalPercent, h.handleGoalPercentInput)
+
+	r.State(state.StatePaydayEnteringAmount, h.handlePaydayAmountInput)
+	r.State(state.StatePaydayEnteringActual, h.handlePaydayActualInput)
+	r.State(state.StateSettingTimezone, h.handleTimezoneInput)
+
+	r.State(state.StateEditingName, h.handleEditNameInput)
+	r.State(state.StateEditingAmount, h.handleEditAmountInput)
+	r.State(state.StateEditingIncomeHour, h.handleEditHourInput)
+
+	r.Fallback(h.handleIdleText)
+	r.StateError(h.handleStateError)
+
+	// сообщение с кнопками получки остается в чате, остальные экраны заменяются новыми
+	r.Callback("payday/{income:int}", h.handlePaydayBackCallback)
+	r.Callback("payday/{income:int}/goal/{goal:int}", h.handlePaydayGoalCallback)
+	r.Callback("payday/{income:int}/goal/{goal:int}/add", h.handlePaydayAddCallback)
+	r.Callback("payday/{income:int}/confirm/{date:int}", h.handlePaydayConfirmCallback)
+	r.Callback("payday/{income:int}/correct/{date:int}", h.handlePaydayCorrectCallback)
+	r.Callback("payday/{income:int}/complete", h.handlePaydayCompleteCallback)
+
+	r.Callback("testpayday/{income:int}", h.handleTestPaydayBackCallback)
+	r.Callback("testpayday/{income:int}/goal/{goal:int}", h.handleTestPaydayGoalCallback)
+	r.Callback("testpayday/{income:int}/goal/{goal:int}/add", h.handleTestPaydayAddCallback)
+	r.Callback("testpayday/{income:int}/complete", h.handleTestPaydayCompleteCallback)
+
+	r.Callback("incomes", h.replacing(h.handleShowIncomesCallback))
+	r.Callback("incomes/new", h.replacing(h.handleAddIncomeCallback))
+	r.Callback("income/{id:int}/got/{amount:int}", h.replacing(h.handleIrregularGotCallback))
+	r.Callback("report/{month:int}", h.replacing(h.handleIncomeReportCallback))
+
+	r.Callback("expenses", h.replacing(h.handleShowExpensesCallback))
+	r.Callback("expenses/new", h.replacing(h.handleAddExpenseCallback))
+	r.Callback("expenses/categories", h.replacing(h.handleExpenseCategoriesCallback))
+	r.Callback("category/{id:int}/limit", h.replacing(h.handleCategoryLimitCallback))
+
+	r.Callback("goals", h.replacing(h.handleShowGoalsCallback))
+	r.Callback("goals/new", h.replacing(h.handleCreateGoalCallback))
+	r.Callback("goals/archive", h.replacing(h.handleGoalArchiveCallback))
+	r.Callback("goal/{id:int}", h.replacing(h.handleGoalCallback))
+	r.Callback("goal/{id:int}/contrib", h.replacing(h.handleContributeCallback))
+	r.Callback("goal/{id:int}/withdraw", h.replacing(h.handleWithdrawCallback))
+	r.Callback("goal/{id:int}/history", h.replacing(h.handleGoalHistoryCallback))
+	r.Callback("goal/{id:int}/priority", h.replacing(h.handleGoalPriorityCallback))
+	r.Callback("goal/{id:int}/percent", h.replacing(h.handleGoalPercentCallback))
+	r.Callback("goal/{id:int}/pause", h.replacing(h.handleGoalStatusCallback("pause")))
+	r.Callback("goal/{id:int}/resume", h.replacing(h.handleGoalStatusCallback("resume")))
+	r.Callback("goal/{id:int}/archive", h.replacing(h.handleGoalStatusCallback("archive")))
+	r.Callback("goal/{id:int}/delete/move/{to:int}/{issued:int}", h.replacing(h.handleDeleteMove))
+
+	// общие для доходов, расходов и целей: type - income, expense или goal
+	r.Callback("{type}/{id:int}/edit", h.replacing(h.handleEditMenuCallback))
+	r.Callback("{type}/{id:int}/edit/{field}", h.replacing(h.handleEditFieldCallback))
+	r.Callback("{type}/{id:int}/delete", h.replacing(h.askDeleteConfirmation))
+	r.Callback("{type}/{id:int}/delete/confirm/{issued:int}", h.replacing(h.handleDeleteConfirm))
+	r.Callback("{type}/{id:int}/delete/cancel", h.replacing(h.handleDeleteCancel))
+	r.Callback("{type}/{id:int}/undo", h.replacing(h.handleUndoCallback))
+
+	r.Callback("strategy/{code}", h.replacing(h.handleStrategyCallback))
+	r.Callback("timezone", h.replacing(h.handleTimezoneMenuCallback))
+	r.Callback("timezone/custom", h.replacing(h.handleCustomTimezoneCallback))
+	r.Callback("timezone/set/{name...}", h.replacing(h.handleTimezoneCallback))
+
+	r.UnknownCallback(h.handleUnknownCallback)
+
+	return r
+}
+
+// replacing удаляет сообщение с нажатой кнопкой: обработчик покажет вместо него новый экран
+func (h *BotHandler) replacing(handler router.Handler) router.Handler {
+	return func(c *router.Context) {
+		deleteMsg := tgbotapi.NewDeleteMessage(c.ChatID(), c.Callback.Message.MessageID)
+		if _, err := h.bot.Request(c, deleteMsg); err != nil {
+			c.Logf("Failed to delete original message: %v", err)
+		}
+		handler(c)
+	}
+}
@@ -0,0 +1,157 @@
+package bot_handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/router"
+	"github.com/Lina3386/telegram-bot/internal/services"
+	"github.com/Lina3386/telegram-bot/internal/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+type allocationStrategyInfo struct {
+	code        string
+	title       string
+	description string
+}
+
+// порядок, в котором стратегии показываются в настройках
+var allocationStrategyInfos = []allocationStrategyInfo{
+	{models.AllocationPriorityWeighted, "⚖️ По приоритетам", "больше денег целям с высоким приоритетом, но каждая получает долю"},
+	{models.AllocationWaterfall, "🌊 Водопад", "сначала полностью первая цель, затем следующая"},
+	{models.AllocationEqual, "🟰 Поровну", "одинаковая сумма каждой цели"},
+	{models.AllocationDeadline, "⏰ По срокам", "сначала обязательный взнос для успевания к дате, остаток по приоритету"},
+	{models.AllocationFixedPercent, "📊 Фиксированные доли", "каждая цель получает заданный вами процент"},
+}
+
+func allocationStrategyTitle(code string) string {
+	for _, info := range allocationStrategyInfos {
+		if info.code == code {
+			return info.title
+		}
+	}
+	return allocationStrategyInfos[0].title
+}
+
+func (h *BotHandler) HandleSettings(c *router.Context) {
+	h.showSettings(c)
+}
+
+func (h *BotHandler) showSettings(c *router.Context) {
+	chatID := c.ChatID()
+	user := c.User
+
+	text := fmt.Sprintf(
+		"⚙️ <b>Настройки</b>\n\n"+
+			"<b>Базовая валюта:</b> %s (сменить: /currency USD)\n"+
+			"<b>Часовой пояс:</b> %s\n"+
+			"<b>Распределение по целям:</b> %s\n\n"+
+			"Как делить свободные деньги между целями:\n\n",
+		user.BaseCurrency,
+		timezoneTitle(user.Timezone),
+		allocationStrategyTitle(user.AllocationStrategy),
+	)
+
+	var buttons [][]tgbotapi.InlineKeyboardButton
+	for _, info := range allocationStrategyInfos {
+		text += fmt.Sprintf("%s - %s\n", info.title, info.description)
+
+		title := info.title
+		if info.code == user.AllocationStrategy {
+			title = "✅ " + title
+		}
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(title, "strategy/"+info.code),
+		))
+	}
+
+	if user.AllocationStrategy == models.AllocationFixedPercent {
+		text += "\n💡 Долю каждой цели можно задать в карточке цели кнопкой 📊"
+	}
+
+	buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🕒 Часовой пояс", "timezone"),
+	))
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "HTML"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons...)
+	h.bot.Send(c, msg)
+}
+
+func (h *BotHandler) handleStrategyCallback(c *router.Context) {
+	userID := c.UserID()
+
+	err := h.financeService.SetAllocationStrategy(c, userID, c.Params.String("code"))
+	if err != nil {
+		c.Logf("Failed to set allocation strategy: %v", err)
+		h.answerCallback(c, c.Callback.ID, "❌ Ошибка")
+		return
+	}
+
+	h.answerCallback(c, c.Callback.ID, "✅ Стратегия изменена")
+	c.User.AllocationStrategy = c.Params.String("code")
+	h.showSettings(c)
+}
+
+// ввод доли цели для стратегии фиксированных долей
+func (h *BotHandler) handleChangeGoalPercent(c *router.Context, goalID int64) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	goal, err := h.financeService.GetUserGoalByID(c, userID, goalID)
+	if err != nil {
+		log.Printf("Failed to get goal: %v", err)
+		h.sendMessage(c, chatID, "❌ Цель не найдена")
+		return
+	}
+
+	if !h.setTempData(c, "percent_goal_id", fmt.Sprintf("%d", goalID)) ||
+		!h.setState(c, state.StateSettingGoalPercent) {
+		return
+	}
+
+	h.sendMessage(c, chatID, fmt.Sprintf(
+		"📊 Доля цели «%s» сейчас %d%%\n\nВведите новый процент от 0 до 100:",
+		goal.GoalName, goal.AllocationPercent,
+	))
+}
+
+func (h *BotHandler) handleGoalPercentInput(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	session, ok := h.dialogSession(c)
+	if !ok {
+		return
+	}
+
+	percent, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(c.Text()), "%"))
+	if err != nil || percent < 0 || percent > 100 {
+		h.sendMessage(c, chatID, "❌ Введите число от 0 до 100")
+		return
+	}
+
+	goalID, _ := strconv.ParseInt(session.Get("percent_goal_id"), 10, 64)
+
+	err = h.financeService.SetGoalAllocationPercent(c, userID, goalID, percent)
+	if errors.Is(err, services.ErrAllocationOver100) {
+		h.sendMessage(c, chatID, "❌ Сумма долей всех активных целей не может превышать 100%. Введите меньшее значение:")
+		return
+	}
+	if err != nil {
+		c.Logf("Failed to set goal percent: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при сохранении доли")
+		h.clearState(c)
+		return
+	}
+
+	h.clearState(c)
+	h.sendMessageWithKeyboard(c, chatID, fmt.Sprintf("✅ Доля цели: %d%%", percent), h.mainMenu())
+	h.showGoalDetailsV2(c, goalID)
+}
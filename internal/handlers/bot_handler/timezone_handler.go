@@ -0,0 +1,102 @@
+package bot_handler
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Lina3386/telegram-bot/internal/router"
+	"github.com/Lina3386/telegram-bot/internal/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+type timezoneInfo struct {
+	name  string
+	title string
+}
+
+// часовые пояса России для быстрого выбора
+var timezoneInfos = []timezoneInfo{
+	{"Europe/Kaliningrad", "Калининград (UTC+2)"},
+	{"Europe/Moscow", "Москва (UTC+3)"},
+	{"Europe/Samara", "Самара (UTC+4)"},
+	{"Asia/Yekaterinburg", "Екатеринбург (UTC+5)"},
+	{"Asia/Omsk", "Омск (UTC+6)"},
+	{"Asia/Novosibirsk", "Новосибирск (UTC+7)"},
+	{"Asia/Krasnoyarsk", "Красноярск (UTC+7)"},
+	{"Asia/Irkutsk", "Иркутск (UTC+8)"},
+	{"Asia/Yakutsk", "Якутск (UTC+9)"},
+	{"Asia/Vladivostok", "Владивосток (UTC+10)"},
+	{"Asia/Magadan", "Магадан (UTC+11)"},
+	{"Asia/Kamchatka", "Камчатка (UTC+12)"},
+}
+
+func timezoneTitle(name string) string {
+	for _, info := range timezoneInfos {
+		if info.name == name {
+			return info.title
+		}
+	}
+	return name
+}
+
+func (h *BotHandler) HandleTimezone(c *router.Context) {
+	// /timezone Asia/Tomsk или /timezone +7
+	if args := strings.TrimSpace(c.Message.CommandArguments()); args != "" {
+		h.setTimezone(c, args)
+		return
+	}
+	h.showTimezonePicker(c)
+}
+
+func (h *BotHandler) showTimezonePicker(c *router.Context) {
+	chatID := c.ChatID()
+
+	text := "🕒 Выберите часовой пояс.\n\nПо нему считаются дни дохода, время уведомлений и начало месяца."
+	text += fmt.Sprintf("\n\nСейчас: %s, %s", timezoneTitle(c.User.Timezone), c.User.Now().Format("15:04"))
+
+	var buttons [][]tgbotapi.InlineKeyboardButton
+	for i := 0; i < len(timezoneInfos); i += 2 {
+		var row []tgbotapi.InlineKeyboardButton
+		for _, info := range timezoneInfos[i:min(i+2, len(timezoneInfos))] {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(info.title, "timezone/set/"+info.name))
+		}
+		buttons = append(buttons, row)
+	}
+	buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✏️ Другой пояс", "timezone/custom"),
+	))
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons...)
+	h.bot.Send(c, msg)
+}
+
+func (h *BotHandler) handleTimezoneCallback(c *router.Context) {
+	h.answerCallback(c, c.Callback.ID, "✅")
+	h.setTimezone(c, c.Params.String("name"))
+}
+
+func (h *BotHandler) handleTimezoneInput(c *router.Context) {
+	h.setTimezone(c, c.Text())
+}
+
+func (h *BotHandler) setTimezone(c *router.Context, timezone string) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	name, err := h.financeService.SetTimezone(c, userID, timezone)
+	if err != nil {
+		log.Printf("Failed to set timezone: %v", err)
+		if !h.setState(c, state.StateSettingTimezone) {
+			return
+		}
+		h.sendMessage(c, chatID, "❌ Не знаю такой часовой пояс. Введите название вроде Asia/Tomsk или смещение от UTC, например +7:")
+		return
+	}
+
+	h.clearState(c)
+	c.User.Timezone = name
+	now := c.User.Now()
+	h.sendMessageWithKeyboard(c, chatID, fmt.Sprintf("✅ Часовой пояс: %s\n🕒 Сейчас у вас %s", timezoneTitle(name), now.Format("02.01.2006 15:04")), h.mainMenu())
+}
@@ -0,0 +1,81 @@
+package bot_handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/router"
+	"github.com/Lina3386/telegram-bot/internal/services"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// подтверждение удаления с кнопкой отмены; после undoWindow кнопка перестает работать
+func (h *BotHandler) sendUndoOffer(c *router.Context, text string, undoCallback string) {
+	msg := tgbotapi.NewMessage(c.ChatID(), fmt.Sprintf("%s\n\nОтменить можно в течение %s", text, undoWindowText(h.financeService.UndoWindow())))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("↩️ Отменить", undoCallback),
+	))
+	if _, err := h.bot.Send(c, msg); err != nil {
+		log.Printf("Failed to send undo offer: %v", err)
+	}
+}
+
+func undoWindowText(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%d мин", max(int(d.Minutes()), 1))
+	}
+	return fmt.Sprintf("%d ч", int(d.Hours()))
+}
+
+// handleUndoCallback - income/12/undo, expense/12/undo, goal/12/undo
+func (h *BotHandler) handleUndoCallback(c *router.Context) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	resourceType, id := c.Params.String("type"), c.Params.Int64("id")
+
+	var text string
+	var err error
+	switch resourceType {
+	case "income":
+		var income *models.Income
+		income, err = h.financeService.RestoreIncome(c, userID, id)
+		if err == nil {
+			text = fmt.Sprintf("↩️ Доход «%s» восстановлен", income.Name)
+		}
+	case "expense":
+		var expense *models.Expense
+		expense, err = h.financeService.RestoreExpense(c, userID, id)
+		if err == nil {
+			text = fmt.Sprintf("↩️ Расход «%s» восстановлен", expense.Name)
+		}
+	case "goal":
+		var goal *models.SavingsGoal
+		goal, err = h.financeService.RestoreGoal(c, userID, id)
+		if err == nil {
+			text = fmt.Sprintf("↩️ Цель «%s» восстановлена вместе с историей взносов", goal.GoalName)
+		}
+	default:
+		h.answerCallback(c, c.Callback.ID, "❌ Неизвестное действие")
+		return
+	}
+
+	if errors.Is(err, services.ErrUndoExpired) {
+		h.answerCallback(c, c.Callback.ID, "⌛ Время на отмену истекло")
+		h.sendMessage(c, chatID, "⌛ Отменить удаление уже нельзя: время на отмену истекло")
+		return
+	}
+	if err != nil {
+		c.Logf("Failed to restore %s %d: %v", resourceType, id, err)
+		h.answerCallback(c, c.Callback.ID, "❌ Ошибка при восстановлении")
+		return
+	}
+
+	h.answerCallback(c, c.Callback.ID, "↩️ Восстановлено")
+	h.sendMessage(c, chatID, text)
+
+	h.showDeleteSource(c, resourceType, id)
+}
@@ -4,128 +4,77 @@ import (
 	"context"
 	"fmt"
 	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/router"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"log"
-	"strconv"
-	"strings"
-	"time"
 )
 
-func (h *BotHandler) showGoalDetails(userID int64, chatID int64, goalID int64) {
-	ctx := context.Background()
+func (h *BotHandler) showPaydayMenu(c *router.Context, incomeID int64, incomeName string, incomeAmount models.Money, incomeCurrency string) {
+	userID := c.UserID()
+	chatID := c.ChatID()
 
-	goal, err := h.financeService.GetUserGoalByID(ctx, userID, goalID)
-	if err != nil {
-		log.Printf("Failed to get goal: %v", err)
-		h.sendMessage(chatID, "❌ Ошибка при загрузке цели")
-		return
-	}
-
-	progress := int64(0)
-	if goal.TargetAmount > 0 {
-		progress = (goal.CurrentAmount * 100) / goal.TargetAmount
-	}
-
-	priorityText := []string{"", "🔴 Высший", "🟡 Средний", "🟢 Низкий"}[goal.Priority]
-	if priorityText == "" {
-		priorityText = "🟡 Средний"
-	}
-
-	timeToGoal := h.calculateTimeToGoal(goal.TargetAmount, goal.MonthlyContrib, goal.CurrentAmount)
-
-	statusText := "Активна ✅"
-	if goal.Status == "completed" {
-		statusText = "Завершена 🎉"
-	}
-
-	// Полная информация о цели
-	text := fmt.Sprintf(
-		"🎯 %s\n\n"+
-			"Приоритет: %s (%d)\n"+
-			"Статус: %s\n\n"+
-			"Целевая сумма: %d₽\n"+
-			"Накоплено: %d₽\n"+
-			"Прогресс: %d%%\n"+
-			"Осталось: %d₽\n\n"+
-			"Месячный взнос: %d₽\n"+
-			"Время до цели: %s\n"+
-			"Дата достижения: %s",
-		goal.GoalName,
-		priorityText, goal.Priority,
-		statusText,
-		goal.TargetAmount,
-		goal.CurrentAmount,
-		progress,
-		goal.TargetAmount-goal.CurrentAmount,
-		goal.MonthlyContrib,
-		timeToGoal,
-		goal.TargetDate.Format("02.01.2006"),
-	)
-
-	// Кнопки действий
-	var buttons [][]tgbotapi.InlineKeyboardButton
-
-	if goal.Status == "active" {
-		// Внести, Снять
-		contributeBtn := tgbotapi.NewInlineKeyboardButtonData("💰 Внести", fmt.Sprintf("contrib_%d", goal.ID))
-		withdrawBtn := tgbotapi.NewInlineKeyboardButtonData("📤 Снять", fmt.Sprintf("withdraw_%d", goal.ID))
-
-		buttons = append(buttons, []tgbotapi.InlineKeyboardButton{contributeBtn, withdrawBtn})
-	}
-
-	deleteBtn := tgbotapi.NewInlineKeyboardButtonData("🗑️ Удалить цель", fmt.Sprintf("delete_goal_%d", goal.ID))
-	backBtn := tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад к целям", "back_to_goals")
-
-	buttons = append(buttons, []tgbotapi.InlineKeyboardButton{deleteBtn, backBtn})
-
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = keyboard
-	h.bot.Send(msg)
-}
-
-func (h *BotHandler) showPaydayMenu(userID int64, chatID int64, incomeID int64, incomeName string, incomeAmount int64, ctx context.Context) {
-	goals, err := h.financeService.GetUserActiveGoalsByTelegramID(ctx, userID)
+	goals, err := h.financeService.GetUserActiveGoalsByTelegramID(c, userID)
 	if err != nil {
 		log.Printf("Failed to get goals: %v", err)
-		h.sendMessage(chatID, "❌ Ошибка при загрузке целей")
+		h.sendMessage(c, chatID, "❌ Ошибка при загрузке целей")
 		return
 	}
 
+	now := c.User.Now()
 	if len(goals) == 0 {
-		msg := fmt.Sprintf("💰 Сегодня: %s\n\n%s: %d₽\n\n🎯 У вас нет активных целей для накопления",
-			time.Now().Format("02.01.2006"), incomeName, incomeAmount)
-		h.sendMessageWithKeyboard(chatID, msg, h.mainMenu())
+		msg := fmt.Sprintf("💰 Сегодня: %s\n\n%s: %s\n\n🎯 У вас нет активных целей для накопления",
+			now.Format("02.01.2006"), incomeName, models.FormatAmount(incomeAmount, incomeCurrency))
+		h.sendMessageWithKeyboard(c, chatID, msg, h.mainMenu())
 		return
 	}
 
-	contributedMap := make(map[int64]int64)
-	currentMonth := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+	contributedMap := make(map[int64]models.Money)
+	currentMonth := models.MonthStart(now)
 
 	for _, goal := range goals {
-		monthlyContribRecord, err := h.financeService.GetMonthlyContribution(ctx, userID, goal.ID, currentMonth)
+		monthlyContribRecord, err := h.financeService.GetMonthlyContribution(c, userID, goal.ID, currentMonth)
 		if err == nil && monthlyContribRecord != nil {
 			contributedMap[goal.ID] = monthlyContribRecord.AmountContributed
-			log.Printf("[PAYDAY_MENU] Goal %d (%s): using monthly_contributions %d₽ (goal.MonthlyAccumulated was %d₽)", goal.ID, goal.GoalName, contributedMap[goal.ID], goal.MonthlyAccumulated)
+			log.Printf("[PAYDAY_MENU] Goal %d (%s): using monthly_contributions %s (goal.MonthlyAccumulated was %s)", goal.ID, goal.GoalName, contributedMap[goal.ID], goal.MonthlyAccumulated)
 		} else {
 			contributedMap[goal.ID] = goal.MonthlyAccumulated
 		}
 	}
 
-	totalMonthlyPlan := int64(0)
-	totalAlreadyContributed := int64(0)
+	// общий план показываем в валюте дохода
+	converter, err := h.financeService.CurrencyConverter(c)
+	if err != nil {
+		log.Printf("Failed to load rates: %v", err)
+	}
+
+	totalMonthlyPlan := models.Money(0)
+	totalAlreadyContributed := models.Money(0)
 	for _, goal := range goals {
-		totalMonthlyPlan += goal.MonthlyContrib
-		totalAlreadyContributed += contributedMap[goal.ID]
+		if converter == nil {
+			break
+		}
+		plan, err := converter.Convert(goal.MonthlyContrib, goal.Currency, incomeCurrency)
+		if err != nil {
+			log.Printf("[PAYDAY_MENU] Failed to convert goal %d: %v", goal.ID, err)
+			continue
+		}
+		contributed, err := converter.Convert(contributedMap[goal.ID], goal.Currency, incomeCurrency)
+		if err != nil {
+			log.Printf("[PAYDAY_MENU] Failed to convert goal %d: %v", goal.ID, err)
+			continue
+		}
+		totalMonthlyPlan += plan
+		totalAlreadyContributed += contributed
 	}
 
 	text := fmt.Sprintf(
 		"💰 Сегодня: %s\n\n"+
 			"🎯 День дохода: %s\n"+
-			"Сумма: %d₽\n\n"+
+			"Сумма: %s\n\n"+
 			"Нужно отложить в этом месяце:\n"+
-			"%d/%d₽\n\n",
-		time.Now().Format("02.01.2006"), incomeName, incomeAmount, totalAlreadyContributed, totalMonthlyPlan,
+			"%s/%s\n\n",
+		now.Format("02.01.2006"), incomeName, models.FormatAmount(incomeAmount, incomeCurrency),
+		totalAlreadyContributed, models.FormatAmount(totalMonthlyPlan, incomeCurrency),
 	)
 
 	// Информация по целям
@@ -135,21 +84,18 @@ func (h *BotHandler) showPaydayMenu(userID int64, chatID int64, incomeID int64,
 			remaining = 0
 		}
 
-		progress := int64(0)
-		if goal.TargetAmount > 0 {
-			progress = (goal.CurrentAmount * 100) / goal.TargetAmount
-		}
+		progress := goal.CurrentAmount.Percent(goal.TargetAmount)
 
 		contributed := contributedMap[goal.ID]
 
 		text += fmt.Sprintf(
 			"%d. %s (%d)\n"+
-				"   Накоплено: %d/%d₽ (%d%%)\n"+
-				"   Отложить: %d/%d₽\n"+
-				"   Осталось: %d₽\n\n",
+				"   Накоплено: %s/%s (%d%%)\n"+
+				"   Отложить: %s/%s\n"+
+				"   Осталось: %s\n\n",
 			i+1, goal.GoalName, goal.Priority,
-			goal.CurrentAmount, goal.TargetAmount, progress,
-			contributed, goal.MonthlyContrib, remaining,
+			goal.CurrentAmount, models.FormatAmount(goal.TargetAmount, goal.Currency), progress,
+			contributed, models.FormatAmount(goal.MonthlyContrib, goal.Currency), models.FormatAmount(remaining, goal.Currency),
 		)
 	}
 
@@ -158,34 +104,34 @@ func (h *BotHandler) showPaydayMenu(userID int64, chatID int64, incomeID int64,
 	for _, goal := range goals {
 		btn := tgbotapi.NewInlineKeyboardButtonData(
 			fmt.Sprintf("💰 %s (%d)", goal.GoalName, goal.Priority),
-			fmt.Sprintf("payday_goal_%d_%d", incomeID, goal.ID),
+			fmt.Sprintf("payday/%d/goal/%d", incomeID, goal.ID),
 		)
 		buttons = append(buttons, []tgbotapi.InlineKeyboardButton{btn})
 	}
 
 	completeBtn := tgbotapi.NewInlineKeyboardButtonData(
 		"✅ Завершить",
-		fmt.Sprintf("payday_complete_%d", incomeID),
+		fmt.Sprintf("payday/%d/complete", incomeID),
 	)
 	buttons = append(buttons, []tgbotapi.InlineKeyboardButton{completeBtn})
 
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons...)
-	h.bot.Send(msg)
+	h.bot.Send(c, msg)
 }
 
 func (h *BotHandler) showPaydayGoalMenu(userID int64, chatID int64, incomeID int64, goalID int64, ctx context.Context) {
 	goal, err := h.financeService.GetUserGoalByID(ctx, userID, goalID)
 	if err != nil {
 		log.Printf("Failed to get goal: %v", err)
-		h.sendMessage(chatID, "❌ Ошибка при загрузке цели")
+		h.sendMessage(ctx, chatID, "❌ Ошибка при загрузке цели")
 		return
 	}
 
-	currentMonth := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.Now().Location())
+	currentMonth := models.MonthStart(h.financeService.UserNow(ctx, userID))
 	contrib, err := h.financeService.GetMonthlyContribution(ctx, userID, goalID, currentMonth)
 
-	contributed := int64(0)
+	contributed := models.Money(0)
 	if err == nil && contrib != nil {
 		contributed = contrib.AmountContributed
 	}
@@ -198,50 +144,51 @@ func (h *BotHandler) showPaydayGoalMenu(userID int64, chatID int64, incomeID int
 	text := fmt.Sprintf(
 		"🎯 Цель: %s\n"+
 			"Приоритет: (%d)\n\n"+
-			"Накоплено: %d/%d₽\n"+
-			"Осталось накопить: %d₽\n\n"+
+			"Накоплено: %s/%s\n"+
+			"Осталось накопить: %s\n\n"+
 			"Можно отложить в этом месяце:\n"+
-			"%d/%d₽\n\n"+
-			"Всего накоплено: %d₽",
+			"%s/%s\n\n"+
+			"Всего накоплено: %s",
 		goal.GoalName, goal.Priority,
-		goal.CurrentAmount, goal.TargetAmount,
-		remaining,
-		contributed, goal.MonthlyContrib,
-		goal.CurrentAmount,
+		goal.CurrentAmount, models.FormatAmount(goal.TargetAmount, goal.Currency),
+		models.FormatAmount(remaining, goal.Currency),
+		contributed, models.FormatAmount(goal.MonthlyContrib, goal.Currency),
+		models.FormatAmount(goal.CurrentAmount, goal.Currency),
 	)
 
 	buttons := [][]tgbotapi.InlineKeyboardButton{
 		{
-			tgbotapi.NewInlineKeyboardButtonData("➕ Добавить", fmt.Sprintf("payday_add_contribution_%d_%d", incomeID, goalID)),
+			tgbotapi.NewInlineKeyboardButtonData("➕ Добавить", fmt.Sprintf("payday/%d/goal/%d/add", incomeID, goalID)),
 		},
 		{
-			tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("payday_back_%d", incomeID)),
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("payday/%d", incomeID)),
 		},
 	}
 
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons...)
 
-	h.bot.Send(msg)
+	h.bot.Send(ctx, msg)
 }
 
-func (h *BotHandler) showGoalDetailsV2(userID int64, chatID int64, goalID int64) {
-	ctx := context.Background()
+func (h *BotHandler) showGoalDetailsV2(c *router.Context, goalID int64) {
+	userID := c.UserID()
+	chatID := c.ChatID()
 
-	goal, err := h.financeService.GetUserGoalByID(ctx, userID, goalID)
+	goal, err := h.financeService.GetUserGoalByID(c, userID, goalID)
 	if err != nil {
 		log.Printf("Failed to get goal: %v", err)
-		h.answerCallback("", "❌ Ошибка")
+		h.answerCallback(c, "", "❌ Ошибка")
 		return
 	}
 
-	allGoals, err := h.financeService.GetUserGoals(ctx, userID)
+	allGoals, err := h.financeService.GetUserGoals(c, userID)
 	if err != nil {
 		log.Printf("Failed to get goals: %v", err)
 		allGoals = []models.SavingsGoal{}
 	}
 
-	monthlyStats, err := h.financeService.GetGoalMonthlyStats(ctx, goalID)
+	monthlyStats, err := h.financeService.GetGoalMonthlyStats(c, goalID)
 	if err != nil {
 		log.Printf("Failed to get monthly stats: %v", err)
 		monthlyStats = make(map[string]interface{})
@@ -259,28 +206,27 @@ func (h *BotHandler) showGoalDetailsV2(userID int64, chatID int64, goalID int64)
 	}
 
 	// Статус прогресса
-	progress := int64(0)
-	if goal.TargetAmount > 0 {
-		progress = (goal.CurrentAmount * 100) / goal.TargetAmount
-	}
+	progress := goal.CurrentAmount.Percent(goal.TargetAmount)
 
 	statusText := "🔄 В процессе"
 	if goal.Status == "completed" {
 		statusText = "✅ Достигнута"
 	} else if goal.Status == "paused" {
 		statusText = "⏸️ На паузе"
+	} else if goal.Status == models.GoalStatusArchived {
+		statusText = "🗄 В архиве"
 	}
 
 	// Месячный прогресс
-	monthlyAccumulated := int64(0)
-	monthlyBudget := int64(0)
+	monthlyAccumulated := models.Money(0)
+	monthlyBudget := models.Money(0)
 	monthlyProgress := int64(0)
 
 	if monthlyStats != nil {
-		if val, ok := monthlyStats["monthly_accumulated"].(int64); ok {
+		if val, ok := monthlyStats["monthly_accumulated"].(models.Money); ok {
 			monthlyAccumulated = val
 		}
-		if val, ok := monthlyStats["monthly_budget_limit"].(int64); ok {
+		if val, ok := monthlyStats["monthly_budget_limit"].(models.Money); ok {
 			monthlyBudget = val
 		}
 		if val, ok := monthlyStats["monthly_progress"].(int64); ok {
@@ -293,24 +239,28 @@ func (h *BotHandler) showGoalDetailsV2(userID int64, chatID int64, goalID int64)
 		"🎯 <b>%s</b>\n\n"+
 			"<b>Приоритет:</b> %s\n"+
 			"<b>Статус:</b> %s\n\n"+
-			"<b>Целевая сумма:</b> %d₽\n"+
-			"<b>Накоплено:</b> %d₽\n"+
+			"<b>Целевая сумма:</b> %s\n"+
+			"<b>Накоплено:</b> %s\n"+
 			"<b>Прогресс:</b> %d%%\n"+
-			"<b>Осталось:</b> %d₽\n\n"+
-			"<b>На этот месяц:</b> %d₽ / %d₽ (%d%%)\n"+
+			"<b>Осталось:</b> %s\n\n"+
+			"<b>На этот месяц:</b> %s / %s (%d%%)\n"+
 			"<b>Дата достижения:</b> %s",
 		goal.GoalName,
 		priorityText,
 		statusText,
-		goal.TargetAmount,
-		goal.CurrentAmount,
+		models.FormatAmount(goal.TargetAmount, goal.Currency),
+		models.FormatAmount(goal.CurrentAmount, goal.Currency),
 		progress,
-		goal.TargetAmount-goal.CurrentAmount,
-		monthlyAccumulated, monthlyBudget, monthlyProgress,
+		models.FormatAmount(goal.TargetAmount-goal.CurrentAmount, goal.Currency),
+		models.FormatAmount(monthlyAccumulated, goal.Currency), models.FormatAmount(monthlyBudget, goal.Currency), monthlyProgress,
 		goal.TargetDate.Format("02.01.2006"),
 	)
+	text += goalDeadlineText(*goal, c.User.Now())
+	if goal.CompletedAt.Valid {
+		text += fmt.Sprintf("\n🏆 Достигнута %s за %s", goal.CompletedAt.Time.Format("02.01.2006"), formatDuration(goal.CreatedAt, goal.CompletedAt.Time))
+	}
 
-	log.Printf("[GOAL_DETAILS_V2] Goal %d (%s): Target=%d₽, Current=%d₽, Remaining=%d₽, MonthlyAccum=%d₽, MonthlyBudget=%d₽, MonthlyContrib=%d₽",
+	log.Printf("[GOAL_DETAILS_V2] Goal %d (%s): Target=%s, Current=%s, Remaining=%s, MonthlyAccum=%s, MonthlyBudget=%s, MonthlyContrib=%s",
 		goal.ID, goal.GoalName, goal.TargetAmount, goal.CurrentAmount, goal.TargetAmount-goal.CurrentAmount, monthlyAccumulated, monthlyBudget, goal.MonthlyContrib)
 	log.Printf("[GOAL_DETAILS_V2] Message text: %s", text)
 
@@ -319,20 +269,45 @@ func (h *BotHandler) showGoalDetailsV2(userID int64, chatID int64, goalID int64)
 
 	if goal.Status == "active" {
 		// Внести, Снять
-		contributeBtn := tgbotapi.NewInlineKeyboardButtonData("💰 Внести", fmt.Sprintf("contrib_%d", goal.ID))
-		withdrawBtn := tgbotapi.NewInlineKeyboardButtonData("📤 Снять", fmt.Sprintf("withdraw_%d", goal.ID))
+		contributeBtn := tgbotapi.NewInlineKeyboardButtonData("💰 Внести", fmt.Sprintf("goal/%d/contrib", goal.ID))
+		withdrawBtn := tgbotapi.NewInlineKeyboardButtonData("📤 Снять", fmt.Sprintf("goal/%d/withdraw", goal.ID))
 		buttons = append(buttons, []tgbotapi.InlineKeyboardButton{contributeBtn, withdrawBtn})
 
 		// Кнопка изменения приоритета (только если больше одной цели)
 		if len(allGoals) > 1 {
-			changePriorityBtn := tgbotapi.NewInlineKeyboardButtonData("🔀 Изменить приоритет", fmt.Sprintf("changepriority_%d", goal.ID))
+			changePriorityBtn := tgbotapi.NewInlineKeyboardButtonData("🔀 Изменить приоритет", fmt.Sprintf("goal/%d/priority", goal.ID))
 			buttons = append(buttons, []tgbotapi.InlineKeyboardButton{changePriorityBtn})
 		}
+
+		// доля цели нужна только стратегии фиксированных долей
+		if c.User.AllocationStrategy == models.AllocationFixedPercent {
+			percentBtn := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("📊 Доля: %d%%", goal.AllocationPercent), fmt.Sprintf("goal/%d/percent", goal.ID))
+			buttons = append(buttons, []tgbotapi.InlineKeyboardButton{percentBtn})
+		}
+	}
+
+	// пауза, возобновление и архив
+	var statusButtons []tgbotapi.InlineKeyboardButton
+	switch goal.Status {
+	case models.GoalStatusActive:
+		statusButtons = append(statusButtons, tgbotapi.NewInlineKeyboardButtonData("⏸️ Пауза", fmt.Sprintf("goal/%d/pause", goal.ID)))
+	case models.GoalStatusPaused:
+		statusButtons = append(statusButtons, tgbotapi.NewInlineKeyboardButtonData("▶️ Возобновить", fmt.Sprintf("goal/%d/resume", goal.ID)))
+	case models.GoalStatusArchived:
+		statusButtons = append(statusButtons, tgbotapi.NewInlineKeyboardButtonData("♻️ Вернуть из архива", fmt.Sprintf("goal/%d/resume", goal.ID)))
+	}
+	if goal.Status != models.GoalStatusArchived {
+		statusButtons = append(statusButtons, tgbotapi.NewInlineKeyboardButtonData("🗄 В архив", fmt.Sprintf("goal/%d/archive", goal.ID)))
 	}
+	buttons = append(buttons, statusButtons)
+
+	historyBtn := tgbotapi.NewInlineKeyboardButtonData("📜 История", fmt.Sprintf("goal/%d/history", goal.ID))
+	editBtn := tgbotapi.NewInlineKeyboardButtonData("✏️ Изменить", fmt.Sprintf("goal/%d/edit", goal.ID))
+	buttons = append(buttons, []tgbotapi.InlineKeyboardButton{historyBtn, editBtn})
 
 	// Кнопки удаления и возврата
-	deleteBtn := tgbotapi.NewInlineKeyboardButtonData("🗑️ Удалить цель", fmt.Sprintf("delete_goal_%d", goal.ID))
-	backBtn := tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад к целям", "back_to_goals")
+	deleteBtn := tgbotapi.NewInlineKeyboardButtonData("🗑️ Удалить цель", fmt.Sprintf("goal/%d/delete", goal.ID))
+	backBtn := tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад к целям", "goals")
 	buttons = append(buttons, []tgbotapi.InlineKeyboardButton{deleteBtn, backBtn})
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
@@ -341,26 +316,28 @@ func (h *BotHandler) showGoalDetailsV2(userID int64, chatID int64, goalID int64)
 	msg.ReplyMarkup = keyboard
 	msg.ParseMode = "HTML"
 
-	h.bot.Send(msg)
+	h.bot.Send(c, msg)
 }
 
-func (h *BotHandler) showGoalDetailsV2WithBack(userID int64, chatID int64, goalID int64, backCallback string, backText string) {
-	ctx := context.Background()
+// showPaydayGoalDetails - карточка цели из меню получки, с возвратом к получке
+func (h *BotHandler) showPaydayGoalDetails(c *router.Context, goalID int64, incomeID int64) {
+	userID := c.UserID()
+	chatID := c.ChatID()
 
-	goal, err := h.financeService.GetUserGoalByID(ctx, userID, goalID)
+	goal, err := h.financeService.GetUserGoalByID(c, userID, goalID)
 	if err != nil {
 		log.Printf("Failed to get goal: %v", err)
-		h.answerCallback("", "❌ Ошибка")
+		h.answerCallback(c, "", "❌ Ошибка")
 		return
 	}
 
-	allGoals, err := h.financeService.GetUserGoals(ctx, userID)
+	allGoals, err := h.financeService.GetUserGoals(c, userID)
 	if err != nil {
 		log.Printf("Failed to get goals: %v", err)
 		allGoals = []models.SavingsGoal{}
 	}
 
-	monthlyStats, err := h.financeService.GetGoalMonthlyStats(ctx, goalID)
+	monthlyStats, err := h.financeService.GetGoalMonthlyStats(c, goalID)
 	if err != nil {
 		log.Printf("Failed to get monthly stats: %v", err)
 		monthlyStats = make(map[string]interface{})
@@ -378,28 +355,27 @@ func (h *BotHandler) showGoalDetailsV2WithBack(userID int64, chatID int64, goalI
 	}
 
 	// Статус прогресса
-	progress := int64(0)
-	if goal.TargetAmount > 0 {
-		progress = (goal.CurrentAmount * 100) / goal.TargetAmount
-	}
+	progress := goal.CurrentAmount.Percent(goal.TargetAmount)
 
 	statusText := "🔄 В процессе"
 	if goal.Status == "completed" {
 		statusText = "✅ Достигнута"
 	} else if goal.Status == "paused" {
 		statusText = "⏸️ На паузе"
+	} else if goal.Status == models.GoalStatusArchived {
+		statusText = "🗄 В архиве"
 	}
 
 	// Месячный прогресс
-	monthlyAccumulated := int64(0)
-	monthlyBudget := int64(0)
+	monthlyAccumulated := models.Money(0)
+	monthlyBudget := models.Money(0)
 	monthlyProgress := int64(0)
 
 	if monthlyStats != nil {
-		if val, ok := monthlyStats["monthly_accumulated"].(int64); ok {
+		if val, ok := monthlyStats["monthly_accumulated"].(models.Money); ok {
 			monthlyAccumulated = val
 		}
-		if val, ok := monthlyStats["monthly_budget_limit"].(int64); ok {
+		if val, ok := monthlyStats["monthly_budget_limit"].(models.Money); ok {
 			monthlyBudget = val
 		}
 		if val, ok := monthlyStats["monthly_progress"].(int64); ok {
@@ -412,46 +388,39 @@ func (h *BotHandler) showGoalDetailsV2WithBack(userID int64, chatID int64, goalI
 		"🎯 <b>%s</b>\n\n"+
 			"<b>Приоритет:</b> %s\n"+
 			"<b>Статус:</b> %s\n\n"+
-			"<b>Целевая сумма:</b> %d₽\n"+
-			"<b>Накоплено:</b> %d₽\n"+
+			"<b>Целевая сумма:</b> %s\n"+
+			"<b>Накоплено:</b> %s\n"+
 			"<b>Прогресс:</b> %d%%\n"+
-			"<b>Осталось:</b> %d₽\n\n"+
-			"<b>На этот месяц:</b> %d₽ / %d₽ (%d%%)\n"+
+			"<b>Осталось:</b> %s\n\n"+
+			"<b>На этот месяц:</b> %s / %s (%d%%)\n"+
 			"<b>Дата достижения:</b> %s",
 		goal.GoalName,
 		priorityText,
 		statusText,
-		goal.TargetAmount,
-		goal.CurrentAmount,
+		models.FormatAmount(goal.TargetAmount, goal.Currency),
+		models.FormatAmount(goal.CurrentAmount, goal.Currency),
 		progress,
-		goal.TargetAmount-goal.CurrentAmount,
-		monthlyAccumulated, monthlyBudget, monthlyProgress,
+		models.FormatAmount(goal.TargetAmount-goal.CurrentAmount, goal.Currency),
+		models.FormatAmount(monthlyAccumulated, goal.Currency), models.FormatAmount(monthlyBudget, goal.Currency), monthlyProgress,
 		goal.TargetDate.Format("02.01.2006"),
 	)
-
-	incomeIDForPayday := int64(0)
-	if strings.HasPrefix(backCallback, "payday_back_") {
-		incomeIDStr := strings.TrimPrefix(backCallback, "payday_back_")
-		if incomeIDParsed, err := strconv.ParseInt(incomeIDStr, 10, 64); err == nil {
-			incomeIDForPayday = incomeIDParsed
-		}
-	}
+	text += goalDeadlineText(*goal, c.User.Now())
 
 	var buttons [][]tgbotapi.InlineKeyboardButton
 
 	if goal.Status == "active" {
-		contributeBtn := tgbotapi.NewInlineKeyboardButtonData("💰 Внести", fmt.Sprintf("payday_add_%d_%d", incomeIDForPayday, goalID))
-		withdrawBtn := tgbotapi.NewInlineKeyboardButtonData("📤 Снять", fmt.Sprintf("withdraw_%d", goal.ID))
+		contributeBtn := tgbotapi.NewInlineKeyboardButtonData("💰 Внести", fmt.Sprintf("payday/%d/goal/%d/add", incomeID, goalID))
+		withdrawBtn := tgbotapi.NewInlineKeyboardButtonData("📤 Снять", fmt.Sprintf("goal/%d/withdraw", goal.ID))
 		buttons = append(buttons, []tgbotapi.InlineKeyboardButton{contributeBtn, withdrawBtn})
 
 		if len(allGoals) > 1 {
-			changePriorityBtn := tgbotapi.NewInlineKeyboardButtonData("🔀 Изменить приоритет", fmt.Sprintf("changepriority_%d", goal.ID))
+			changePriorityBtn := tgbotapi.NewInlineKeyboardButtonData("🔀 Изменить приоритет", fmt.Sprintf("goal/%d/priority", goal.ID))
 			buttons = append(buttons, []tgbotapi.InlineKeyboardButton{changePriorityBtn})
 		}
 	}
 
-	deleteBtn := tgbotapi.NewInlineKeyboardButtonData("🗑️ Удалить цель", fmt.Sprintf("delete_goal_%d", goal.ID))
-	backBtn := tgbotapi.NewInlineKeyboardButtonData(backText, backCallback)
+	deleteBtn := tgbotapi.NewInlineKeyboardButtonData("🗑️ Удалить цель", fmt.Sprintf("goal/%d/delete", goal.ID))
+	backBtn := tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад к получке", fmt.Sprintf("payday/%d", incomeID))
 	buttons = append(buttons, []tgbotapi.InlineKeyboardButton{deleteBtn, backBtn})
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
@@ -460,20 +429,21 @@ func (h *BotHandler) showGoalDetailsV2WithBack(userID int64, chatID int64, goalI
 	msg.ReplyMarkup = keyboard
 	msg.ParseMode = "HTML"
 
-	h.bot.Send(msg)
+	h.bot.Send(c, msg)
 }
 
-func (h *BotHandler) showTestGoalDetailsV2WithBack(userID int64, chatID int64, goalID int64, incomeID int64) {
-	ctx := context.Background()
+func (h *BotHandler) showTestGoalDetailsV2WithBack(c *router.Context, goalID int64, incomeID int64) {
+	userID := c.UserID()
+	chatID := c.ChatID()
 
-	goal, err := h.financeService.GetUserGoalByID(ctx, userID, goalID)
+	goal, err := h.financeService.GetUserGoalByID(c, userID, goalID)
 	if err != nil {
 		log.Printf("Failed to get goal: %v", err)
-		h.answerCallback("", "❌ Ошибка теста")
+		h.answerCallback(c, "", "❌ Ошибка теста")
 		return
 	}
 
-	monthlyStats, err := h.financeService.GetGoalMonthlyStats(ctx, goalID)
+	monthlyStats, err := h.financeService.GetGoalMonthlyStats(c, goalID)
 	if err != nil {
 		log.Printf("Failed to get monthly stats: %v", err)
 		monthlyStats = make(map[string]interface{})
@@ -490,27 +460,26 @@ func (h *BotHandler) showTestGoalDetailsV2WithBack(userID int64, chatID int64, g
 		priorityText = fmt.Sprintf("%d", goal.Priority)
 	}
 
-	progress := int64(0)
-	if goal.TargetAmount > 0 {
-		progress = (goal.CurrentAmount * 100) / goal.TargetAmount
-	}
+	progress := goal.CurrentAmount.Percent(goal.TargetAmount)
 
 	statusText := "🔄 В процессе"
 	if goal.Status == "completed" {
 		statusText = "✅ Достигнута"
 	} else if goal.Status == "paused" {
 		statusText = "⏸️ На паузе"
+	} else if goal.Status == models.GoalStatusArchived {
+		statusText = "🗄 В архиве"
 	}
 
-	monthlyAccumulated := int64(0)
-	monthlyBudget := int64(0)
+	monthlyAccumulated := models.Money(0)
+	monthlyBudget := models.Money(0)
 	monthlyProgress := int64(0)
 
 	if monthlyStats != nil {
-		if val, ok := monthlyStats["monthly_accumulated"].(int64); ok {
+		if val, ok := monthlyStats["monthly_accumulated"].(models.Money); ok {
 			monthlyAccumulated = val
 		}
-		if val, ok := monthlyStats["monthly_budget_limit"].(int64); ok {
+		if val, ok := monthlyStats["monthly_budget_limit"].(models.Money); ok {
 			monthlyBudget = val
 		}
 		if val, ok := monthlyStats["monthly_progress"].(int64); ok {
@@ -522,32 +491,33 @@ func (h *BotHandler) showTestGoalDetailsV2WithBack(userID int64, chatID int64, g
 		"🎯 <b>%s</b> (ТЕСТ)\n\n"+
 			"<b>Приоритет:</b> %s\n"+
 			"<b>Статус:</b> %s\n\n"+
-			"<b>Целевая сумма:</b> %d₽\n"+
-			"<b>Накоплено:</b> %d₽\n"+
+			"<b>Целевая сумма:</b> %s\n"+
+			"<b>Накоплено:</b> %s\n"+
 			"<b>Прогресс:</b> %d%%\n"+
-			"<b>Осталось:</b> %d₽\n\n"+
-			"<b>На этот месяц:</b> %d₽ / %d₽ (%d%%)\n"+
+			"<b>Осталось:</b> %s\n\n"+
+			"<b>На этот месяц:</b> %s / %s (%d%%)\n"+
 			"<b>Дата достижения:</b> %s",
 		goal.GoalName,
 		priorityText,
 		statusText,
-		goal.TargetAmount,
-		goal.CurrentAmount,
+		models.FormatAmount(goal.TargetAmount, goal.Currency),
+		models.FormatAmount(goal.CurrentAmount, goal.Currency),
 		progress,
-		goal.TargetAmount-goal.CurrentAmount,
-		monthlyAccumulated, monthlyBudget, monthlyProgress,
+		models.FormatAmount(goal.TargetAmount-goal.CurrentAmount, goal.Currency),
+		models.FormatAmount(monthlyAccumulated, goal.Currency), models.FormatAmount(monthlyBudget, goal.Currency), monthlyProgress,
 		goal.TargetDate.Format("02.01.2006"),
 	)
+	text += goalDeadlineText(*goal, c.User.Now())
 
 	var buttons [][]tgbotapi.InlineKeyboardButton
 
 	if goal.Status == "active" {
-		addBtn := tgbotapi.NewInlineKeyboardButtonData("💰 Внести (тест)", fmt.Sprintf("test_payday_add_%d_%d", incomeID, goalID))
+		addBtn := tgbotapi.NewInlineKeyboardButtonData("💰 Внести (тест)", fmt.Sprintf("testpayday/%d/goal/%d/add", incomeID, goalID))
 		buttons = append(buttons, []tgbotapi.InlineKeyboardButton{addBtn})
 	}
 
-	testCompleteBtn := tgbotapi.NewInlineKeyboardButtonData("✅ Завершить тест", fmt.Sprintf("test_payday_complete_%d", incomeID))
-	backBtn := tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад к тесту", fmt.Sprintf("test_payday_back_%d", incomeID))
+	testCompleteBtn := tgbotapi.NewInlineKeyboardButtonData("✅ Завершить тест", fmt.Sprintf("testpayday/%d/complete", incomeID))
+	backBtn := tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад к тесту", fmt.Sprintf("testpayday/%d", incomeID))
 	buttons = append(buttons, []tgbotapi.InlineKeyboardButton{backBtn, testCompleteBtn})
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
@@ -556,5 +526,73 @@ func (h *BotHandler) showTestGoalDetailsV2WithBack(userID int64, chatID int64, g
 	msg.ReplyMarkup = keyboard
 	msg.ParseMode = "HTML"
 
-	h.bot.Send(msg)
+	h.bot.Send(c, msg)
+}
+
+func (h *BotHandler) showGoalHistory(c *router.Context, goalID int64) {
+	userID := c.UserID()
+	chatID := c.ChatID()
+
+	goal, err := h.financeService.GetUserGoalByID(c, userID, goalID)
+	if err != nil {
+		log.Printf("Failed to get goal: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при загрузке цели")
+		return
+	}
+
+	transactions, err := h.financeService.GetGoalHistory(c, userID, goalID, goalHistoryLimit)
+	if err != nil {
+		log.Printf("Failed to get goal history: %v", err)
+		h.sendMessage(c, chatID, "❌ Ошибка при загрузке истории")
+		return
+	}
+
+	text := fmt.Sprintf("📜 <b>История: %s</b>\n\n", goal.GoalName)
+
+	if len(transactions) == 0 {
+		text += "Операций пока нет"
+	}
+
+	for _, tx := range transactions {
+		sign := "➕"
+		if tx.Amount < 0 {
+			sign = "➖"
+		}
+
+		amount := tx.Amount
+		if amount < 0 {
+			amount = -amount
+		}
+
+		text += fmt.Sprintf("%s %s %s — %s\n", tx.CreatedAt.Format("02.01.2006 15:04"), sign, models.FormatAmount(amount, goal.Currency), goalTxKindText(tx.Kind))
+		if tx.Comment != "" {
+			text += fmt.Sprintf("   <i>%s</i>\n", tx.Comment)
+		}
+	}
+
+	text += fmt.Sprintf("\n<b>Баланс:</b> %s", models.FormatAmount(goal.CurrentAmount, goal.Currency))
+
+	backBtn := tgbotapi.NewInlineKeyboardButtonData("🔙 Вернуться к цели", fmt.Sprintf("goal/%d", goal.ID))
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup([]tgbotapi.InlineKeyboardButton{backBtn})
+	msg.ParseMode = "HTML"
+	h.bot.Send(c, msg)
+}
+
+func goalTxKindText(kind string) string {
+	switch kind {
+	case models.GoalTxDeposit:
+		return "Пополнение"
+	case models.GoalTxWithdrawal:
+		return "Снятие"
+	case models.GoalTxPaydayAllocation:
+		return "Из получки"
+	case models.GoalTxCorrection:
+		return "Корректировка"
+	case models.GoalTxTransfer:
+		return "Перенос"
+	default:
+		return kind
+	}
 }
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// валюта по умолчанию для новых пользователей и записей
+const DefaultCurrency = "RUB"
+
+var currencySymbols = map[string]string{
+	"RUB": "₽",
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"CNY": "¥",
+	"KZT": "₸",
+	"TRY": "₺",
+}
+
+// NormalizeCurrency принимает ISO-код в любом регистре или символ валюты
+func NormalizeCurrency(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	for code, symbol := range currencySymbols {
+		if value == symbol {
+			return code, true
+		}
+	}
+
+	if strings.EqualFold(value, "руб") || strings.EqualFold(value, "р") {
+		return "RUB", true
+	}
+
+	code := strings.ToUpper(value)
+	if len(code) != 3 {
+		return "", false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", false
+		}
+	}
+	return code, true
+}
+
+func CurrencySymbol(currency string) string {
+	if symbol, ok := currencySymbols[currency]; ok {
+		return symbol
+	}
+	return " " + currency
+}
+
+func FormatAmount(amount Money, currency string) string {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return amount.String() + CurrencySymbol(currency)
+}
+
+// ParseAmountWithCurrency разбирает сумму с необязательной валютой: "1500", "1 499,90 USD", "15к$", "$1500";
+// если валюта не указана, возвращает пустую строку
+func ParseAmountWithCurrency(text string) (Money, string, error) {
+	text = strings.TrimSpace(text)
+
+	start := strings.IndexFunc(text, isMoneyDigit)
+	end := strings.LastIndexFunc(text, isMoneyDigit)
+	if start < 0 {
+		return 0, "", fmt.Errorf("%w: no amount in %q", ErrInvalidMoney, text)
+	}
+
+	prefix := strings.TrimSpace(text[:start])
+	number := text[start : end+1]
+	suffix := strings.TrimSpace(text[end+1:])
+
+	currency := ""
+	if prefix != "" {
+		code, ok := NormalizeCurrency(prefix)
+		if !ok {
+			return 0, "", fmt.Errorf("unknown currency %q", prefix)
+		}
+		currency = code
+	}
+
+	if suffix != "" {
+		code, shorthand, ok := splitAmountSuffix(suffix)
+		if !ok || (code != "" && currency != "") {
+			return 0, "", fmt.Errorf("unknown currency %q", suffix)
+		}
+		number += shorthand
+		if code != "" {
+			currency = code
+		}
+	}
+
+	amount, err := ParseMoney(number)
+	if err != nil {
+		return 0, "", err
+	}
+	return amount, currency, nil
+}
+
+func isMoneyDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// хвост после числа: валюта, сокращение ("к", "млн") или сокращение и валюта
+func splitAmountSuffix(suffix string) (string, string, bool) {
+	if code, ok := NormalizeCurrency(suffix); ok {
+		return code, "", true
+	}
+
+	lower := strings.ToLower(suffix)
+	for _, m := range moneyMultipliers {
+		if !strings.HasPrefix(lower, m.suffix) {
+			continue
+		}
+		rest := strings.TrimSpace(suffix[len(m.suffix):])
+		if rest == "" {
+			return "", m.suffix, true
+		}
+		if code, ok := NormalizeCurrency(rest); ok {
+			return code, m.suffix, true
+		}
+	}
+	return "", "", false
+}
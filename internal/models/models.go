@@ -3,81 +3,290 @@ package models
 import (
 	"database/sql"
 	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/schedule"
 )
 
 type User struct {
-	ID             int64  `db:"id"`
-	TelegramID     int64  `db:"telegram_id"`
-	Username       string `db:"username"`
-	AuthToken      string `db:"auth_token"`
-	MonthlyExpense int64
-	CreatedAt      time.Time `db:"created_at"`
-	UpdatedAt      time.Time `db:"updated_at"`
+	ID                 int64  `db:"id"`
+	TelegramID         int64  `db:"telegram_id"`
+	Username           string `db:"username"`
+	AuthToken          string `db:"auth_token"`
+	MonthlyExpense     Money
+	BaseCurrency       string    `db:"base_currency"`
+	AllocationStrategy string    `db:"allocation_strategy"`
+	Timezone           string    `db:"timezone"` // IANA, например Asia/Vladivostok
+	CreatedAt          time.Time `db:"created_at"`
+	UpdatedAt          time.Time `db:"updated_at"`
 }
 
 type Income struct {
-	ID               int64     `db:"id"`
-	UserID           int64     `db:"user_id"`
-	Name             string    `db:"name"`
-	Amount           int64     `db:"amount"`
-	Frequency        string    `db:"frequency"`
-	RecurringDay     int       `db:"recurring_day"`
-	NotificationHour int       `db:"notification_hour"`
-	NextPayDate      time.Time `db:"next_pay_date"`
-	CreatedAt        time.Time `db:"created_at"`
-	UpdatedAt        time.Time `db:"updated_at"`
+	ID               int64        `db:"id"`
+	UserID           int64        `db:"user_id"`
+	Name             string       `db:"name"`
+	Amount           Money        `db:"amount"`
+	Currency         string       `db:"currency"`
+	Frequency        string       `db:"frequency"`
+	RecurringDay     int          `db:"recurring_day"`
+	SecondDay        int          `db:"second_day"`  // второй день для выплат два раза в месяц
+	AnchorDate       sql.NullTime `db:"anchor_date"` // опорная дата для выплат раз в две недели и раз в квартал
+	PayShift         string       `db:"pay_shift"`   // перенос с нерабочих дней: none, previous, next
+	NotificationHour int          `db:"notification_hour"`
+	NextPayDate      time.Time    `db:"next_pay_date"`
+	CreatedAt        time.Time    `db:"created_at"`
+	UpdatedAt        time.Time    `db:"updated_at"`
+
+	Installments []IncomeInstallment // части дохода, только для frequency = installments
 }
 
+const (
+	// доход, приходящий частями (аванс и зарплата)
+	IncomeFrequencyInstallments = "installments"
+	// доход без расписания: поступления записываются вручную, план - среднее за последние месяцы
+	IncomeFrequencyIrregular = "irregular"
+)
+
+// часть дохода: задается суммой или процентом от Income.Amount
+type IncomeInstallment struct {
+	ID       int64 `db:"id"`
+	IncomeID int64 `db:"income_id"`
+	Day      int   `db:"day"`
+	Amount   Money `db:"amount"`
+	Percent  int   `db:"percent"`
+}
+
+func (i *Income) Schedule() schedule.Rule {
+	rule := schedule.Rule{
+		Kind:      schedule.Kind(i.Frequency),
+		Day:       i.RecurringDay,
+		SecondDay: i.SecondDay,
+		Shift:     schedule.Shift(i.PayShift),
+	}
+	if i.AnchorDate.Valid {
+		rule.Anchor = i.AnchorDate.Time
+	}
+	return rule
+}
+
+const (
+	ExpenseKindRecurring = "recurring"
+	ExpenseKindOneOff    = "one_off"
+)
+
 type Expense struct {
-	ID        int64     `db:"id"`
-	UserID    int64     `db:"user_id"`
-	Name      string    `db:"name"`
-	Amount    int64     `db:"amount"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	ID           int64         `db:"id"`
+	UserID       int64         `db:"user_id"`
+	Name         string        `db:"name"`
+	Amount       Money         `db:"amount"`
+	Currency     string        `db:"currency"`
+	CategoryID   sql.NullInt64 `db:"category_id"`
+	Kind         string        `db:"kind"`
+	Frequency    string        `db:"frequency"`     // только для регулярных
+	RecurringDay int           `db:"recurring_day"` // только для регулярных
+	SpentAt      sql.NullTime  `db:"spent_at"`      // только для разовых
+	CreatedAt    time.Time     `db:"created_at"`
+	UpdatedAt    time.Time     `db:"updated_at"`
+}
+
+func (i *Income) IsIrregular() bool {
+	return i.Frequency == IncomeFrequencyIrregular
+}
+
+func (i *Income) HasInstallments() bool {
+	return i.Frequency == IncomeFrequencyInstallments && len(i.Installments) > 0
+}
+
+// расписание каждой части: ежемесячно в ее день с переносом дохода
+func (i *Income) InstallmentSchedules() []schedule.Rule {
+	rules := make([]schedule.Rule, len(i.Installments))
+	for k, inst := range i.Installments {
+		rules[k] = schedule.Rule{Kind: schedule.Monthly, Day: inst.Day, Shift: schedule.Shift(i.PayShift)}
+	}
+	return rules
+}
+
+// суммы частей; копейки от округления процентов достаются последней процентной части
+func (i *Income) InstallmentAmounts() []Money {
+	amounts := make([]Money, len(i.Installments))
+	sum := Money(0)
+	lastPercent := -1
+	for k, inst := range i.Installments {
+		if inst.Percent > 0 {
+			amounts[k] = i.Amount.MulDiv(int64(inst.Percent), 100)
+			lastPercent = k
+		} else {
+			amounts[k] = inst.Amount
+		}
+		sum += amounts[k]
+	}
+	if lastPercent >= 0 && sum != i.Amount {
+		amounts[lastPercent] += i.Amount - sum
+	}
+	return amounts
+}
+
+// InstallmentOn - номер части, которая приходит в день payDate, или -1
+func (i *Income) InstallmentOn(payDate time.Time) int {
+	if !i.HasInstallments() {
+		return -1
+	}
+	for k, rule := range i.InstallmentSchedules() {
+		d := rule.Next(payDate.AddDate(0, 0, -1))
+		if d.Year() == payDate.Year() && d.Month() == payDate.Month() && d.Day() == payDate.Day() {
+			return k
+		}
+	}
+	return -1
+}
+
+// PaydayAmount - сколько приходит в день payDate: часть дохода или вся сумма
+func (i *Income) PaydayAmount(payDate time.Time) Money {
+	if k := i.InstallmentOn(payDate); k >= 0 {
+		return i.InstallmentAmounts()[k]
+	}
+	return i.Amount
+}
+
+func (e *Expense) Schedule() schedule.Rule {
+	return schedule.Rule{Kind: schedule.Kind(e.Frequency), Day: e.RecurringDay}
 }
 
+// категория расходов с необязательным месячным лимитом
+type ExpenseCategory struct {
+	ID           int64     `db:"id"`
+	UserID       int64     `db:"user_id"`
+	Name         string    `db:"name"`
+	IsDefault    bool      `db:"is_default"`
+	MonthlyLimit Money     `db:"monthly_limit"`
+	CreatedAt    time.Time `db:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at"`
+}
+
+// стратегии распределения свободных средств между целями
+const (
+	AllocationPriorityWeighted = "priority_weighted"
+	AllocationWaterfall        = "waterfall"
+	AllocationEqual            = "equal"
+	AllocationDeadline         = "deadline"
+	AllocationFixedPercent     = "fixed_percent"
+)
+
 // цель накопления
+const (
+	GoalStatusActive    = "active"
+	GoalStatusPaused    = "paused"
+	GoalStatusCompleted = "completed"
+	GoalStatusArchived  = "archived"
+)
+
 type SavingsGoal struct {
 	ID                 int64        `db:"id"`
 	UserID             int64        `db:"user_id"`
 	GoalName           string       `db:"goal_name"`
-	TargetAmount       int64        `db:"target_amount"`
-	CurrentAmount      int64        `db:"current_amount"`
-	MonthlyContrib     int64        `db:"monthly_contrib"`
-	MonthlyBudgetLimit int64        `db:"monthly_budget_limit"`
-	MonthlyAccumulated int64        `db:"monthly_accumulated"`
+	Currency           string       `db:"currency"`
+	TargetAmount       Money        `db:"target_amount"`
+	CurrentAmount      Money        `db:"current_amount"`
+	MonthlyContrib     Money        `db:"monthly_contrib"`
+	MonthlyBudgetLimit Money        `db:"monthly_budget_limit"`
+	MonthlyAccumulated Money        `db:"monthly_accumulated"`
 	MonthStarted       sql.NullTime `db:"month_started"`
-	TargetDate         time.Time    `db:"target_date"`
+	TargetDate         time.Time    `db:"target_date"` // расчетная дата достижения при текущем плане
+	Deadline           sql.NullTime `db:"deadline"`    // срок, заданный пользователем
 	Priority           int          `db:"priority"`
 	Status             string       `db:"status"`
+	AllocationPercent  int          `db:"allocation_percent"` // только для стратегии фиксированных процентов
+	CompletedAt        sql.NullTime `db:"completed_at"`
 	CreatedAt          time.Time    `db:"created_at"`
 	UpdatedAt          time.Time    `db:"updated_at"`
 }
 
+// цель на паузе не получает денег, но сохраняет свое место в очереди приоритетов
+func (g SavingsGoal) HoldsPriority() bool {
+	return g.Status == GoalStatusActive || g.Status == GoalStatusPaused
+}
+
 type MonthlyContribution struct {
 	ID                int64     `db:"id"`
 	UserID            int64     `db:"user_id"`
 	GoalID            int64     `db:"goal_id"`
 	Month             time.Time `db:"month"`
-	AmountContributed int64     `db:"amount"`
+	AmountContributed Money     `db:"amount"`
 	CreatedAt         time.Time `db:"created_at"`
 	UpdatedAt         time.Time `db:"updated_at"`
 }
 
+// виды операций в журнале цели
+const (
+	GoalTxDeposit          = "deposit"
+	GoalTxWithdrawal       = "withdrawal"
+	GoalTxPaydayAllocation = "payday_allocation"
+	GoalTxCorrection       = "correction"
+	GoalTxTransfer         = "transfer"
+)
+
+// операция по цели накопления (журнал только дополняется)
+type GoalTransaction struct {
+	ID        int64         `db:"id"`
+	GoalID    int64         `db:"goal_id"`
+	UserID    int64         `db:"user_id"`
+	Kind      string        `db:"kind"`
+	Amount    Money         `db:"amount"`
+	IncomeID  sql.NullInt64 `db:"income_id"`
+	Comment   string        `db:"comment"`
+	CreatedAt time.Time     `db:"created_at"`
+}
+
 type IncomeProcessingLog struct {
 	ID            int64
 	IncomeID      int64
 	UserID        int64
-	ProcessedDate time.Time
-	IncomeAmount  int64
+	ProcessedDate time.Time // плановая дата выплаты
+	IncomeAmount  Money     // плановая сумма
+	ActualAmount  Money
+	ReceivedDate  sql.NullTime // не задана - поступление не подтверждено
 	Status        string
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 }
 
+func (l *IncomeProcessingLog) Confirmed() bool {
+	return l.ReceivedDate.Valid
+}
+
+// Received - фактическая сумма, а пока поступление не подтверждено - плановая
+func (l *IncomeProcessingLog) Received() Money {
+	if l.Confirmed() {
+		return l.ActualAmount
+	}
+	return l.IncomeAmount
+}
+
+// IsLate - деньги пришли позже плановой даты
+func (l *IncomeProcessingLog) IsLate() bool {
+	return l.Confirmed() && l.ReceivedDate.Time.After(l.ProcessedDate)
+}
+
 type UserState struct {
 	UserID   int64
 	State    string
 	TempDate map[string]string
 }
+
+// курс валюты в рублях за единицу
+type ExchangeRate struct {
+	Currency  string    `db:"currency"`
+	Rate      float64   `db:"rate"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// PendingNotification - уведомление, ожидающее повторной отправки
+type PendingNotification struct {
+	ID            int64
+	ChatID        int64
+	Text          string
+	ReplyMarkup   sql.NullString // JSON inline-клавиатуры
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+}
@@ -0,0 +1,245 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money - сумма в минимальных единицах валюты (копейках, центах)
+type Money int64
+
+// минимальных единиц в одной основной
+const minorUnits = 100
+
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+// MoneyFromMajor переводит целые рубли (доллары, ...) в Money
+func MoneyFromMajor(major int64) Money {
+	return Money(major * minorUnits)
+}
+
+// Major возвращает целую часть суммы
+func (m Money) Major() int64 {
+	return int64(m) / minorUnits
+}
+
+// MulFloat умножает сумму на коэффициент с округлением до минимальной единицы
+func (m Money) MulFloat(k float64) Money {
+	return Money(math.Round(float64(m) * k))
+}
+
+// MulDiv возвращает m * num / den с отбрасыванием дробной части минимальной единицы
+func (m Money) MulDiv(num, den int64) Money {
+	if den == 0 {
+		return 0
+	}
+	return Money(int64(m) * num / den)
+}
+
+// Percent - доля m от total в процентах (0, если total не положителен)
+func (m Money) Percent(total Money) int64 {
+	if total <= 0 {
+		return 0
+	}
+	return int64(m) * 100 / int64(total)
+}
+
+// MoneyLocale задает разделители при выводе суммы
+type MoneyLocale struct {
+	GroupSeparator   string
+	DecimalSeparator string
+}
+
+var (
+	LocaleRU = MoneyLocale{GroupSeparator: "\u00a0", DecimalSeparator: ","}
+	LocaleEN = MoneyLocale{GroupSeparator: ",", DecimalSeparator: "."}
+)
+
+// Format выводит сумму с разделителями разрядов; копейки показываются, только если они есть
+func (m Money) Format(locale MoneyLocale) string {
+	sign := ""
+	value := int64(m)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	digits := strconv.FormatInt(value/minorUnits, 10)
+	var grouped strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteString(locale.GroupSeparator)
+		}
+		grouped.WriteRune(d)
+	}
+
+	result := sign + grouped.String()
+	if minor := value % minorUnits; minor != 0 {
+		result += fmt.Sprintf("%s%02d", locale.DecimalSeparator, minor)
+	}
+	return result
+}
+
+func (m Money) String() string {
+	return m.Format(LocaleRU)
+}
+
+// множители сокращений; длинные идут первыми, чтобы "млн" не разбиралось как "м"
+var moneyMultipliers = []struct {
+	suffix string
+	factor int64
+}{
+	{"тыс.", 1_000},
+	{"тыс", 1_000},
+	{"млн", 1_000_000},
+	{"к", 1_000},
+	{"k", 1_000},
+	{"м", 1_000_000},
+	{"m", 1_000_000},
+}
+
+// знаки валюты, которые можно написать сразу после суммы; сама валюта при этом не меняется,
+// для суммы в другой валюте есть ParseAmountWithCurrency
+var moneyCurrencySuffixes = []string{"₽", "$", "€", "£", "¥", "₸", "₺", "руб.", "руб", "р."}
+
+// ParseMoney разбирает "1 499,90", "1499.9", "1,499.90", "15 000", "15,000", "12 000₽", "15к", "1.5k";
+// пробелы - разделитель разрядов. Одиночные точка или запятая - десятичный разделитель, но если после
+// них ровно три цифры ("15,000", "1.500"), это разделитель разрядов
+func ParseMoney(text string) (Money, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	for _, sign := range moneyCurrencySuffixes {
+		if strings.HasSuffix(text, sign) {
+			text = strings.TrimSpace(strings.TrimSuffix(text, sign))
+			break
+		}
+	}
+
+	factor := int64(1)
+	for _, m := range moneyMultipliers {
+		if strings.HasSuffix(text, m.suffix) {
+			factor = m.factor
+			text = strings.TrimSpace(strings.TrimSuffix(text, m.suffix))
+			break
+		}
+	}
+
+	text = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\u00a0', '\u202f', '\'':
+			return -1
+		}
+		return r
+	}, text)
+	if text == "" {
+		return 0, ErrInvalidMoney
+	}
+
+	// "1,500к" - полторы тысячи, а не полтора миллиона
+	intPart, fracPart, err := splitMoneyNumber(text, factor == 1)
+	if err != nil {
+		return 0, err
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+	if len(intPart)+len(fracPart) > 15 {
+		return 0, fmt.Errorf("%w: too large", ErrInvalidMoney)
+	}
+
+	for _, r := range intPart + fracPart {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, text)
+		}
+	}
+
+	// число без разделителя, умноженное на множитель и 100, делим на 10^len(дробной части)
+	number, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidMoney, err)
+	}
+
+	scale := int64(1)
+	for range fracPart {
+		scale *= 10
+	}
+
+	minor := number * factor * minorUnits
+	if minor/(factor*minorUnits) != number {
+		return 0, fmt.Errorf("%w: too large", ErrInvalidMoney)
+	}
+	if minor%scale != 0 {
+		return 0, fmt.Errorf("%w: too many decimal places", ErrInvalidMoney)
+	}
+
+	return Money(minor / scale), nil
+}
+
+// делит число на целую и дробную части; разделителем дробной части считается
+// последний из встреченных знаков, а повторяющийся знак - разделителем разрядов.
+// Одиночный знак перед тремя последними цифрами при allowGrouping - тоже разделитель разрядов
+func splitMoneyNumber(text string, allowGrouping bool) (string, string, error) {
+	commas := strings.Count(text, ",")
+	dots := strings.Count(text, ".")
+
+	var decimalSep string
+	switch {
+	case commas > 0 && dots > 0:
+		if strings.LastIndex(text, ",") > strings.LastIndex(text, ".") {
+			decimalSep = ","
+		} else {
+			decimalSep = "."
+		}
+	case commas == 1 && !(allowGrouping && isThousandsGroup(text, ",")):
+		decimalSep = ","
+	case dots == 1 && !(allowGrouping && isThousandsGroup(text, ".")):
+		decimalSep = "."
+	}
+
+	for _, sep := range []string{",", "."} {
+		if sep == decimalSep {
+			continue
+		}
+		if strings.Contains(text, sep) {
+			if err := checkGroups(text, sep, decimalSep); err != nil {
+				return "", "", err
+			}
+			text = strings.ReplaceAll(text, sep, "")
+		}
+	}
+
+	if decimalSep == "" {
+		return text, "", nil
+	}
+	if strings.Count(text, decimalSep) > 1 {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidMoney, text)
+	}
+
+	intPart, fracPart, _ := strings.Cut(text, decimalSep)
+	if fracPart == "" {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidMoney, text)
+	}
+	return intPart, fracPart, nil
+}
+
+// "15,000", "1.500": до знака от одной до трех цифр (не ноль), после - ровно три
+func isThousandsGroup(text, sep string) bool {
+	before, after, _ := strings.Cut(text, sep)
+	return len(before) >= 1 && len(before) <= 3 && before[0] != '0' && len(after) == 3
+}
+
+// разделитель разрядов допустим только между группами по три цифры
+func checkGroups(text, sep, decimalSep string) error {
+	if decimalSep != "" {
+		text, _, _ = strings.Cut(text, decimalSep)
+	}
+	groups := strings.Split(text, sep)
+	for i, group := range groups {
+		if (i == 0 && (len(group) == 0 || len(group) > 3)) || (i > 0 && len(group) != 3) {
+			return fmt.Errorf("%w: misplaced %q", ErrInvalidMoney, sep)
+		}
+	}
+	return nil
+}
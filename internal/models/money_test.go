@@ -0,0 +1,133 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		text string
+		want Money
+	}{
+		{"1500", 150000},
+		{"1 499,90", 149990},
+		{"1499.9", 149990},
+		{"1,499.90", 149990},
+		{"1.499,90", 149990},
+		{"0,5", 50},
+		{".5", 50},
+		{"12,34", 1234},
+		{"1.5", 150},
+		{"0,500", 50},
+
+		// одиночный знак перед тремя цифрами - разделитель разрядов
+		{"15,000", 1500000},
+		{"1,500", 150000},
+		{"1.500", 150000},
+		{"1,500,000", 150000000},
+		{"1.500.000", 150000000},
+		{"15\u00a0000", 1500000},
+
+		// знак валюты сразу после суммы
+		{"12 000₽", 1200000},
+		{"12000 ₽", 1200000},
+		{"100$", 10000},
+		{"1,500€", 150000},
+		{"500 руб.", 50000},
+		{"500р.", 50000},
+		{"15к₽", 1500000},
+
+		// сокращения
+		{"15к", 1500000},
+		{"15 тыс", 1500000},
+		{"1.5k", 150000},
+		{"1,500к", 150000},
+		{"1,005к", 100500},
+		{"2млн", 200000000},
+		{"2 М", 200000000},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.text)
+		if err != nil {
+			t.Errorf("ParseMoney(%q) error = %v", tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseMoneyErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"abc",
+		"₽",
+		"12,3456",
+		"1,50,000",
+		"1.2.3",
+		"1,",
+		"999999999999999999",
+		"-100",
+	} {
+		if got, err := ParseMoney(text); !errors.Is(err, ErrInvalidMoney) {
+			t.Errorf("ParseMoney(%q) = %d, %v; want ErrInvalidMoney", text, got, err)
+		}
+	}
+}
+
+func TestMoneyFormat(t *testing.T) {
+	tests := []struct {
+		amount Money
+		locale MoneyLocale
+		want   string
+	}{
+		{0, LocaleRU, "0"},
+		{150000, LocaleRU, "1\u00a0500"},
+		{149990, LocaleRU, "1\u00a0499,90"},
+		{5, LocaleRU, "0,05"},
+		{-1234567, LocaleRU, "-12\u00a0345,67"},
+		{123456789, LocaleEN, "1,234,567.89"},
+		{100000000, LocaleEN, "1,000,000"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.amount.Format(tt.locale); got != tt.want {
+			t.Errorf("Money(%d).Format() = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+
+	if got := FormatAmount(150000, "USD"); got != "1\u00a0500$" {
+		t.Errorf("FormatAmount(USD) = %q", got)
+	}
+	if got := FormatAmount(150000, "CHF"); got != "1\u00a0500 CHF" {
+		t.Errorf("FormatAmount(CHF) = %q", got)
+	}
+}
+
+func TestParseAmountWithCurrency(t *testing.T) {
+	tests := []struct {
+		text     string
+		want     Money
+		currency string
+	}{
+		{"1500", 150000, ""},
+		{"1 499,90 USD", 149990, "USD"},
+		{"15к$", 1500000, "USD"},
+		{"$1500", 150000, "USD"},
+		{"15,000 руб", 1500000, "RUB"},
+	}
+
+	for _, tt := range tests {
+		got, currency, err := ParseAmountWithCurrency(tt.text)
+		if err != nil {
+			t.Errorf("ParseAmountWithCurrency(%q) error = %v", tt.text, err)
+			continue
+		}
+		if got != tt.want || currency != tt.currency {
+			t.Errorf("ParseAmountWithCurrency(%q) = %d %q, want %d %q", tt.text, got, currency, tt.want, tt.currency)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// зона по умолчанию для новых пользователей
+const DefaultTimezone = "Europe/Moscow"
+
+// NormalizeTimezone принимает IANA-имя ("Asia/Vladivostok") или смещение ("+10", "UTC+10", "GMT-3")
+// и возвращает IANA-имя, которое можно сохранить у пользователя
+func NormalizeTimezone(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.EqualFold(value, "local") {
+		return "", fmt.Errorf("unknown timezone %q", value)
+	}
+
+	offset := strings.ToUpper(value)
+	offset = strings.TrimPrefix(offset, "UTC")
+	offset = strings.TrimPrefix(offset, "GMT")
+	if offset != "" && (offset[0] == '+' || offset[0] == '-') {
+		hours, err := strconv.Atoi(offset[1:])
+		if err != nil || hours > 14 {
+			return "", fmt.Errorf("unknown timezone %q", value)
+		}
+		if hours == 0 {
+			return "UTC", nil
+		}
+		// в Etc/GMT знак обратный: Etc/GMT-10 это UTC+10
+		sign := "-"
+		if offset[0] == '-' {
+			sign = "+"
+		}
+		return fmt.Sprintf("Etc/GMT%s%d", sign, hours), nil
+	}
+
+	loc, err := time.LoadLocation(value)
+	if err != nil {
+		return "", fmt.Errorf("unknown timezone %q: %w", value, err)
+	}
+	return loc.String(), nil
+}
+
+// Location - зона пользователя; при пустой или неизвестной зоне используется зона по умолчанию
+func (u *User) Location() *time.Location {
+	for _, name := range []string{u.Timezone, DefaultTimezone} {
+		if name == "" {
+			continue
+		}
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// Now - текущее время в зоне пользователя
+func (u *User) Now() time.Time {
+	return time.Now().In(u.Location())
+}
+
+// MonthStart - первое число месяца t как дата для DATE-колонок (полночь UTC);
+// t должно быть в зоне пользователя
+func MonthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// DayStart - календарный день t как дата для DATE-колонок
+func DayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// MonthRange - границы месяца t в зоне t, переведенные в UTC, для сравнения с метками created_at
+func MonthRange(t time.Time) (time.Time, time.Time) {
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	return start.UTC(), start.AddDate(0, 1, 0).UTC()
+}
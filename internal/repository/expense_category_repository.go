@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/client/db"
+	"github.com/Lina3386/telegram-bot/internal/models"
+)
+
+type ExpenseCategoryRepository struct {
+	db db.QueryExecer
+}
+
+func NewExpenseCategoryRepository(db db.QueryExecer) *ExpenseCategoryRepository {
+	return &ExpenseCategoryRepository{db: db}
+}
+
+func (r *ExpenseCategoryRepository) CreateCategory(ctx context.Context, userID int64, name string, isDefault bool) (*models.ExpenseCategory, error) {
+	category := &models.ExpenseCategory{}
+	query := `INSERT INTO expense_categories (user_id, name, is_default) VALUES ($1, $2, $3)
+	         ON CONFLICT (user_id, name) DO UPDATE SET updated_at = expense_categories.updated_at
+	         RETURNING id, monthly_limit, is_default, created_at, updated_at`
+	err := r.db.QueryRowContext(ctx, query, userID, name, isDefault).Scan(&category.ID, &category.MonthlyLimit, &category.IsDefault, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create category: %w", err)
+	}
+	category.UserID = userID
+	category.Name = name
+	return category, nil
+}
+
+func (r *ExpenseCategoryRepository) GetUserCategories(ctx context.Context, userID int64) ([]models.ExpenseCategory, error) {
+	query := `SELECT id, user_id, name, is_default, monthly_limit, created_at, updated_at
+	         FROM expense_categories WHERE user_id = $1 ORDER BY is_default DESC, id ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.ExpenseCategory
+	for rows.Next() {
+		category := models.ExpenseCategory{}
+		err := rows.Scan(&category.ID, &category.UserID, &category.Name, &category.IsDefault, &category.MonthlyLimit, &category.CreatedAt, &category.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+func (r *ExpenseCategoryRepository) GetCategoryByID(ctx context.Context, categoryID int64) (*models.ExpenseCategory, error) {
+	category := &models.ExpenseCategory{}
+	query := `SELECT id, user_id, name, is_default, monthly_limit, created_at, updated_at
+	         FROM expense_categories WHERE id = $1`
+	err := r.db.QueryRowContext(ctx, query, categoryID).Scan(&category.ID, &category.UserID, &category.Name, &category.IsDefault, &category.MonthlyLimit, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+	return category, nil
+}
+
+// категории всех пользователей, у которых задан лимит
+func (r *ExpenseCategoryRepository) GetCategoriesWithLimit(ctx context.Context) ([]models.ExpenseCategory, error) {
+	query := `SELECT id, user_id, name, is_default, monthly_limit, created_at, updated_at
+	         FROM expense_categories WHERE monthly_limit > 0 ORDER BY user_id, id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.ExpenseCategory
+	for rows.Next() {
+		category := models.ExpenseCategory{}
+		err := rows.Scan(&category.ID, &category.UserID, &category.Name, &category.IsDefault, &category.MonthlyLimit, &category.CreatedAt, &category.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+func (r *ExpenseCategoryRepository) UpdateCategoryLimit(ctx context.Context, categoryID int64, limit models.Money) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE expense_categories SET monthly_limit = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`,
+		limit, categoryID,
+	)
+	return err
+}
+
+// отмечает предупреждение о лимите; false, если оно уже отправлялось в этом месяце
+func (r *ExpenseCategoryRepository) CreateBudgetAlert(ctx context.Context, categoryID int64, month time.Time, threshold int) (bool, error) {
+	res, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO category_budget_alerts (category_id, month, threshold) VALUES ($1, $2, $3)
+		 ON CONFLICT (category_id, month, threshold) DO NOTHING`,
+		categoryID, month, threshold,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to create budget alert: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
@@ -2,22 +2,23 @@ package repository
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"time"
 
+	"github.com/Lina3386/telegram-bot/internal/client/db"
 	"github.com/Lina3386/telegram-bot/internal/models"
 )
 
 type ExpenseRepository struct {
-	db *sql.DB
+	db db.QueryExecer
 }
 
-func NewExpenseRepository(db *sql.DB) *ExpenseRepository {
+func NewExpenseRepository(db db.QueryExecer) *ExpenseRepository {
 	return &ExpenseRepository{db: db}
 }
 
 func (r *ExpenseRepository) GetUserExpenses(ctx context.Context, userID int64) ([]models.Expense, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, name, amount, created_at, updated_at FROM expenses WHERE user_id = $1`, userID)
+	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, name, amount, currency, category_id, kind, frequency, recurring_day, spent_at, created_at, updated_at FROM expenses WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at ASC`, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -26,7 +27,7 @@ func (r *ExpenseRepository) GetUserExpenses(ctx context.Context, userID int64) (
 	var expenses []models.Expense
 	for rows.Next() {
 		expense := models.Expense{}
-		err := rows.Scan(&expense.ID, &expense.UserID, &expense.Name, &expense.Amount, &expense.CreatedAt, &expense.UpdatedAt)
+		err := rows.Scan(&expense.ID, &expense.UserID, &expense.Name, &expense.Amount, &expense.Currency, &expense.CategoryID, &expense.Kind, &expense.Frequency, &expense.RecurringDay, &expense.SpentAt, &expense.CreatedAt, &expense.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -36,15 +37,17 @@ func (r *ExpenseRepository) GetUserExpenses(ctx context.Context, userID int64) (
 	return expenses, rows.Err()
 }
 
-func (r *ExpenseRepository) CreateExpense(ctx context.Context, userID int64, name string, amount int64) (*models.Expense, error) {
-	expense := &models.Expense{}
-	err := r.db.QueryRowContext(ctx, `INSERT INTO expenses (user_id, name, amount) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`, userID, name, amount).Scan(&expense.ID, &expense.CreatedAt, &expense.UpdatedAt)
+func (r *ExpenseRepository) CreateExpense(ctx context.Context, expense *models.Expense) (*models.Expense, error) {
+	query := `INSERT INTO expenses (user_id, name, amount, currency, category_id, kind, frequency, recurring_day, spent_at)
+	         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	         RETURNING id, created_at, updated_at`
+	err := r.db.QueryRowContext(ctx, query,
+		expense.UserID, expense.Name, expense.Amount, expense.Currency, expense.CategoryID,
+		expense.Kind, expense.Frequency, expense.RecurringDay, expense.SpentAt,
+	).Scan(&expense.ID, &expense.CreatedAt, &expense.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
-	expense.UserID = userID
-	expense.Name = name
-	expense.Amount = amount
 
 	return expense, nil
 }
@@ -53,11 +56,11 @@ func (r *ExpenseRepository) GetExpenseByID(ctx context.Context, expenseID int64)
 	expense := &models.Expense{}
 	err := r.db.QueryRowContext(
 		ctx,
-		`SELECT id, user_id, name, amount, created_at, updated_at 
+		`SELECT id, user_id, name, amount, currency, category_id, kind, frequency, recurring_day, spent_at, created_at, updated_at 
 		 FROM expenses 
-		 WHERE id = $1`,
+		 WHERE id = $1 AND deleted_at IS NULL`,
 		expenseID,
-	).Scan(&expense.ID, &expense.UserID, &expense.Name, &expense.Amount, &expense.CreatedAt, &expense.UpdatedAt)
+	).Scan(&expense.ID, &expense.UserID, &expense.Name, &expense.Amount, &expense.Currency, &expense.CategoryID, &expense.Kind, &expense.Frequency, &expense.RecurringDay, &expense.SpentAt, &expense.CreatedAt, &expense.UpdatedAt)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get expense: %w", err)
@@ -66,16 +69,40 @@ func (r *ExpenseRepository) GetExpenseByID(ctx context.Context, expenseID int64)
 	return expense, nil
 }
 
-func (r *ExpenseRepository) DeleteExpense(ctx context.Context, expenseID int64) error {
+// DeleteExpense скрывает расход; насовсем он удаляется в PurgeDeletedExpenses
+func (r *ExpenseRepository) DeleteExpense(ctx context.Context, expenseID int64, deletedAt time.Time) error {
 	_, err := r.db.ExecContext(
 		ctx,
-		`DELETE FROM expenses WHERE id = $1`,
-		expenseID,
+		`UPDATE expenses SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`,
+		deletedAt, expenseID,
 	)
 	return err
 }
 
-func (r *ExpenseRepository) UpdateExpense(ctx context.Context, expenseID int64, name string, amount int64) error {
+// RestoreExpense возвращает расход, удаленный позже deletedAfter; false - возвращать нечего
+func (r *ExpenseRepository) RestoreExpense(ctx context.Context, expenseID int64, userID int64, deletedAfter time.Time) (bool, error) {
+	res, err := r.db.ExecContext(
+		ctx,
+		`UPDATE expenses SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND user_id = $2 AND deleted_at > $3`,
+		expenseID, userID, deletedAfter,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to restore expense: %w", err)
+	}
+	restored, err := res.RowsAffected()
+	return restored > 0, err
+}
+
+func (r *ExpenseRepository) PurgeDeletedExpenses(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM expenses WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted expenses: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (r *ExpenseRepository) UpdateExpense(ctx context.Context, expenseID int64, name string, amount models.Money) error {
 	_, err := r.db.ExecContext(
 		ctx,
 		`UPDATE expenses SET name = $1, amount = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`,
@@ -83,3 +110,12 @@ func (r *ExpenseRepository) UpdateExpense(ctx context.Context, expenseID int64,
 	)
 	return err
 }
+
+func (r *ExpenseRepository) UpdateExpenseSchedule(ctx context.Context, expenseID int64, frequency string, recurringDay int) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE expenses SET frequency = $1, recurring_day = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`,
+		frequency, recurringDay, expenseID,
+	)
+	return err
+}
@@ -6,35 +6,38 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/Lina3386/telegram-bot/internal/client/db"
 	"github.com/Lina3386/telegram-bot/internal/models"
 )
 
 type GoalRepository struct {
-	db *sql.DB
+	db db.QueryExecer
 }
 
-func NewGoalRepository(db *sql.DB) *GoalRepository {
+func NewGoalRepository(db db.QueryExecer) *GoalRepository {
 	return &GoalRepository{db: db}
 }
 
-func (r *GoalRepository) CreateGoal(ctx context.Context, userID int64, goalName string, targetAmount int64, monthlyContrib int64, targetDate time.Time, priority int) (*models.SavingsGoal, error) {
+func (r *GoalRepository) CreateGoal(ctx context.Context, userID int64, goalName string, currency string, targetAmount models.Money, monthlyContrib models.Money, targetDate time.Time, deadline sql.NullTime, priority int) (*models.SavingsGoal, error) {
 	goal := &models.SavingsGoal{}
-	err := r.db.QueryRowContext(ctx, `INSERT INTO savings_goals (user_id, goal_name, target_amount, monthly_contrib, target_date, priority) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at`, userID, goalName, targetAmount, monthlyContrib, targetDate, priority).Scan(&goal.ID, &goal.CreatedAt, &goal.UpdatedAt)
+	err := r.db.QueryRowContext(ctx, `INSERT INTO savings_goals (user_id, goal_name, currency, target_amount, monthly_contrib, target_date, deadline, priority) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at, updated_at`, userID, goalName, currency, targetAmount, monthlyContrib, targetDate, deadline, priority).Scan(&goal.ID, &goal.CreatedAt, &goal.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create goal: %w", err)
 	}
 	goal.UserID = userID
 	goal.GoalName = goalName
+	goal.Currency = currency
 	goal.TargetAmount = targetAmount
 	goal.MonthlyContrib = monthlyContrib
 	goal.TargetDate = targetDate
+	goal.Deadline = deadline
 	goal.Priority = priority
 	goal.Status = "active"
 	return goal, nil
 }
 
 func (r *GoalRepository) GetUserActiveGoals(ctx context.Context, userID int64) ([]models.SavingsGoal, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, goal_name, target_amount, current_amount, monthly_contrib, monthly_budget_limit, monthly_accumulated, month_started, target_date, priority, status, created_at, updated_at FROM savings_goals WHERE user_id = $1 AND status = 'active' ORDER BY priority ASC, created_at ASC`, userID)
+	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, goal_name, currency, target_amount, current_amount, monthly_contrib, monthly_budget_limit, monthly_accumulated, month_started, target_date, deadline, priority, status, allocation_percent, completed_at, created_at, updated_at FROM savings_goals WHERE user_id = $1 AND status = 'active' AND deleted_at IS NULL ORDER BY priority ASC, created_at ASC`, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +46,7 @@ func (r *GoalRepository) GetUserActiveGoals(ctx context.Context, userID int64) (
 	var goals []models.SavingsGoal
 	for rows.Next() {
 		goal := models.SavingsGoal{}
-		err := rows.Scan(&goal.ID, &goal.UserID, &goal.GoalName, &goal.TargetAmount, &goal.CurrentAmount, &goal.MonthlyContrib, &goal.MonthlyBudgetLimit, &goal.MonthlyAccumulated, &goal.MonthStarted, &goal.TargetDate, &goal.Priority, &goal.Status, &goal.CreatedAt, &goal.UpdatedAt)
+		err := rows.Scan(&goal.ID, &goal.UserID, &goal.GoalName, &goal.Currency, &goal.TargetAmount, &goal.CurrentAmount, &goal.MonthlyContrib, &goal.MonthlyBudgetLimit, &goal.MonthlyAccumulated, &goal.MonthStarted, &goal.TargetDate, &goal.Deadline, &goal.Priority, &goal.Status, &goal.AllocationPercent, &goal.CompletedAt, &goal.CreatedAt, &goal.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -54,7 +57,7 @@ func (r *GoalRepository) GetUserActiveGoals(ctx context.Context, userID int64) (
 }
 
 func (r *GoalRepository) GetUserGoals(ctx context.Context, userID int64) ([]models.SavingsGoal, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, goal_name, target_amount, current_amount, monthly_contrib, monthly_budget_limit, monthly_accumulated, month_started, target_date, priority, status, created_at, updated_at FROM savings_goals WHERE user_id = $1 ORDER BY priority ASC, created_at ASC`, userID)
+	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, goal_name, currency, target_amount, current_amount, monthly_contrib, monthly_budget_limit, monthly_accumulated, month_started, target_date, deadline, priority, status, allocation_percent, completed_at, created_at, updated_at FROM savings_goals WHERE user_id = $1 AND deleted_at IS NULL ORDER BY priority ASC, created_at ASC`, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +66,28 @@ func (r *GoalRepository) GetUserGoals(ctx context.Context, userID int64) ([]mode
 	var goals []models.SavingsGoal
 	for rows.Next() {
 		goal := models.SavingsGoal{}
-		err := rows.Scan(&goal.ID, &goal.UserID, &goal.GoalName, &goal.TargetAmount, &goal.CurrentAmount, &goal.MonthlyContrib, &goal.MonthlyBudgetLimit, &goal.MonthlyAccumulated, &goal.MonthStarted, &goal.TargetDate, &goal.Priority, &goal.Status, &goal.CreatedAt, &goal.UpdatedAt)
+		err := rows.Scan(&goal.ID, &goal.UserID, &goal.GoalName, &goal.Currency, &goal.TargetAmount, &goal.CurrentAmount, &goal.MonthlyContrib, &goal.MonthlyBudgetLimit, &goal.MonthlyAccumulated, &goal.MonthStarted, &goal.TargetDate, &goal.Deadline, &goal.Priority, &goal.Status, &goal.AllocationPercent, &goal.CompletedAt, &goal.CreatedAt, &goal.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, goal)
+	}
+
+	return goals, rows.Err()
+}
+
+// достигнутые и архивные цели, последние завершенные первыми
+func (r *GoalRepository) GetUserArchivedGoals(ctx context.Context, userID int64) ([]models.SavingsGoal, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, goal_name, currency, target_amount, current_amount, monthly_contrib, monthly_budget_limit, monthly_accumulated, month_started, target_date, deadline, priority, status, allocation_percent, completed_at, created_at, updated_at FROM savings_goals WHERE user_id = $1 AND status IN ('completed', 'archived') AND deleted_at IS NULL ORDER BY completed_at DESC NULLS LAST, updated_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var goals []models.SavingsGoal
+	for rows.Next() {
+		goal := models.SavingsGoal{}
+		err := rows.Scan(&goal.ID, &goal.UserID, &goal.GoalName, &goal.Currency, &goal.TargetAmount, &goal.CurrentAmount, &goal.MonthlyContrib, &goal.MonthlyBudgetLimit, &goal.MonthlyAccumulated, &goal.MonthStarted, &goal.TargetDate, &goal.Deadline, &goal.Priority, &goal.Status, &goal.AllocationPercent, &goal.CompletedAt, &goal.CreatedAt, &goal.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -86,8 +110,9 @@ func (r *GoalRepository) UpdateGoal(ctx context.Context, goal *models.SavingsGoa
             target_date = $8,
             priority = $9,
             status = $10,
+            completed_at = $11,
             updated_at = CURRENT_TIMESTAMP
-        WHERE id = $11
+        WHERE id = $12
     `
 
 	_, err := r.db.ExecContext(ctx, query,
@@ -101,6 +126,7 @@ func (r *GoalRepository) UpdateGoal(ctx context.Context, goal *models.SavingsGoa
 		goal.TargetDate,
 		goal.Priority,
 		goal.Status,
+		goal.CompletedAt,
 		goal.ID,
 	)
 
@@ -110,28 +136,81 @@ func (r *GoalRepository) UpdateGoal(ctx context.Context, goal *models.SavingsGoa
 func (r *GoalRepository) GetGoalByID(ctx context.Context, goalID int64) (*models.SavingsGoal, error) {
 	goal := &models.SavingsGoal{}
 	query := `
-        SELECT id, user_id, goal_name, target_amount, current_amount,
+        SELECT id, user_id, goal_name, currency, target_amount, current_amount,
                monthly_contrib, monthly_budget_limit, monthly_accumulated,
-               month_started, target_date, priority, status, created_at, updated_at
+               month_started, target_date, deadline, priority, status, allocation_percent, completed_at, created_at, updated_at
         FROM savings_goals
-        WHERE id = $1
+        WHERE id = $1 AND deleted_at IS NULL
     `
 
 	err := r.db.QueryRowContext(ctx, query, goalID).Scan(
-		&goal.ID, &goal.UserID, &goal.GoalName, &goal.TargetAmount,
+		&goal.ID, &goal.UserID, &goal.GoalName, &goal.Currency, &goal.TargetAmount,
 		&goal.CurrentAmount, &goal.MonthlyContrib, &goal.MonthlyBudgetLimit,
-		&goal.MonthlyAccumulated, &goal.MonthStarted, &goal.TargetDate,
-		&goal.Priority, &goal.Status, &goal.CreatedAt, &goal.UpdatedAt,
+		&goal.MonthlyAccumulated, &goal.MonthStarted, &goal.TargetDate, &goal.Deadline,
+		&goal.Priority, &goal.Status, &goal.AllocationPercent, &goal.CompletedAt, &goal.CreatedAt, &goal.UpdatedAt,
 	)
 
 	return goal, err
 }
 
-func (r *GoalRepository) DeleteGoal(ctx context.Context, goalID int64) error {
+func (r *GoalRepository) UpdateAllocationPercent(ctx context.Context, goalID int64, percent int) error {
+	_, err := r.db.ExecContext(
+		ctx, `UPDATE savings_goals
+		SET allocation_percent = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`, percent, goalID,
+	)
+	return err
+}
+
+// DeleteGoal скрывает цель вместе с историей; насовсем она удаляется в PurgeDeletedGoals
+func (r *GoalRepository) DeleteGoal(ctx context.Context, goalID int64, deletedAt time.Time) error {
 	_, err := r.db.ExecContext(
 		ctx,
-		`DELETE FROM savings_goals WHERE id = $1`,
-		goalID,
+		`UPDATE savings_goals SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`,
+		deletedAt, goalID,
 	)
 	return err
 }
+
+// GetDeletedGoal - цель пользователя, удаленная позже deletedAfter
+func (r *GoalRepository) GetDeletedGoal(ctx context.Context, goalID int64, userID int64, deletedAfter time.Time) (*models.SavingsGoal, error) {
+	goal := &models.SavingsGoal{}
+	query := `
+        SELECT id, user_id, goal_name, currency, target_amount, current_amount,
+               monthly_contrib, monthly_budget_limit, monthly_accumulated,
+               month_started, target_date, deadline, priority, status, allocation_percent, completed_at, created_at, updated_at
+        FROM savings_goals
+        WHERE id = $1 AND user_id = $2 AND deleted_at > $3
+    `
+
+	err := r.db.QueryRowContext(ctx, query, goalID, userID, deletedAfter).Scan(
+		&goal.ID, &goal.UserID, &goal.GoalName, &goal.Currency, &goal.TargetAmount,
+		&goal.CurrentAmount, &goal.MonthlyContrib, &goal.MonthlyBudgetLimit,
+		&goal.MonthlyAccumulated, &goal.MonthStarted, &goal.TargetDate, &goal.Deadline,
+		&goal.Priority, &goal.Status, &goal.AllocationPercent, &goal.CompletedAt, &goal.CreatedAt, &goal.UpdatedAt,
+	)
+
+	return goal, err
+}
+
+// RestoreGoal возвращает удаленную цель на приоритет priority
+func (r *GoalRepository) RestoreGoal(ctx context.Context, goalID int64, priority int) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE savings_goals SET deleted_at = NULL, priority = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`,
+		priority, goalID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to restore goal: %w", err)
+	}
+	return nil
+}
+
+// PurgeDeletedGoals удаляет цели насовсем; взносы и история уходят каскадом
+func (r *GoalRepository) PurgeDeletedGoals(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM savings_goals WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted goals: %w", err)
+	}
+	return res.RowsAffected()
+}
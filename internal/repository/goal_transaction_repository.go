@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/client/db"
+	"github.com/Lina3386/telegram-bot/internal/models"
+)
+
+type GoalTransactionRepository struct {
+	db db.QueryExecer
+}
+
+func NewGoalTransactionRepository(db db.QueryExecer) *GoalTransactionRepository {
+	return &GoalTransactionRepository{db: db}
+}
+
+func (r *GoalTransactionRepository) CreateTransaction(ctx context.Context, tx *models.GoalTransaction) (*models.GoalTransaction, error) {
+	if tx.CreatedAt.IsZero() {
+		tx.CreatedAt = time.Now().UTC()
+	}
+
+	query := `INSERT INTO goal_transactions (goal_id, user_id, kind, amount, income_id, comment, created_at)
+	         VALUES ($1, $2, $3, $4, $5, $6, $7)
+	         RETURNING id`
+	err := r.db.QueryRowContext(ctx, query, tx.GoalID, tx.UserID, tx.Kind, tx.Amount, tx.IncomeID, tx.Comment, tx.CreatedAt).Scan(&tx.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create goal transaction: %w", err)
+	}
+	return tx, nil
+}
+
+func (r *GoalTransactionRepository) GetGoalTransactions(ctx context.Context, goalID int64, limit int) ([]models.GoalTransaction, error) {
+	query := `SELECT id, goal_id, user_id, kind, amount, income_id, comment, created_at
+	         FROM goal_transactions
+	         WHERE goal_id = $1
+	         ORDER BY created_at DESC, id DESC
+	         LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, goalID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []models.GoalTransaction
+	for rows.Next() {
+		tx := models.GoalTransaction{}
+		err := rows.Scan(&tx.ID, &tx.GoalID, &tx.UserID, &tx.Kind, &tx.Amount, &tx.IncomeID, &tx.Comment, &tx.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, tx)
+	}
+
+	return transactions, rows.Err()
+}
+
+// баланс цели по всему журналу
+func (r *GoalTransactionRepository) GetGoalBalance(ctx context.Context, goalID int64) (models.Money, error) {
+	var balance models.Money
+	query := `SELECT COALESCE(SUM(amount), 0) FROM goal_transactions WHERE goal_id = $1`
+	err := r.db.QueryRowContext(ctx, query, goalID).Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get goal balance: %w", err)
+	}
+	return balance, nil
+}
+
+// сумма взносов за период без учета корректировок и переносов между целями
+func (r *GoalTransactionRepository) GetGoalContributedBetween(ctx context.Context, goalID int64, from, to time.Time) (models.Money, error) {
+	var total models.Money
+	query := `SELECT COALESCE(SUM(amount), 0) FROM goal_transactions
+	         WHERE goal_id = $1 AND kind NOT IN ($2, $3) AND created_at >= $4 AND created_at < $5`
+	err := r.db.QueryRowContext(ctx, query, goalID, models.GoalTxCorrection, models.GoalTxTransfer, from, to).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get goal contributions: %w", err)
+	}
+	return total, nil
+}
@@ -5,18 +5,20 @@ import (
 	"database/sql"
 	"fmt"
 	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/client/db"
 	"github.com/Lina3386/telegram-bot/internal/models"
 )
 
 type IncomeProcessingLogRepository struct {
-	db *sql.DB
+	db db.QueryExecer
 }
 
-func NewIncomeProcessingLogRepository(db *sql.DB) *IncomeProcessingLogRepository {
+func NewIncomeProcessingLogRepository(db db.QueryExecer) *IncomeProcessingLogRepository {
 	return &IncomeProcessingLogRepository{db: db}
 }
 
-func (r *IncomeProcessingLogRepository) CreateProcessingLog(ctx context.Context, incomeID, userID int64, processedDate time.Time, incomeAmount int64) (*models.IncomeProcessingLog, error) {
+func (r *IncomeProcessingLogRepository) CreateProcessingLog(ctx context.Context, incomeID, userID int64, processedDate time.Time, incomeAmount models.Money) (*models.IncomeProcessingLog, error) {
 	log := &models.IncomeProcessingLog{}
 	query := `INSERT INTO income_processing_log (income_id, user_id, processed_date, income_amount) VALUES ($1, $2, $3, $4) RETURNING id, created_at`
 	err := r.db.QueryRowContext(ctx, query, incomeID, userID, processedDate, incomeAmount).Scan(&log.ID, &log.CreatedAt)
@@ -32,8 +34,8 @@ func (r *IncomeProcessingLogRepository) CreateProcessingLog(ctx context.Context,
 
 func (r *IncomeProcessingLogRepository) GetProcessingLogByIncomeDate(ctx context.Context, incomeID int64, processedDate time.Time) (*models.IncomeProcessingLog, error) {
 	log := &models.IncomeProcessingLog{}
-	query := `SELECT id, income_id, user_id, processed_date, income_amount, created_at FROM income_processing_log WHERE income_id = $1 AND processed_date = $2`
-	err := r.db.QueryRowContext(ctx, query, incomeID, processedDate).Scan(&log.ID, &log.IncomeID, &log.UserID, &log.ProcessedDate, &log.IncomeAmount, &log.CreatedAt)
+	query := `SELECT id, income_id, user_id, processed_date, income_amount, actual_amount, received_date, created_at FROM income_processing_log WHERE income_id = $1 AND processed_date = $2`
+	err := r.db.QueryRowContext(ctx, query, incomeID, processedDate).Scan(&log.ID, &log.IncomeID, &log.UserID, &log.ProcessedDate, &log.IncomeAmount, &log.ActualAmount, &log.ReceivedDate, &log.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -41,16 +43,53 @@ func (r *IncomeProcessingLogRepository) GetProcessingLogByIncomeDate(ctx context
 }
 
 func (r *IncomeProcessingLogRepository) GetProcessingLogsByUserDate(ctx context.Context, userID int64, processedDate time.Time) ([]models.IncomeProcessingLog, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT id, income_id, user_id, processed_date, income_amount, created_at FROM income_processing_log WHERE user_id = $1 AND processed_date = $2`, userID, processedDate)
+	rows, err := r.db.QueryContext(ctx, `SELECT id, income_id, user_id, processed_date, income_amount, actual_amount, received_date, created_at FROM income_processing_log WHERE user_id = $1 AND processed_date = $2`, userID, processedDate)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	return scanProcessingLogs(rows)
+}
+
+// GetProcessingLogsByUserPeriod - выплаты пользователя с плановой датой в [from, to)
+func (r *IncomeProcessingLogRepository) GetProcessingLogsByUserPeriod(ctx context.Context, userID int64, from, to time.Time) ([]models.IncomeProcessingLog, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, income_id, user_id, processed_date, income_amount, actual_amount, received_date, created_at FROM income_processing_log WHERE user_id = $1 AND processed_date >= $2 AND processed_date < $3 ORDER BY processed_date, id`, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get processing logs: %w", err)
+	}
+	defer rows.Close()
+
+	return scanProcessingLogs(rows)
+}
+
+// последняя записанная выплата по доходу
+func (r *IncomeProcessingLogRepository) GetLastProcessingLog(ctx context.Context, incomeID int64) (*models.IncomeProcessingLog, error) {
+	log := &models.IncomeProcessingLog{}
+	query := `SELECT id, income_id, user_id, processed_date, income_amount, actual_amount, received_date, created_at FROM income_processing_log WHERE income_id = $1 ORDER BY processed_date DESC, id DESC LIMIT 1`
+	err := r.db.QueryRowContext(ctx, query, incomeID).Scan(&log.ID, &log.IncomeID, &log.UserID, &log.ProcessedDate, &log.IncomeAmount, &log.ActualAmount, &log.ReceivedDate, &log.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// SumActualByIncomePeriod - сколько подтверждено поступлений по доходу с датой в [from, to)
+func (r *IncomeProcessingLogRepository) SumActualByIncomePeriod(ctx context.Context, incomeID int64, from, to time.Time) (models.Money, error) {
+	var total models.Money
+	query := `SELECT COALESCE(SUM(actual_amount), 0) FROM income_processing_log WHERE income_id = $1 AND received_date IS NOT NULL AND processed_date >= $2 AND processed_date < $3`
+	err := r.db.QueryRowContext(ctx, query, incomeID, from, to).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum actual income: %w", err)
+	}
+	return total, nil
+}
+
+func scanProcessingLogs(rows *sql.Rows) ([]models.IncomeProcessingLog, error) {
 	var logs []models.IncomeProcessingLog
 	for rows.Next() {
 		log := models.IncomeProcessingLog{}
-		err := rows.Scan(&log.ID, &log.IncomeID, &log.UserID, &log.ProcessedDate, &log.IncomeAmount, &log.CreatedAt)
+		err := rows.Scan(&log.ID, &log.IncomeID, &log.UserID, &log.ProcessedDate, &log.IncomeAmount, &log.ActualAmount, &log.ReceivedDate, &log.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -60,6 +99,15 @@ func (r *IncomeProcessingLogRepository) GetProcessingLogsByUserDate(ctx context.
 	return logs, rows.Err()
 }
 
+// ConfirmProcessingLog записывает, сколько и когда пришло на самом деле
+func (r *IncomeProcessingLogRepository) ConfirmProcessingLog(ctx context.Context, logID int64, actualAmount models.Money, receivedDate time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE income_processing_log SET actual_amount = $1, received_date = $2 WHERE id = $3`, actualAmount, receivedDate, logID)
+	if err != nil {
+		return fmt.Errorf("failed to confirm processing log: %w", err)
+	}
+	return nil
+}
+
 func (r *IncomeProcessingLogRepository) IsIncomeProcessedOnDate(ctx context.Context, incomeID int64, processedDate time.Time) (bool, error) {
 	var count int
 	query := `SELECT COUNT(*) FROM income_processing_log WHERE income_id = $1 AND processed_date = $2`
@@ -6,18 +6,19 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/Lina3386/telegram-bot/internal/client/db"
 	"github.com/Lina3386/telegram-bot/internal/models"
 )
 
 type IncomeRepository struct {
-	db *sql.DB
+	db db.QueryExecer
 }
 
-func NewIncomeRepository(db *sql.DB) *IncomeRepository {
+func NewIncomeRepository(db db.QueryExecer) *IncomeRepository {
 	return &IncomeRepository{db: db}
 }
 
-func (r *IncomeRepository) CreateIncome(ctx context.Context, userID int64, name string, amount int64, recurringDay int, nextPayDate time.Time) (*models.Income, error) {
+func (r *IncomeRepository) CreateIncome(ctx context.Context, userID int64, name string, amount models.Money, recurringDay int, nextPayDate time.Time) (*models.Income, error) {
 	income := &models.Income{}
 	query := `INSERT INTO incomes (user_id, name, amount, recurring_day, next_pay_date) 
 	         VALUES ($1, $2, $3, $4, $5) 
@@ -31,6 +32,7 @@ func (r *IncomeRepository) CreateIncome(ctx context.Context, userID int64, name
 	income.UserID = userID
 	income.Name = name
 	income.Amount = amount
+	income.Currency = models.DefaultCurrency
 	income.RecurringDay = recurringDay
 	income.NextPayDate = nextPayDate
 
@@ -41,19 +43,23 @@ func (r *IncomeRepository) CreateIncomeWithFrequency(
 	ctx context.Context,
 	userID int64,
 	name string,
-	amount int64,
+	amount models.Money,
+	currency string,
 	frequency string,
 	recurringDay int,
+	secondDay int,
+	anchorDate sql.NullTime,
+	payShift string,
 	notificationHour int,
 	nextPayDate time.Time,
 ) (*models.Income, error) {
 	income := &models.Income{}
 
-	query := `INSERT INTO incomes (user_id, name, amount, frequency, recurring_day, notification_hour, next_pay_date)
-				VALUES ($1, $2, $3, $4, $5, $6, $7)
+	query := `INSERT INTO incomes (user_id, name, amount, currency, frequency, recurring_day, second_day, anchor_date, pay_shift, notification_hour, next_pay_date)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 				RETURNING id, frequency, notification_hour, created_at, updated_at`
 
-	err := r.db.QueryRowContext(ctx, query, userID, name, amount, frequency, recurringDay, notificationHour, nextPayDate).Scan(&income.ID, &income.Frequency, &income.NotificationHour, &income.CreatedAt, &income.UpdatedAt)
+	err := r.db.QueryRowContext(ctx, query, userID, name, amount, currency, frequency, recurringDay, secondDay, anchorDate, payShift, notificationHour, nextPayDate).Scan(&income.ID, &income.Frequency, &income.NotificationHour, &income.CreatedAt, &income.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create income: %w", err)
 	}
@@ -61,8 +67,12 @@ func (r *IncomeRepository) CreateIncomeWithFrequency(
 	income.UserID = userID
 	income.Name = name
 	income.Amount = amount
+	income.Currency = currency
 	income.Frequency = frequency
 	income.RecurringDay = recurringDay
+	income.SecondDay = secondDay
+	income.AnchorDate = anchorDate
+	income.PayShift = payShift
 	income.NotificationHour = notificationHour
 	income.NextPayDate = nextPayDate
 
@@ -72,23 +82,29 @@ func (r *IncomeRepository) CreateIncomeWithFrequency(
 func (r *IncomeRepository) GetIncomeByID(ctx context.Context, incomeID int64) (*models.Income, error) {
 	income := &models.Income{}
 
-	query := `SELECT id, user_id, name, amount, frequency, recurring_day, notification_hour, next_pay_date, created_at, updated_at FROM incomes
-				WHERE id = $1`
+	query := `SELECT id, user_id, name, amount, currency, frequency, recurring_day, second_day, anchor_date, pay_shift, notification_hour, next_pay_date, created_at, updated_at FROM incomes
+				WHERE id = $1 AND deleted_at IS NULL`
 
 	err := r.db.QueryRowContext(ctx, query, incomeID).
-		Scan(&income.ID, &income.UserID, &income.Name, &income.Amount, &income.Frequency, &income.RecurringDay,
-			&income.NotificationHour, &income.NextPayDate, &income.CreatedAt, &income.UpdatedAt)
+		Scan(&income.ID, &income.UserID, &income.Name, &income.Amount, &income.Currency, &income.Frequency, &income.RecurringDay,
+			&income.SecondDay, &income.AnchorDate, &income.PayShift, &income.NotificationHour, &income.NextPayDate, &income.CreatedAt, &income.UpdatedAt)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if income.Frequency == models.IncomeFrequencyInstallments {
+		if income.Installments, err = r.GetIncomeInstallments(ctx, income.ID); err != nil {
+			return nil, err
+		}
+	}
+
 	return income, nil
 }
 
 func (r *IncomeRepository) GetUserIncomes(ctx context.Context, userID int64) ([]models.Income, error) {
-	query := `SELECT id, user_id, name, amount, frequency, recurring_day, notification_hour, next_pay_date, created_at, updated_at
-				FROM incomes WHERE user_id = $1 ORDER BY created_at ASC`
+	query := `SELECT id, user_id, name, amount, currency, frequency, recurring_day, second_day, anchor_date, pay_shift, notification_hour, next_pay_date, created_at, updated_at
+				FROM incomes WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at ASC`
 
 	rows, err := r.db.QueryContext(ctx, query, userID)
 	if err != nil {
@@ -100,8 +116,8 @@ func (r *IncomeRepository) GetUserIncomes(ctx context.Context, userID int64) ([]
 	var incomes []models.Income
 	for rows.Next() {
 		income := models.Income{}
-		err := rows.Scan(&income.ID, &income.UserID, &income.Name, &income.Amount,
-			&income.Frequency, &income.RecurringDay, &income.NotificationHour, &income.NextPayDate,
+		err := rows.Scan(&income.ID, &income.UserID, &income.Name, &income.Amount, &income.Currency,
+			&income.Frequency, &income.RecurringDay, &income.SecondDay, &income.AnchorDate, &income.PayShift, &income.NotificationHour, &income.NextPayDate,
 			&income.CreatedAt, &income.UpdatedAt)
 
 		if err != nil {
@@ -109,25 +125,22 @@ func (r *IncomeRepository) GetUserIncomes(ctx context.Context, userID int64) ([]
 		}
 		incomes = append(incomes, income)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return incomes, rows.Err()
+	return incomes, r.attachInstallments(ctx, incomes)
 }
 
-func (r *IncomeRepository) GetIncomesByPayDate(ctx context.Context, payDate time.Time) ([]models.Income, error) {
-	year, month, day := payDate.Date()
-	localLoc := time.FixedZone("MSK", 3*60*60)
-	startOfDay := time.Date(year, month, day, 0, 0, 0, 0, localLoc).UTC()
-	endOfDay := startOfDay.AddDate(0, 0, 1)
-
-	query := `SELECT id, user_id, name, amount, frequency, recurring_day, notification_hour, next_pay_date, created_at, updated_at
+// доходы с датой выплаты раньше before; next_pay_date хранит дату и время в зоне пользователя,
+// поэтому сравнивать с ней нужно тоже "настенное" время. у нерегулярных доходов расписания нет
+func (r *IncomeRepository) GetIncomesDueBefore(ctx context.Context, before time.Time) ([]models.Income, error) {
+	query := `SELECT id, user_id, name, amount, currency, frequency, recurring_day, second_day, anchor_date, pay_shift, notification_hour, next_pay_date, created_at, updated_at
 	         FROM incomes
-	         WHERE next_pay_date >= $1 AND next_pay_date < $2
-	         ORDER BY created_at ASC`
+	         WHERE next_pay_date < $1 AND frequency <> $2 AND deleted_at IS NULL
+	         ORDER BY next_pay_date ASC, created_at ASC`
 
-	rows, err := r.db.QueryContext(
-		ctx, query,
-		startOfDay, endOfDay,
-	)
+	rows, err := r.db.QueryContext(ctx, query, before, models.IncomeFrequencyIrregular)
 	if err != nil {
 		return nil, err
 	}
@@ -136,8 +149,8 @@ func (r *IncomeRepository) GetIncomesByPayDate(ctx context.Context, payDate time
 	var incomes []models.Income
 	for rows.Next() {
 		income := models.Income{}
-		err := rows.Scan(&income.ID, &income.UserID, &income.Name, &income.Amount,
-			&income.Frequency, &income.RecurringDay, &income.NotificationHour, &income.NextPayDate,
+		err := rows.Scan(&income.ID, &income.UserID, &income.Name, &income.Amount, &income.Currency,
+			&income.Frequency, &income.RecurringDay, &income.SecondDay, &income.AnchorDate, &income.PayShift, &income.NotificationHour, &income.NextPayDate,
 			&income.CreatedAt, &income.UpdatedAt)
 
 		if err != nil {
@@ -145,45 +158,79 @@ func (r *IncomeRepository) GetIncomesByPayDate(ctx context.Context, payDate time
 		}
 		incomes = append(incomes, income)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return incomes, rows.Err()
+	return incomes, r.attachInstallments(ctx, incomes)
 }
 
-func (r *IncomeRepository) GetIncomesByPayDateAndHour(ctx context.Context, payDate time.Time, hour int) ([]models.Income, error) {
-	year, month, day := payDate.Date()
-	localLoc := time.FixedZone("MSK", 3*60*60)
-
-	startOfHour := time.Date(year, month, day, hour, 0, 0, 0, localLoc).UTC()
-	endOfHour := time.Date(year, month, day, hour, 59, 59, 999999999, localLoc).UTC()
+func (r *IncomeRepository) CreateIncomeInstallment(ctx context.Context, incomeID int64, day int, amount models.Money, percent int) (*models.IncomeInstallment, error) {
+	installment := &models.IncomeInstallment{IncomeID: incomeID, Day: day, Amount: amount, Percent: percent}
+	query := `INSERT INTO income_installments (income_id, day, amount, percent) VALUES ($1, $2, $3, $4) RETURNING id`
+	err := r.db.QueryRowContext(ctx, query, incomeID, day, amount, percent).Scan(&installment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create income installment: %w", err)
+	}
+	return installment, nil
+}
 
-	query := `SELECT id, user_id, name, amount, frequency, recurring_day, notification_hour, next_pay_date, created_at, updated_at
-	         FROM incomes
-	         WHERE next_pay_date >= $1 AND next_pay_date <= $2 AND notification_hour = $3
-	         ORDER BY created_at ASC`
+// части в порядке ввода: первой обычно идет аванс
+func (r *IncomeRepository) GetIncomeInstallments(ctx context.Context, incomeID int64) ([]models.IncomeInstallment, error) {
+	query := `SELECT id, income_id, day, amount, percent FROM income_installments WHERE income_id = $1 ORDER BY id ASC`
 
-	rows, err := r.db.QueryContext(
-		ctx, query,
-		startOfHour, endOfHour, hour,
-	)
+	rows, err := r.db.QueryContext(ctx, query, incomeID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var incomes []models.Income
+	var installments []models.IncomeInstallment
 	for rows.Next() {
-		income := models.Income{}
-		err := rows.Scan(&income.ID, &income.UserID, &income.Name, &income.Amount,
-			&income.Frequency, &income.RecurringDay, &income.NotificationHour, &income.NextPayDate,
-			&income.CreatedAt, &income.UpdatedAt)
+		installment := models.IncomeInstallment{}
+		if err := rows.Scan(&installment.ID, &installment.IncomeID, &installment.Day, &installment.Amount, &installment.Percent); err != nil {
+			return nil, err
+		}
+		installments = append(installments, installment)
+	}
 
+	return installments, rows.Err()
+}
+
+func (r *IncomeRepository) attachInstallments(ctx context.Context, incomes []models.Income) error {
+	for i := range incomes {
+		if incomes[i].Frequency != models.IncomeFrequencyInstallments {
+			continue
+		}
+		installments, err := r.GetIncomeInstallments(ctx, incomes[i].ID)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		incomes = append(incomes, income)
+		incomes[i].Installments = installments
 	}
+	return nil
+}
+
+// UpdateIncome сохраняет все изменяемые поля дохода; части дохода хранятся отдельно
+func (r *IncomeRepository) UpdateIncome(ctx context.Context, income *models.Income) error {
+	query := `UPDATE incomes
+				SET name = $1, amount = $2, frequency = $3, recurring_day = $4, second_day = $5, anchor_date = $6,
+					pay_shift = $7, notification_hour = $8, next_pay_date = $9, updated_at = CURRENT_TIMESTAMP
+				WHERE id = $10`
+	_, err := r.db.ExecContext(ctx, query, income.Name, income.Amount, income.Frequency, income.RecurringDay, income.SecondDay, income.AnchorDate,
+		income.PayShift, income.NotificationHour, income.NextPayDate, income.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update income: %w", err)
+	}
+	return nil
+}
 
-	return incomes, rows.Err()
+func (r *IncomeRepository) DeleteIncomeInstallments(ctx context.Context, incomeID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM income_installments WHERE income_id = $1`, incomeID)
+	if err != nil {
+		return fmt.Errorf("failed to delete income installments: %w", err)
+	}
+	return nil
 }
 
 func (r *IncomeRepository) UpdateIncomeNextPayDate(ctx context.Context, incomeID int64, nextPayDate time.Time) error {
@@ -192,8 +239,29 @@ func (r *IncomeRepository) UpdateIncomeNextPayDate(ctx context.Context, incomeID
 	return err
 }
 
-func (r *IncomeRepository) DeleteIncome(ctx context.Context, incomeID int64) error {
-	query := `DELETE FROM incomes WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, incomeID)
+// DeleteIncome скрывает доход; насовсем он удаляется в PurgeDeletedIncomes
+func (r *IncomeRepository) DeleteIncome(ctx context.Context, incomeID int64, deletedAt time.Time) error {
+	query := `UPDATE incomes SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, deletedAt, incomeID)
 	return err
 }
+
+// RestoreIncome возвращает доход, удаленный позже deletedAfter; false - возвращать нечего
+func (r *IncomeRepository) RestoreIncome(ctx context.Context, incomeID int64, userID int64, deletedAfter time.Time) (bool, error) {
+	query := `UPDATE incomes SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+				WHERE id = $1 AND user_id = $2 AND deleted_at > $3`
+	res, err := r.db.ExecContext(ctx, query, incomeID, userID, deletedAfter)
+	if err != nil {
+		return false, fmt.Errorf("failed to restore income: %w", err)
+	}
+	restored, err := res.RowsAffected()
+	return restored > 0, err
+}
+
+func (r *IncomeRepository) PurgeDeletedIncomes(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM incomes WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted incomes: %w", err)
+	}
+	return res.RowsAffected()
+}
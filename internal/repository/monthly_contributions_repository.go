@@ -2,21 +2,22 @@ package repository
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/client/db"
 	"github.com/Lina3386/telegram-bot/internal/models"
 )
 
 type MonthlyContributionsRepository struct {
-	db *sql.DB
+	db db.QueryExecer
 }
 
-func NewMonthlyContributionsRepository(db *sql.DB) *MonthlyContributionsRepository {
+func NewMonthlyContributionsRepository(db db.QueryExecer) *MonthlyContributionsRepository {
 	return &MonthlyContributionsRepository{db: db}
 }
 
-func (r *MonthlyContributionsRepository) CreateContribution(ctx context.Context, userID, goalID int64, month time.Time, amountContributed int64) (*models.MonthlyContribution, error) {
+func (r *MonthlyContributionsRepository) CreateContribution(ctx context.Context, userID, goalID int64, month time.Time, amountContributed models.Money) (*models.MonthlyContribution, error) {
 	contribution := &models.MonthlyContribution{}
 	query := `INSERT INTO monthly_contributions (user_id, goal_id, month, amount_contributed) VALUES ($1, $2, $3, $4) ON CONFLICT (user_id, goal_id, month) DO NOTHING RETURNING id, created_at, updated_at`
 	err := r.db.QueryRowContext(ctx, query, userID, goalID, month, amountContributed).Scan(&contribution.ID, &contribution.CreatedAt, &contribution.UpdatedAt)
@@ -46,8 +47,19 @@ func (r *MonthlyContributionsRepository) UpdateContribution(ctx context.Context,
 	return err
 }
 
+// CountGoalContributions - сколько месяцев с взносами накопилось у цели
+func (r *MonthlyContributionsRepository) CountGoalContributions(ctx context.Context, goalID int64) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM monthly_contributions WHERE goal_id = $1`, goalID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count goal contributions: %w", err)
+	}
+	return count, nil
+}
+
 func (r *MonthlyContributionsRepository) GetUserContributionsByMonth(ctx context.Context, userID int64, month time.Time) ([]models.MonthlyContribution, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, goal_id, month, amount_contributed, created_at, updated_at FROM monthly_contributions WHERE user_id = $1 AND month = $2`, userID, month)
+	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, goal_id, month, amount_contributed, created_at, updated_at FROM monthly_contributions
+		WHERE user_id = $1 AND month = $2 AND goal_id IN (SELECT id FROM savings_goals WHERE deleted_at IS NULL)`, userID, month)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/client/db"
+	"github.com/Lina3386/telegram-bot/internal/models"
+)
+
+type NotificationRepository struct {
+	db db.QueryExecer
+}
+
+func NewNotificationRepository(db db.QueryExecer) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+func (r *NotificationRepository) EnqueueNotification(ctx context.Context, n *models.PendingNotification) error {
+	query := `INSERT INTO notification_outbox (chat_id, text, reply_markup, attempts, last_error, next_attempt_at, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	err := r.db.QueryRowContext(ctx, query, n.ChatID, n.Text, n.ReplyMarkup, n.Attempts, n.LastError, n.NextAttemptAt, n.CreatedAt).Scan(&n.ID)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue notification: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueNotifications забирает уведомления, которым пора на повторную отправку, и сдвигает их
+// next_attempt_at на leaseUntil - одним запросом, поэтому несколько экземпляров бота не отправят одно
+// уведомление дважды. Если экземпляр упадет, не успев отправить, уведомление вернется после leaseUntil
+func (r *NotificationRepository) ClaimDueNotifications(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]models.PendingNotification, error) {
+	query := `UPDATE notification_outbox SET next_attempt_at = $2
+	          WHERE id IN (
+	              SELECT id FROM notification_outbox WHERE next_attempt_at <= $1
+	              ORDER BY next_attempt_at, id LIMIT $3
+	              FOR UPDATE SKIP LOCKED
+	          )
+	          RETURNING id, chat_id, text, reply_markup, attempts, last_error, next_attempt_at, created_at`
+	rows, err := r.db.QueryContext(ctx, query, now, leaseUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []models.PendingNotification
+	for rows.Next() {
+		n := models.PendingNotification{}
+		err := rows.Scan(&n.ID, &n.ChatID, &n.Text, &n.ReplyMarkup, &n.Attempts, &n.LastError, &n.NextAttemptAt, &n.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// RescheduleNotification записывает неудачную попытку и время следующей
+func (r *NotificationRepository) RescheduleNotification(ctx context.Context, id int64, attempts int, lastError string, nextAttemptAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE notification_outbox SET attempts = $1, last_error = $2, next_attempt_at = $3 WHERE id = $4`, attempts, lastError, nextAttemptAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule notification: %w", err)
+	}
+	return nil
+}
+
+func (r *NotificationRepository) DeleteNotification(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM notification_outbox WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Lina3386/telegram-bot/internal/client/db"
+	"github.com/Lina3386/telegram-bot/internal/models"
+)
+
+type RateRepository struct {
+	db db.QueryExecer
+}
+
+func NewRateRepository(db db.QueryExecer) *RateRepository {
+	return &RateRepository{db: db}
+}
+
+func (r *RateRepository) SetRate(ctx context.Context, currency string, rate float64) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO rates (currency, rate) VALUES ($1, $2)
+		 ON CONFLICT (currency) DO UPDATE SET rate = EXCLUDED.rate, updated_at = CURRENT_TIMESTAMP`,
+		currency, rate,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set rate: %w", err)
+	}
+	return nil
+}
+
+func (r *RateRepository) GetRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT currency, rate, updated_at FROM rates ORDER BY currency`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []models.ExchangeRate
+	for rows.Next() {
+		rate := models.ExchangeRate{}
+		if err := rows.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, rows.Err()
+}
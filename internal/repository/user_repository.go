@@ -2,23 +2,23 @@ package repository
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"github.com/Lina3386/telegram-bot/internal/client/db"
 	"github.com/Lina3386/telegram-bot/internal/models"
 )
 
 type UserRepository struct {
-	db *sql.DB
+	db db.QueryExecer
 }
 
-func NewUserRepository(db *sql.DB) *UserRepository {
+func NewUserRepository(db db.QueryExecer) *UserRepository {
 	return &UserRepository{db: db}
 }
 
 func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
 	err := r.db.QueryRowContext(ctx,
-		`INSERT INTO users (telegram_id, username, auth_token) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`,
-		user.TelegramID, user.Username, user.AuthToken).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+		`INSERT INTO users (telegram_id, username, auth_token) VALUES ($1, $2, $3) RETURNING id, base_currency, allocation_strategy, timezone, created_at, updated_at`,
+		user.TelegramID, user.Username, user.AuthToken).Scan(&user.ID, &user.BaseCurrency, &user.AllocationStrategy, &user.Timezone, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
@@ -30,9 +30,9 @@ func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) (*mo
 func (r *UserRepository) GetUserByTelegramID(ctx context.Context, telegramID int64) (*models.User, error) {
 	user := &models.User{}
 	err := r.db.QueryRowContext(ctx,
-		`SELECT id, telegram_id, username, auth_token, monthly_expense, created_at, updated_at FROM users WHERE telegram_id = $1`, telegramID,
+		`SELECT id, telegram_id, username, auth_token, monthly_expense, base_currency, allocation_strategy, timezone, created_at, updated_at FROM users WHERE telegram_id = $1`, telegramID,
 	).Scan(&user.ID, &user.TelegramID, &user.Username, &user.AuthToken,
-		&user.MonthlyExpense, &user.CreatedAt, &user.UpdatedAt)
+		&user.MonthlyExpense, &user.BaseCurrency, &user.AllocationStrategy, &user.Timezone, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -44,9 +44,9 @@ func (r *UserRepository) GetUserByTelegramID(ctx context.Context, telegramID int
 func (r *UserRepository) GetUserByID(ctx context.Context, userID int64) (*models.User, error) {
 	user := &models.User{}
 	err := r.db.QueryRowContext(ctx,
-		`SELECT id, telegram_id, username, auth_token, monthly_expense, created_at, updated_at FROM users WHERE id = $1`, userID,
+		`SELECT id, telegram_id, username, auth_token, monthly_expense, base_currency, allocation_strategy, timezone, created_at, updated_at FROM users WHERE id = $1`, userID,
 	).Scan(&user.ID, &user.TelegramID, &user.Username, &user.AuthToken,
-		&user.MonthlyExpense, &user.CreatedAt, &user.UpdatedAt)
+		&user.MonthlyExpense, &user.BaseCurrency, &user.AllocationStrategy, &user.Timezone, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -63,3 +63,30 @@ func (r *UserRepository) UpdateMonthlyExpense(ctx context.Context, userID int64,
 	)
 	return err
 }
+
+func (r *UserRepository) UpdateBaseCurrency(ctx context.Context, userID int64, currency string) error {
+	_, err := r.db.ExecContext(
+		ctx, `UPDATE users
+		SET base_currency = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`, currency, userID,
+	)
+	return err
+}
+
+func (r *UserRepository) UpdateAllocationStrategy(ctx context.Context, userID int64, strategy string) error {
+	_, err := r.db.ExecContext(
+		ctx, `UPDATE users
+		SET allocation_strategy = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`, strategy, userID,
+	)
+	return err
+}
+
+func (r *UserRepository) UpdateTimezone(ctx context.Context, userID int64, timezone string) error {
+	_, err := r.db.ExecContext(
+		ctx, `UPDATE users
+		SET timezone = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`, timezone, userID,
+	)
+	return err
+}
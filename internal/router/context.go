@@ -0,0 +1,90 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Context - одно входящее обновление со всем, что о нем известно к моменту вызова обработчика.
+// Сам является context.Context: его таймаут и отмена действуют на запросы к БД из обработчика
+type Context struct {
+	context.Context
+
+	Update   tgbotapi.Update
+	Message  *tgbotapi.Message       // текст или команда; при нажатии кнопки - nil
+	Callback *tgbotapi.CallbackQuery // нажатая inline-кнопка
+	Params   Params                  // параметры из шаблона callback-маршрута
+
+	// заполняет middleware загрузки пользователя; NewUser - пользователь зарегистрирован этим обновлением
+	User    *models.User
+	NewUser bool
+
+	logPrefix string
+}
+
+func newContext(ctx context.Context, update tgbotapi.Update) *Context {
+	c := &Context{
+		Context:  ctx,
+		Update:   update,
+		Message:  update.Message,
+		Callback: update.CallbackQuery,
+	}
+	c.logPrefix = fmt.Sprintf("[update=%d user=%d chat=%d]", update.UpdateID, c.UserID(), c.ChatID())
+	return c
+}
+
+func (c *Context) From() *tgbotapi.User {
+	switch {
+	case c.Message != nil:
+		return c.Message.From
+	case c.Callback != nil:
+		return c.Callback.From
+	}
+	return nil
+}
+
+// UserID - telegram id отправителя
+func (c *Context) UserID() int64 {
+	if from := c.From(); from != nil {
+		return from.ID
+	}
+	return 0
+}
+
+// ChatID - чат, куда отвечать: для кнопки это чат сообщения, к которому она прикреплена
+func (c *Context) ChatID() int64 {
+	switch {
+	case c.Message != nil && c.Message.Chat != nil:
+		return c.Message.Chat.ID
+	case c.Callback != nil && c.Callback.Message != nil && c.Callback.Message.Chat != nil:
+		return c.Callback.Message.Chat.ID
+	}
+	return 0
+}
+
+// Text - текст сообщения или callback data кнопки
+func (c *Context) Text() string {
+	switch {
+	case c.Message != nil:
+		return c.Message.Text
+	case c.Callback != nil:
+		return c.Callback.Data
+	}
+	return ""
+}
+
+// Logf пишет в лог с полями обновления: update, user, chat
+func (c *Context) Logf(format string, args ...any) {
+	log.Printf(c.logPrefix+" "+format, args...)
+}
+
+// WithContext подменяет context.Context, например чтобы добавить таймаут
+func (c *Context) WithContext(ctx context.Context) *Context {
+	cp := *c
+	cp.Context = ctx
+	return &cp
+}
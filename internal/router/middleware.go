@@ -0,0 +1,73 @@
+package router
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+)
+
+// Middleware оборачивает обработку обновления: может что-то сделать до и после next или не вызывать его вовсе
+type Middleware func(next Handler) Handler
+
+// Recover не дает панике в обработчике уронить цикл обновлений; onPanic сообщает пользователю об ошибке
+func Recover(onPanic Handler) Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			defer func() {
+				if p := recover(); p != nil {
+					c.Logf("[PANIC] %v\n%s", p, debug.Stack())
+					if onPanic != nil {
+						onPanic(c)
+					}
+				}
+			}()
+			next(c)
+		}
+	}
+}
+
+// Timeout ограничивает время обработки одного обновления
+func Timeout(d time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			ctx, cancel := context.WithTimeout(c.Context, d)
+			defer cancel()
+			next(c.WithContext(ctx))
+		}
+	}
+}
+
+// Logging пишет, что пришло и сколько заняла обработка
+func Logging() Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			kind := "message"
+			if c.Callback != nil {
+				kind = "callback"
+			}
+			c.Logf("%s: %s", kind, c.Text())
+
+			started := time.Now()
+			next(c)
+			if elapsed := time.Since(started); elapsed > time.Second {
+				c.Logf("slow %s handled in %s", kind, elapsed.Round(time.Millisecond))
+			}
+		}
+	}
+}
+
+// Authorize пропускает дальше только обновления, для которых allow вернул true; остальным отвечает deny
+func Authorize(allow func(c *Context) bool, deny Handler) Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			if !allow(c) {
+				c.Logf("access denied")
+				if deny != nil {
+					deny(c)
+				}
+				return
+			}
+			next(c)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package router
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Params - параметры, извлеченные из callback data по шаблону маршрута
+type Params map[string]string
+
+func (p Params) String(name string) string {
+	return p[name]
+}
+
+// Int64 - значение параметра {name:int}; шаблон уже проверил, что это число
+func (p Params) Int64(name string) int64 {
+	v, _ := strconv.ParseInt(p[name], 10, 64)
+	return v
+}
+
+func (p Params) Int(name string) int {
+	return int(p.Int64(name))
+}
+
+type segmentKind int
+
+const (
+	segmentLiteral segmentKind = iota
+	segmentParam
+	segmentInt
+	segmentRest
+)
+
+type segment struct {
+	kind  segmentKind
+	value string // текст литерала или имя параметра
+}
+
+// pattern - разобранный шаблон вида goal/{id:int}/contrib.
+// {name} - любой непустой сегмент, {name:int} - целое число, {name...} - весь остаток, только в конце
+type pattern struct {
+	raw      string
+	segments []segment
+}
+
+func parsePattern(raw string) (*pattern, error) {
+	if raw == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+
+	p := &pattern{raw: raw}
+	seen := make(map[string]bool)
+	parts := strings.Split(raw, "/")
+	for i, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("pattern %q: empty segment", raw)
+		}
+		if !strings.HasPrefix(part, "{") {
+			if strings.ContainsAny(part, "{}") {
+				return nil, fmt.Errorf("pattern %q: bad segment %q", raw, part)
+			}
+			p.segments = append(p.segments, segment{kind: segmentLiteral, value: part})
+			continue
+		}
+		if !strings.HasSuffix(part, "}") {
+			return nil, fmt.Errorf("pattern %q: unclosed parameter %q", raw, part)
+		}
+
+		name := part[1 : len(part)-1]
+		kind := segmentParam
+		switch {
+		case strings.HasSuffix(name, ":int"):
+			name = strings.TrimSuffix(name, ":int")
+			kind = segmentInt
+		case strings.HasSuffix(name, "..."):
+			name = strings.TrimSuffix(name, "...")
+			kind = segmentRest
+			if i != len(parts)-1 {
+				return nil, fmt.Errorf("pattern %q: %q must be the last segment", raw, part)
+			}
+		}
+		if name == "" || strings.ContainsAny(name, "{}:.") {
+			return nil, fmt.Errorf("pattern %q: bad parameter name in %q", raw, part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("pattern %q: duplicate parameter %q", raw, name)
+		}
+		seen[name] = true
+		p.segments = append(p.segments, segment{kind: kind, value: name})
+	}
+	return p, nil
+}
+
+func (p *pattern) match(data string) (Params, bool) {
+	parts := strings.Split(data, "/")
+	params := Params{}
+	for i, seg := range p.segments {
+		if i >= len(parts) {
+			return nil, false
+		}
+		part := parts[i]
+		switch seg.kind {
+		case segmentLiteral:
+			if part != seg.value {
+				return nil, false
+			}
+		case segmentParam:
+			if part == "" {
+				return nil, false
+			}
+			params[seg.value] = part
+		case segmentInt:
+			if _, err := strconv.ParseInt(part, 10, 64); err != nil {
+				return nil, false
+			}
+			params[seg.value] = part
+		case segmentRest:
+			rest := strings.Join(parts[i:], "/")
+			if rest == "" {
+				return nil, false
+			}
+			params[seg.value] = rest
+			return params, true
+		}
+	}
+	if len(parts) != len(p.segments) {
+		return nil, false
+	}
+	return params, true
+}
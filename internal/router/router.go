@@ -0,0 +1,190 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Lina3386/telegram-bot/internal/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+type Handler func(c *Context)
+
+type callbackRoute struct {
+	pattern *pattern
+	handler Handler
+}
+
+// Router раскладывает обновления по обработчикам: команды, тексты кнопок меню,
+// шаги диалога по состоянию пользователя и callback-кнопки по шаблонам
+type Router struct {
+	commands  map[string]Handler
+	texts     map[string]Handler
+	states    map[state.DialogState]Handler
+	callbacks []callbackRoute
+
+	stateOf     StateFunc
+	middlewares []Middleware
+
+	unknownCommand  Handler
+	fallback        Handler
+	unknownCallback Handler
+	stateError      Handler
+}
+
+// StateFunc - текущий шаг диалога пользователя
+type StateFunc func(ctx context.Context, userID int64) (state.DialogState, error)
+
+func New(stateOf StateFunc) *Router {
+	return &Router{
+		commands: make(map[string]Handler),
+		texts:    make(map[string]Handler),
+		states:   make(map[state.DialogState]Handler),
+		stateOf:  stateOf,
+	}
+}
+
+// Command - /name; регистр команды не важен
+func (r *Router) Command(name string, handler Handler) {
+	name = strings.ToLower(strings.TrimPrefix(name, "/"))
+	if _, ok := r.commands[name]; ok {
+		panic(fmt.Sprintf("router: command /%s registered twice", name))
+	}
+	r.commands[name] = handler
+}
+
+// Text - кнопки reply-клавиатуры и их текстовые синонимы; срабатывают в любом состоянии диалога
+func (r *Router) Text(handler Handler, texts ...string) {
+	for _, text := range texts {
+		if _, ok := r.texts[text]; ok {
+			panic(fmt.Sprintf("router: text %q registered twice", text))
+		}
+		r.texts[text] = handler
+	}
+}
+
+// State - шаг диалога: текст, который не совпал с кнопками меню, уходит обработчику текущего состояния
+func (r *Router) State(s state.DialogState, handler Handler) {
+	if _, ok := r.states[s]; ok {
+		panic(fmt.Sprintf("router: state %q registered twice", s))
+	}
+	r.states[s] = handler
+}
+
+// Callback - кнопка с callback data по шаблону, например goal/{id:int}/contrib.
+// Маршруты проверяются в порядке регистрации, срабатывает первый подошедший
+func (r *Router) Callback(raw string, handler Handler) {
+	p, err := parsePattern(raw)
+	if err != nil {
+		panic("router: " + err.Error())
+	}
+	for _, route := range r.callbacks {
+		if route.pattern.raw == raw {
+			panic(fmt.Sprintf("router: callback %q registered twice", raw))
+		}
+	}
+	r.callbacks = append(r.callbacks, callbackRoute{pattern: p, handler: handler})
+}
+
+func (r *Router) UnknownCommand(handler Handler) {
+	r.unknownCommand = handler
+}
+
+// Fallback - текст, для которого нет ни кнопки, ни обработчика состояния
+func (r *Router) Fallback(handler Handler) {
+	r.fallback = handler
+}
+
+func (r *Router) UnknownCallback(handler Handler) {
+	r.unknownCallback = handler
+}
+
+// StateError - текст пришел, но шаг диалога прочитать не удалось
+func (r *Router) StateError(handler Handler) {
+	r.stateError = handler
+}
+
+// Use добавляет middleware вокруг всей обработки, включая выбор маршрута; первый добавленный - внешний
+func (r *Router) Use(middlewares ...Middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// Dispatch обрабатывает одно обновление; ctx - родительский контекст, например до остановки бота
+func (r *Router) Dispatch(ctx context.Context, update tgbotapi.Update) {
+	if update.Message == nil && update.CallbackQuery == nil {
+		return
+	}
+	if update.CallbackQuery != nil && update.CallbackQuery.Message == nil {
+		// кнопки inline-режима боту не приходят, но без сообщения отвечать некуда
+		return
+	}
+
+	handler := r.route
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		handler = r.middlewares[i](handler)
+	}
+	handler(newContext(ctx, update))
+}
+
+func (r *Router) route(c *Context) {
+	if c.Message != nil {
+		r.routeMessage(c)
+		return
+	}
+	r.routeCallback(c)
+}
+
+func (r *Router) routeMessage(c *Context) {
+	message := c.Message
+	if message.From == nil {
+		return
+	}
+
+	if message.IsCommand() {
+		if handler, ok := r.commands[strings.ToLower(message.Command())]; ok {
+			handler(c)
+			return
+		}
+		if r.unknownCommand != nil {
+			r.unknownCommand(c)
+		}
+		return
+	}
+
+	if handler, ok := r.texts[message.Text]; ok {
+		handler(c)
+		return
+	}
+	current, err := r.stateOf(c, message.From.ID)
+	if err != nil {
+		// без шага диалога текст нельзя разобрать - не отдаем его обработчику наугад
+		c.Logf("[ROUTER] Failed to get dialog state: %v", err)
+		if r.stateError != nil {
+			r.stateError(c)
+		}
+		return
+	}
+	if handler, ok := r.states[current]; ok {
+		handler(c)
+		return
+	}
+	if r.fallback != nil {
+		r.fallback(c)
+	}
+}
+
+func (r *Router) routeCallback(c *Context) {
+	for _, route := range r.callbacks {
+		if params, ok := route.pattern.match(c.Callback.Data); ok {
+			c.Params = params
+			route.handler(c)
+			return
+		}
+	}
+
+	c.Logf("[ROUTER] No route for callback %q", c.Callback.Data)
+	if r.unknownCallback != nil {
+		r.unknownCallback(c)
+	}
+}
@@ -0,0 +1,201 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		data    string
+		want    Params
+	}{
+		{"literal", "goals", "goals", Params{}},
+		{"literal mismatch", "goals", "incomes", nil},
+		{"int param", "goal/{id:int}/contrib", "goal/12/contrib", Params{"id": "12"}},
+		{"int param rejects text", "goal/{id:int}/contrib", "goal/abc/contrib", nil},
+		{"negative int", "report/{n:int}", "report/-1", Params{"n": "-1"}},
+		{"string param", "{type}/{id:int}/undo", "expense/7/undo", Params{"type": "expense", "id": "7"}},
+		{"empty segment", "{type}/{id:int}/undo", "/7/undo", nil},
+		{"too short", "goal/{id:int}/contrib", "goal/12", nil},
+		{"too long", "goal/{id:int}", "goal/12/contrib", nil},
+		{"rest", "timezone/set/{name...}", "timezone/set/America/Argentina/Salta", Params{"name": "America/Argentina/Salta"}},
+		{"rest is required", "timezone/set/{name...}", "timezone/set", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := parsePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("parsePattern(%q) error = %v", tt.pattern, err)
+			}
+			got, ok := p.match(tt.data)
+			if ok != (tt.want != nil) {
+				t.Fatalf("match(%q) ok = %v, want %v", tt.data, ok, tt.want != nil)
+			}
+			for name, value := range tt.want {
+				if got[name] != value {
+					t.Errorf("match(%q)[%s] = %q, want %q", tt.data, name, got[name], value)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("match(%q) = %v, want %v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePatternErrors(t *testing.T) {
+	for _, raw := range []string{"", "goal//contrib", "goal/{id", "goal/{}", "{rest...}/tail", "{id}/{id:int}", "go{al}"} {
+		if _, err := parsePattern(raw); err == nil {
+			t.Errorf("parsePattern(%q) error = nil, want error", raw)
+		}
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	current := state.StateIdle
+	r := New(func(context.Context, int64) (state.DialogState, error) { return current, nil })
+
+	var got string
+	r.Command("start", func(*Context) { got = "start" })
+	r.UnknownCommand(func(*Context) { got = "unknown command" })
+	r.Text(func(*Context) { got = "goals" }, "🍀 Цели", "цели")
+	r.State(state.StateCreatingGoal, func(*Context) { got = "goal name" })
+	r.Fallback(func(*Context) { got = "fallback" })
+	r.Callback("goal/{id:int}/contrib", func(c *Context) { got = "contrib " + c.Params.String("id") })
+	r.Callback("goal/{id:int}", func(c *Context) { got = "goal " + c.Params.String("id") })
+	r.UnknownCallback(func(*Context) { got = "unknown callback" })
+
+	message := func(text string) tgbotapi.Update {
+		msg := &tgbotapi.Message{From: &tgbotapi.User{ID: 1}, Chat: &tgbotapi.Chat{ID: 1}, Text: text}
+		if len(text) > 0 && text[0] == '/' {
+			msg.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(text)}}
+		}
+		return tgbotapi.Update{Message: msg}
+	}
+	callback := func(data string) tgbotapi.Update {
+		return tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+			From:    &tgbotapi.User{ID: 1},
+			Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 1}},
+			Data:    data,
+		}}
+	}
+
+	tests := []struct {
+		name   string
+		state  state.DialogState
+		update tgbotapi.Update
+		want   string
+	}{
+		{"command", state.StateIdle, message("/start"), "start"},
+		{"unknown command", state.StateIdle, message("/nope"), "unknown command"},
+		{"menu text", state.StateIdle, message("цели"), "goals"},
+		{"menu text wins over state", state.StateCreatingGoal, message("🍀 Цели"), "goals"},
+		{"state", state.StateCreatingGoal, message("Отпуск"), "goal name"},
+		{"fallback", state.StateIdle, message("привет"), "fallback"},
+		{"callback with params", state.StateIdle, callback("goal/5/contrib"), "contrib 5"},
+		{"shorter callback", state.StateIdle, callback("goal/5"), "goal 5"},
+		{"unknown callback", state.StateIdle, callback("contrib_5"), "unknown callback"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current = tt.state
+			got = ""
+			r.Dispatch(context.Background(), tt.update)
+			if got != tt.want {
+				t.Errorf("Dispatch() handled as %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatchStateError(t *testing.T) {
+	r := New(func(context.Context, int64) (state.DialogState, error) {
+		return state.StateIdle, errors.New("db is down")
+	})
+
+	var got string
+	r.Text(func(*Context) { got = "goals" }, "цели")
+	r.Fallback(func(*Context) { got = "fallback" })
+	r.StateError(func(*Context) { got = "state error" })
+
+	update := func(text string) tgbotapi.Update {
+		return tgbotapi.Update{Message: &tgbotapi.Message{From: &tgbotapi.User{ID: 1}, Chat: &tgbotapi.Chat{ID: 1}, Text: text}}
+	}
+
+	r.Dispatch(context.Background(), update("цели"))
+	if got != "goals" {
+		t.Errorf("menu text handled as %q, want goals", got)
+	}
+	r.Dispatch(context.Background(), update("Отпуск"))
+	if got != "state error" {
+		t.Errorf("dialog text handled as %q, want state error", got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	r := New(func(context.Context, int64) (state.DialogState, error) { return state.StateIdle, nil })
+
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(c *Context) {
+				calls = append(calls, name)
+				next(c)
+			}
+		}
+	}
+
+	var recovered, denied bool
+	var hasDeadline bool
+	r.Use(
+		Recover(func(*Context) { recovered = true }),
+		trace("outer"),
+		Timeout(time.Minute),
+		Authorize(func(c *Context) bool { return c.UserID() != 2 }, func(*Context) { denied = true }),
+		trace("inner"),
+	)
+	r.Command("start", func(c *Context) {
+		calls = append(calls, "handler")
+		_, hasDeadline = c.Deadline()
+	})
+	r.Command("boom", func(*Context) { panic("boom") })
+
+	command := func(userID int64, text string) tgbotapi.Update {
+		return tgbotapi.Update{Message: &tgbotapi.Message{
+			From:     &tgbotapi.User{ID: userID},
+			Chat:     &tgbotapi.Chat{ID: userID},
+			Text:     text,
+			Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(text)}},
+		}}
+	}
+
+	r.Dispatch(context.Background(), command(1, "/start"))
+	if got := strings.Join(calls, ","); got != "outer,inner,handler" {
+		t.Errorf("calls = %s, want outer,inner,handler", got)
+	}
+	if !hasDeadline {
+		t.Error("handler context has no deadline")
+	}
+
+	calls = nil
+	r.Dispatch(context.Background(), command(2, "/start"))
+	if !denied || strings.Join(calls, ",") != "outer" {
+		t.Errorf("denied = %v, calls = %v; want denied before inner middleware", denied, calls)
+	}
+
+	r.Dispatch(context.Background(), command(1, "/boom"))
+	if !recovered {
+		t.Error("panic was not recovered")
+	}
+}
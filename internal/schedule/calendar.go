@@ -0,0 +1,188 @@
+package schedule
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// производственный календарь, по которому выплаты переносятся с выходных и праздников
+var DefaultCalendar = NewRussianCalendar()
+
+// нерабочие праздничные дни по ст. 112 ТК РФ
+var russianHolidays = []struct {
+	month time.Month
+	day   int
+}{
+	{time.January, 1}, {time.January, 2}, {time.January, 3}, {time.January, 4},
+	{time.January, 5}, {time.January, 6}, {time.January, 7}, {time.January, 8},
+	{time.February, 23},
+	{time.March, 8},
+	{time.May, 1},
+	{time.May, 9},
+	{time.June, 12},
+	{time.November, 4},
+}
+
+type Calendar struct {
+	mu sync.RWMutex
+	// переносы из файла важнее встроенных правил
+	holidays map[time.Time]bool
+	workdays map[time.Time]bool
+}
+
+func NewRussianCalendar() *Calendar {
+	return &Calendar{
+		holidays: make(map[time.Time]bool),
+		workdays: make(map[time.Time]bool),
+	}
+}
+
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	day := dateOf(t, time.UTC)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.workdays[day] {
+		return true
+	}
+	if c.holidays[day] || isWeekend(day) {
+		return false
+	}
+	return !isRussianHoliday(day) && !isTransferredHoliday(day)
+}
+
+func (c *Calendar) AddHoliday(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	day := dateOf(t, time.UTC)
+	c.holidays[day] = true
+	delete(c.workdays, day)
+}
+
+func (c *Calendar) AddWorkday(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	day := dateOf(t, time.UTC)
+	c.workdays[day] = true
+	delete(c.holidays, day)
+}
+
+// PreviousBusinessDay - сам день, если он рабочий, иначе ближайший рабочий до него
+func (c *Calendar) PreviousBusinessDay(t time.Time) time.Time {
+	for !c.IsBusinessDay(t) {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t
+}
+
+// NextBusinessDay - сам день, если он рабочий, иначе ближайший рабочий после него
+func (c *Calendar) NextBusinessDay(t time.Time) time.Time {
+	for !c.IsBusinessDay(t) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// LoadFile загружает переносы из файла, по дню на строку:
+//
+//	# постановление о переносе выходных на 2026 год
+//	2026-01-09 holiday
+//	27.12.2025 workday
+//
+// вместо holiday/workday можно писать "выходной"/"рабочий"
+func (c *Calendar) LoadFile(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open calendar file: %w", err)
+	}
+	defer file.Close()
+	return c.Load(file)
+}
+
+func (c *Calendar) Load(r io.Reader) (int, error) {
+	loaded := 0
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return loaded, fmt.Errorf("calendar file line %d: expected \"DATE holiday|workday\"", lineNum)
+		}
+
+		day, err := parseCalendarDate(fields[0])
+		if err != nil {
+			return loaded, fmt.Errorf("calendar file line %d: %w", lineNum, err)
+		}
+
+		switch strings.ToLower(fields[1]) {
+		case "holiday", "выходной":
+			c.AddHoliday(day)
+		case "workday", "рабочий":
+			c.AddWorkday(day)
+		default:
+			return loaded, fmt.Errorf("calendar file line %d: unknown day type %q", lineNum, fields[1])
+		}
+		loaded++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return loaded, fmt.Errorf("failed to read calendar file: %w", err)
+	}
+	return loaded, nil
+}
+
+func parseCalendarDate(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "02.01.2006"} {
+		if day, err := time.Parse(layout, value); err == nil {
+			return day, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+func isWeekend(day time.Time) bool {
+	return day.Weekday() == time.Saturday || day.Weekday() == time.Sunday
+}
+
+func isRussianHoliday(day time.Time) bool {
+	for _, h := range russianHolidays {
+		if day.Month() == h.month && day.Day() == h.day {
+			return true
+		}
+	}
+	return false
+}
+
+// праздник, выпавший на выходной, переносится на следующий рабочий день;
+// январские переносы каждый год задаются постановлением, их берем из файла
+func isTransferredHoliday(day time.Time) bool {
+	for _, h := range russianHolidays {
+		if h.month == time.January {
+			continue
+		}
+		holiday := time.Date(day.Year(), h.month, h.day, 0, 0, 0, 0, time.UTC)
+		if !isWeekend(holiday) {
+			continue
+		}
+		transferred := holiday.AddDate(0, 0, 1)
+		for isWeekend(transferred) || isRussianHoliday(transferred) {
+			transferred = transferred.AddDate(0, 0, 1)
+		}
+		if transferred.Equal(day) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarIsBusinessDay(t *testing.T) {
+	cal := NewRussianCalendar()
+	if _, err := cal.Load(strings.NewReader("# переносы\n2026-01-09 holiday\n27.12.2025 рабочий\n2026-06-11 выходной\n")); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		day  time.Time
+		want bool
+	}{
+		{"ordinary monday", date(2026, 1, 12), true},
+		{"saturday", date(2026, 1, 10), false},
+		{"new year holidays", date(2026, 1, 5), false},
+		{"defender day", date(2026, 2, 23), false},
+		{"day after defender day", date(2026, 2, 24), true},
+		{"women's day on sunday moves to monday", date(2026, 3, 9), false},
+		{"victory day on saturday moves to monday", date(2026, 5, 11), false},
+		{"unity day", date(2026, 11, 4), false},
+		{"holiday from file", date(2026, 1, 9), false},
+		{"working saturday from file", date(2025, 12, 27), true},
+		{"bridge day from file", date(2026, 6, 11), false},
+		{"time of day is ignored", time.Date(2026, 1, 12, 23, 0, 0, 0, time.FixedZone("UTC+10", 10*60*60)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cal.IsBusinessDay(tt.day); got != tt.want {
+				t.Errorf("IsBusinessDay(%s) = %v, want %v", tt.day.Format("2006-01-02"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalendarLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"missing type", "2026-01-09\n"},
+		{"bad date", "2026-13-01 holiday\n"},
+		{"unknown type", "2026-01-09 shortday\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRussianCalendar().Load(strings.NewReader(tt.file)); err == nil {
+				t.Errorf("Load(%q) error = nil, want error", tt.file)
+			}
+		})
+	}
+}
+
+func TestRuleNextWithShift(t *testing.T) {
+	cal := NewRussianCalendar()
+	cal.AddHoliday(date(2026, 1, 9))
+
+	tests := []struct {
+		name  string
+		rule  Rule
+		after time.Time
+		want  time.Time
+	}{
+		{"no shift keeps weekend", Rule{Kind: Monthly, Day: 10}, date(2026, 1, 1), date(2026, 1, 10)},
+		{"previous skips holidays back to december", Rule{Kind: Monthly, Day: 10, Shift: ShiftPrevious}, date(2025, 12, 20), date(2025, 12, 31)},
+		{"previous already passed", Rule{Kind: Monthly, Day: 10, Shift: ShiftPrevious}, date(2026, 1, 1), date(2026, 2, 10)},
+		{"previous on weekday", Rule{Kind: Monthly, Day: 10, Shift: ShiftPrevious}, date(2026, 2, 1), date(2026, 2, 10)},
+		{"next from weekend", Rule{Kind: Monthly, Day: 10, Shift: ShiftNext}, date(2026, 1, 1), date(2026, 1, 12)},
+		{"next after nominal date", Rule{Kind: Monthly, Day: 10, Shift: ShiftNext}, date(2026, 1, 10), date(2026, 1, 12)},
+		{"next after shifted date", Rule{Kind: Monthly, Day: 10, Shift: ShiftNext}, date(2026, 1, 12), date(2026, 2, 10)},
+		{"next over transferred holiday", Rule{Kind: Monthly, Day: 8, Shift: ShiftNext}, date(2026, 3, 1), date(2026, 3, 10)},
+		{"semimonthly advance on saturday", Rule{Kind: SemiMonthly, Day: 10, SecondDay: 25, Shift: ShiftPrevious}, date(2026, 4, 11), date(2026, 4, 24)},
+		{"last business day honours calendar", Rule{Kind: LastBusinessDay}, date(2026, 12, 1), date(2026, 12, 31)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.Calendar = cal
+			if got := tt.rule.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after.Format("2006-01-02"), got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
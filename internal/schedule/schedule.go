@@ -0,0 +1,224 @@
+// Package schedule описывает правила повторения выплат и считает по ним даты.
+// Все даты календарные: время суток не учитывается, результат - полночь в зоне аргумента.
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+type Kind string
+
+const (
+	Monthly         Kind = "monthly"           // каждый месяц в день Day
+	LastBusinessDay Kind = "last_business_day" // последний рабочий день месяца
+	Weekly          Kind = "weekly"            // каждую неделю в день недели Day (0 - воскресенье)
+	Biweekly        Kind = "biweekly"          // раз в две недели, считая от Anchor
+	SemiMonthly     Kind = "semimonthly"       // два раза в месяц: Day и SecondDay
+	Quarterly       Kind = "quarterly"         // раз в квартал в день Day, месяцы отсчитываются от Anchor
+)
+
+// что делать, если день выплаты выпал на выходной или праздник
+type Shift string
+
+const (
+	ShiftNone     Shift = "none"
+	ShiftPrevious Shift = "previous"
+	ShiftNext     Shift = "next"
+)
+
+// насколько далеко перенос вперед может увести выплату (январские праздники с выходными)
+const maxShiftDays = 14
+
+type Rule struct {
+	Kind      Kind
+	Day       int
+	SecondDay int
+	// опорная дата: для Biweekly - любая из дат выплаты, для Quarterly - задает месяцы квартала.
+	// без нее Biweekly отсчитывается от первой найденной даты, а Quarterly идет с января
+	Anchor time.Time
+	Shift  Shift
+	// nil - DefaultCalendar
+	Calendar *Calendar
+}
+
+func (r Rule) Validate() error {
+	switch r.Kind {
+	case Monthly, Quarterly:
+		if r.Day < 1 || r.Day > 31 {
+			return fmt.Errorf("day of month out of range: %d", r.Day)
+		}
+	case SemiMonthly:
+		if r.Day < 1 || r.Day > 31 || r.SecondDay < 1 || r.SecondDay > 31 {
+			return fmt.Errorf("days of month out of range: %d, %d", r.Day, r.SecondDay)
+		}
+		if r.Day == r.SecondDay {
+			return fmt.Errorf("semimonthly days must differ: %d", r.Day)
+		}
+	case Weekly, Biweekly:
+		if r.Anchor.IsZero() && (r.Day < 0 || r.Day > 6) {
+			return fmt.Errorf("weekday out of range: %d", r.Day)
+		}
+	case LastBusinessDay:
+	default:
+		return fmt.Errorf("unknown schedule kind %q", r.Kind)
+	}
+
+	switch r.Shift {
+	case "", ShiftNone, ShiftPrevious, ShiftNext:
+	default:
+		return fmt.Errorf("unknown shift %q", r.Shift)
+	}
+	return nil
+}
+
+// Next - первая дата выплаты строго после календарного дня after, с учетом переноса с нерабочих дней
+func (r Rule) Next(after time.Time) time.Time {
+	if r.Shift == "" || r.Shift == ShiftNone || r.Kind == LastBusinessDay {
+		return r.next(after)
+	}
+
+	day := dateOf(after, after.Location())
+	from := day
+	if r.Shift == ShiftNext {
+		// выплата, по расписанию уже прошедшая, могла переехать на дни после after
+		from = day.AddDate(0, 0, -maxShiftDays)
+	}
+	for d := r.next(from); ; d = r.next(d) {
+		if shifted := r.shift(d); shifted.After(day) {
+			return shifted
+		}
+	}
+}
+
+func (r Rule) calendar() *Calendar {
+	if r.Calendar != nil {
+		return r.Calendar
+	}
+	return DefaultCalendar
+}
+
+func (r Rule) shift(d time.Time) time.Time {
+	switch r.Shift {
+	case ShiftPrevious:
+		return r.calendar().PreviousBusinessDay(d)
+	case ShiftNext:
+		return r.calendar().NextBusinessDay(d)
+	default:
+		return d
+	}
+}
+
+// дата по расписанию без переноса
+func (r Rule) next(after time.Time) time.Time {
+	day := dateOf(after, after.Location())
+
+	switch r.Kind {
+	case Weekly:
+		return day.AddDate(0, 0, daysUntilWeekday(day, r.Day))
+
+	case Biweekly:
+		if r.Anchor.IsZero() {
+			return day.AddDate(0, 0, daysUntilWeekday(day, r.Day))
+		}
+		anchor := dateOf(r.Anchor, day.Location())
+		periods := floorDiv(daysBetween(anchor, day), 14) + 1
+		return anchor.AddDate(0, 0, periods*14)
+
+	case LastBusinessDay:
+		for i := 0; ; i++ {
+			last := monthDay(day.Year(), day.Month()+time.Month(i), 31, day.Location())
+			if d := r.calendar().PreviousBusinessDay(last); d.After(day) {
+				return d
+			}
+		}
+
+	case SemiMonthly:
+		for i := 0; ; i++ {
+			first := monthDay(day.Year(), day.Month()+time.Month(i), min(r.Day, r.SecondDay), day.Location())
+			second := monthDay(day.Year(), day.Month()+time.Month(i), max(r.Day, r.SecondDay), day.Location())
+			if first.After(day) {
+				return first
+			}
+			if second.After(day) {
+				return second
+			}
+		}
+
+	case Quarterly:
+		firstMonth := time.January
+		if !r.Anchor.IsZero() {
+			firstMonth = r.Anchor.Month()
+		}
+		for i := 0; ; i++ {
+			month := day.Month() + time.Month(i)
+			if (int(month)-int(firstMonth))%3 != 0 {
+				continue
+			}
+			if d := monthDay(day.Year(), month, r.Day, day.Location()); d.After(day) {
+				return d
+			}
+		}
+
+	default:
+		for i := 0; ; i++ {
+			if d := monthDay(day.Year(), day.Month()+time.Month(i), r.Day, day.Location()); d.After(day) {
+				return d
+			}
+		}
+	}
+}
+
+// Occurrences - все даты выплат в полуинтервале [from, to)
+func (r Rule) Occurrences(from, to time.Time) []time.Time {
+	var dates []time.Time
+	for d := r.Next(from.AddDate(0, 0, -1)); d.Before(to); d = r.Next(d) {
+		if r.Kind == Biweekly && r.Anchor.IsZero() {
+			r.Anchor = d
+		}
+		dates = append(dates, d)
+	}
+	return dates
+}
+
+// CountInMonth - сколько выплат приходится на месяц
+func (r Rule) CountInMonth(year int, month time.Month) int {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return len(r.Occurrences(start, start.AddDate(0, 1, 0)))
+}
+
+func dateOf(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// день месяца; если в месяце меньше дней - последний день
+func monthDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, loc)
+	if day > last.Day() {
+		return last
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// дней до следующего такого дня недели, от 1 до 7
+func daysUntilWeekday(day time.Time, weekday int) int {
+	days := (weekday - int(day.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return days
+}
+
+func daysBetween(from, to time.Time) int {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
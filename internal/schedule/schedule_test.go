@@ -0,0 +1,125 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestRuleNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  Rule
+		after time.Time
+		want  time.Time
+	}{
+		{"monthly later this month", Rule{Kind: Monthly, Day: 10}, date(2025, 12, 5), date(2025, 12, 10)},
+		{"monthly same day goes to next month", Rule{Kind: Monthly, Day: 10}, date(2025, 12, 10), date(2026, 1, 10)},
+		{"monthly time of day is ignored", Rule{Kind: Monthly, Day: 10}, time.Date(2025, 12, 9, 23, 59, 0, 0, time.UTC), date(2025, 12, 10)},
+		{"monthly clamps to short month", Rule{Kind: Monthly, Day: 31}, date(2026, 1, 31), date(2026, 2, 28)},
+		{"monthly clamps in leap year", Rule{Kind: Monthly, Day: 30}, date(2028, 1, 30), date(2028, 2, 29)},
+		{"monthly across year", Rule{Kind: Monthly, Day: 5}, date(2025, 12, 20), date(2026, 1, 5)},
+
+		{"last business day on friday", Rule{Kind: LastBusinessDay}, date(2026, 1, 1), date(2026, 1, 30)},
+		{"last business day is the last day", Rule{Kind: LastBusinessDay}, date(2025, 12, 1), date(2025, 12, 31)},
+		{"last business day after it passed", Rule{Kind: LastBusinessDay}, date(2026, 1, 30), date(2026, 2, 27)},
+
+		{"weekly later this week", Rule{Kind: Weekly, Day: 5}, date(2025, 12, 15), date(2025, 12, 19)},
+		{"weekly same weekday is next week", Rule{Kind: Weekly, Day: 1}, date(2025, 12, 15), date(2025, 12, 22)},
+		{"weekly sunday", Rule{Kind: Weekly, Day: 0}, date(2025, 12, 15), date(2025, 12, 21)},
+
+		{"biweekly before anchor", Rule{Kind: Biweekly, Anchor: date(2025, 12, 19)}, date(2025, 12, 1), date(2025, 12, 5)},
+		{"biweekly on anchor", Rule{Kind: Biweekly, Anchor: date(2025, 12, 19)}, date(2025, 12, 19), date(2026, 1, 2)},
+		{"biweekly skips off week", Rule{Kind: Biweekly, Anchor: date(2025, 12, 19)}, date(2025, 12, 26), date(2026, 1, 2)},
+		{"biweekly far after anchor", Rule{Kind: Biweekly, Anchor: date(2025, 1, 3)}, date(2025, 12, 20), date(2026, 1, 2)},
+		{"biweekly without anchor", Rule{Kind: Biweekly, Day: 5}, date(2025, 12, 15), date(2025, 12, 19)},
+
+		{"semimonthly first day", Rule{Kind: SemiMonthly, Day: 25, SecondDay: 10}, date(2025, 12, 1), date(2025, 12, 10)},
+		{"semimonthly second day", Rule{Kind: SemiMonthly, Day: 10, SecondDay: 25}, date(2025, 12, 10), date(2025, 12, 25)},
+		{"semimonthly next month", Rule{Kind: SemiMonthly, Day: 10, SecondDay: 25}, date(2025, 12, 25), date(2026, 1, 10)},
+		{"semimonthly clamps", Rule{Kind: SemiMonthly, Day: 15, SecondDay: 31}, date(2026, 2, 15), date(2026, 2, 28)},
+
+		{"quarterly from january", Rule{Kind: Quarterly, Day: 15}, date(2025, 12, 20), date(2026, 1, 15)},
+		{"quarterly same month", Rule{Kind: Quarterly, Day: 15}, date(2026, 4, 1), date(2026, 4, 15)},
+		{"quarterly anchored months", Rule{Kind: Quarterly, Day: 20, Anchor: date(2025, 2, 20)}, date(2025, 12, 1), date(2026, 2, 20)},
+		{"quarterly anchored same month passed", Rule{Kind: Quarterly, Day: 20, Anchor: date(2025, 2, 20)}, date(2025, 11, 25), date(2026, 2, 20)},
+		{"quarterly anchored next", Rule{Kind: Quarterly, Day: 20, Anchor: date(2025, 2, 20)}, date(2025, 11, 1), date(2025, 11, 20)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after.Format("2006-01-02"), got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestRuleNextKeepsLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+10", 10*60*60)
+	after := time.Date(2025, 12, 9, 23, 30, 0, 0, loc)
+
+	got := Rule{Kind: Monthly, Day: 10}.Next(after)
+	want := time.Date(2025, 12, 10, 0, 0, 0, 0, loc)
+	if !got.Equal(want) || got.Location() != loc {
+		t.Errorf("Next = %s, want %s", got, want)
+	}
+}
+
+func TestRuleCountInMonth(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  Rule
+		year  int
+		month time.Month
+		want  int
+	}{
+		{"monthly", Rule{Kind: Monthly, Day: 31}, 2026, time.February, 1},
+		{"last business day", Rule{Kind: LastBusinessDay}, 2026, time.May, 1},
+		{"weekly four", Rule{Kind: Weekly, Day: 1}, 2026, time.February, 4},
+		{"weekly five", Rule{Kind: Weekly, Day: 1}, 2025, time.December, 5},
+		{"biweekly anchored three", Rule{Kind: Biweekly, Anchor: date(2025, 12, 19)}, 2026, time.January, 3},
+		{"biweekly anchored two", Rule{Kind: Biweekly, Anchor: date(2025, 12, 19)}, 2026, time.February, 2},
+		{"biweekly without anchor", Rule{Kind: Biweekly, Day: 1}, 2025, time.December, 3},
+		{"semimonthly", Rule{Kind: SemiMonthly, Day: 10, SecondDay: 25}, 2026, time.March, 2},
+		{"quarterly in month", Rule{Kind: Quarterly, Day: 10, Anchor: date(2025, 3, 10)}, 2026, time.June, 1},
+		{"quarterly off month", Rule{Kind: Quarterly, Day: 10, Anchor: date(2025, 3, 10)}, 2026, time.July, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.CountInMonth(tt.year, tt.month); got != tt.want {
+				t.Errorf("CountInMonth(%d-%02d) = %d, want %d", tt.year, tt.month, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{"monthly", Rule{Kind: Monthly, Day: 10}, false},
+		{"monthly zero day", Rule{Kind: Monthly}, true},
+		{"weekly sunday", Rule{Kind: Weekly, Day: 0}, false},
+		{"weekly bad weekday", Rule{Kind: Weekly, Day: 7}, true},
+		{"biweekly anchored", Rule{Kind: Biweekly, Anchor: date(2025, 12, 19)}, false},
+		{"semimonthly", Rule{Kind: SemiMonthly, Day: 10, SecondDay: 25}, false},
+		{"semimonthly same days", Rule{Kind: SemiMonthly, Day: 10, SecondDay: 10}, true},
+		{"last business day", Rule{Kind: LastBusinessDay}, false},
+		{"unknown", Rule{Kind: "yearly", Day: 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
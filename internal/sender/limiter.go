@@ -0,0 +1,159 @@
+package sender
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// лимиты Telegram: около 30 сообщений в секунду на бота, 1 в секунду в личный чат и 20 в минуту в группу
+const (
+	globalInterval  = time.Second / 30
+	globalBurst     = 30
+	privateInterval = time.Second
+	privateBurst    = 3
+	groupInterval   = time.Minute / 20
+	groupBurst      = 3
+
+	// как часто выбрасывать корзины чатов, в которые давно ничего не отправлялось
+	sweepInterval = time.Minute
+)
+
+// bucket - корзина токенов в виде "времени, к которому корзина опустеет": сообщение можно отправить,
+// пока до этого времени меньше burst интервалов
+type bucket struct {
+	interval time.Duration
+	burst    int
+	tat      time.Time // theoretical arrival time следующего сообщения
+	paused   time.Time // до какого момента Telegram попросил не отправлять (retry_after)
+}
+
+func newBucket(interval time.Duration, burst int) *bucket {
+	return &bucket{interval: interval, burst: burst}
+}
+
+// reserve занимает место под одно сообщение не раньше at и возвращает, когда его можно отправить
+func (b *bucket) reserve(at time.Time) time.Time {
+	if at.Before(b.paused) {
+		at = b.paused
+	}
+	if b.tat.Before(at) {
+		b.tat = at
+	}
+
+	sendAt := b.tat.Add(-time.Duration(b.burst-1) * b.interval)
+	if sendAt.Before(at) {
+		sendAt = at
+	}
+	b.tat = b.tat.Add(b.interval)
+	return sendAt
+}
+
+func (b *bucket) pause(until time.Time) {
+	if until.After(b.paused) {
+		b.paused = until
+	}
+}
+
+// idle - корзина полная и не на паузе, ее можно удалить и потом создать заново
+func (b *bucket) idle(now time.Time) bool {
+	return !b.tat.After(now) && !b.paused.After(now)
+}
+
+// Limiter ограничивает исходящие сообщения общей корзиной и корзиной на каждый чат
+type Limiter struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	global    *bucket
+	chats     map[int64]*bucket
+	lastSweep time.Time
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{
+		now:    time.Now,
+		global: newBucket(globalInterval, globalBurst),
+		chats:  make(map[int64]*bucket),
+	}
+}
+
+// Wait ждет, пока в чат chatID можно отправить сообщение, не нарушая ни его лимит, ни общий.
+// chatID = 0 - запрос не к чату, учитывается только общий лимит
+func (l *Limiter) Wait(ctx context.Context, chatID int64) error {
+	// общий лимит занимаем только когда подошла очередь в чате, иначе ожидающий чат держал бы место у всех остальных
+	if chatID != 0 {
+		if err := sleep(ctx, l.reserveChat(chatID)); err != nil {
+			return err
+		}
+	}
+	return sleep(ctx, l.reserveGlobal())
+}
+
+func (l *Limiter) reserveChat(chatID int64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	return l.chat(chatID).reserve(now).Sub(now)
+}
+
+func (l *Limiter) reserveGlobal() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	return l.global.reserve(now).Sub(now)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pause - Telegram ответил 429: в этот чат ничего не отправляем retryAfter, остальные чаты не ждут
+func (l *Limiter) Pause(chatID int64, retryAfter time.Duration) {
+	if chatID == 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.chat(chatID).pause(l.now().Add(retryAfter))
+}
+
+func (l *Limiter) chat(chatID int64) *bucket {
+	b, ok := l.chats[chatID]
+	if !ok {
+		// отрицательные id - группы и каналы
+		if chatID < 0 {
+			b = newBucket(groupInterval, groupBurst)
+		} else {
+			b = newBucket(privateInterval, privateBurst)
+		}
+		l.chats[chatID] = b
+	}
+	return b
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for chatID, b := range l.chats {
+		if b.idle(now) {
+			delete(l.chats, chatID)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package sender
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestBucketReserve(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := newBucket(time.Second, 3)
+
+	// первые burst сообщений уходят сразу, дальше - по одному в интервал
+	want := []time.Duration{0, 0, 0, time.Second, 2 * time.Second}
+	for i, w := range want {
+		if got := b.reserve(start).Sub(start); got != w {
+			t.Errorf("message %d: delay = %s, want %s", i, got, w)
+		}
+	}
+
+	// за время простоя корзина снова наполняется
+	later := start.Add(time.Minute)
+	if got := b.reserve(later).Sub(later); got != 0 {
+		t.Errorf("after idle: delay = %s, want 0", got)
+	}
+	if !newBucket(time.Second, 3).idle(start) {
+		t.Error("new bucket is not idle")
+	}
+}
+
+func TestBucketPause(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := newBucket(time.Second, 3)
+
+	b.pause(start.Add(5 * time.Second))
+	if got := b.reserve(start).Sub(start); got != 5*time.Second {
+		t.Errorf("delay during pause = %s, want 5s", got)
+	}
+	if b.idle(start) {
+		t.Error("paused bucket is idle")
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter()
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < privateBurst; i++ {
+		if d := l.reserveChat(1); d != 0 {
+			t.Fatalf("message %d to chat 1: delay = %s, want 0", i, d)
+		}
+	}
+	if d := l.reserveChat(1); d != privateInterval {
+		t.Errorf("chat 1 over burst: delay = %s, want %s", d, privateInterval)
+	}
+
+	// другой чат не ждет первого
+	if d := l.reserveChat(2); d != 0 {
+		t.Errorf("chat 2: delay = %s, want 0", d)
+	}
+	// группа - свой лимит
+	if d := l.chat(-100).interval; d != groupInterval {
+		t.Errorf("group interval = %s, want %s", d, groupInterval)
+	}
+
+	l.Pause(2, 3*time.Second)
+	if d := l.reserveChat(2); d != 3*time.Second {
+		t.Errorf("chat 2 after 429: delay = %s, want 3s", d)
+	}
+	if d := l.reserveChat(3); d != 0 {
+		t.Errorf("chat 3 after 429 in chat 2: delay = %s, want 0", d)
+	}
+	// 429 на запрос не к чату не останавливает все чаты
+	l.Pause(0, time.Minute)
+	if d := l.reserveGlobal(); d != 0 {
+		t.Errorf("global after 429 without chat: delay = %s, want 0", d)
+	}
+
+	// общий лимит: сообщения во все чаты вместе упираются в него после globalBurst
+	l = NewLimiter()
+	l.now = func() time.Time { return now }
+	var last time.Duration
+	for i := 0; i <= globalBurst; i++ {
+		last = l.reserveGlobal()
+	}
+	if last != globalInterval {
+		t.Errorf("message over global burst: delay = %s, want %s", last, globalInterval)
+	}
+
+	// простаивающие чаты выбрасываются
+	l.reserveChat(5)
+	l.reserveChat(6)
+	now = now.Add(time.Hour)
+	l.reserveChat(1)
+	if len(l.chats) != 1 {
+		t.Errorf("chats after sweep = %d, want 1", len(l.chats))
+	}
+}
+
+func TestErrors(t *testing.T) {
+	flood := &tgbotapi.Error{Code: 429, Message: "Too Many Requests", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 7}}
+	if got := RetryAfter(flood); got != 7*time.Second {
+		t.Errorf("RetryAfter(429) = %s, want 7s", got)
+	}
+	if got := RetryAfter(errors.New("timeout")); got != 0 {
+		t.Errorf("RetryAfter(network error) = %s, want 0", got)
+	}
+
+	blocked := &tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}
+	if !IsPermanent(blocked) {
+		t.Error("403 is not permanent")
+	}
+	if IsPermanent(flood) || IsPermanent(errors.New("timeout")) {
+		t.Error("429 or network error is permanent")
+	}
+}
+
+func TestChatOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       tgbotapi.Chattable
+		chatID  int64
+		limited bool
+	}{
+		{"message", tgbotapi.NewMessage(42, "hi"), 42, true},
+		{"edit", tgbotapi.NewEditMessageText(42, 1, "hi"), 42, true},
+		{"delete", tgbotapi.NewDeleteMessage(42, 1), 42, false},
+		{"callback", tgbotapi.NewCallback("id", "ok"), 0, false},
+	}
+
+	for _, tt := range tests {
+		chatID, limited := chatOf(tt.c)
+		if chatID != tt.chatID || limited != tt.limited {
+			t.Errorf("chatOf(%s) = %d, %v; want %d, %v", tt.name, chatID, limited, tt.chatID, tt.limited)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// сколько раз пробовать отправить, если Telegram отвечает 429
+const maxSendAttempts = 3
+
+// Metrics - счетчики исходящих сообщений, доступны через expvar как "telegram_outbound"
+var Metrics = expvar.NewMap("telegram_outbound")
+
+const (
+	metricSent      = "sent"
+	metricThrottled = "throttled" // ответы 429 от Telegram
+	metricFailed    = "failed"    // не отправлено после всех попыток
+	metricQueued    = "queued"    // уведомление сохранено для повторной отправки
+	metricDropped   = "dropped"   // уведомление потеряно насовсем
+)
+
+// Sender отправляет запросы к Telegram с учетом лимитов; методы повторяют BotAPI, поэтому подменяет его там,
+// где бот что-то отправляет
+type Sender struct {
+	bot     *tgbotapi.BotAPI
+	limiter *Limiter
+}
+
+func New(bot *tgbotapi.BotAPI) *Sender {
+	return &Sender{
+		bot:     bot,
+		limiter: NewLimiter(),
+	}
+}
+
+func (s *Sender) Send(ctx context.Context, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	var msg tgbotapi.Message
+	err := s.do(ctx, c, func() error {
+		var err error
+		msg, err = s.bot.Send(c)
+		return err
+	})
+	return msg, err
+}
+
+func (s *Sender) Request(ctx context.Context, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	var resp *tgbotapi.APIResponse
+	err := s.do(ctx, c, func() error {
+		var err error
+		resp, err = s.bot.Request(c)
+		return err
+	})
+	return resp, err
+}
+
+// do ждет своей очереди по лимитам и отправляет; на 429 ставит чат на паузу и пробует снова.
+// Ожидание прерывается вместе с ctx - таймаутом обновления или остановкой бота
+func (s *Sender) do(ctx context.Context, c tgbotapi.Chattable, send func() error) error {
+	chatID, limited := chatOf(c)
+
+	var err error
+	for attempt := 1; attempt <= maxSendAttempts; attempt++ {
+		if limited {
+			if err := s.limiter.Wait(ctx, chatID); err != nil {
+				Metrics.Add(metricFailed, 1)
+				return err
+			}
+		}
+
+		err = send()
+		retryAfter := RetryAfter(err)
+		if retryAfter == 0 {
+			break
+		}
+
+		Metrics.Add(metricThrottled, 1)
+		log.Printf("[SENDER] Flood limit for chat %d, retry after %s (attempt %d/%d)", chatID, retryAfter, attempt, maxSendAttempts)
+		if attempt == maxSendAttempts {
+			break
+		}
+		// 429 в одном чате не повод останавливать остальные: пауза только для этого чата
+		if chatID != 0 {
+			s.limiter.Pause(chatID, retryAfter)
+		}
+		if !limited {
+			if err := sleep(ctx, retryAfter); err != nil {
+				Metrics.Add(metricFailed, 1)
+				return err
+			}
+		}
+	}
+
+	if err != nil {
+		Metrics.Add(metricFailed, 1)
+		return err
+	}
+	Metrics.Add(metricSent, 1)
+	return nil
+}
+
+// chatOf - чат запроса и расходует ли запрос его лимит. Удаления относятся к чату (429 на них ставит
+// чат на паузу), но лимит не тратят; ответы на кнопки и прочие запросы не к чату не ограничиваем
+func chatOf(c tgbotapi.Chattable) (int64, bool) {
+	switch c := c.(type) {
+	case tgbotapi.MessageConfig:
+		return c.ChatID, true
+	case tgbotapi.EditMessageTextConfig:
+		return c.ChatID, true
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return c.ChatID, true
+	case tgbotapi.DocumentConfig:
+		return c.ChatID, true
+	case tgbotapi.PhotoConfig:
+		return c.ChatID, true
+	case tgbotapi.DeleteMessageConfig:
+		return c.ChatID, false
+	}
+	return 0, false
+}
+
+// RetryAfter - сколько Telegram попросил подождать; 0, если ошибка не 429
+func RetryAfter(err error) time.Duration {
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests {
+		if apiErr.RetryAfter > 0 {
+			return time.Duration(apiErr.RetryAfter) * time.Second
+		}
+		return time.Second
+	}
+	return 0
+}
+
+// IsPermanent - повторная отправка не поможет: бот заблокирован, чат не найден, сообщение некорректно
+func IsPermanent(err error) bool {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == http.StatusBadRequest || apiErr.Code == http.StatusForbidden || apiErr.Code == http.StatusNotFound
+}
+
+func CountQueued() {
+	Metrics.Add(metricQueued, 1)
+}
+
+func CountDropped() {
+	Metrics.Add(metricDropped, 1)
+}
+
+// Summary - счетчики одной строкой для логов
+func Summary() string {
+	get := func(name string) int64 {
+		if v, ok := Metrics.Get(name).(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	return fmt.Sprintf("sent=%d throttled=%d failed=%d queued=%d dropped=%d",
+		get(metricSent), get(metricThrottled), get(metricFailed), get(metricQueued), get(metricDropped))
+}
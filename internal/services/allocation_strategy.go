@@ -0,0 +1,508 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+)
+
+// остаток до цели, при котором рекомендация на получку предлагает закрыть цель целиком; в рублях
+var paydayCloseGoalThreshold = models.MoneyFromMajor(5000)
+
+var (
+	ErrAllocationOver100 = errors.New("allocation percents exceed 100")
+	ErrDeadlineInPast    = errors.New("deadline must be in the future")
+)
+
+// AllocationGoal - цель с суммой, которую в нее еще можно положить; суммы в базовой валюте
+type AllocationGoal struct {
+	Goal     models.SavingsGoal
+	Capacity models.Money
+}
+
+// AllocationStrategy делит свободные средства между целями, отсортированными по приоритету.
+// Результат выровнен по goals: каждая доля не больше Capacity, сумма не больше available.
+type AllocationStrategy interface {
+	Allocate(available models.Money, goals []AllocationGoal, now time.Time) []models.Money
+}
+
+var allocationStrategies = map[string]AllocationStrategy{
+	models.AllocationPriorityWeighted: PriorityWeightedStrategy{},
+	models.AllocationWaterfall:        WaterfallStrategy{},
+	models.AllocationEqual:            EqualSplitStrategy{},
+	models.AllocationDeadline:         DeadlineStrategy{},
+	models.AllocationFixedPercent:     FixedPercentStrategy{},
+}
+
+// AllocationStrategyByName возвращает стратегию по коду; неизвестный код - стратегия по приоритетам
+func AllocationStrategyByName(name string) AllocationStrategy {
+	if strategy, ok := allocationStrategies[name]; ok {
+		return strategy
+	}
+	return PriorityWeightedStrategy{}
+}
+
+func IsAllocationStrategy(name string) bool {
+	_, ok := allocationStrategies[name]
+	return ok
+}
+
+// PriorityWeightedStrategy - треугольные веса n, n-1, ..., 1 по приоритету;
+// то, что не помещается в цель, переходит к следующим целям пропорционально их весам
+type PriorityWeightedStrategy struct{}
+
+func (PriorityWeightedStrategy) Allocate(available models.Money, goals []AllocationGoal, _ time.Time) []models.Money {
+	allocated := make([]models.Money, len(goals))
+	if available <= 0 || len(goals) == 0 {
+		return allocated
+	}
+
+	n := int64(len(goals))
+	weights := make([]int64, len(goals))
+	for i := range goals {
+		weights[i] = n - int64(i)
+	}
+
+	shared := models.Money(0)
+	for i := range goals {
+		allocated[i] = available.MulDiv(weights[i], n*(n+1)/2)
+		shared += allocated[i]
+	}
+	// копейки, оставшиеся от деления, достаются цели с высшим приоритетом
+	allocated[0] += available - shared
+
+	for i := range goals {
+		if allocated[i] <= goals[i].Capacity {
+			continue
+		}
+
+		excess := allocated[i] - goals[i].Capacity
+		allocated[i] = goals[i].Capacity
+
+		sumLowerWeights := int64(0)
+		for j := i + 1; j < len(goals); j++ {
+			sumLowerWeights += weights[j]
+		}
+		if sumLowerWeights == 0 {
+			continue
+		}
+
+		distributed := models.Money(0)
+		for j := i + 1; j < len(goals); j++ {
+			added := excess.MulDiv(weights[j], sumLowerWeights)
+			allocated[j] += added
+			distributed += added
+		}
+		// остаток от деления - следующей по приоритету цели
+		allocated[i+1] += excess - distributed
+	}
+
+	return allocated
+}
+
+// WaterfallStrategy заполняет цели строго по очереди: следующая получает деньги, только когда предыдущая заполнена
+type WaterfallStrategy struct{}
+
+func (WaterfallStrategy) Allocate(available models.Money, goals []AllocationGoal, _ time.Time) []models.Money {
+	allocated := make([]models.Money, len(goals))
+	fillInOrder(available, goals, allocated)
+	return allocated
+}
+
+// EqualSplitStrategy делит поровну; доля заполненной цели делится между остальными
+type EqualSplitStrategy struct{}
+
+func (EqualSplitStrategy) Allocate(available models.Money, goals []AllocationGoal, _ time.Time) []models.Money {
+	allocated := make([]models.Money, len(goals))
+
+	for available > 0 {
+		var open []int
+		for i := range goals {
+			if allocated[i] < goals[i].Capacity {
+				open = append(open, i)
+			}
+		}
+		if len(open) == 0 {
+			break
+		}
+
+		share := available / models.Money(len(open))
+		remainder := available % models.Money(len(open))
+
+		spent := models.Money(0)
+		for k, i := range open {
+			amount := share
+			if models.Money(k) < remainder {
+				amount++
+			}
+			if free := goals[i].Capacity - allocated[i]; amount > free {
+				amount = free
+			}
+			allocated[i] += amount
+			spent += amount
+		}
+
+		if spent == 0 {
+			break
+		}
+		available -= spent
+	}
+
+	return allocated
+}
+
+// DeadlineStrategy сначала дает каждой цели сумму, нужную для достижения к сроку,
+// а остаток распределяет по приоритетам
+type DeadlineStrategy struct{}
+
+func (DeadlineStrategy) Allocate(available models.Money, goals []AllocationGoal, now time.Time) []models.Money {
+	allocated := reserveRequired(available, goals, now)
+	for i := range allocated {
+		available -= allocated[i]
+	}
+	fillInOrder(available, goals, allocated)
+	return allocated
+}
+
+// FixedPercentStrategy отдает каждой цели заданный процент свободных средств; нераспределенный процент остается свободным
+type FixedPercentStrategy struct{}
+
+func (FixedPercentStrategy) Allocate(available models.Money, goals []AllocationGoal, _ time.Time) []models.Money {
+	allocated := make([]models.Money, len(goals))
+	if available <= 0 {
+		return allocated
+	}
+
+	for i, g := range goals {
+		amount := available.MulDiv(int64(g.Goal.AllocationPercent), 100)
+		if amount > g.Capacity {
+			amount = g.Capacity
+		}
+		allocated[i] = amount
+	}
+	return allocated
+}
+
+// дозаполняет цели по порядку и возвращает остаток
+func fillInOrder(available models.Money, goals []AllocationGoal, allocated []models.Money) models.Money {
+	for i := range goals {
+		if available <= 0 {
+			break
+		}
+		amount := goals[i].Capacity - allocated[i]
+		if amount <= 0 {
+			continue
+		}
+		if amount > available {
+			amount = available
+		}
+		allocated[i] += amount
+		available -= amount
+	}
+	return available
+}
+
+// RequiredMonthlyAmount - сколько нужно откладывать в месяц, чтобы успеть к сроку; для целей без срока - 0
+func RequiredMonthlyAmount(goal models.SavingsGoal, now time.Time) models.Money {
+	remaining := goal.TargetAmount - goal.CurrentAmount
+	if !goal.Deadline.Valid || remaining <= 0 {
+		return 0
+	}
+
+	months := monthsUntil(now, goal.Deadline.Time)
+	required := remaining / models.Money(months)
+	if remaining%models.Money(months) != 0 {
+		required++
+	}
+	return required
+}
+
+// DeadlineShortfall - сколько не хватит к сроку, если откладывать по текущему месячному плану;
+// now - время в зоне пользователя
+func DeadlineShortfall(goal models.SavingsGoal, now time.Time) models.Money {
+	remaining := goal.TargetAmount - goal.CurrentAmount
+	if !goal.Deadline.Valid || remaining <= 0 {
+		return 0
+	}
+
+	if goal.Deadline.Time.Before(models.DayStart(now)) {
+		return remaining
+	}
+
+	// в текущем месяце осталось внести только то, что еще не внесено по плану
+	planned := goal.MonthlyBudgetLimit - goal.MonthlyAccumulated
+	if planned < 0 {
+		planned = 0
+	}
+	if months := monthsUntil(now, goal.Deadline.Time); months > 1 {
+		planned += goal.MonthlyContrib * models.Money(months-1)
+	}
+
+	if planned >= remaining {
+		return 0
+	}
+	return remaining - planned
+}
+
+// обязательные взносы целей со сроком: от ближайшего срока к дальнему, каждый не больше Capacity
+func reserveRequired(available models.Money, goals []AllocationGoal, now time.Time) []models.Money {
+	order := make([]int, len(goals))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		da, db := goals[order[a]].Goal.Deadline, goals[order[b]].Goal.Deadline
+		if da.Valid != db.Valid {
+			return da.Valid
+		}
+		return da.Valid && da.Time.Before(db.Time)
+	})
+
+	needed := make([]AllocationGoal, len(goals))
+	for i, idx := range order {
+		g := goals[idx]
+		needed[i] = AllocationGoal{Goal: g.Goal, Capacity: min(RequiredMonthlyAmount(g.Goal, now), g.Capacity)}
+	}
+	filled := make([]models.Money, len(goals))
+	fillInOrder(available, needed, filled)
+
+	reserved := make([]models.Money, len(goals))
+	for i, idx := range order {
+		reserved[idx] = filled[i]
+	}
+	return reserved
+}
+
+// цели со сроком получают обязательный взнос раньше стратегии: от ближайшего срока к дальнему.
+// Стратегия делит остаток с учетом уже зарезервированного; срок у целей для нее снят,
+// чтобы стратегия по срокам не резервировала взнос второй раз
+func allocateWithDeadlines(strategy AllocationStrategy, available models.Money, goals []AllocationGoal, now time.Time) []models.Money {
+	reserved := reserveRequired(available, goals, now)
+
+	rest := make([]AllocationGoal, len(goals))
+	for i, g := range goals {
+		rest[i] = AllocationGoal{Goal: g.Goal, Capacity: g.Capacity - reserved[i]}
+		rest[i].Goal.Deadline.Valid = false
+		available -= reserved[i]
+	}
+	allocated := strategy.Allocate(available, rest, now)
+	for i := range allocated {
+		allocated[i] += reserved[i]
+	}
+	return allocated
+}
+
+// число месяцев до даты, включая текущий; не меньше одного
+func monthsUntil(now, date time.Time) int {
+	months := (date.Year()-now.Year())*12 + int(date.Month()-now.Month())
+	if date.Day() >= now.Day() {
+		months++
+	}
+	if months < 1 {
+		months = 1
+	}
+	return months
+}
+
+func (s *FinanceService) GetAllocationStrategy(ctx context.Context, telegramID int64) (string, error) {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return "", fmt.Errorf("user not found: %w", err)
+	}
+	return user.AllocationStrategy, nil
+}
+
+func (s *FinanceService) SetAllocationStrategy(ctx context.Context, telegramID int64, strategy string) error {
+	if !IsAllocationStrategy(strategy) {
+		return fmt.Errorf("unknown allocation strategy %q", strategy)
+	}
+
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+
+	if err := s.userRepo.UpdateAllocationStrategy(ctx, user.ID, strategy); err != nil {
+		return fmt.Errorf("failed to update allocation strategy: %w", err)
+	}
+
+	log.Printf("[ALLOCATION] User %d switched to %s", user.ID, strategy)
+
+	_, err = s.DistributeFundsToGoals(ctx, telegramID)
+	if err != nil {
+		log.Printf("Failed to distribute funds after changing strategy: %v", err)
+	}
+	return nil
+}
+
+// процент для стратегии фиксированных долей; сумма процентов по активным целям не больше 100
+func (s *FinanceService) SetGoalAllocationPercent(ctx context.Context, telegramID int64, goalID int64, percent int) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("percent must be between 0 and 100")
+	}
+
+	goal, err := s.GetUserGoalByID(ctx, telegramID, goalID)
+	if err != nil {
+		return err
+	}
+
+	goals, err := s.goalRepo.GetUserActiveGoals(ctx, goal.UserID)
+	if err != nil {
+		return err
+	}
+	total := percent
+	for _, g := range goals {
+		if g.ID != goalID {
+			total += g.AllocationPercent
+		}
+	}
+	if total > 100 {
+		return fmt.Errorf("%w: %d%%", ErrAllocationOver100, total)
+	}
+
+	if err := s.goalRepo.UpdateAllocationPercent(ctx, goalID, percent); err != nil {
+		return fmt.Errorf("failed to update allocation percent: %w", err)
+	}
+
+	_, err = s.DistributeFundsToGoals(ctx, telegramID)
+	if err != nil {
+		log.Printf("Failed to distribute funds after changing percent: %v", err)
+	}
+	return nil
+}
+
+// переводит цели в базовую валюту; порядок сохраняется
+func goalsInCurrency(converter *CurrencyConverter, goals []models.SavingsGoal, currency string) ([]models.SavingsGoal, error) {
+	converted := make([]models.SavingsGoal, len(goals))
+	for i, goal := range goals {
+		c := goal
+		for _, amount := range []*models.Money{&c.TargetAmount, &c.CurrentAmount, &c.MonthlyContrib, &c.MonthlyBudgetLimit, &c.MonthlyAccumulated} {
+			var err error
+			if *amount, err = converter.Convert(*amount, goal.Currency, currency); err != nil {
+				return nil, err
+			}
+		}
+		c.Currency = currency
+		converted[i] = c
+	}
+	return converted, nil
+}
+
+// месячный план по стратегии пользователя: цели должны быть отсортированы по приоритету,
+// результат - в базовой валюте
+func (s *FinanceService) allocateMonthly(user *models.User, converter *CurrencyConverter, goals []models.SavingsGoal, available models.Money) ([]models.Money, error) {
+	baseGoals, err := goalsInCurrency(converter, goals, user.BaseCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	input := make([]AllocationGoal, len(baseGoals))
+	for i, goal := range baseGoals {
+		input[i] = AllocationGoal{Goal: goal}
+		if goal.Status == "active" && goal.TargetAmount > goal.CurrentAmount {
+			input[i].Capacity = goal.TargetAmount - goal.CurrentAmount
+		}
+	}
+
+	strategy := AllocationStrategyByName(user.AllocationStrategy)
+	return allocateWithDeadlines(strategy, available, input, user.Now()), nil
+}
+
+// рекомендации по поступлению: доход делится по стратегии пользователя в пределах
+// остатка месячного плана каждой цели; почти достигнутую цель предлагаем закрыть целиком.
+// у дохода частями делится только пришедшая в payDate часть, а план ограничивается ее долей,
+// чтобы аванс не забирал весь месячный план
+func (s *FinanceService) paydayRecommendations(
+	ctx context.Context,
+	income models.Income,
+	payDate time.Time,
+	amount models.Money,
+	goals []models.SavingsGoal,
+	contributedMap map[int64]models.Money,
+) (map[int64]models.Money, error) {
+	user, err := s.userRepo.GetUserByID(ctx, income.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	converter, err := s.CurrencyConverter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return paydayAllocation(converter, user, user.Now(), income, payDate, amount, goals, contributedMap)
+}
+
+func paydayAllocation(
+	converter *CurrencyConverter,
+	user *models.User,
+	now time.Time,
+	income models.Income,
+	payDate time.Time,
+	amount models.Money,
+	goals []models.SavingsGoal,
+	contributedMap map[int64]models.Money,
+) (map[int64]models.Money, error) {
+	base := user.BaseCurrency
+	incomeAmount, err := converter.Convert(amount, income.Currency, base)
+	if err != nil {
+		return nil, err
+	}
+	closeGoalThreshold, err := converter.Convert(paydayCloseGoalThreshold, "RUB", base)
+	if err != nil {
+		return nil, err
+	}
+
+	planNum, planDen := int64(1), int64(1)
+	if k := income.InstallmentOn(payDate); k >= 0 {
+		planNum, planDen = installmentPlanShare(income, k)
+	}
+
+	sorted := make([]models.SavingsGoal, len(goals))
+	copy(sorted, goals)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Priority < sorted[j].Priority })
+
+	baseGoals, err := goalsInCurrency(converter, sorted, base)
+	if err != nil {
+		return nil, err
+	}
+
+	input := make([]AllocationGoal, len(baseGoals))
+	for i, goal := range baseGoals {
+		contributed, err := converter.Convert(contributedMap[goal.ID], sorted[i].Currency, base)
+		if err != nil {
+			return nil, err
+		}
+
+		remainingToTarget := goal.TargetAmount - goal.CurrentAmount
+		if remainingToTarget < 0 {
+			remainingToTarget = 0
+		}
+
+		capacity := goal.MonthlyBudgetLimit.MulDiv(planNum, planDen) - contributed
+		if capacity < 0 {
+			capacity = 0
+		}
+		if capacity > remainingToTarget || remainingToTarget <= closeGoalThreshold {
+			capacity = remainingToTarget
+		}
+
+		input[i] = AllocationGoal{Goal: goal, Capacity: capacity}
+	}
+
+	allocated := allocateWithDeadlines(AllocationStrategyByName(user.AllocationStrategy), incomeAmount, input, now)
+
+	recommendations := make(map[int64]models.Money, len(sorted))
+	for i, goal := range sorted {
+		if recommendations[goal.ID], err = converter.Convert(allocated[i], base, goal.Currency); err != nil {
+			return nil, err
+		}
+	}
+
+	return recommendations, nil
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"database/sql"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+)
+
+var allocationNow = time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func open(capacity models.Money) AllocationGoal {
+	return AllocationGoal{Capacity: capacity}
+}
+
+// цель со сроком: нужно собрать target к deadline, положить можно capacity
+func dated(target models.Money, deadline time.Time, capacity models.Money) AllocationGoal {
+	return AllocationGoal{
+		Goal:     models.SavingsGoal{TargetAmount: target, Deadline: sql.NullTime{Time: deadline, Valid: true}},
+		Capacity: capacity,
+	}
+}
+
+func percent(p int, capacity models.Money) AllocationGoal {
+	return AllocationGoal{Goal: models.SavingsGoal{AllocationPercent: p}, Capacity: capacity}
+}
+
+type allocationCase struct {
+	name      string
+	available models.Money
+	goals     []AllocationGoal
+	want      []models.Money
+}
+
+func runAllocationCases(t *testing.T, strategy AllocationStrategy, tests []allocationCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strategy.Allocate(tt.available, tt.goals, allocationNow)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Allocate(%d) = %v, want %v", tt.available, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriorityWeightedStrategy(t *testing.T) {
+	runAllocationCases(t, PriorityWeightedStrategy{}, []allocationCase{
+		{"no goals", 1000, nil, []models.Money{}},
+		{"nothing available", 0, []AllocationGoal{open(100), open(100)}, []models.Money{0, 0}},
+		{"weights 3:2:1, remainder kopeck to first", 100, []AllocationGoal{open(1000), open(1000), open(1000)}, []models.Money{51, 33, 16}},
+		{"excess of full goal goes to lower ones", 600, []AllocationGoal{open(100), open(1000), open(1000)}, []models.Money{100, 334, 166}},
+		{"all goals full, rest stays free", 1000, []AllocationGoal{open(100), open(50)}, []models.Money{100, 50}},
+	})
+}
+
+func TestWaterfallStrategy(t *testing.T) {
+	runAllocationCases(t, WaterfallStrategy{}, []allocationCase{
+		{"no goals", 1000, nil, []models.Money{}},
+		{"fills in order", 250, []AllocationGoal{open(100), open(200), open(300)}, []models.Money{100, 150, 0}},
+		{"all goals full", 1000, []AllocationGoal{open(100), open(200), open(300)}, []models.Money{100, 200, 300}},
+		{"skips goal without capacity", 50, []AllocationGoal{open(0), open(100)}, []models.Money{0, 50}},
+	})
+}
+
+func TestEqualSplitStrategy(t *testing.T) {
+	runAllocationCases(t, EqualSplitStrategy{}, []allocationCase{
+		{"no goals", 1000, nil, []models.Money{}},
+		{"remainder kopeck to first", 100, []AllocationGoal{open(1000), open(1000), open(1000)}, []models.Money{34, 33, 33}},
+		{"share of full goal is split between others", 100, []AllocationGoal{open(10), open(1000), open(1000)}, []models.Money{10, 45, 45}},
+		{"all goals full", 100, []AllocationGoal{open(10), open(20)}, []models.Money{10, 20}},
+	})
+}
+
+func TestDeadlineStrategy(t *testing.T) {
+	// 1200 за 6 месяцев - 200 в месяц, 300 за 3 месяца - 100 в месяц
+	june := dated(1200, date(2026, 6, 15), 1200)
+	march := dated(300, date(2026, 3, 15), 300)
+
+	runAllocationCases(t, DeadlineStrategy{}, []allocationCase{
+		{"no goals", 1000, nil, []models.Money{}},
+		{"required amount first, rest by priority", 500, []AllocationGoal{open(10000), june}, []models.Money{300, 200}},
+		{"not enough for required amount", 150, []AllocationGoal{open(10000), june}, []models.Money{0, 150}},
+		{"nearest deadline reserved first", 250, []AllocationGoal{open(10000), june, march}, []models.Money{0, 150, 100}},
+		{"all goals full", 20000, []AllocationGoal{open(1000), june}, []models.Money{1000, 1200}},
+		{"without deadlines works as waterfall", 250, []AllocationGoal{open(100), open(200)}, []models.Money{100, 150}},
+	})
+}
+
+func TestFixedPercentStrategy(t *testing.T) {
+	runAllocationCases(t, FixedPercentStrategy{}, []allocationCase{
+		{"no goals", 1000, nil, []models.Money{}},
+		{"nothing available", 0, []AllocationGoal{percent(50, 1000)}, []models.Money{0}},
+		{"kopecks are rounded down, rest stays free", 1001, []AllocationGoal{percent(50, 1000), percent(30, 1000)}, []models.Money{500, 300}},
+		{"capped by capacity", 1000, []AllocationGoal{percent(50, 100)}, []models.Money{100}},
+	})
+}
+
+func TestAllocateWithDeadlines(t *testing.T) {
+	june := dated(1200, date(2026, 6, 15), 1200)
+
+	tests := []struct {
+		name      string
+		strategy  AllocationStrategy
+		available models.Money
+		goals     []AllocationGoal
+		want      []models.Money
+	}{
+		{"no goals", WaterfallStrategy{}, 1000, nil, []models.Money{}},
+		{"required amount before strategy", WaterfallStrategy{}, 500, []AllocationGoal{open(10000), june}, []models.Money{300, 200}},
+		{"deadline strategy does not reserve twice", DeadlineStrategy{}, 500, []AllocationGoal{open(10000), june}, []models.Money{300, 200}},
+		{"required amount capped by capacity", WaterfallStrategy{}, 500, []AllocationGoal{open(10000), dated(1200, date(2026, 6, 15), 50)}, []models.Money{450, 50}},
+		{"strategy shares the rest", EqualSplitStrategy{}, 400, []AllocationGoal{open(10000), june}, []models.Money{100, 300}},
+		{"all goals full", PriorityWeightedStrategy{}, 20000, []AllocationGoal{open(1000), june}, []models.Money{1000, 1200}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := allocateWithDeadlines(tt.strategy, tt.available, tt.goals, allocationNow)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("allocateWithDeadlines(%d) = %v, want %v", tt.available, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequiredMonthlyAmount(t *testing.T) {
+	tests := []struct {
+		name string
+		goal models.SavingsGoal
+		want models.Money
+	}{
+		{"no deadline", models.SavingsGoal{TargetAmount: 1000}, 0},
+		{"already reached", deadlineGoal(1000, 1200, date(2026, 6, 15)), 0},
+		{"even split over 6 months", deadlineGoal(1200, 0, date(2026, 6, 15)), 200},
+		{"remainder kopeck rounds up", deadlineGoal(1000, 0, date(2026, 6, 15)), 167},
+		{"counts what is already saved", deadlineGoal(1200, 600, date(2026, 6, 15)), 100},
+		{"deadline later this month", deadlineGoal(1000, 0, date(2026, 1, 20)), 1000},
+		{"deadline day before today's day next month", deadlineGoal(1000, 0, date(2026, 2, 10)), 1000},
+		{"deadline passed", deadlineGoal(1000, 0, date(2025, 12, 1)), 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RequiredMonthlyAmount(tt.goal, allocationNow); got != tt.want {
+				t.Errorf("RequiredMonthlyAmount = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func deadlineGoal(target, current models.Money, deadline time.Time) models.SavingsGoal {
+	return models.SavingsGoal{
+		TargetAmount:  target,
+		CurrentAmount: current,
+		Deadline:      sql.NullTime{Time: deadline, Valid: true},
+	}
+}
+
+func TestDeadlineShortfall(t *testing.T) {
+	planned := func(limit, accumulated, contrib models.Money) models.SavingsGoal {
+		g := deadlineGoal(1200, 0, date(2026, 6, 15))
+		g.MonthlyBudgetLimit, g.MonthlyAccumulated, g.MonthlyContrib = limit, accumulated, contrib
+		return g
+	}
+	// срок - дата без времени, в сам день срока он еще не прошел
+	dueToday := deadlineGoal(1000, 0, date(2026, 1, 15))
+	dueToday.MonthlyBudgetLimit = 1000
+
+	tests := []struct {
+		name string
+		goal models.SavingsGoal
+		want models.Money
+	}{
+		{"no deadline", models.SavingsGoal{TargetAmount: 1000}, 0},
+		{"already reached", deadlineGoal(1000, 1500, date(2026, 6, 15)), 0},
+		{"deadline passed", deadlineGoal(1000, 200, date(2026, 1, 10)), 800},
+		{"deadline is today", dueToday, 0},
+		{"plan is enough", planned(200, 0, 200), 0},
+		{"plan is short", planned(100, 0, 100), 600},
+		{"month plan already exceeded", planned(100, 150, 100), 700},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DeadlineShortfall(tt.goal, allocationNow); got != tt.want {
+				t.Errorf("DeadlineShortfall = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaydayAllocation(t *testing.T) {
+	rub := models.MoneyFromMajor
+	converter := &CurrencyConverter{rates: map[string]float64{"USD": 100}}
+
+	goal := func(id int64, currency string, target, current, limit models.Money) models.SavingsGoal {
+		return models.SavingsGoal{ID: id, Priority: int(id), Currency: currency, TargetAmount: target, CurrentAmount: current, MonthlyBudgetLimit: limit}
+	}
+	monthly := models.Income{Currency: "RUB", Frequency: "monthly", RecurringDay: 10, Amount: rub(100000)}
+	installments := models.Income{
+		Currency:  "RUB",
+		Frequency: models.IncomeFrequencyInstallments,
+		Amount:    rub(100000),
+		Installments: []models.IncomeInstallment{
+			{Day: 10, Percent: 40},
+			{Day: 25, Percent: 60},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		base        string
+		strategy    string
+		income      models.Income
+		amount      models.Money
+		goals       []models.SavingsGoal
+		contributed map[int64]models.Money
+		want        map[int64]models.Money
+	}{
+		{
+			name:     "no goals",
+			strategy: models.AllocationWaterfall,
+			income:   monthly,
+			amount:   rub(10000),
+			want:     map[int64]models.Money{},
+		},
+		{
+			name:        "limited by what is left of the month plan",
+			strategy:    models.AllocationWaterfall,
+			income:      monthly,
+			amount:      rub(15000),
+			goals:       []models.SavingsGoal{goal(2, "RUB", rub(100000), 0, rub(10000)), goal(1, "RUB", rub(100000), 0, rub(10000))},
+			contributed: map[int64]models.Money{1: rub(4000)},
+			want:        map[int64]models.Money{1: rub(6000), 2: rub(9000)},
+		},
+		{
+			name:     "nearly reached goal is offered in full",
+			strategy: models.AllocationWaterfall,
+			income:   monthly,
+			amount:   rub(10000),
+			goals:    []models.SavingsGoal{goal(1, "RUB", rub(100000), rub(97000), rub(1000))},
+			want:     map[int64]models.Money{1: rub(3000)},
+		},
+		{
+			name:     "reached goal gets nothing",
+			strategy: models.AllocationWaterfall,
+			income:   monthly,
+			amount:   rub(10000),
+			goals:    []models.SavingsGoal{goal(1, "RUB", rub(1000), rub(1500), rub(1000)), goal(2, "RUB", rub(100000), 0, rub(2000))},
+			want:     map[int64]models.Money{1: 0, 2: rub(2000)},
+		},
+		{
+			name:     "remainder kopeck to the first goal",
+			strategy: models.AllocationEqual,
+			income:   monthly,
+			amount:   10001,
+			goals:    []models.SavingsGoal{goal(1, "RUB", rub(100000), 0, rub(10000)), goal(2, "RUB", rub(100000), 0, rub(10000))},
+			want:     map[int64]models.Money{1: 5001, 2: 5000},
+		},
+		{
+			name:     "goal in another currency",
+			strategy: models.AllocationWaterfall,
+			income:   monthly,
+			amount:   rub(10000),
+			goals:    []models.SavingsGoal{goal(1, "RUB", rub(100000), 0, rub(3000)), goal(2, "USD", models.MoneyFromMajor(1000), 0, models.MoneyFromMajor(50))},
+			want:     map[int64]models.Money{1: rub(3000), 2: models.MoneyFromMajor(50)},
+		},
+		{
+			name:     "close threshold is in rubles for another base currency",
+			base:     "USD",
+			strategy: models.AllocationWaterfall,
+			income:   models.Income{Currency: "USD", Frequency: "monthly", RecurringDay: 10, Amount: models.MoneyFromMajor(1000)},
+			amount:   models.MoneyFromMajor(1000),
+			goals: []models.SavingsGoal{
+				goal(1, "USD", models.MoneyFromMajor(3000), 0, models.MoneyFromMajor(100)),
+				goal(2, "USD", models.MoneyFromMajor(1040), models.MoneyFromMajor(1000), models.MoneyFromMajor(10)),
+			},
+			want: map[int64]models.Money{1: models.MoneyFromMajor(100), 2: models.MoneyFromMajor(40)},
+		},
+		{
+			name:     "advance takes only its share of the plan",
+			strategy: models.AllocationWaterfall,
+			income:   installments,
+			amount:   rub(40000),
+			goals:    []models.SavingsGoal{goal(1, "RUB", rub(100000), 0, rub(10000))},
+			want:     map[int64]models.Money{1: rub(4000)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := tt.base
+			if base == "" {
+				base = "RUB"
+			}
+			user := &models.User{BaseCurrency: base, AllocationStrategy: tt.strategy}
+			got, err := paydayAllocation(converter, user, allocationNow, tt.income, date(2026, 2, 10), tt.amount, tt.goals, tt.contributed)
+			if err != nil {
+				t.Fatalf("paydayAllocation: %v", err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("paydayAllocation = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,9 +3,12 @@ package services
 import (
 	"context"
 	"crypto/sha256"
+	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/Lina3386/telegram-bot/internal/models"
+	"log"
 	"time"
 
 	"github.com/Lina3386/telegram-bot/internal/repository"
@@ -19,25 +22,36 @@ func NewAuthService(repo *repository.UserRepository) *AuthService {
 	return &AuthService{repo: repo}
 }
 
-func (s *AuthService) RegisterTelegramUser(ctx context.Context, telegramID int64, username string) (string, error) {
-	existingUser, _ := s.repo.GetUserByTelegramID(ctx, telegramID)
-	if existingUser != nil {
-		return s.generateToken(existingUser.ID)
+// EnsureTelegramUser возвращает пользователя по telegram id и регистрирует его, если он пишет впервые.
+// created - пользователь зарегистрирован этим вызовом
+func (s *AuthService) EnsureTelegramUser(ctx context.Context, telegramID int64, username string) (user *models.User, created bool, err error) {
+	user, err = s.repo.GetUserByTelegramID(ctx, telegramID)
+	if err == nil {
+		return user, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
 	}
 
-	user := &models.User{
+	token, err := s.generateToken(telegramID)
+	if err != nil {
+		return nil, false, err
+	}
+	user, err = s.repo.CreateUser(ctx, &models.User{
 		TelegramID: telegramID,
 		Username:   username,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
-	}
-
-	_, err := s.repo.CreateUser(ctx, user)
+		AuthToken:  token,
+	})
 	if err != nil {
-		return "", err
+		// параллельное обновление могло зарегистрировать его раньше
+		if existing, errGet := s.repo.GetUserByTelegramID(ctx, telegramID); errGet == nil {
+			return existing, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to register user: %w", err)
 	}
 
-	return s.generateToken(user.ID)
+	log.Printf("[AUTH] Registered user %d (telegram %d, %s)", user.ID, telegramID, username)
+	return user, true, nil
 }
 
 func (s *AuthService) generateToken(userID int64) (string, error) {
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+)
+
+var ErrNoExchangeRate = errors.New("no exchange rate")
+
+// CurrencyConverter пересчитывает суммы по таблице курсов; курсы заданы в рублях за единицу валюты
+type CurrencyConverter struct {
+	rates map[string]float64
+}
+
+func (c *CurrencyConverter) rate(currency string) (float64, error) {
+	if currency == "" || currency == "RUB" {
+		return 1, nil
+	}
+	rate, ok := c.rates[currency]
+	if !ok {
+		return 0, fmt.Errorf("%w for %s", ErrNoExchangeRate, currency)
+	}
+	return rate, nil
+}
+
+func (c *CurrencyConverter) Convert(amount models.Money, from, to string) (models.Money, error) {
+	if from == to || amount == 0 {
+		return amount, nil
+	}
+
+	fromRate, err := c.rate(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := c.rate(to)
+	if err != nil {
+		return 0, err
+	}
+
+	return amount.MulFloat(fromRate / toRate), nil
+}
+
+func (s *FinanceService) CurrencyConverter(ctx context.Context) (*CurrencyConverter, error) {
+	rates, err := s.rateRepo.GetRates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rates: %w", err)
+	}
+
+	converter := &CurrencyConverter{rates: make(map[string]float64, len(rates))}
+	for _, rate := range rates {
+		converter.rates[rate.Currency] = rate.Rate
+	}
+	return converter, nil
+}
+
+func (s *FinanceService) SetExchangeRate(ctx context.Context, currency string, rate float64) error {
+	code, ok := models.NormalizeCurrency(currency)
+	if !ok {
+		return fmt.Errorf("invalid currency %q", currency)
+	}
+	if code == "RUB" {
+		return fmt.Errorf("RUB rate is fixed to 1")
+	}
+	if rate <= 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return fmt.Errorf("rate must be positive")
+	}
+
+	if err := s.rateRepo.SetRate(ctx, code, rate); err != nil {
+		return err
+	}
+
+	log.Printf("[RATES] %s = %.4f RUB", code, rate)
+	return nil
+}
+
+func (s *FinanceService) GetExchangeRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	return s.rateRepo.GetRates(ctx)
+}
+
+// загружает курсы из файла со строками вида "USD 92.5"; пустые строки и # комментарии пропускаются
+func (s *FinanceService) LoadExchangeRatesFromFile(ctx context.Context, path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open rates file: %w", err)
+	}
+	defer file.Close()
+
+	loaded := 0
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(strings.ReplaceAll(line, ",", " "))
+		if len(fields) != 2 {
+			return loaded, fmt.Errorf("rates file line %d: expected \"CODE RATE\"", lineNum)
+		}
+
+		rate, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return loaded, fmt.Errorf("rates file line %d: invalid rate: %w", lineNum, err)
+		}
+
+		if err := s.SetExchangeRate(ctx, fields[0], rate); err != nil {
+			return loaded, fmt.Errorf("rates file line %d: %w", lineNum, err)
+		}
+		loaded++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return loaded, fmt.Errorf("failed to read rates file: %w", err)
+	}
+	return loaded, nil
+}
+
+func (s *FinanceService) SetBaseCurrency(ctx context.Context, telegramID int64, currency string) error {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+
+	code, ok := models.NormalizeCurrency(currency)
+	if !ok {
+		return fmt.Errorf("invalid currency %q", currency)
+	}
+
+	// без курса базовую валюту не посчитать
+	converter, err := s.CurrencyConverter(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := converter.rate(code); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdateBaseCurrency(ctx, user.ID, code); err != nil {
+		return fmt.Errorf("failed to update base currency: %w", err)
+	}
+
+	_, err = s.DistributeFundsToGoals(ctx, telegramID)
+	if err != nil {
+		log.Printf("Failed to distribute funds after changing base currency: %v", err)
+	}
+	return nil
+}
+
+// суммы в валюте без курса нельзя учесть в итогах
+func (s *FinanceService) ensureRate(ctx context.Context, currency string) error {
+	converter, err := s.CurrencyConverter(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = converter.rate(currency)
+	return err
+}
+
+func (s *FinanceService) GetBaseCurrency(ctx context.Context, telegramID int64) (string, error) {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return "", fmt.Errorf("user not found: %w", err)
+	}
+	return user.BaseCurrency, nil
+}
+
+// оставшаяся до цели сумма в базовой валюте
+func remainingToTargetIn(converter *CurrencyConverter, goal models.SavingsGoal, currency string) (models.Money, error) {
+	remaining := goal.TargetAmount - goal.CurrentAmount
+	if remaining < 0 {
+		remaining = 0
+	}
+	return converter.Convert(remaining, goal.Currency, currency)
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/schedule"
+)
+
+var ErrTargetBelowSaved = errors.New("goal target is not above saved amount")
+
+// час уведомлений, если у дохода его еще не было (нерегулярный стал регулярным)
+const defaultNotificationHour = 18
+
+func (s *FinanceService) RenameIncome(ctx context.Context, telegramID int64, incomeID int64, name string) error {
+	income, err := s.GetUserIncomeByID(ctx, telegramID, incomeID)
+	if err != nil {
+		return err
+	}
+	income.Name = name
+	return s.incomeRepo.UpdateIncome(ctx, income)
+}
+
+// UpdateIncomeAmount меняет сумму дохода; у дохода частями, заданными суммами, части должны сойтись с новой суммой
+func (s *FinanceService) UpdateIncomeAmount(ctx context.Context, telegramID int64, incomeID int64, amount models.Money) (*models.Income, error) {
+	income, err := s.GetUserIncomeByID(ctx, telegramID, incomeID)
+	if err != nil {
+		return nil, err
+	}
+	if income.HasInstallments() {
+		if err := ValidateInstallments(amount, income.Installments); err != nil {
+			return nil, err
+		}
+	}
+
+	income.Amount = amount
+	if err := s.incomeRepo.UpdateIncome(ctx, income); err != nil {
+		return nil, err
+	}
+
+	s.redistributeAfterEdit(ctx, telegramID)
+	log.Printf("[EDIT] Income %d amount -> %s", income.ID, amount)
+	return income, nil
+}
+
+func (s *FinanceService) UpdateIncomeNotificationHour(ctx context.Context, telegramID int64, incomeID int64, hour int) error {
+	if hour < 0 || hour > 23 {
+		return fmt.Errorf("notification hour out of range: %d", hour)
+	}
+	income, err := s.GetUserIncomeByID(ctx, telegramID, incomeID)
+	if err != nil {
+		return err
+	}
+	income.NotificationHour = hour
+	return s.incomeRepo.UpdateIncome(ctx, income)
+}
+
+// UpdateIncomeSchedule меняет расписание дохода. rule.Kind может быть и частотой без правила:
+// installments (тогда нужны части) или irregular. amount - вся сумма дохода, у дохода частями части
+// должны давать ее в сумме
+func (s *FinanceService) UpdateIncomeSchedule(ctx context.Context, telegramID int64, incomeID int64, amount models.Money, rule schedule.Rule, installments []models.IncomeInstallment) (*models.Income, error) {
+	income, err := s.GetUserIncomeByID(ctx, telegramID, incomeID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetUserByID(ctx, income.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	if rule.Shift == "" {
+		rule.Shift = schedule.ShiftNone
+	}
+	if income.IsIrregular() && string(rule.Kind) != models.IncomeFrequencyIrregular {
+		income.NotificationHour = defaultNotificationHour
+	}
+
+	income.Amount = amount
+	income.Frequency = string(rule.Kind)
+	income.PayShift = string(rule.Shift)
+	income.SecondDay = 0
+	income.AnchorDate = sql.NullTime{}
+	income.Installments = nil
+
+	switch string(rule.Kind) {
+	case models.IncomeFrequencyIrregular:
+		income.RecurringDay = 0
+		income.PayShift = string(schedule.ShiftNone)
+		income.NextPayDate = payDateAt(user.Now())
+
+	case models.IncomeFrequencyInstallments:
+		if err := ValidateInstallments(amount, installments); err != nil {
+			return nil, err
+		}
+		income.RecurringDay = installments[0].Day
+		income.Installments = installments
+		income.NextPayDate = s.calculateNextPayDateForIncome(income, firstPayDateAfter(user.Now(), income.NotificationHour))
+
+	default:
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid schedule: %w", err)
+		}
+		income.RecurringDay = rule.Day
+		income.SecondDay = rule.SecondDay
+		if !rule.Anchor.IsZero() {
+			income.AnchorDate = sql.NullTime{Time: rule.Anchor, Valid: true}
+		}
+		income.NextPayDate = s.calculateNextPayDateForIncome(income, firstPayDateAfter(user.Now(), income.NotificationHour))
+	}
+
+	err = s.txManager.ReadCommitted(ctx, func(ctx context.Context) error {
+		if errTx := s.incomeRepo.UpdateIncome(ctx, income); errTx != nil {
+			return errTx
+		}
+		if errTx := s.incomeRepo.DeleteIncomeInstallments(ctx, income.ID); errTx != nil {
+			return errTx
+		}
+
+		saved := make([]models.IncomeInstallment, 0, len(installments))
+		for _, inst := range income.Installments {
+			part, errTx := s.incomeRepo.CreateIncomeInstallment(ctx, income.ID, inst.Day, inst.Amount, inst.Percent)
+			if errTx != nil {
+				return errTx
+			}
+			saved = append(saved, *part)
+		}
+		income.Installments = saved
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.redistributeAfterEdit(ctx, telegramID)
+	log.Printf("[EDIT] Income %d schedule -> %s, next pay date %s", income.ID, income.Frequency, income.NextPayDate.Format("02.01.2006"))
+	return income, nil
+}
+
+func (s *FinanceService) getUserExpense(ctx context.Context, telegramID int64, expenseID int64) (*models.Expense, error) {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	expense, err := s.expenseRepo.GetExpenseByID(ctx, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	if expense.UserID != user.ID {
+		return nil, fmt.Errorf("expense does not belong to user")
+	}
+	return expense, nil
+}
+
+func (s *FinanceService) GetUserExpenseByID(ctx context.Context, telegramID int64, expenseID int64) (*models.Expense, error) {
+	return s.getUserExpense(ctx, telegramID, expenseID)
+}
+
+func (s *FinanceService) RenameExpense(ctx context.Context, telegramID int64, expenseID int64, name string) error {
+	expense, err := s.getUserExpense(ctx, telegramID, expenseID)
+	if err != nil {
+		return err
+	}
+	return s.expenseRepo.UpdateExpense(ctx, expense.ID, name, expense.Amount)
+}
+
+func (s *FinanceService) UpdateExpenseAmount(ctx context.Context, telegramID int64, expenseID int64, amount models.Money) error {
+	expense, err := s.getUserExpense(ctx, telegramID, expenseID)
+	if err != nil {
+		return err
+	}
+	if err := s.expenseRepo.UpdateExpense(ctx, expense.ID, expense.Name, amount); err != nil {
+		return fmt.Errorf("failed to update expense: %w", err)
+	}
+
+	s.redistributeAfterEdit(ctx, telegramID)
+	log.Printf("[EDIT] Expense %d amount -> %s", expense.ID, amount)
+	return nil
+}
+
+// UpdateExpenseSchedule - периодичность регулярного расхода; у разовой траты ее нет
+func (s *FinanceService) UpdateExpenseSchedule(ctx context.Context, telegramID int64, expenseID int64, frequency string, recurringDay int) error {
+	expense, err := s.getUserExpense(ctx, telegramID, expenseID)
+	if err != nil {
+		return err
+	}
+	if expense.Kind == models.ExpenseKindOneOff {
+		return fmt.Errorf("one-off expense %d has no schedule", expense.ID)
+	}
+	if err := (schedule.Rule{Kind: schedule.Kind(frequency), Day: recurringDay}).Validate(); err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+
+	if err := s.expenseRepo.UpdateExpenseSchedule(ctx, expense.ID, frequency, recurringDay); err != nil {
+		return fmt.Errorf("failed to update expense schedule: %w", err)
+	}
+
+	s.redistributeAfterEdit(ctx, telegramID)
+	log.Printf("[EDIT] Expense %d schedule -> %s/%d", expense.ID, frequency, recurringDay)
+	return nil
+}
+
+func (s *FinanceService) RenameGoal(ctx context.Context, telegramID int64, goalID int64, name string) error {
+	goal, err := s.GetUserGoalByID(ctx, telegramID, goalID)
+	if err != nil {
+		return err
+	}
+	goal.GoalName = name
+	return s.goalRepo.UpdateGoal(ctx, goal)
+}
+
+// UpdateGoalTarget меняет сумму цели; накопленное и приоритет сохраняются
+func (s *FinanceService) UpdateGoalTarget(ctx context.Context, telegramID int64, goalID int64, target models.Money) (*models.SavingsGoal, error) {
+	goal, err := s.GetUserGoalByID(ctx, telegramID, goalID)
+	if err != nil {
+		return nil, err
+	}
+	if target <= goal.CurrentAmount {
+		return nil, ErrTargetBelowSaved
+	}
+
+	goal.TargetAmount = target
+	if err := s.goalRepo.UpdateGoal(ctx, goal); err != nil {
+		return nil, fmt.Errorf("failed to update goal: %w", err)
+	}
+
+	s.redistributeAfterEdit(ctx, telegramID)
+	log.Printf("[EDIT] Goal %d target -> %s", goal.ID, target)
+	return s.goalRepo.GetGoalByID(ctx, goal.ID)
+}
+
+func (s *FinanceService) redistributeAfterEdit(ctx context.Context, telegramID int64) {
+	if _, err := s.DistributeFundsToGoals(ctx, telegramID); err != nil {
+		log.Printf("Failed to distribute funds after edit: %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+)
+
+// категории, которые создаются каждому пользователю при первом обращении
+var defaultExpenseCategories = []string{"Еда", "Жильё", "Транспорт"}
+
+// пороги предупреждений о лимите категории, в процентах
+var categoryBudgetThresholds = []int{100, 80}
+
+type CategorySpending struct {
+	Category models.ExpenseCategory
+	Spent    models.Money
+}
+
+// процент использования лимита; 0, если лимит не задан
+func (c CategorySpending) Percent() int {
+	if c.Category.MonthlyLimit <= 0 {
+		return 0
+	}
+	return int(c.Spent * 100 / c.Category.MonthlyLimit)
+}
+
+type CategoryBudgetAlert struct {
+	TelegramID   int64
+	CategoryName string
+	Spent        models.Money
+	Limit        models.Money
+	Currency     string
+	Threshold    int
+}
+
+func (s *FinanceService) GetUserCategories(ctx context.Context, telegramID int64) ([]models.ExpenseCategory, error) {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	return s.getOrCreateCategories(ctx, user.ID)
+}
+
+func (s *FinanceService) getOrCreateCategories(ctx context.Context, userID int64) ([]models.ExpenseCategory, error) {
+	categories, err := s.categoryRepo.GetUserCategories(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	if len(categories) > 0 {
+		return categories, nil
+	}
+
+	for _, name := range defaultExpenseCategories {
+		category, err := s.categoryRepo.CreateCategory(ctx, userID, name, true)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, *category)
+	}
+
+	log.Printf("[CATEGORY] Default categories created for user %d", userID)
+	return categories, nil
+}
+
+func (s *FinanceService) CreateCategory(ctx context.Context, telegramID int64, name string) (*models.ExpenseCategory, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("category name is empty")
+	}
+
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	return s.categoryRepo.CreateCategory(ctx, user.ID, name, false)
+}
+
+func (s *FinanceService) GetUserCategoryByID(ctx context.Context, telegramID int64, categoryID int64) (*models.ExpenseCategory, error) {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	category, err := s.categoryRepo.GetCategoryByID(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if category.UserID != user.ID {
+		return nil, fmt.Errorf("category does not belong to user")
+	}
+
+	return category, nil
+}
+
+// limit = 0 снимает лимит с категории
+func (s *FinanceService) SetCategoryLimit(ctx context.Context, telegramID int64, categoryID int64, limit models.Money) error {
+	if limit < 0 {
+		return fmt.Errorf("limit must not be negative")
+	}
+
+	if _, err := s.GetUserCategoryByID(ctx, telegramID, categoryID); err != nil {
+		return err
+	}
+
+	return s.categoryRepo.UpdateCategoryLimit(ctx, categoryID, limit)
+}
+
+// расходы по категориям за месяц в базовой валюте; расходы без категории идут отдельной строкой с ID = 0
+func (s *FinanceService) GetCategorySpending(ctx context.Context, telegramID int64) ([]CategorySpending, error) {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	return s.categorySpending(ctx, user.ID)
+}
+
+func (s *FinanceService) categorySpending(ctx context.Context, userID int64) ([]CategorySpending, error) {
+	categories, err := s.getOrCreateCategories(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	expenses, err := s.expenseRepo.GetUserExpenses(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	converter, err := s.CurrencyConverter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// лимиты категорий задаются в базовой валюте
+	now := user.Now()
+	spentByCategory := make(map[int64]models.Money)
+	for _, expense := range expenses {
+		spent, err := converter.Convert(expenseAmountForMonth(expense, now.Year(), now.Month()), expense.Currency, user.BaseCurrency)
+		if err != nil {
+			return nil, err
+		}
+		spentByCategory[expense.CategoryID.Int64] += spent
+	}
+
+	result := make([]CategorySpending, 0, len(categories)+1)
+	for _, category := range categories {
+		result = append(result, CategorySpending{Category: category, Spent: spentByCategory[category.ID]})
+	}
+
+	if uncategorized := spentByCategory[0]; uncategorized > 0 {
+		result = append(result, CategorySpending{
+			Category: models.ExpenseCategory{UserID: userID, Name: "Без категории"},
+			Spent:    uncategorized,
+		})
+	}
+
+	return result, nil
+}
+
+// ищет категории, перешедшие порог лимита, и отмечает предупреждения, чтобы не отправлять их повторно
+func (s *FinanceService) CheckCategoryBudgets(ctx context.Context) ([]CategoryBudgetAlert, error) {
+	categories, err := s.categoryRepo.GetCategoriesWithLimit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories with limit: %w", err)
+	}
+
+	spendingByUser := make(map[int64]map[int64]models.Money)
+	var alerts []CategoryBudgetAlert
+
+	for _, category := range categories {
+		spent, ok := spendingByUser[category.UserID]
+		if !ok {
+			spending, err := s.categorySpending(ctx, category.UserID)
+			if err != nil {
+				log.Printf("[CATEGORY] Failed to get spending for user %d: %v", category.UserID, err)
+				continue
+			}
+			spent = make(map[int64]models.Money, len(spending))
+			for _, cs := range spending {
+				spent[cs.Category.ID] = cs.Spent
+			}
+			spendingByUser[category.UserID] = spent
+		}
+
+		cs := CategorySpending{Category: category, Spent: spent[category.ID]}
+		for _, threshold := range categoryBudgetThresholds {
+			if cs.Percent() < threshold {
+				continue
+			}
+
+			month := models.MonthStart(s.userNowByID(ctx, category.UserID))
+			created, err := s.categoryRepo.CreateBudgetAlert(ctx, category.ID, month, threshold)
+			if err != nil {
+				log.Printf("[CATEGORY] Failed to record alert for category %d: %v", category.ID, err)
+				break
+			}
+			if !created {
+				break
+			}
+
+			user, err := s.userRepo.GetUserByID(ctx, category.UserID)
+			if err != nil {
+				log.Printf("[CATEGORY] Failed to get user %d: %v", category.UserID, err)
+				break
+			}
+
+			alerts = append(alerts, CategoryBudgetAlert{
+				TelegramID:   user.TelegramID,
+				CategoryName: category.Name,
+				Spent:        cs.Spent,
+				Limit:        category.MonthlyLimit,
+				Currency:     user.BaseCurrency,
+				Threshold:    threshold,
+			})
+			// предупреждаем только о самом высоком пройденном пороге
+			break
+		}
+	}
+
+	return alerts, nil
+}
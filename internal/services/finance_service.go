@@ -2,15 +2,23 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"time"
 
+	"github.com/Lina3386/telegram-bot/internal/client/db"
 	"github.com/Lina3386/telegram-bot/internal/models"
 	"github.com/Lina3386/telegram-bot/internal/repository"
+	"github.com/Lina3386/telegram-bot/internal/schedule"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// TaskQueue - фоновые задачи с порядком по ключу (telegram id пользователя), обычно пул воркеров обновлений
+type TaskQueue interface {
+	Submit(ctx context.Context, key int64, fn func()) error
+}
+
 type FinanceService struct {
 	incomeRepo         *repository.IncomeRepository
 	expenseRepo        *repository.ExpenseRepository
@@ -18,9 +26,18 @@ type FinanceService struct {
 	userRepo           *repository.UserRepository
 	processingLogRepo  *repository.IncomeProcessingLogRepository
 	monthlyContribRepo *repository.MonthlyContributionsRepository
+	goalTxRepo         *repository.GoalTransactionRepository
+	categoryRepo       *repository.ExpenseCategoryRepository
+	rateRepo           *repository.RateRepository
+	txManager          db.TxManager
+	tasks              TaskQueue
+	// за сколько месяцев усредняется нерегулярный доход
+	irregularWindow int
+	// сколько удаление можно отменить
+	undoWindow time.Duration
 }
 
-func NewFinanceService(userRepo *repository.UserRepository, incomeRepo *repository.IncomeRepository, expenseRepo *repository.ExpenseRepository, goalRepo *repository.GoalRepository, monthlyContribRepo *repository.MonthlyContributionsRepository, processingLogRepo *repository.IncomeProcessingLogRepository) *FinanceService {
+func NewFinanceService(userRepo *repository.UserRepository, incomeRepo *repository.IncomeRepository, expenseRepo *repository.ExpenseRepository, goalRepo *repository.GoalRepository, monthlyContribRepo *repository.MonthlyContributionsRepository, processingLogRepo *repository.IncomeProcessingLogRepository, goalTxRepo *repository.GoalTransactionRepository, categoryRepo *repository.ExpenseCategoryRepository, rateRepo *repository.RateRepository, txManager db.TxManager, tasks TaskQueue, irregularWindow int, undoWindow time.Duration) *FinanceService {
 	return &FinanceService{
 		userRepo:           userRepo,
 		incomeRepo:         incomeRepo,
@@ -28,100 +45,84 @@ func NewFinanceService(userRepo *repository.UserRepository, incomeRepo *reposito
 		goalRepo:           goalRepo,
 		monthlyContribRepo: monthlyContribRepo,
 		processingLogRepo:  processingLogRepo,
+		goalTxRepo:         goalTxRepo,
+		categoryRepo:       categoryRepo,
+		rateRepo:           rateRepo,
+		txManager:          txManager,
+		tasks:              tasks,
+		irregularWindow:    irregularWindow,
+		undoWindow:         undoWindow,
 	}
 }
 
-func (s *FinanceService) CalculateTotalIncome(ctx context.Context, telegramID int64) (int64, error) {
-	incomes, err := s.GetUserIncomes(ctx, telegramID)
+// доходы за текущий месяц
+func (s *FinanceService) CalculateTotalIncome(ctx context.Context, telegramID int64) (models.Money, error) {
+	now := s.UserNow(ctx, telegramID)
+	return s.CalculateTotalIncomeForMonth(ctx, telegramID, now.Year(), now.Month())
+}
+
+// сумма в базовой валюте пользователя
+func (s *FinanceService) CalculateTotalIncomeForMonth(ctx context.Context, telegramID int64, year int, month time.Month) (models.Money, error) {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return 0, fmt.Errorf("user not found: %w", err)
+	}
+
+	incomes, err := s.incomeRepo.GetUserIncomes(ctx, user.ID)
 	if err != nil {
 		return 0, err
 	}
 
-	now := time.Now()
-	year, month, _ := now.Date()
+	converter, err := s.CurrencyConverter(ctx)
+	if err != nil {
+		return 0, err
+	}
 
-	var total int64
+	// где пользователь подтвердил фактическую сумму, считаем по факту
+	adjustments, err := s.incomeActualAdjustments(ctx, user.ID, year, month)
+	if err != nil {
+		log.Printf("[INCOME_CALC] Failed to load actual income, using plan: %v", err)
+	}
+
+	var total models.Money
 	log.Printf("[INCOME_CALC] Starting calculation for %d-%d", year, month)
 
 	for _, income := range incomes {
-		amount := int64(0)
-
-		switch income.Frequency {
-		case "monthly":
-			amount = income.Amount
-			log.Printf("[INCOME_CALC] Monthly '%s': %d₽", income.Name, amount)
-
-		case "weekly":
-			weekdayCount := countWeekdaysInMonth(year, month, income.RecurringDay)
-			amount = income.Amount * int64(weekdayCount)
-			log.Printf("[INCOME_CALC] Weekly '%s': %d₽/week × %d times = %d₽ (day=%d)",
-				income.Name, income.Amount, weekdayCount, amount, income.RecurringDay)
-
-		case "biweekly":
-			biweeklyCount := countBiweeklyOccurrences(year, month, income.RecurringDay)
-			amount = income.Amount * int64(biweeklyCount)
-
-			log.Printf("[INCOME_CALC] Biweekly '%s': %d₽ × %d times = %d₽ (day=%d)",
-				income.Name, income.Amount, biweeklyCount, amount, income.RecurringDay)
-
-		default:
-			log.Printf("[INCOME_CALC] Unknown frequency '%s' for '%s', skipping", income.Frequency, income.Name)
+		amount, err := s.plannedIncomeForMonth(ctx, income, year, month, user.Location())
+		if err != nil {
+			log.Printf("[INCOME_CALC] Invalid schedule for '%s', skipping: %v", income.Name, err)
+			continue
 		}
+		amount += adjustments[income.ID]
+		log.Printf("[INCOME_CALC] %s '%s': %s per payment, %s in month (actual correction %s)", income.Frequency, income.Name, income.Amount, amount, adjustments[income.ID])
 
-		total += amount
-	}
-
-	log.Printf("[INCOME_CALC] TOTAL INCOME: %d₽", total)
-	return total, nil
-}
-
-func daysInMonth(year int, month time.Month) int {
-	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
-}
-
-func countWeekdaysInMonth(year int, month time.Month, targetWeekday int) int {
-	daysInMonth := daysInMonth(year, month)
-	count := 0
-
-	for day := 1; day <= daysInMonth; day++ {
-		date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
-		weekday := int(date.Weekday())
-		if weekday == targetWeekday {
-			count++
-			log.Printf("  [DEBUG] %d-%02d-%02d is weekday %d (target=%d) ✓", year, month, day, weekday, targetWeekday)
+		converted, err := converter.Convert(amount, income.Currency, user.BaseCurrency)
+		if err != nil {
+			return 0, err
 		}
+		total += converted
 	}
-	log.Printf("[WEEKDAY_COUNT] Year=%d, Month=%d, TargetWeekday=%d, Count=%d", year, month, targetWeekday, count)
 
-	return count
+	log.Printf("[INCOME_CALC] TOTAL INCOME: %s %s", total, user.BaseCurrency)
+	return total, nil
 }
 
-func countBiweeklyOccurrences(year int, month time.Month, targetWeekday int) int {
-	daysInMonth := daysInMonth(year, month)
-	firstOccurrence := -1
-
-	for day := 1; day <= daysInMonth; day++ {
-		date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
-		if int(date.Weekday()) == targetWeekday {
-			firstOccurrence = day
-			break
+// сколько доход приносит за месяц: сумма выплат по расписанию, у дохода частями - по каждой части
+func incomeAmountForMonth(income models.Income, year int, month time.Month) (models.Money, error) {
+	if income.HasInstallments() {
+		amounts := income.InstallmentAmounts()
+		total := models.Money(0)
+		for k, rule := range income.InstallmentSchedules() {
+			total += amounts[k] * models.Money(rule.CountInMonth(year, month))
 		}
+		return total, nil
 	}
 
-	if firstOccurrence == -1 {
-		log.Printf("[BIWEEKLY_COUNT] Year=%d, Month=%d, TargetWeekday=%d, FirstOccurrence=NOT_FOUND, Count=0", year, month, targetWeekday)
-		return 0
-	}
-
-	// через 14 дней от первого вхождения
-	count := 1
-	for day := firstOccurrence + 14; day <= daysInMonth; day += 14 {
-		count++
+	rule := income.Schedule()
+	if err := rule.Validate(); err != nil {
+		return 0, err
 	}
-
-	log.Printf("[BIWEEKLY_COUNT] Year=%d, Month=%d, TargetWeekday=%d, FirstOccurrence=%d, Count=%d",
-		year, month, targetWeekday, firstOccurrence, count)
-	return count
+	return income.Amount * models.Money(rule.CountInMonth(year, month)), nil
 }
 
 func (s *FinanceService) DistributeFundsToGoals(ctx context.Context, telegramID int64) ([]models.SavingsGoal, error) {
@@ -130,7 +131,9 @@ func (s *FinanceService) DistributeFundsToGoals(ctx context.Context, telegramID
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
-	availableForSavings, err := s.CalculateAvailableForSavings(ctx, telegramID)
+	// разовые траты уменьшают бюджет только своего месяца
+	now := user.Now()
+	availableForSavings, err := s.CalculateAvailableForSavingsForMonth(ctx, telegramID, now.Year(), now.Month())
 	if err != nil {
 		return nil, err
 	}
@@ -160,94 +163,40 @@ func (s *FinanceService) DistributeFundsToGoals(ctx context.Context, telegramID
 		}
 	}
 
-	n := int64(len(goals))
-
-	summaryFactorial := (n * (n + 1)) / 2
-
-	if summaryFactorial == 0 {
-		summaryFactorial = 1
+	// распределение считается в базовой валюте, взносы сохраняются в валюте цели
+	converter, err := s.CurrencyConverter(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	// пропорционально приоритетам с учетом остатка до цели
-	allocated := make([]int64, len(goals))
-	totalAllocated := int64(0)
-
-	log.Printf("[DISTRIBUTION] Starting with availableForSavings=%d₽ for %d goals", availableForSavings, n)
-
-	for i := range goals {
-		if goals[i].Status == "active" {
-			priorityWeight := n - int64(goals[i].Priority) + 1
-
-			contrib := (availableForSavings * priorityWeight) / summaryFactorial
+	log.Printf("[DISTRIBUTION] Starting with availableForSavings=%s for %d goals, strategy %s", availableForSavings, len(goals), user.AllocationStrategy)
 
-			remainingToTarget := goals[i].TargetAmount - goals[i].CurrentAmount
-			if remainingToTarget < 0 {
-				remainingToTarget = 0
-			}
-
-			if contrib > remainingToTarget {
-				contrib = remainingToTarget
-			}
-
-			allocated[i] = contrib
-			totalAllocated += contrib
-
-			log.Printf("[DISTRIBUTION] Goal %d (%s, priority %d): calculated %d₽, remaining_to_target %d₽, allocated %d₽",
-				goals[i].ID, goals[i].GoalName, goals[i].Priority, (availableForSavings*priorityWeight)/summaryFactorial, remainingToTarget, contrib)
-		}
+	allocated, err := s.allocateMonthly(user, converter, goals, availableForSavings)
+	if err != nil {
+		return nil, err
 	}
 
-	remainingToDistribute := availableForSavings - totalAllocated
-	log.Printf("[DISTRIBUTION] Phase 1 total allocated: %d₽, remaining to distribute: %d₽", totalAllocated, remainingToDistribute)
-
-	if remainingToDistribute > 0 {
-		eligibleGoals := []int{}
-		for i, goal := range goals {
-			if goal.Status == "active" {
-				remainingToTarget := goal.TargetAmount - goal.CurrentAmount
-				if remainingToTarget > allocated[i] { // Есть место для дополнительных средств
-					eligibleGoals = append(eligibleGoals, i)
-				}
-			}
-		}
-
-		if len(eligibleGoals) > 0 {
-			baseAmount := remainingToDistribute / int64(len(eligibleGoals))
-			remainder := remainingToDistribute % int64(len(eligibleGoals))
-
-			for j, goalIndex := range eligibleGoals {
-				extraAmount := baseAmount
-				if j < int(remainder) {
-					extraAmount++
-				}
-
-				remainingToTarget := goals[goalIndex].TargetAmount - goals[goalIndex].CurrentAmount - allocated[goalIndex]
-				if extraAmount > remainingToTarget {
-					extraAmount = remainingToTarget
-				}
-
-				allocated[goalIndex] += extraAmount
-				totalAllocated += extraAmount
-
-				log.Printf("[DISTRIBUTION] Goal %d (%s): +%d₽ extra (remaining limit %d₽), total now %d₽",
-					goals[goalIndex].ID, goals[goalIndex].GoalName, extraAmount, remainingToTarget, allocated[goalIndex])
-			}
-		}
+	totalAllocated := models.Money(0)
+	for i := range goals {
+		totalAllocated += allocated[i]
+		log.Printf("[DISTRIBUTION] Goal %d (%s, priority %d): allocated %s", goals[i].ID, goals[i].GoalName, goals[i].Priority, allocated[i])
 	}
-
-	log.Printf("[DISTRIBUTION] Final allocation total: %d₽ (available: %d₽)", totalAllocated, availableForSavings)
+	log.Printf("[DISTRIBUTION] Final allocation total: %s (available: %s)", totalAllocated, availableForSavings)
 
 	for i := range goals {
 		if goals[i].Status == "active" {
-			contrib := allocated[i]
+			contrib, err := converter.Convert(allocated[i], user.BaseCurrency, goals[i].Currency)
+			if err != nil {
+				return nil, err
+			}
 			if contrib == 0 && availableForSavings > 0 {
-				contrib = 1 // Минимум для активной цели
+				contrib = models.MoneyFromMajor(1) // Минимум для активной цели
 			}
 
 			goals[i].MonthlyContrib = contrib
 			goals[i].MonthlyBudgetLimit = contrib
 
-			currentMonth := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+			currentMonth := models.MonthStart(now)
 
 			if !goals[i].MonthStarted.Valid || goals[i].MonthStarted.Time != currentMonth {
 				goals[i].MonthStarted.Valid = true
@@ -261,12 +210,13 @@ func (s *FinanceService) DistributeFundsToGoals(ctx context.Context, telegramID
 				if monthsNeeded == 0 {
 					monthsNeeded = 1
 				}
-				goals[i].TargetDate = time.Now().AddDate(0, int(monthsNeeded), 0)
+				goals[i].TargetDate = now.AddDate(0, int(monthsNeeded), 0)
 			}
 
 			err = s.goalRepo.UpdateGoal(ctx, &goals[i])
 			if err != nil {
 				log.Printf("Failed to update goal %d: %v", goals[i].ID, err)
+				return nil, err
 			}
 		}
 	}
@@ -295,17 +245,33 @@ func (s *FinanceService) GetUserByTelegramID(ctx context.Context, telegramID int
 	return s.userRepo.GetUserByTelegramID(ctx, telegramID)
 }
 
-func (s *FinanceService) CreateIncomeWithFrequency(ctx context.Context, telegramID int64, name string, amount int64, frequency string, recurringDay int, nextPayDate time.Time) (*models.Income, error) {
-	return s.CreateIncomeWithFrequencyAndHour(ctx, telegramID, name, amount, frequency, recurringDay, 18, nextPayDate)
-}
-
-func (s *FinanceService) CreateIncomeWithFrequencyAndHour(ctx context.Context, telegramID int64, name string, amount int64, frequency string, recurringDay int, notificationHour int, nextPayDate time.Time) (*models.Income, error) {
+// пустая валюта означает базовую валюту пользователя; первая выплата считается по правилу в зоне пользователя
+func (s *FinanceService) CreateScheduledIncome(ctx context.Context, telegramID int64, name string, amount models.Money, currency string, rule schedule.Rule, notificationHour int) (*models.Income, error) {
 	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
 	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
+	if err := rule.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid schedule: %w", err)
+	}
+	if rule.Shift == "" {
+		rule.Shift = schedule.ShiftNone
+	}
+	if currency == "" {
+		currency = user.BaseCurrency
+	}
+	if err := s.ensureRate(ctx, currency); err != nil {
+		return nil, err
+	}
+
+	nextPayDate := payDateAt(rule.Next(firstPayDateAfter(user.Now(), notificationHour)))
+
+	var anchorDate sql.NullTime
+	if !rule.Anchor.IsZero() {
+		anchorDate = sql.NullTime{Time: models.DayStart(rule.Anchor), Valid: true}
+	}
 
-	income, err := s.incomeRepo.CreateIncomeWithFrequency(ctx, user.ID, name, amount, frequency, recurringDay, notificationHour, nextPayDate)
+	income, err := s.incomeRepo.CreateIncomeWithFrequency(ctx, user.ID, name, amount, currency, string(rule.Kind), rule.Day, rule.SecondDay, anchorDate, string(rule.Shift), notificationHour, nextPayDate)
 	if err != nil {
 		log.Printf("Failed to create income: %v", err)
 		return nil, err
@@ -316,7 +282,7 @@ func (s *FinanceService) CreateIncomeWithFrequencyAndHour(ctx context.Context, t
 		log.Printf("Failed to distribute funds after creating income: %v", err)
 	}
 
-	log.Printf("Income created: %s, Amount=%d, Frequency=%s, RecurringDay=%d, NotificationHour=%d", name, amount, frequency, recurringDay, notificationHour)
+	log.Printf("Income created: %s, Amount=%s, Frequency=%s, RecurringDay=%d, NotificationHour=%d, NextPayDate=%s", name, amount, rule.Kind, rule.Day, notificationHour, nextPayDate.Format("02.01.2006"))
 	return income, nil
 }
 
@@ -328,12 +294,8 @@ func (s *FinanceService) GetUserIncomes(ctx context.Context, telegramID int64) (
 	return s.incomeRepo.GetUserIncomes(ctx, user.ID)
 }
 
-func (s *FinanceService) GetIncomesByPayDate(ctx context.Context, payDate time.Time) ([]models.Income, error) {
-	return s.incomeRepo.GetIncomesByPayDate(ctx, payDate)
-}
-
-func (s *FinanceService) GetIncomesByPayDateAndHour(ctx context.Context, payDate time.Time, hour int) ([]models.Income, error) {
-	return s.incomeRepo.GetIncomesByPayDateAndHour(ctx, payDate, hour)
+func (s *FinanceService) GetIncomesDueBefore(ctx context.Context, before time.Time) ([]models.Income, error) {
+	return s.incomeRepo.GetIncomesDueBefore(ctx, before)
 }
 
 func (s *FinanceService) UpdateIncomeNextPayDate(ctx context.Context, incomeID int64, nextPayDate time.Time) error {
@@ -368,19 +330,24 @@ func (s *FinanceService) GetUserIncomeByID(ctx context.Context, telegramID int64
 	return income, nil
 }
 
-func (s *FinanceService) DeleteIncome(ctx context.Context, telegramID int64, incomeID int64) error {
+// DeleteIncome скрывает доход; в течение undoWindow его можно вернуть через RestoreIncome
+func (s *FinanceService) DeleteIncome(ctx context.Context, telegramID int64, incomeID int64) (*models.Income, error) {
 	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
 	if err != nil {
-		return fmt.Errorf("user not found: %w", err)
+		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
 	income, err := s.incomeRepo.GetIncomeByID(ctx, incomeID)
 	if err != nil {
-		return fmt.Errorf("income not found: %w", err)
+		return nil, fmt.Errorf("income not found: %w", err)
 	}
 
 	if income.UserID != user.ID {
-		return fmt.Errorf("income does not belong to user")
+		return nil, fmt.Errorf("income does not belong to user")
+	}
+
+	if err := s.incomeRepo.DeleteIncome(ctx, incomeID, time.Now().UTC()); err != nil {
+		return nil, err
 	}
 
 	_, err = s.DistributeFundsToGoals(ctx, telegramID)
@@ -388,16 +355,56 @@ func (s *FinanceService) DeleteIncome(ctx context.Context, telegramID int64, inc
 		log.Printf("Failed to distribute funds after deleting income: %v", err)
 	}
 
-	return s.incomeRepo.DeleteIncome(ctx, incomeID)
+	return income, nil
+}
+
+func (s *FinanceService) CreateRecurringExpense(ctx context.Context, telegramID int64, name string, amount models.Money, currency string, frequency string, recurringDay int, categoryID int64) (*models.Expense, error) {
+	return s.createExpense(ctx, telegramID, &models.Expense{
+		Name:         name,
+		Amount:       amount,
+		Currency:     currency,
+		Kind:         models.ExpenseKindRecurring,
+		Frequency:    frequency,
+		RecurringDay: recurringDay,
+	}, categoryID)
 }
 
-func (s *FinanceService) CreateExpense(ctx context.Context, telegramID int64, name string, amount int64) (*models.Expense, error) {
+func (s *FinanceService) CreateOneOffExpense(ctx context.Context, telegramID int64, name string, amount models.Money, currency string, spentAt time.Time, categoryID int64) (*models.Expense, error) {
+	return s.createExpense(ctx, telegramID, &models.Expense{
+		Name:      name,
+		Amount:    amount,
+		Currency:  currency,
+		Kind:      models.ExpenseKindOneOff,
+		Frequency: "monthly",
+		SpentAt:   sql.NullTime{Time: spentAt, Valid: true},
+	}, categoryID)
+}
+
+func (s *FinanceService) createExpense(ctx context.Context, telegramID int64, expense *models.Expense, categoryID int64) (*models.Expense, error) {
 	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
 	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
+	expense.UserID = user.ID
+	if expense.Currency == "" {
+		expense.Currency = user.BaseCurrency
+	}
+	if err := s.ensureRate(ctx, expense.Currency); err != nil {
+		return nil, err
+	}
+
+	if categoryID > 0 {
+		cat, err := s.categoryRepo.GetCategoryByID(ctx, categoryID)
+		if err != nil {
+			return nil, err
+		}
+		if cat.UserID != user.ID {
+			return nil, fmt.Errorf("category does not belong to user")
+		}
+		expense.CategoryID = sql.NullInt64{Int64: categoryID, Valid: true}
+	}
 
-	expense, err := s.expenseRepo.CreateExpense(ctx, user.ID, name, amount)
+	expense, err = s.expenseRepo.CreateExpense(ctx, expense)
 	if err != nil {
 		log.Printf("Failed to create expense: %v", err)
 		return nil, err
@@ -408,7 +415,7 @@ func (s *FinanceService) CreateExpense(ctx context.Context, telegramID int64, na
 		log.Printf("Failed to distribute funds after creating expense: %v", err)
 	}
 
-	log.Printf("Expense created: %s, Amount=%d", name, amount)
+	log.Printf("Expense created: %s, Amount=%s, Kind=%s", expense.Name, expense.Amount, expense.Kind)
 	return expense, nil
 }
 
@@ -420,27 +427,76 @@ func (s *FinanceService) GetUserExpenses(ctx context.Context, telegramID int64)
 	return s.expenseRepo.GetUserExpenses(ctx, user.ID)
 }
 
-func (s *FinanceService) CalculateTotalExpense(ctx context.Context, telegramID int64) (int64, error) {
-	expenses, err := s.GetUserExpenses(ctx, telegramID)
+// расходы за текущий месяц
+func (s *FinanceService) CalculateTotalExpense(ctx context.Context, telegramID int64) (models.Money, error) {
+	now := s.UserNow(ctx, telegramID)
+	return s.CalculateTotalExpenseForMonth(ctx, telegramID, now.Year(), now.Month())
+}
+
+// сумма в базовой валюте пользователя
+func (s *FinanceService) CalculateTotalExpenseForMonth(ctx context.Context, telegramID int64, year int, month time.Month) (models.Money, error) {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return 0, fmt.Errorf("user not found: %w", err)
+	}
+
+	expenses, err := s.expenseRepo.GetUserExpenses(ctx, user.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	converter, err := s.CurrencyConverter(ctx)
 	if err != nil {
 		return 0, err
 	}
 
-	var total int64
+	var total models.Money
 	for _, expense := range expenses {
-		total += expense.Amount
+		converted, err := converter.Convert(expenseAmountForMonth(expense, year, month), expense.Currency, user.BaseCurrency)
+		if err != nil {
+			return 0, err
+		}
+		total += converted
 	}
 
+	log.Printf("[EXPENSE_CALC] TOTAL EXPENSE for %d-%d: %s %s", year, month, total, user.BaseCurrency)
 	return total, nil
 }
 
-func (s *FinanceService) CalculateAvailableForSavings(ctx context.Context, telegramID int64) (int64, error) {
-	totalIncome, err := s.CalculateTotalIncome(ctx, telegramID)
+// сколько расход стоит в указанном месяце: регулярный - по частоте, разовый - только в месяце покупки
+func expenseAmountForMonth(expense models.Expense, year int, month time.Month) models.Money {
+	if expense.Kind == models.ExpenseKindOneOff {
+		if !expense.SpentAt.Valid {
+			return 0
+		}
+		spentYear, spentMonth, _ := expense.SpentAt.Time.Date()
+		if spentYear == year && spentMonth == month {
+			return expense.Amount
+		}
+		return 0
+	}
+
+	switch expense.Frequency {
+	case "weekly", "biweekly":
+		return expense.Amount * models.Money(expense.Schedule().CountInMonth(year, month))
+	default:
+		return expense.Amount
+	}
+}
+
+// доступно для сбережений в текущем месяце
+func (s *FinanceService) CalculateAvailableForSavings(ctx context.Context, telegramID int64) (models.Money, error) {
+	now := s.UserNow(ctx, telegramID)
+	return s.CalculateAvailableForSavingsForMonth(ctx, telegramID, now.Year(), now.Month())
+}
+
+func (s *FinanceService) CalculateAvailableForSavingsForMonth(ctx context.Context, telegramID int64, year int, month time.Month) (models.Money, error) {
+	totalIncome, err := s.CalculateTotalIncomeForMonth(ctx, telegramID, year, month)
 	if err != nil {
 		return 0, err
 	}
 
-	totalExpense, err := s.CalculateTotalExpense(ctx, telegramID)
+	totalExpense, err := s.CalculateTotalExpenseForMonth(ctx, telegramID, year, month)
 	if err != nil {
 		return 0, err
 	}
@@ -453,21 +509,39 @@ func (s *FinanceService) CalculateAvailableForSavings(ctx context.Context, teleg
 	return available, nil
 }
 
-func (s *FinanceService) CreateGoal(ctx context.Context, telegramID int64, goalName string, targetAmount int64, priority int) (*models.SavingsGoal, error) {
+// пустая валюта означает базовую валюту пользователя; срок необязателен
+func (s *FinanceService) CreateGoal(ctx context.Context, telegramID int64, goalName string, targetAmount models.Money, currency string, deadline sql.NullTime, priority int) (*models.SavingsGoal, error) {
 	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
 	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
-	targetDate := time.Now().AddDate(0, 1, 0)
+	// срок - дата; сравниваем с сегодняшним днем пользователя, как при вводе срока
+	today := models.DayStart(user.Now())
+	if deadline.Valid && !deadline.Time.After(today) {
+		return nil, ErrDeadlineInPast
+	}
+	if currency == "" {
+		currency = user.BaseCurrency
+	}
+	if err := s.ensureRate(ctx, currency); err != nil {
+		return nil, err
+	}
+
+	targetDate := today.AddDate(0, 1, 0)
+	if deadline.Valid {
+		targetDate = deadline.Time
+	}
 
 	goal, err := s.goalRepo.CreateGoal(
 		ctx,
 		user.ID,
 		goalName,
+		currency,
 		targetAmount,
 		0,
 		targetDate,
+		deadline,
 		priority,
 	)
 
@@ -487,7 +561,7 @@ func (s *FinanceService) CreateGoal(ctx context.Context, telegramID int64, goalN
 		return goal, nil
 	}
 
-	log.Printf("Goal created: %s, Target=%d, MonthlyContrib=%d, Priority=%d, TargetDate=%s",
+	log.Printf("Goal created: %s, Target=%s, MonthlyContrib=%s, Priority=%d, TargetDate=%s",
 		goalName, targetAmount, goal.MonthlyContrib, priority, goal.TargetDate.Format("02.01.2006"))
 	return goal, nil
 }
@@ -499,7 +573,7 @@ func (s *FinanceService) reindexGoalPriorities(ctx context.Context, userID int64
 	}
 
 	for i := range goals {
-		if goals[i].Priority >= newPriority && goals[i].Status == "active" {
+		if goals[i].Priority >= newPriority && goals[i].HoldsPriority() {
 			goals[i].Priority++
 			err = s.goalRepo.UpdateGoal(ctx, &goals[i])
 			if err != nil {
@@ -545,83 +619,149 @@ func (s *FinanceService) GetUserGoalByID(ctx context.Context, telegramID int64,
 	return goal, nil
 }
 
-func (s *FinanceService) ContributeToGoal(ctx context.Context, goalID int64, amount int64) (*models.SavingsGoal, error) {
+func (s *FinanceService) ContributeToGoal(ctx context.Context, goalID int64, amount models.Money) (*models.SavingsGoal, error) {
 	return s.ContributeToGoalWithMonthlyTracking(ctx, goalID, amount)
 }
 
-func (s *FinanceService) WithdrawFromGoal(ctx context.Context, goalID int64, amount int64) (*models.SavingsGoal, error) {
-	goal, err := s.goalRepo.GetGoalByID(ctx, goalID)
+// взнос из поступления дохода, в журнале отмечается как распределение получки
+func (s *FinanceService) ContributeToGoalFromPayday(ctx context.Context, goalID int64, incomeID int64, amount models.Money) (*models.SavingsGoal, error) {
+	return s.contributeToGoal(ctx, goalID, amount, models.GoalTxPaydayAllocation, sql.NullInt64{Int64: incomeID, Valid: incomeID > 0}, "")
+}
+
+func (s *FinanceService) WithdrawFromGoal(ctx context.Context, goalID int64, amount models.Money) (*models.SavingsGoal, error) {
+	var goal *models.SavingsGoal
+
+	err := s.txManager.ReadCommitted(ctx, func(ctx context.Context) error {
+		var errTx error
+		goal, errTx = s.goalRepo.GetGoalByID(ctx, goalID)
+		if errTx != nil {
+			return errTx
+		}
+
+		balance, errTx := s.goalTxRepo.GetGoalBalance(ctx, goalID)
+		if errTx != nil {
+			return errTx
+		}
+
+		// нельзя снять больше, чем лежит на цели
+		if amount > balance {
+			amount = balance
+		}
+
+		if amount > 0 {
+			_, errTx = s.goalTxRepo.CreateTransaction(ctx, &models.GoalTransaction{
+				GoalID: goal.ID,
+				UserID: goal.UserID,
+				Kind:   models.GoalTxWithdrawal,
+				Amount: -amount,
+			})
+			if errTx != nil {
+				return errTx
+			}
+		}
+
+		if errTx = s.reconcileGoal(ctx, goal); errTx != nil {
+			return errTx
+		}
+
+		if goal.Status == "completed" && goal.CurrentAmount < goal.TargetAmount {
+			priority, errTx := s.nextGoalPriority(ctx, goal.UserID)
+			if errTx != nil {
+				return errTx
+			}
+			goal.Status = "active"
+			goal.Priority = priority
+			goal.CompletedAt = sql.NullTime{}
+		}
+
+		return s.goalRepo.UpdateGoal(ctx, goal)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	todayDate := time.Now()
-	monthStartDate := time.Date(todayDate.Year(), todayDate.Month(), 1, 0, 0, 0, 0, time.UTC)
+	log.Printf("Withdrew %s from goal %d, new amount: %s, monthly: %s", amount, goalID, goal.CurrentAmount, goal.MonthlyAccumulated)
+	return goal, nil
+}
 
-	var goalMonthStart time.Time
-	if goal.MonthStarted.Valid {
-		goalMonthStart = time.Date(goal.MonthStarted.Time.Year(), goal.MonthStarted.Time.Month(), goal.MonthStarted.Time.Day(), 0, 0, 0, 0, time.UTC)
+// пересчитывает баланс, месячные накопления и запись monthly_contributions по журналу операций
+func (s *FinanceService) reconcileGoal(ctx context.Context, goal *models.SavingsGoal) error {
+	balance, err := s.goalTxRepo.GetGoalBalance(ctx, goal.ID)
+	if err != nil {
+		return err
 	}
 
-	if !goal.MonthStarted.Valid || goalMonthStart != monthStartDate {
-		goal.MonthlyAccumulated = 0
-		goal.MonthStarted.Valid = true
-		goal.MonthStarted.Time = monthStartDate
-	}
+	now := s.userNowByID(ctx, goal.UserID)
+	currentMonth := models.MonthStart(now)
+	monthFrom, monthTo := models.MonthRange(now)
 
-	if goal.CurrentAmount < amount {
-		goal.CurrentAmount = 0
-	} else {
-		goal.CurrentAmount -= amount
+	monthly, err := s.goalTxRepo.GetGoalContributedBetween(ctx, goal.ID, monthFrom, monthTo)
+	if err != nil {
+		return err
+	}
+	if monthly < 0 {
+		monthly = 0
 	}
 
-	if goal.MonthlyAccumulated >= amount {
-		goal.MonthlyAccumulated -= amount
-	} else {
-		goal.MonthlyAccumulated = 0
+	if goal.CurrentAmount != balance || goal.MonthlyAccumulated != monthly {
+		log.Printf("[LEDGER] Goal %d reconciled: CurrentAmount %s -> %s, MonthlyAccumulated %s -> %s",
+			goal.ID, goal.CurrentAmount, balance, goal.MonthlyAccumulated, monthly)
 	}
 
-	currentMonth := time.Date(todayDate.Year(), todayDate.Month(), 1, 0, 0, 0, 0, time.UTC)
-	monthlyContribRecord, err := s.monthlyContribRepo.GetContributionByUserGoalMonth(ctx, goal.UserID, goalID, currentMonth)
+	goal.CurrentAmount = balance
+	goal.MonthlyAccumulated = monthly
+	goal.MonthStarted.Valid = true
+	goal.MonthStarted.Time = currentMonth
 
+	monthlyContribRecord, err := s.monthlyContribRepo.GetContributionByUserGoalMonth(ctx, goal.UserID, goal.ID, currentMonth)
 	if err != nil || monthlyContribRecord == nil {
-		_, createErr := s.monthlyContribRepo.CreateContribution(ctx, goal.UserID, goalID, currentMonth, goal.MonthlyAccumulated)
-		if createErr != nil {
-			log.Printf("[WITHDRAW] Failed to create monthly contribution after withdrawal: %v", createErr)
+		if monthly == 0 {
+			return nil
 		}
-	} else {
-		monthlyContribRecord.AmountContributed = goal.MonthlyAccumulated
-		if updateErr := s.monthlyContribRepo.UpdateContribution(ctx, monthlyContribRecord); updateErr != nil {
-			log.Printf("[WITHDRAW] Failed to update monthly contribution after withdrawal: %v", updateErr)
+		if _, createErr := s.monthlyContribRepo.CreateContribution(ctx, goal.UserID, goal.ID, currentMonth, monthly); createErr != nil {
+			log.Printf("[LEDGER] Failed to create monthly contribution: %v", createErr)
+			return createErr
 		}
+		return nil
 	}
 
-	if goal.Status == "completed" && goal.CurrentAmount < goal.TargetAmount {
-		goal.Status = "active"
+	if monthlyContribRecord.AmountContributed != monthly {
+		monthlyContribRecord.AmountContributed = monthly
+		if updateErr := s.monthlyContribRepo.UpdateContribution(ctx, monthlyContribRecord); updateErr != nil {
+			log.Printf("[LEDGER] Failed to update monthly contribution: %v", updateErr)
+			return updateErr
+		}
 	}
 
-	err = s.goalRepo.UpdateGoal(ctx, goal)
+	return nil
+}
+
+func (s *FinanceService) GetGoalHistory(ctx context.Context, telegramID int64, goalID int64, limit int) ([]models.GoalTransaction, error) {
+	goal, err := s.GetUserGoalByID(ctx, telegramID, goalID)
 	if err != nil {
 		return nil, err
 	}
-
-	log.Printf("Withdrew %d from goal %d, new amount: %d, monthly: %d", amount, goalID, goal.CurrentAmount, goal.MonthlyAccumulated)
-	return goal, nil
+	return s.goalTxRepo.GetGoalTransactions(ctx, goal.ID, limit)
 }
 
-func (s *FinanceService) DeleteExpense(ctx context.Context, telegramID int64, expenseID int64) error {
+// DeleteExpense скрывает расход; в течение undoWindow его можно вернуть через RestoreExpense
+func (s *FinanceService) DeleteExpense(ctx context.Context, telegramID int64, expenseID int64) (*models.Expense, error) {
 	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
 	if err != nil {
-		return fmt.Errorf("user not found: %w", err)
+		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
 	expense, err := s.expenseRepo.GetExpenseByID(ctx, expenseID)
 	if err != nil {
-		return fmt.Errorf("expense not found: %w", err)
+		return nil, fmt.Errorf("expense not found: %w", err)
 	}
 
 	if expense.UserID != user.ID {
-		return fmt.Errorf("expense does not belong to user")
+		return nil, fmt.Errorf("expense does not belong to user")
+	}
+
+	if err := s.expenseRepo.DeleteExpense(ctx, expenseID, time.Now().UTC()); err != nil {
+		return nil, err
 	}
 
 	_, err = s.DistributeFundsToGoals(ctx, telegramID)
@@ -629,33 +769,43 @@ func (s *FinanceService) DeleteExpense(ctx context.Context, telegramID int64, ex
 		log.Printf("Failed to distribute funds after deleting expense: %v", err)
 	}
 
-	return s.expenseRepo.DeleteExpense(ctx, expenseID)
+	return expense, nil
 }
 
-func (s *FinanceService) DeleteGoal(ctx context.Context, telegramID int64, goalID int64) error {
+// DeleteGoal скрывает цель; взносы и история остаются до очистки, чтобы RestoreGoal мог вернуть все как было
+func (s *FinanceService) DeleteGoal(ctx context.Context, telegramID int64, goalID int64) (*models.SavingsGoal, error) {
 	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
 	if err != nil {
-		return fmt.Errorf("user not found: %w", err)
+		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
-	goal, err := s.goalRepo.GetGoalByID(ctx, goalID)
-	if err != nil {
-		return fmt.Errorf("goal not found: %w", err)
-	}
+	var goal *models.SavingsGoal
+	err = s.txManager.ReadCommitted(ctx, func(ctx context.Context) error {
+		var errTx error
+		goal, errTx = s.goalRepo.GetGoalByID(ctx, goalID)
+		if errTx != nil {
+			return fmt.Errorf("goal not found: %w", errTx)
+		}
 
-	if goal.UserID != user.ID {
-		return fmt.Errorf("goal does not belong to user")
-	}
+		if goal.UserID != user.ID {
+			return fmt.Errorf("goal does not belong to user")
+		}
 
-	s.reindexDeletedGoalPriorities(ctx, user.ID, goal.Priority)
+		if errTx = s.reindexDeletedGoalPriorities(ctx, user.ID, goal.Priority); errTx != nil {
+			return errTx
+		}
+
+		if errTx = s.goalRepo.DeleteGoal(ctx, goalID, time.Now().UTC()); errTx != nil {
+			return errTx
+		}
 
-	err = s.goalRepo.DeleteGoal(ctx, goalID)
+		_, errTx = s.DistributeFundsToGoals(ctx, telegramID)
+		return errTx
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	_, err = s.DistributeFundsToGoals(ctx, telegramID)
-	return err
+	return goal, nil
 }
 
 // переиндексирует приоритеты после удаления цели
@@ -666,11 +816,12 @@ func (s *FinanceService) reindexDeletedGoalPriorities(ctx context.Context, userI
 	}
 
 	for i := range goals {
-		if goals[i].Priority > deletedPriority && goals[i].Status == "active" {
+		if goals[i].Priority > deletedPriority && goals[i].HoldsPriority() {
 			goals[i].Priority--
 			err = s.goalRepo.UpdateGoal(ctx, &goals[i])
 			if err != nil {
 				log.Printf("Failed to update goal priority after deletion: %v", err)
+				return err
 			}
 		}
 	}
@@ -678,7 +829,7 @@ func (s *FinanceService) reindexDeletedGoalPriorities(ctx context.Context, userI
 	return nil
 }
 
-func (s *FinanceService) CreateMonthlyContribution(ctx context.Context, userID, goalID int64, month time.Time, amount int64) (*models.MonthlyContribution, error) {
+func (s *FinanceService) CreateMonthlyContribution(ctx context.Context, userID, goalID int64, month time.Time, amount models.Money) (*models.MonthlyContribution, error) {
 	return s.monthlyContribRepo.CreateContribution(ctx, userID, goalID, month, amount)
 }
 
@@ -699,7 +850,7 @@ func (s *FinanceService) GetUserByID(ctx context.Context, userID int64) (*models
 }
 
 type BotAPI interface {
-	Send(msg tgbotapi.Chattable) (tgbotapi.Message, error)
+	Send(ctx context.Context, msg tgbotapi.Chattable) (tgbotapi.Message, error)
 }
 
 func (s *FinanceService) TestPaydayNotification(bot BotAPI, ctx context.Context, telegramID int64, incomeID int64) error {
@@ -718,37 +869,41 @@ func (s *FinanceService) TestPaydayNotification(bot BotAPI, ctx context.Context,
 	}
 
 	// Отправляем тестовое уведомление - справку о тесте
-	now := time.Now()
+	now := user.Now()
 	dateStr := now.Format("02.01.2006")
 
 	testMsg := tgbotapi.NewMessage(telegramID, fmt.Sprintf(
 		"🧪 ТЕСТОВОЕ УВЕДОМЛЕНИЕ ЗАПУЩЕНО!\n\n"+
 			"💰 Сегодня: %s\n"+
 			"🎯 День получения: %s\n"+
-			"Сумма: %d₽\n\n"+
+			"Сумма: %s\n\n"+
 			"⚠️ Ожидайте интерактивное меню ниже...",
-		dateStr, income.Name, income.Amount,
+		dateStr, income.Name, models.FormatAmount(income.Amount, income.Currency),
 	))
 
-	_, err = bot.Send(testMsg)
+	_, err = bot.Send(ctx, testMsg)
 	if err != nil {
 		return fmt.Errorf("failed to send test start message: %w", err)
 	}
 
 	// меню точно как настоящее уведомление
-	s.showTestPaydayMenu(bot, ctx, telegramID, incomeID, income.Name, income.Amount)
+	s.showTestPaydayMenu(bot, ctx, telegramID, income)
 
 	return nil
 }
 
-func (s *FinanceService) showTestPaydayMenu(bot BotAPI, ctx context.Context, telegramID int64, incomeID int64, incomeName string, incomeAmount int64) {
+func (s *FinanceService) showTestPaydayMenu(bot BotAPI, ctx context.Context, telegramID int64, income *models.Income) {
+	incomeID := income.ID
+	incomeName := income.Name
+	incomeAmount := models.FormatAmount(income.PaydayAmount(income.NextPayDate), income.Currency)
+
 	goals, err := s.GetUserActiveGoalsByTelegramID(ctx, telegramID)
 	if err != nil {
 		log.Printf("❌ Failed to get goals for test payday: %v", err)
 		return
 	}
 
-	now := time.Now()
+	now := s.UserNow(ctx, telegramID)
 	dateStr := now.Format("02.01.2006")
 
 	if len(goals) == 0 {
@@ -756,47 +911,63 @@ func (s *FinanceService) showTestPaydayMenu(bot BotAPI, ctx context.Context, tel
 			"💰 ТЕСТОВОЕ УВЕДОМЛЕНИЕ\n(не обновляет расписание)\n\n"+
 				"💰 Сегодня: %s\n"+
 				"🎯 День дохода: %s\n"+
-				"Сумма: %d₽\n\n"+
+				"Сумма: %s\n\n"+
 				"ℹ️ У вас нет активных целей для накопления",
 			dateStr, incomeName, incomeAmount,
 		)
 
 		testMsg := tgbotapi.NewMessage(telegramID, msg)
-		bot.Send(testMsg)
+		bot.Send(ctx, testMsg)
 		return
 	}
 
-	currentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	currentMonth := models.MonthStart(now)
 	monthlyContributions, err := s.GetMonthlyContributions(ctx, telegramID, currentMonth)
 	if err != nil {
 		log.Printf("Failed to get monthly contributions for test: %v", err)
 		monthlyContributions = make([]models.MonthlyContribution, 0)
 	}
 
-	contributedMap := make(map[int64]int64)
+	contributedMap := make(map[int64]models.Money)
 	for _, contrib := range monthlyContributions {
 		contributedMap[contrib.GoalID] += contrib.AmountContributed
 	}
 
-	recommendedMap := s.calculateTestSmartPaydayRecommendations(incomeAmount, goals, contributedMap)
+	recommendedMap, err := s.paydayRecommendations(ctx, *income, income.NextPayDate, income.PaydayAmount(income.NextPayDate), goals, contributedMap)
+	if err != nil {
+		log.Printf("Failed to calculate test payday recommendations: %v", err)
+		recommendedMap = make(map[int64]models.Money)
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, income.UserID)
+	if err != nil {
+		log.Printf("❌ Failed to get user for test payday: %v", err)
+		return
+	}
+	converter, err := s.CurrencyConverter(ctx)
+	if err != nil {
+		log.Printf("❌ Failed to load exchange rates: %v", err)
+		return
+	}
 
-	totalRecommended := int64(0)
-	for _, amount := range recommendedMap {
-		totalRecommended += amount
+	totalRecommended := models.Money(0)
+	for _, goal := range goals {
+		recommended, _ := converter.Convert(recommendedMap[goal.ID], goal.Currency, user.BaseCurrency)
+		totalRecommended += recommended
 	}
 
 	text := fmt.Sprintf(
 		"🧪 ТЕСТОВОЕ УВЕДОМЛЕНИЕ\n💰 Сегодня: %s\n\n"+
 			"🎯 День поступления: %s\n"+
-			"Сумма: %d₽\n\n"+
-			"📈 Рекомендуем отложить: %d₽\n"+
+			"Сумма: %s\n\n"+
+			"📈 Рекомендуем отложить: %s\n"+
 			"(из этого поступления)\n\n"+
 			"ИНТЕРАКТИВНОЕ ТЕСТИРОВАНИЕ:\n"+
 			"• Нажмите на цель ниже\n"+
 			"• Введите сумму\n"+
 			"• Возвратитесь к этому меню\n"+
 			"• Повторите до завершения\n\n",
-		dateStr, incomeName, incomeAmount, totalRecommended,
+		dateStr, incomeName, incomeAmount, models.FormatAmount(totalRecommended, user.BaseCurrency),
 	)
 
 	for i, goal := range goals {
@@ -805,21 +976,18 @@ func (s *FinanceService) showTestPaydayMenu(bot BotAPI, ctx context.Context, tel
 			remaining = 0
 		}
 
-		progress := int64(0)
-		if goal.TargetAmount > 0 {
-			progress = (goal.CurrentAmount * 100) / goal.TargetAmount
-		}
+		progress := goal.CurrentAmount.Percent(goal.TargetAmount)
 
 		recommended := recommendedMap[goal.ID]
 
 		text += fmt.Sprintf(
 			"%d. %s (%d)\n"+
-				" Накоплено: %d/%d₽ (%d%%)\n"+
-				" Отложить 💰: %d₽ (рекомендация)\n"+
-				" До цели: %d₽\n\n",
+				" Накоплено: %s/%s (%d%%)\n"+
+				" Отложить 💰: %s (рекомендация)\n"+
+				" До цели: %s\n\n",
 			i+1, goal.GoalName, goal.Priority,
-			goal.CurrentAmount, goal.TargetAmount, progress,
-			recommended, remaining,
+			goal.CurrentAmount, models.FormatAmount(goal.TargetAmount, goal.Currency), progress,
+			models.FormatAmount(recommended, goal.Currency), models.FormatAmount(remaining, goal.Currency),
 		)
 	}
 
@@ -827,81 +995,27 @@ func (s *FinanceService) showTestPaydayMenu(bot BotAPI, ctx context.Context, tel
 	for _, goal := range goals {
 		btn := tgbotapi.NewInlineKeyboardButtonData(
 			fmt.Sprintf("💰 %s (%d)", goal.GoalName, goal.Priority),
-			fmt.Sprintf("test_payday_goal_%d_%d", incomeID, goal.ID),
+			fmt.Sprintf("testpayday/%d/goal/%d", incomeID, goal.ID),
 		)
 		buttons = append(buttons, []tgbotapi.InlineKeyboardButton{btn})
 	}
 
 	completeBtn := tgbotapi.NewInlineKeyboardButtonData(
 		"✅ Завершить тест",
-		fmt.Sprintf("test_payday_complete_%d", incomeID),
+		fmt.Sprintf("testpayday/%d/complete", incomeID),
 	)
 	buttons = append(buttons, []tgbotapi.InlineKeyboardButton{completeBtn})
 
 	msg := tgbotapi.NewMessage(telegramID, text)
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons...)
 
-	_, err = bot.Send(msg)
+	_, err = bot.Send(ctx, msg)
 	if err != nil {
 		log.Printf("❌ Failed to send test payday notification: %v", err)
 	}
 }
 
-func (s *FinanceService) calculateTestSmartPaydayRecommendations(incomeAmount int64, goals []models.SavingsGoal, contributedMap map[int64]int64) map[int64]int64 {
-	recommendations := make(map[int64]int64)
-	remainingIncome := incomeAmount
-
-	sortedGoals := make([]models.SavingsGoal, len(goals))
-	copy(sortedGoals, goals)
-
-	for i := 0; i < len(sortedGoals)-1; i++ {
-		for j := i + 1; j < len(sortedGoals); j++ {
-			if sortedGoals[i].Priority > sortedGoals[j].Priority {
-				sortedGoals[i], sortedGoals[j] = sortedGoals[j], sortedGoals[i]
-			}
-		}
-	}
-
-	for _, goal := range sortedGoals {
-		if remainingIncome <= 0 {
-			break
-		}
-
-		alreadyContributed := contributedMap[goal.ID]
-		remainingForGoal := goal.MonthlyBudgetLimit - alreadyContributed
-		if remainingForGoal < 0 {
-			remainingForGoal = 0
-		}
-
-		remainingToTarget := goal.TargetAmount - goal.CurrentAmount
-		if remainingToTarget < 0 {
-			remainingToTarget = 0
-		}
-
-		maxRecommend := remainingToTarget
-		if maxRecommend > remainingForGoal {
-			maxRecommend = remainingForGoal
-		}
-		if maxRecommend > remainingIncome {
-			maxRecommend = remainingIncome
-		}
-
-		if maxRecommend > incomeAmount/2 {
-			maxRecommend = incomeAmount / 2
-		}
-
-		if remainingToTarget <= 5000 {
-			maxRecommend = remainingToTarget
-		}
-
-		recommendations[goal.ID] = maxRecommend
-		remainingIncome -= maxRecommend
-	}
-
-	return recommendations
-}
-
-func (s *FinanceService) LogIncomeProcessing(ctx context.Context, incomeID, userID int64, processedDate time.Time, incomeAmount int64) (*models.IncomeProcessingLog, error) {
+func (s *FinanceService) LogIncomeProcessing(ctx context.Context, incomeID, userID int64, processedDate time.Time, incomeAmount models.Money) (*models.IncomeProcessingLog, error) {
 	return s.processingLogRepo.CreateProcessingLog(ctx, incomeID, userID, processedDate, incomeAmount)
 }
 
@@ -915,49 +1029,40 @@ func (s *FinanceService) CompletePaydaySession(ctx context.Context, incomeID int
 		return fmt.Errorf("failed to get income: %w", err)
 	}
 
-	nextPayDate := s.calculateNextPayDateForIncome(income)
+	nextPayDate := s.calculateNextPayDateForIncome(income, s.userNowByID(ctx, income.UserID))
 	return s.incomeRepo.UpdateIncomeNextPayDate(ctx, incomeID, nextPayDate)
 }
 
-func (s *FinanceService) calculateNextPayDateForIncome(income *models.Income) time.Time {
-	now := time.Now()
-
-	switch income.Frequency {
-	case "monthly":
-		next := time.Date(now.Year(), now.Month()+1, income.RecurringDay, 9, 0, 0, 0, now.Location())
-		maxDay := daysInMonth(next.Year(), next.Month())
-		if income.RecurringDay > maxDay {
-			next = time.Date(next.Year(), next.Month(), maxDay, 9, 0, 0, 0, now.Location())
-		}
-		return next
-
-	case "weekly":
-		daysUntil := (income.RecurringDay - int(now.Weekday()) + 7) % 7
-		if daysUntil <= 0 {
-			daysUntil = 7 // Если сегодня этот день, то на следующей неделе
-		}
+// следующая выплата после дня after (в зоне пользователя); результат - его "настенное" время в UTC,
+// в таком виде next_pay_date и хранится
+func (s *FinanceService) calculateNextPayDateForIncome(income *models.Income, after time.Time) time.Time {
+	if income.IsIrregular() {
+		// расписания нет - дата последнего поступления не меняется
+		return income.NextPayDate
+	}
+	if !income.HasInstallments() {
+		return payDateAt(income.Schedule().Next(after))
+	}
 
-		next := now.AddDate(0, 0, daysUntil)
-		return time.Date(next.Year(), next.Month(), next.Day(), 9, 0, 0, 0, next.Location())
-
-	case "biweekly":
-		daysUntil := (income.RecurringDay - int(now.Weekday()) + 7) % 7
-
-		if daysUntil == 0 {
-			// Сегодня этот день - через 14 дней
-			daysUntil = 14
-		} else if daysUntil < 0 {
-			// Прошли уже этот день на неделе - + полная неделя и дни до дня
-			daysUntil = (7 - int(now.Weekday()) + income.RecurringDay + 7)
-		} else {
-			// Будет на этой неделе - через неделю
-			daysUntil = daysUntil + 7
+	// у дохода частями - ближайшая из частей
+	var next time.Time
+	for _, rule := range income.InstallmentSchedules() {
+		if d := rule.Next(after); next.IsZero() || d.Before(next) {
+			next = d
 		}
+	}
+	return payDateAt(next)
+}
 
-		next := now.AddDate(0, 0, daysUntil)
-		return time.Date(next.Year(), next.Month(), next.Day(), 9, 0, 0, 0, next.Location())
-
-	default:
-		return now.AddDate(0, 0, 1)
+// день, после которого ищется первая выплата нового дохода:
+// сегодняшняя выплата еще впереди, если час уведомления не наступил
+func firstPayDateAfter(now time.Time, notificationHour int) time.Time {
+	if now.Hour() >= notificationHour {
+		return now
 	}
+	return now.AddDate(0, 0, -1)
+}
+
+func payDateAt(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 9, 0, 0, 0, time.UTC)
 }
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+)
+
+var ErrSameGoal = errors.New("cannot move goal balance to itself")
+
+// что пропадет вместе с целью и куда можно перенести накопленное
+type GoalDeletionInfo struct {
+	Goal          *models.SavingsGoal
+	Contributions int                  // записей о месячных взносах
+	Transfers     []models.SavingsGoal // цели, на которые можно перенести баланс
+}
+
+func (s *FinanceService) GetGoalDeletionInfo(ctx context.Context, telegramID int64, goalID int64) (*GoalDeletionInfo, error) {
+	goal, err := s.GetUserGoalByID(ctx, telegramID, goalID)
+	if err != nil {
+		return nil, err
+	}
+
+	contributions, err := s.monthlyContribRepo.CountGoalContributions(ctx, goal.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &GoalDeletionInfo{Goal: goal, Contributions: contributions}
+	if goal.CurrentAmount <= 0 {
+		return info, nil
+	}
+
+	goals, err := s.goalRepo.GetUserGoals(ctx, goal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range goals {
+		if other.ID != goal.ID && other.HoldsPriority() {
+			info.Transfers = append(info.Transfers, other)
+		}
+	}
+	return info, nil
+}
+
+// TransferGoalBalance переносит весь баланс цели на другую цель, с пересчетом по курсу, если валюты разные
+func (s *FinanceService) TransferGoalBalance(ctx context.Context, telegramID int64, fromGoalID int64, toGoalID int64) (models.Money, *models.SavingsGoal, error) {
+	if fromGoalID == toGoalID {
+		return 0, nil, ErrSameGoal
+	}
+
+	from, err := s.GetUserGoalByID(ctx, telegramID, fromGoalID)
+	if err != nil {
+		return 0, nil, err
+	}
+	to, err := s.GetUserGoalByID(ctx, telegramID, toGoalID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	converter, err := s.CurrencyConverter(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var moved models.Money
+	err = s.txManager.ReadCommitted(ctx, func(ctx context.Context) error {
+		balance, errTx := s.goalTxRepo.GetGoalBalance(ctx, from.ID)
+		if errTx != nil {
+			return errTx
+		}
+		if balance <= 0 {
+			return nil
+		}
+
+		moved, errTx = converter.Convert(balance, from.Currency, to.Currency)
+		if errTx != nil {
+			return errTx
+		}
+
+		_, errTx = s.goalTxRepo.CreateTransaction(ctx, &models.GoalTransaction{
+			GoalID:  from.ID,
+			UserID:  from.UserID,
+			Kind:    models.GoalTxTransfer,
+			Amount:  -balance,
+			Comment: fmt.Sprintf("Перенос на цель «%s»", to.GoalName),
+		})
+		if errTx != nil {
+			return errTx
+		}
+		if errTx = s.reconcileGoal(ctx, from); errTx != nil {
+			return errTx
+		}
+		if errTx = s.goalRepo.UpdateGoal(ctx, from); errTx != nil {
+			return errTx
+		}
+
+		to, errTx = s.contributeToGoal(ctx, to.ID, moved, models.GoalTxTransfer, sql.NullInt64{}, fmt.Sprintf("Перенос с цели «%s»", from.GoalName))
+		return errTx
+	})
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to transfer goal balance: %w", err)
+	}
+
+	log.Printf("[GOAL_TRANSFER] Goal %d -> goal %d: %s", from.ID, to.ID, models.FormatAmount(moved, to.Currency))
+	return moved, to, nil
+}
+
+// DeleteGoalMovingBalance переносит баланс на другую цель и удаляет цель - одной транзакцией
+func (s *FinanceService) DeleteGoalMovingBalance(ctx context.Context, telegramID int64, goalID int64, toGoalID int64) (*models.SavingsGoal, models.Money, *models.SavingsGoal, error) {
+	var (
+		deleted *models.SavingsGoal
+		moved   models.Money
+		to      *models.SavingsGoal
+	)
+	err := s.txManager.ReadCommitted(ctx, func(ctx context.Context) error {
+		var errTx error
+		moved, to, errTx = s.TransferGoalBalance(ctx, telegramID, goalID, toGoalID)
+		if errTx != nil {
+			return errTx
+		}
+		deleted, errTx = s.DeleteGoal(ctx, telegramID, goalID)
+		return errTx
+	})
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return deleted, moved, to, nil
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"time"
@@ -9,56 +10,67 @@ import (
 	"github.com/Lina3386/telegram-bot/internal/models"
 )
 
+// сколько ждать пересчета лимитов целей в фоне
+const redistributeTimeout = 30 * time.Second
+
 func (s *FinanceService) SwapGoalPriorities(ctx context.Context, telegramID int64, goalID int64, newPriority int) error {
 	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
 	if err != nil {
 		return fmt.Errorf("user not found: %w", err)
 	}
 
-	goal, err := s.goalRepo.GetGoalByID(ctx, goalID)
-	if err != nil {
-		return fmt.Errorf("goal not found: %w", err)
-	}
+	return s.txManager.ReadCommitted(ctx, func(ctx context.Context) error {
+		goal, errTx := s.goalRepo.GetGoalByID(ctx, goalID)
+		if errTx != nil {
+			return fmt.Errorf("goal not found: %w", errTx)
+		}
 
-	if goal.UserID != user.ID {
-		return fmt.Errorf("goal does not belong to user")
-	}
+		if goal.UserID != user.ID {
+			return fmt.Errorf("goal does not belong to user")
+		}
 
-	goals, err := s.goalRepo.GetUserActiveGoals(ctx, user.ID)
-	if err != nil {
-		return err
-	}
+		// цели на паузе тоже занимают места в очереди
+		allGoals, errTx := s.goalRepo.GetUserGoals(ctx, user.ID)
+		if errTx != nil {
+			return errTx
+		}
+		var goals []models.SavingsGoal
+		for _, g := range allGoals {
+			if g.HoldsPriority() {
+				goals = append(goals, g)
+			}
+		}
 
-	if newPriority < 1 || newPriority > len(goals) {
-		return fmt.Errorf("invalid priority: must be between 1 and %d", len(goals))
-	}
+		if newPriority < 1 || newPriority > len(goals) {
+			return fmt.Errorf("invalid priority: must be between 1 and %d", len(goals))
+		}
 
-	var targetGoal *models.SavingsGoal
-	for i := range goals {
-		if goals[i].Priority == newPriority && goals[i].ID != goal.ID {
-			targetGoal = &goals[i]
-			break
+		var targetGoal *models.SavingsGoal
+		for i := range goals {
+			if goals[i].Priority == newPriority && goals[i].ID != goal.ID {
+				targetGoal = &goals[i]
+				break
+			}
 		}
-	}
 
-	oldPriority := goal.Priority
-	if targetGoal != nil {
-		targetGoal.Priority = oldPriority
-		err = s.goalRepo.UpdateGoal(ctx, targetGoal)
-		if err != nil {
-			return fmt.Errorf("failed to update target goal: %w", err)
+		oldPriority := goal.Priority
+		if targetGoal != nil {
+			targetGoal.Priority = oldPriority
+			if errTx = s.goalRepo.UpdateGoal(ctx, targetGoal); errTx != nil {
+				return fmt.Errorf("failed to update target goal: %w", errTx)
+			}
 		}
-	}
-	goal.Priority = newPriority
+		goal.Priority = newPriority
 
-	if err := s.goalRepo.UpdateGoal(ctx, goal); err != nil {
-		return fmt.Errorf("failed to update goal: %w", err)
-	}
+		if errTx = s.goalRepo.UpdateGoal(ctx, goal); errTx != nil {
+			return fmt.Errorf("failed to update goal: %w", errTx)
+		}
 
-	log.Printf("Swapped priorities: goal %d (priority %d <-> %d)", goalID, oldPriority, newPriority)
+		log.Printf("Swapped priorities: goal %d (priority %d <-> %d)", goalID, oldPriority, newPriority)
 
-	_, err = s.DistributeFundsToGoals(ctx, telegramID)
-	return err
+		_, errTx = s.DistributeFundsToGoals(ctx, telegramID)
+		return errTx
+	})
 }
 
 func (s *FinanceService) GetGoalPriorityInfo(ctx context.Context, telegramID int64) ([]map[string]interface{}, error) {
@@ -95,7 +107,7 @@ func (s *FinanceService) GetGoalPriorityInfo(ctx context.Context, telegramID int
 	return result, nil
 }
 
-func (s *FinanceService) CalculateMonthlyBudgetDistribution(ctx context.Context, telegramID int64) (map[int64]int64, int64, error) {
+func (s *FinanceService) CalculateMonthlyBudgetDistribution(ctx context.Context, telegramID int64) (map[int64]models.Money, models.Money, error) {
 	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
 	if err != nil {
 		return nil, 0, fmt.Errorf("user not found: %w", err)
@@ -112,7 +124,7 @@ func (s *FinanceService) CalculateMonthlyBudgetDistribution(ctx context.Context,
 	}
 
 	if len(goals) == 0 {
-		return make(map[int64]int64), 0, nil
+		return make(map[int64]models.Money), 0, nil
 	}
 
 	for i := 0; i < len(goals)-1; i++ {
@@ -123,23 +135,27 @@ func (s *FinanceService) CalculateMonthlyBudgetDistribution(ctx context.Context,
 		}
 	}
 
-	distribution := make(map[int64]int64)
-	n := int64(len(goals))
-	summaryFactorial := (n * (n + 1)) / 2
+	converter, err := s.CurrencyConverter(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	for _, goal := range goals {
-		if goal.Status == "active" {
-			priorityWeight := n - int64(goal.Priority) + 1
-			budgetShare := (availableForSavings * priorityWeight) / summaryFactorial
-			distribution[goal.ID] = budgetShare
-		}
+	// доли в базовой валюте, как и availableForSavings
+	allocated, err := s.allocateMonthly(user, converter, goals, availableForSavings)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	distribution := make(map[int64]models.Money, len(goals))
+	for i, goal := range goals {
+		distribution[goal.ID] = allocated[i]
 	}
 
 	return distribution, availableForSavings, nil
 }
 
 func (s *FinanceService) ResetMonthlyContributions(ctx context.Context, userID int64) error {
-	_, err := s.userRepo.GetUserByID(ctx, userID)
+	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
 		return err
 	}
@@ -149,8 +165,7 @@ func (s *FinanceService) ResetMonthlyContributions(ctx context.Context, userID i
 		return err
 	}
 
-	todayDate := time.Now()
-	monthStartDate := time.Date(todayDate.Year(), todayDate.Month(), 1, 0, 0, 0, 0, time.Local)
+	monthStartDate := models.MonthStart(user.Now())
 
 	for _, goal := range goals {
 		goal.MonthStarted.Valid = true
@@ -165,7 +180,7 @@ func (s *FinanceService) ResetMonthlyContributions(ctx context.Context, userID i
 	return nil
 }
 
-func (s *FinanceService) UpdateGoalMonthlyBudget(ctx context.Context, goalID int64, budgetAmount int64) error {
+func (s *FinanceService) UpdateGoalMonthlyBudget(ctx context.Context, goalID int64, budgetAmount models.Money) error {
 	goal, err := s.goalRepo.GetGoalByID(ctx, goalID)
 	if err != nil {
 		return err
@@ -174,10 +189,8 @@ func (s *FinanceService) UpdateGoalMonthlyBudget(ctx context.Context, goalID int
 	goal.MonthlyBudgetLimit = budgetAmount
 	goal.MonthlyAccumulated = 0
 
-	todayDate := time.Now()
-	monthStartDate := time.Date(todayDate.Year(), todayDate.Month(), 1, 0, 0, 0, 0, time.Local)
 	goal.MonthStarted.Valid = true
-	goal.MonthStarted.Time = monthStartDate
+	goal.MonthStarted.Time = models.MonthStart(s.userNowByID(ctx, goal.UserID))
 
 	return s.goalRepo.UpdateGoal(ctx, goal)
 }
@@ -188,16 +201,22 @@ func (s *FinanceService) DistributeFundsToGoalsV2(ctx context.Context, telegramI
 		return fmt.Errorf("user not found: %w", err)
 	}
 
-	availableForSavings, err := s.CalculateAvailableForSavings(ctx, telegramID)
+	now := user.Now()
+	availableForSavings, err := s.CalculateAvailableForSavingsForMonth(ctx, telegramID, now.Year(), now.Month())
 	if err != nil {
 		return err
 	}
 
 	if availableForSavings <= 0 {
-		goals, _ := s.goalRepo.GetUserGoals(ctx, user.ID)
+		goals, err := s.goalRepo.GetUserGoals(ctx, user.ID)
+		if err != nil {
+			return err
+		}
 		for i := range goals {
 			goals[i].MonthlyContrib = 0
-			s.goalRepo.UpdateGoal(ctx, &goals[i])
+			if err := s.goalRepo.UpdateGoal(ctx, &goals[i]); err != nil {
+				return err
+			}
 		}
 		return nil
 	}
@@ -219,73 +238,42 @@ func (s *FinanceService) DistributeFundsToGoalsV2(ctx context.Context, telegramI
 		}
 	}
 
-	n := int64(len(goals))
-	summaryFactorial := (n * (n + 1)) / 2
-	if summaryFactorial == 0 {
-		summaryFactorial = 1
+	// распределение считается в базовой валюте, взносы сохраняются в валюте цели
+	converter, err := s.CurrencyConverter(ctx)
+	if err != nil {
+		return err
 	}
 
-	allocated := make([]int64, len(goals))
-	totalAllocated := int64(0)
-
-	log.Printf("[DISTRIBUTE] Starting with availableForSavings=%d₽ for %d goals", availableForSavings, n)
+	log.Printf("[DISTRIBUTE] Starting with availableForSavings=%s for %d goals, strategy %s", availableForSavings, len(goals), user.AllocationStrategy)
 
-	weights := make([]int64, len(goals))
-	initialAllocated := make([]int64, len(goals))
-
-	for i, goal := range goals {
-		weights[i] = n - int64(goal.Priority) + 1
-		initialAllocated[i] = (availableForSavings * weights[i]) / summaryFactorial
+	allocated, err := s.allocateMonthly(user, converter, goals, availableForSavings)
+	if err != nil {
+		return err
 	}
 
-	copy(allocated, initialAllocated)
-
-	for i, goal := range goals {
-		remainingToTarget := goal.TargetAmount - goal.CurrentAmount
-		if remainingToTarget < 0 {
-			remainingToTarget = 0
-		}
-
-		if allocated[i] > remainingToTarget {
-			excess := allocated[i] - remainingToTarget
-			allocated[i] = remainingToTarget
-			totalAllocated += allocated[i]
-
-			log.Printf("[DISTRIBUTE] Goal %d (%s) excess: %d₽, allocated reduced to %d₽", goal.ID, goal.GoalName, excess, remainingToTarget)
-
-			sumLowerWeights := int64(0)
-			for j := i + 1; j < len(goals); j++ {
-				sumLowerWeights += weights[j]
-			}
-
-			if sumLowerWeights > 0 {
-				for j := i + 1; j < len(goals); j++ {
-					added := (excess * weights[j]) / sumLowerWeights
-					allocated[j] += added
-					log.Printf("[DISTRIBUTE] Goal %d (%s): +%d₽ from excess, new allocated %d₽", goals[j].ID, goals[j].GoalName, added, allocated[j])
-				}
-			}
-		} else {
-			totalAllocated += allocated[i]
-		}
+	totalAllocated := models.Money(0)
+	for i := range goals {
+		totalAllocated += allocated[i]
 	}
-
-	log.Printf("[DISTRIBUTE] Final allocation total: %d₽ (available: %d₽)", totalAllocated, availableForSavings)
+	log.Printf("[DISTRIBUTE] Final allocation total: %s (available: %s)", totalAllocated, availableForSavings)
 
 	for i := range goals {
 		if goals[i].Status != "active" {
 			continue
 		}
 
-		contrib := allocated[i]
+		contrib, err := converter.Convert(allocated[i], user.BaseCurrency, goals[i].Currency)
+		if err != nil {
+			return err
+		}
 		if contrib == 0 {
-			contrib = 1
+			contrib = models.MoneyFromMajor(1)
 		}
 
 		goals[i].MonthlyContrib = contrib
 		goals[i].MonthlyBudgetLimit = contrib
 
-		currentMonth := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+		currentMonth := models.MonthStart(now)
 
 		if !goals[i].MonthStarted.Valid || goals[i].MonthStarted.Time != currentMonth {
 			log.Printf("[DISTRIBUTE] Month changed for goal %d, resetting accumulated", goals[i].ID)
@@ -298,7 +286,7 @@ func (s *FinanceService) DistributeFundsToGoalsV2(ctx context.Context, telegramI
 				log.Printf("[DISTRIBUTE] No monthly contributions found for goal %d, resetting to 0", goals[i].ID)
 			} else {
 				goals[i].MonthlyAccumulated = monthlyContribRecord.AmountContributed
-				log.Printf("[DISTRIBUTE] Found monthly contributions for goal %d, setting MonthlyAccumulated=%d", goals[i].ID, goals[i].MonthlyAccumulated)
+				log.Printf("[DISTRIBUTE] Found monthly contributions for goal %d, setting MonthlyAccumulated=%s", goals[i].ID, goals[i].MonthlyAccumulated)
 			}
 		}
 
@@ -315,108 +303,128 @@ func (s *FinanceService) DistributeFundsToGoalsV2(ctx context.Context, telegramI
 			monthsNeeded = 1
 		}
 
-		goals[i].TargetDate = time.Now().AddDate(0, int(monthsNeeded), 0)
+		goals[i].TargetDate = now.AddDate(0, int(monthsNeeded), 0)
 
-		err := s.goalRepo.UpdateGoal(ctx, &goals[i])
+		err = s.goalRepo.UpdateGoal(ctx, &goals[i])
 		if err != nil {
 			log.Printf("Failed to update goal: %v", err)
+			return err
 		}
 	}
 
 	return nil
 }
 
-func (s *FinanceService) ContributeToGoalWithMonthlyTracking(ctx context.Context, goalID int64, amount int64) (*models.SavingsGoal, error) {
-	goal, err := s.goalRepo.GetGoalByID(ctx, goalID)
-	if err != nil {
-		return nil, err
-	}
-
-	log.Printf("[CONTRIBUTION] Adding %d to goal %d, current MonthlyAccumulated: %d", amount, goalID, goal.MonthlyAccumulated)
-
-	todayDate := time.Now()
-	monthStartDate := time.Date(todayDate.Year(), todayDate.Month(), 1, 0, 0, 0, 0, time.UTC)
-
-	var goalMonthStart time.Time
-	if goal.MonthStarted.Valid {
-		goalMonthStart = time.Date(goal.MonthStarted.Time.Year(), goal.MonthStarted.Time.Month(), goal.MonthStarted.Time.Day(), 0, 0, 0, 0, time.UTC)
-	}
-
-	if !goal.MonthStarted.Valid || goalMonthStart != monthStartDate {
-		log.Printf("[CONTRIBUTION] Month changed for goal %d, resetting MonthlyAccumulated", goalID)
-		goal.MonthlyAccumulated = 0
-		goal.MonthStarted.Valid = true
-		goal.MonthStarted.Time = monthStartDate
-	}
+func (s *FinanceService) ContributeToGoalWithMonthlyTracking(ctx context.Context, goalID int64, amount models.Money) (*models.SavingsGoal, error) {
+	return s.contributeToGoal(ctx, goalID, amount, models.GoalTxDeposit, sql.NullInt64{}, "")
+}
 
-	goal.CurrentAmount += amount
-	goal.MonthlyAccumulated += amount
+func (s *FinanceService) contributeToGoal(ctx context.Context, goalID int64, amount models.Money, kind string, incomeID sql.NullInt64, comment string) (*models.SavingsGoal, error) {
+	var goal *models.SavingsGoal
 
-	currentMonth := time.Date(todayDate.Year(), todayDate.Month(), 1, 0, 0, 0, 0, time.UTC)
-	monthlyContribRecord, err := s.monthlyContribRepo.GetContributionByUserGoalMonth(ctx, goal.UserID, goalID, currentMonth)
+	err := s.txManager.ReadCommitted(ctx, func(ctx context.Context) error {
+		var errTx error
+		goal, errTx = s.goalRepo.GetGoalByID(ctx, goalID)
+		if errTx != nil {
+			return errTx
+		}
 
-	if err != nil || monthlyContribRecord == nil {
-		_, createErr := s.monthlyContribRepo.CreateContribution(ctx, goal.UserID, goalID, currentMonth, goal.MonthlyAccumulated)
-		if createErr != nil {
-			log.Printf("[CONTRIBUTION] Failed to create monthly contribution: %v", createErr)
+		log.Printf("[CONTRIBUTION] Adding %s to goal %d (%s), current MonthlyAccumulated: %s", amount, goalID, kind, goal.MonthlyAccumulated)
+
+		_, errTx = s.goalTxRepo.CreateTransaction(ctx, &models.GoalTransaction{
+			GoalID:   goal.ID,
+			UserID:   goal.UserID,
+			Kind:     kind,
+			Amount:   amount,
+			IncomeID: incomeID,
+			Comment:  comment,
+		})
+		if errTx != nil {
+			return errTx
 		}
-	} else {
-		monthlyContribRecord.AmountContributed = goal.MonthlyAccumulated
-		if updateErr := s.monthlyContribRepo.UpdateContribution(ctx, monthlyContribRecord); updateErr != nil {
-			log.Printf("[CONTRIBUTION] Failed to update monthly contribution: %v", updateErr)
+
+		if errTx = s.reconcileGoal(ctx, goal); errTx != nil {
+			return errTx
 		}
-	}
 
-	log.Printf("[CONTRIBUTION] Goal %d updated: CurrentAmount=%d, MonthlyAccumulated=%d", goalID, goal.CurrentAmount, goal.MonthlyAccumulated)
+		log.Printf("[CONTRIBUTION] Goal %d updated: CurrentAmount=%s, MonthlyAccumulated=%s", goalID, goal.CurrentAmount, goal.MonthlyAccumulated)
 
-	if goal.MonthlyAccumulated > goal.MonthlyBudgetLimit && goal.MonthlyBudgetLimit > 0 {
-		log.Printf("Goal %d exceeded monthly budget: accumulated %d, limit %d",
-			goalID, goal.MonthlyAccumulated, goal.MonthlyBudgetLimit)
-	}
+		if goal.MonthlyAccumulated > goal.MonthlyBudgetLimit && goal.MonthlyBudgetLimit > 0 {
+			log.Printf("Goal %d exceeded monthly budget: accumulated %s, limit %s",
+				goalID, goal.MonthlyAccumulated, goal.MonthlyBudgetLimit)
+		}
 
-	if goal.CurrentAmount >= goal.TargetAmount {
-		goal.Status = "completed"
-		goal.CurrentAmount = goal.TargetAmount
-		log.Printf("Goal %d completed!", goalID)
-	}
+		if goal.CurrentAmount >= goal.TargetAmount && goal.HoldsPriority() {
+			// достигнутая цель освобождает место в очереди приоритетов
+			if errTx = s.reindexDeletedGoalPriorities(ctx, goal.UserID, goal.Priority); errTx != nil {
+				return errTx
+			}
+			goal.Status = "completed"
+			goal.CompletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+			log.Printf("Goal %d completed!", goalID)
+		}
 
-	err = s.goalRepo.UpdateGoal(ctx, goal)
+		if errTx = s.goalRepo.UpdateGoal(ctx, goal); errTx != nil {
+			return errTx
+		}
+		log.Printf("[CONTRIBUTION] Goal %d saved successfully", goalID)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("[CONTRIBUTION] Goal %d saved successfully", goalID)
 
 	goalUser, err := s.userRepo.GetUserByID(ctx, goal.UserID)
-	if err == nil {
-		log.Printf("[CONTRIBUTION] Redistributing funds for user %d", goalUser.TelegramID)
-		s.DistributeFundsToGoalsV2(ctx, goalUser.TelegramID)
+	if err != nil {
+		log.Printf("[CONTRIBUTION] Failed to load user %d for redistribution: %v", goal.UserID, err)
+	} else {
+		s.redistributeLater(ctx, goalUser.TelegramID)
 	}
 
 	return goal, nil
 }
 
+// redistributeLater пересчитывает месячные лимиты целей вне транзакции взноса. Задача встает в очередь
+// с ключом пользователя: взносы делаются из обработчиков, поэтому она выполнится после текущего обновления,
+// то есть после коммита и внешних транзакций, и до следующего обновления этого пользователя
+func (s *FinanceService) redistributeLater(ctx context.Context, telegramID int64) {
+	err := s.tasks.Submit(ctx, telegramID, func() {
+		// ctx обработчика к этому моменту отменен и может нести закрытую транзакцию
+		ctx, cancel := context.WithTimeout(context.Background(), redistributeTimeout)
+		defer cancel()
+
+		log.Printf("[CONTRIBUTION] Redistributing funds for user %d", telegramID)
+		if err := s.DistributeFundsToGoalsV2(ctx, telegramID); err != nil {
+			log.Printf("[CONTRIBUTION] Failed to redistribute funds for user %d: %v", telegramID, err)
+		}
+	})
+	if err != nil {
+		log.Printf("[CONTRIBUTION] Redistribution for user %d skipped: %v", telegramID, err)
+	}
+}
+
 func (s *FinanceService) GetGoalMonthlyStats(ctx context.Context, goalID int64) (map[string]interface{}, error) {
 	goal, err := s.goalRepo.GetGoalByID(ctx, goalID)
 	if err != nil {
 		return nil, err
 	}
 
-	currentMonth := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+	currentMonth := models.MonthStart(s.userNowByID(ctx, goal.UserID))
 
 	monthlyContribRecord, err := s.monthlyContribRepo.GetContributionByUserGoalMonth(ctx, goal.UserID, goalID, currentMonth)
-	monthlyAccumulated := int64(0)
+	monthlyAccumulated := models.Money(0)
 
 	if err == nil && monthlyContribRecord != nil {
 		monthlyAccumulated = monthlyContribRecord.AmountContributed
-		log.Printf("[MONTHLY_STATS] Using monthly_contributions: goal %d, accumulated=%d", goalID, monthlyAccumulated)
+		log.Printf("[MONTHLY_STATS] Using monthly_contributions: goal %d, accumulated=%s", goalID, monthlyAccumulated)
 	} else {
 		monthlyAccumulated = goal.MonthlyAccumulated
-		log.Printf("[MONTHLY_STATS] Using goal.MonthlyAccumulated: goal %d, accumulated=%d", goalID, monthlyAccumulated)
+		log.Printf("[MONTHLY_STATS] Using goal.MonthlyAccumulated: goal %d, accumulated=%s", goalID, monthlyAccumulated)
 	}
 
 	if goal.MonthlyAccumulated != monthlyAccumulated {
 		goal.MonthlyAccumulated = monthlyAccumulated
-		log.Printf("[MONTHLY_STATS] Syncing goal.MonthlyAccumulated for goal %d to %d", goalID, monthlyAccumulated)
+		log.Printf("[MONTHLY_STATS] Syncing goal.MonthlyAccumulated for goal %d to %s", goalID, monthlyAccumulated)
 		s.goalRepo.UpdateGoal(ctx, goal)
 	}
 
@@ -424,11 +432,10 @@ func (s *FinanceService) GetGoalMonthlyStats(ctx context.Context, goalID int64)
 	if goal.MonthStarted.Valid {
 		monthStartedStr = goal.MonthStarted.Time.Format("2006-01-02")
 	}
-	log.Printf("[DEBUG] GetGoalMonthlyStats for goal %d: MonthlyAccumulated=%d, MonthlyBudgetLimit=%d, MonthStarted=%s",
+	log.Printf("[DEBUG] GetGoalMonthlyStats for goal %d: MonthlyAccumulated=%s, MonthlyBudgetLimit=%s, MonthStarted=%s",
 		goalID, goal.MonthlyAccumulated, goal.MonthlyBudgetLimit, monthStartedStr)
 
-	todayDate := time.Now()
-	monthStartDate := time.Date(todayDate.Year(), todayDate.Month(), 1, 0, 0, 0, 0, time.UTC)
+	monthStartDate := currentMonth
 
 	var goalMonthStart time.Time
 	if goal.MonthStarted.Valid {
@@ -460,13 +467,13 @@ func (s *FinanceService) GetGoalMonthlyStats(ctx context.Context, goalID int64)
 			return nil, err
 		}
 		monthlyAccumulated = 0
-		log.Printf("[DEBUG] After reset for goal %d: MonthlyAccumulated=%d", goalID, monthlyAccumulated)
+		log.Printf("[DEBUG] After reset for goal %d: MonthlyAccumulated=%s", goalID, monthlyAccumulated)
 	}
 
 	monthlyBudgetLimit := goal.MonthlyBudgetLimit
 	if monthlyBudgetLimit == 0 {
 		monthlyBudgetLimit = goal.MonthlyContrib
-		log.Printf("[DEBUG] Using MonthlyContrib as budget limit: %d", monthlyBudgetLimit)
+		log.Printf("[DEBUG] Using MonthlyContrib as budget limit: %s", monthlyBudgetLimit)
 	}
 
 	remainingToTarget := goal.TargetAmount - goal.CurrentAmount
@@ -477,7 +484,7 @@ func (s *FinanceService) GetGoalMonthlyStats(ctx context.Context, goalID int64)
 	displayMonthlyBudgetLimit := monthlyBudgetLimit
 	if remainingToTarget > 0 && remainingToTarget <= displayMonthlyBudgetLimit {
 		displayMonthlyBudgetLimit = remainingToTarget
-		log.Printf("[DEBUG] Goal %d (%s) can be closed within monthly limit %d₽, showing remaining %d₽",
+		log.Printf("[DEBUG] Goal %d (%s) can be closed within monthly limit %s, showing remaining %s",
 			goal.ID, goal.GoalName, monthlyBudgetLimit, remainingToTarget)
 	}
 
@@ -486,14 +493,9 @@ func (s *FinanceService) GetGoalMonthlyStats(ctx context.Context, goalID int64)
 		remaining = 0
 	}
 
-	log.Printf("[DEBUG] Final stats for goal %d: accumulated=%d, budget_limit=%d, progress=%d",
+	log.Printf("[DEBUG] Final stats for goal %d: accumulated=%s, budget_limit=%s, progress=%d",
 		goalID, monthlyAccumulated, monthlyBudgetLimit,
-		func() int64 {
-			if monthlyBudgetLimit > 0 {
-				return (monthlyAccumulated * 100) / monthlyBudgetLimit
-			}
-			return 0
-		}())
+		monthlyAccumulated.Percent(monthlyBudgetLimit))
 
 	stats := map[string]interface{}{
 		"goal_id":              goal.ID,
@@ -501,21 +503,13 @@ func (s *FinanceService) GetGoalMonthlyStats(ctx context.Context, goalID int64)
 		"priority":             goal.Priority,
 		"target_amount":        goal.TargetAmount,
 		"current_amount":       goal.CurrentAmount,
-		"progress_percent":     0,
+		"progress_percent":     goal.CurrentAmount.Percent(goal.TargetAmount),
 		"monthly_budget_limit": monthlyBudgetLimit,
 		"monthly_accumulated":  monthlyAccumulated,
 		"monthly_remaining":    remaining,
-		"monthly_progress":     0,
+		"monthly_progress":     monthlyAccumulated.Percent(monthlyBudgetLimit),
 		"target_date":          goal.TargetDate.Format("02.01.2006"),
 	}
 
-	if goal.TargetAmount > 0 {
-		stats["progress_percent"] = (goal.CurrentAmount * 100) / goal.TargetAmount
-	}
-
-	if monthlyBudgetLimit > 0 {
-		stats["monthly_progress"] = (monthlyAccumulated * 100) / monthlyBudgetLimit
-	}
-
 	return stats, nil
 }
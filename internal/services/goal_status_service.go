@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+)
+
+var ErrGoalStatusTransition = errors.New("goal status transition not allowed")
+
+// PauseGoal исключает цель из распределения, сохраняя ее приоритет
+func (s *FinanceService) PauseGoal(ctx context.Context, telegramID int64, goalID int64) error {
+	return s.changeGoalStatus(ctx, telegramID, goalID, func(goal *models.SavingsGoal) error {
+		if goal.Status != models.GoalStatusActive {
+			return ErrGoalStatusTransition
+		}
+		goal.Status = models.GoalStatusPaused
+		goal.MonthlyContrib = 0
+		goal.MonthlyBudgetLimit = 0
+		return nil
+	})
+}
+
+// ResumeGoal возвращает цель с паузы или из архива; из архива цель встает в конец очереди
+func (s *FinanceService) ResumeGoal(ctx context.Context, telegramID int64, goalID int64) error {
+	return s.changeGoalStatus(ctx, telegramID, goalID, func(goal *models.SavingsGoal) error {
+		switch goal.Status {
+		case models.GoalStatusPaused:
+			goal.Status = models.GoalStatusActive
+		case models.GoalStatusArchived:
+			if goal.CompletedAt.Valid && goal.CurrentAmount >= goal.TargetAmount {
+				goal.Status = models.GoalStatusCompleted
+				return nil
+			}
+			priority, err := s.nextGoalPriority(ctx, goal.UserID)
+			if err != nil {
+				return err
+			}
+			goal.Status = models.GoalStatusActive
+			goal.Priority = priority
+		default:
+			return ErrGoalStatusTransition
+		}
+		return nil
+	})
+}
+
+// ArchiveGoal убирает цель из списка и освобождает ее место в очереди приоритетов
+func (s *FinanceService) ArchiveGoal(ctx context.Context, telegramID int64, goalID int64) error {
+	return s.changeGoalStatus(ctx, telegramID, goalID, func(goal *models.SavingsGoal) error {
+		if goal.Status == models.GoalStatusArchived {
+			return ErrGoalStatusTransition
+		}
+		if goal.HoldsPriority() {
+			if err := s.reindexDeletedGoalPriorities(ctx, goal.UserID, goal.Priority); err != nil {
+				return err
+			}
+		}
+		goal.Status = models.GoalStatusArchived
+		goal.MonthlyContrib = 0
+		goal.MonthlyBudgetLimit = 0
+		return nil
+	})
+}
+
+// GetArchivedGoals - достигнутые и архивные цели
+func (s *FinanceService) GetArchivedGoals(ctx context.Context, telegramID int64) ([]models.SavingsGoal, error) {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	return s.goalRepo.GetUserArchivedGoals(ctx, user.ID)
+}
+
+func (s *FinanceService) changeGoalStatus(ctx context.Context, telegramID int64, goalID int64, apply func(goal *models.SavingsGoal) error) error {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+
+	return s.txManager.ReadCommitted(ctx, func(ctx context.Context) error {
+		goal, errTx := s.goalRepo.GetGoalByID(ctx, goalID)
+		if errTx != nil {
+			return fmt.Errorf("goal not found: %w", errTx)
+		}
+		if goal.UserID != user.ID {
+			return fmt.Errorf("goal does not belong to user")
+		}
+
+		oldStatus := goal.Status
+		if errTx = apply(goal); errTx != nil {
+			return errTx
+		}
+		if errTx = s.goalRepo.UpdateGoal(ctx, goal); errTx != nil {
+			return fmt.Errorf("failed to update goal: %w", errTx)
+		}
+
+		log.Printf("[GOAL_STATUS] Goal %d: %s -> %s", goal.ID, oldStatus, goal.Status)
+
+		_, errTx = s.DistributeFundsToGoals(ctx, telegramID)
+		return errTx
+	})
+}
+
+// следующий свободный приоритет после активных целей и целей на паузе
+func (s *FinanceService) nextGoalPriority(ctx context.Context, userID int64) (int, error) {
+	goals, err := s.goalRepo.GetUserGoals(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	maxPriority := 0
+	for _, g := range goals {
+		if g.HoldsPriority() && g.Priority > maxPriority {
+			maxPriority = g.Priority
+		}
+	}
+	return maxPriority + 1, nil
+}
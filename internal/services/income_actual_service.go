@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+)
+
+// план и факт по одному доходу за месяц
+type IncomePlanActual struct {
+	Income    models.Income
+	Planned   models.Money // по расписанию, у нерегулярного дохода - среднее
+	Actual    models.Money // подтвержденные поступления, а по неподтвержденным - план
+	Confirmed int
+	Pending   int // выплаты, о которых напомнили, но поступление не подтверждено
+	Late      []models.IncomeProcessingLog
+}
+
+type IncomeMonthReport struct {
+	Month        time.Time
+	BaseCurrency string
+	Incomes      []IncomePlanActual
+	TotalPlanned models.Money // в базовой валюте
+	TotalActual  models.Money
+}
+
+// ConfirmIncomeReceived записывает, сколько и когда пришло по выплате с плановой датой payDate
+func (s *FinanceService) ConfirmIncomeReceived(ctx context.Context, telegramID int64, incomeID int64, payDate time.Time, actualAmount models.Money, receivedDate time.Time) (*models.IncomeProcessingLog, error) {
+	income, err := s.GetUserIncomeByID(ctx, telegramID, incomeID)
+	if err != nil {
+		return nil, err
+	}
+
+	payDate = models.DayStart(payDate)
+	receivedDate = time.Date(receivedDate.Year(), receivedDate.Month(), receivedDate.Day(), 0, 0, 0, 0, time.UTC)
+
+	entry, err := s.processingLogRepo.GetProcessingLogByIncomeDate(ctx, income.ID, payDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		// напоминания не было (например, выплату отметили заранее) - заводим запись сами
+		entry, err = s.processingLogRepo.CreateProcessingLog(ctx, income.ID, income.UserID, payDate, income.PaydayAmount(payDate))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get processing log: %w", err)
+	}
+
+	if err := s.processingLogRepo.ConfirmProcessingLog(ctx, entry.ID, actualAmount, receivedDate); err != nil {
+		return nil, err
+	}
+	entry.ActualAmount = actualAmount
+	entry.ReceivedDate = sql.NullTime{Time: receivedDate, Valid: true}
+
+	log.Printf("[INCOME_ACTUAL] Income %d payday %s: planned %s, received %s on %s",
+		income.ID, payDate.Format("02.01.2006"), entry.IncomeAmount, actualAmount, receivedDate.Format("02.01.2006"))
+	return entry, nil
+}
+
+// GetIncomePlanVsActual - план и факт по доходам пользователя за месяц
+func (s *FinanceService) GetIncomePlanVsActual(ctx context.Context, telegramID int64, year int, month time.Month) (*IncomeMonthReport, error) {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	incomes, err := s.incomeRepo.GetUserIncomes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	from := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	logs, err := s.processingLogRepo.GetProcessingLogsByUserPeriod(ctx, user.ID, from, from.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	converter, err := s.CurrencyConverter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &IncomeMonthReport{Month: from, BaseCurrency: user.BaseCurrency}
+	for _, income := range incomes {
+		planned, err := s.plannedIncomeForMonth(ctx, income, year, month, user.Location())
+		if err != nil {
+			log.Printf("[INCOME_REPORT] Invalid schedule for '%s', skipping: %v", income.Name, err)
+			continue
+		}
+
+		row := IncomePlanActual{Income: income, Planned: planned, Actual: planned}
+		if income.IsIrregular() {
+			// у нерегулярного дохода факт - только записанные поступления
+			row.Actual = 0
+		}
+		for _, entry := range logs {
+			if entry.IncomeID != income.ID {
+				continue
+			}
+			if !entry.Confirmed() {
+				row.Pending++
+				continue
+			}
+			row.Confirmed++
+			if income.IsIrregular() {
+				row.Actual += entry.ActualAmount
+				continue
+			}
+			row.Actual += entry.ActualAmount - entry.IncomeAmount
+			if entry.IsLate() {
+				row.Late = append(row.Late, entry)
+			}
+		}
+
+		convertedPlan, err := converter.Convert(row.Planned, income.Currency, user.BaseCurrency)
+		if err != nil {
+			return nil, err
+		}
+		convertedActual, err := converter.Convert(row.Actual, income.Currency, user.BaseCurrency)
+		if err != nil {
+			return nil, err
+		}
+		report.TotalPlanned += convertedPlan
+		report.TotalActual += convertedActual
+		report.Incomes = append(report.Incomes, row)
+	}
+
+	return report, nil
+}
+
+// поправки к плану за месяц по подтвержденным выплатам: факт минус план, по доходам
+func (s *FinanceService) incomeActualAdjustments(ctx context.Context, userID int64, year int, month time.Month) (map[int64]models.Money, error) {
+	from := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	logs, err := s.processingLogRepo.GetProcessingLogsByUserPeriod(ctx, userID, from, from.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	adjustments := make(map[int64]models.Money)
+	for _, entry := range logs {
+		if entry.Confirmed() {
+			adjustments[entry.IncomeID] += entry.ActualAmount - entry.IncomeAmount
+		}
+	}
+	return adjustments, nil
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/schedule"
+)
+
+var ErrInstallmentsTotal = errors.New("installments do not add up to income amount")
+
+// CreateInstallmentIncome создает доход, приходящий частями; amount - вся сумма за месяц
+func (s *FinanceService) CreateInstallmentIncome(ctx context.Context, telegramID int64, name string, amount models.Money, currency string, installments []models.IncomeInstallment, shift schedule.Shift, notificationHour int) (*models.Income, error) {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	if err := ValidateInstallments(amount, installments); err != nil {
+		return nil, err
+	}
+	if shift == "" {
+		shift = schedule.ShiftNone
+	}
+	if currency == "" {
+		currency = user.BaseCurrency
+	}
+	if err := s.ensureRate(ctx, currency); err != nil {
+		return nil, err
+	}
+
+	planned := &models.Income{
+		Amount:       amount,
+		Frequency:    models.IncomeFrequencyInstallments,
+		PayShift:     string(shift),
+		Installments: installments,
+	}
+	nextPayDate := s.calculateNextPayDateForIncome(planned, firstPayDateAfter(user.Now(), notificationHour))
+
+	var income *models.Income
+	err = s.txManager.ReadCommitted(ctx, func(ctx context.Context) error {
+		var errTx error
+		income, errTx = s.incomeRepo.CreateIncomeWithFrequency(ctx, user.ID, name, amount, currency, models.IncomeFrequencyInstallments,
+			installments[0].Day, 0, sql.NullTime{}, string(shift), notificationHour, nextPayDate)
+		if errTx != nil {
+			return errTx
+		}
+
+		for _, inst := range installments {
+			saved, errTx := s.incomeRepo.CreateIncomeInstallment(ctx, income.ID, inst.Day, inst.Amount, inst.Percent)
+			if errTx != nil {
+				return errTx
+			}
+			income.Installments = append(income.Installments, *saved)
+		}
+		income.PayShift = string(shift)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to create income: %v", err)
+		return nil, err
+	}
+
+	_, err = s.DistributeFundsToGoals(ctx, telegramID)
+	if err != nil {
+		log.Printf("Failed to distribute funds after creating income: %v", err)
+	}
+
+	log.Printf("[INSTALLMENTS] Income created: %s, Amount=%s, Parts=%d, NextPayDate=%s", name, amount, len(installments), nextPayDate.Format("02.01.2006"))
+	return income, nil
+}
+
+// ValidateInstallments - части должны приходиться на разные дни и в сумме давать весь доход
+// (с точностью до копеек, теряемых при округлении процентов)
+func ValidateInstallments(total models.Money, installments []models.IncomeInstallment) error {
+	if len(installments) < 2 {
+		return fmt.Errorf("income needs at least 2 installments, got %d", len(installments))
+	}
+
+	days := make(map[int]bool, len(installments))
+	percentParts := 0
+	sum := models.Money(0)
+	for _, inst := range installments {
+		if inst.Day < 1 || inst.Day > 31 {
+			return fmt.Errorf("installment day out of range: %d", inst.Day)
+		}
+		if days[inst.Day] {
+			return fmt.Errorf("duplicate installment day: %d", inst.Day)
+		}
+		days[inst.Day] = true
+
+		switch {
+		case inst.Percent > 0:
+			percentParts++
+			sum += total.MulDiv(int64(inst.Percent), 100)
+		case inst.Amount > 0:
+			sum += inst.Amount
+		default:
+			return fmt.Errorf("installment on day %d has no amount", inst.Day)
+		}
+	}
+
+	if diff := total - sum; diff < 0 || (diff > 0 && diff >= models.Money(percentParts)) {
+		return fmt.Errorf("%w: %s of %s", ErrInstallmentsTotal, sum, total)
+	}
+	return nil
+}
+
+// доля месячного плана, которая должна быть закрыта к поступлению части k:
+// части, пришедшие в этом месяце до нее и она сама, относительно всего дохода
+func installmentPlanShare(income models.Income, k int) (int64, int64) {
+	amounts := income.InstallmentAmounts()
+	received := models.Money(0)
+	for j, inst := range income.Installments {
+		if inst.Day <= income.Installments[k].Day {
+			received += amounts[j]
+		}
+	}
+	return int64(received), int64(income.Amount)
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+)
+
+var ErrNotIrregularIncome = errors.New("income is not irregular")
+
+// CreateIrregularIncome создает доход без расписания; amount - оценка в месяц, пока нет истории поступлений
+func (s *FinanceService) CreateIrregularIncome(ctx context.Context, telegramID int64, name string, amount models.Money, currency string) (*models.Income, error) {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	if currency == "" {
+		currency = user.BaseCurrency
+	}
+	if err := s.ensureRate(ctx, currency); err != nil {
+		return nil, err
+	}
+
+	// next_pay_date у нерегулярного дохода - дата последнего поступления
+	income, err := s.incomeRepo.CreateIncomeWithFrequency(ctx, user.ID, name, amount, currency, models.IncomeFrequencyIrregular,
+		0, 0, sql.NullTime{}, "none", 0, payDateAt(user.Now()))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.DistributeFundsToGoals(ctx, telegramID)
+	if err != nil {
+		log.Printf("Failed to distribute funds after creating income: %v", err)
+	}
+
+	log.Printf("[IRREGULAR] Income created: %s, estimate=%s", name, amount)
+	return income, nil
+}
+
+// LogIrregularIncome записывает поступление по нерегулярному доходу сегодняшним днем
+func (s *FinanceService) LogIrregularIncome(ctx context.Context, telegramID int64, incomeID int64, amount models.Money) (*models.Income, time.Time, error) {
+	income, err := s.GetUserIncomeByID(ctx, telegramID, incomeID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if !income.IsIrregular() {
+		return nil, time.Time{}, ErrNotIrregularIncome
+	}
+
+	payDate := models.DayStart(s.userNowByID(ctx, income.UserID))
+	err = s.txManager.ReadCommitted(ctx, func(ctx context.Context) error {
+		entry, errTx := s.processingLogRepo.CreateProcessingLog(ctx, income.ID, income.UserID, payDate, amount)
+		if errTx != nil {
+			return errTx
+		}
+		if errTx = s.processingLogRepo.ConfirmProcessingLog(ctx, entry.ID, amount, payDate); errTx != nil {
+			return errTx
+		}
+		return s.incomeRepo.UpdateIncomeNextPayDate(ctx, income.ID, payDateAt(payDate))
+	})
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to log irregular income: %w", err)
+	}
+	income.NextPayDate = payDateAt(payDate)
+
+	log.Printf("[IRREGULAR] Income %d: received %s on %s", income.ID, amount, payDate.Format("02.01.2006"))
+	return income, payDate, nil
+}
+
+// GetIrregularIncomes - доходы пользователя без расписания
+func (s *FinanceService) GetIrregularIncomes(ctx context.Context, telegramID int64) ([]models.Income, error) {
+	incomes, err := s.GetUserIncomes(ctx, telegramID)
+	if err != nil {
+		return nil, err
+	}
+
+	var irregular []models.Income
+	for _, income := range incomes {
+		if income.IsIrregular() {
+			irregular = append(irregular, income)
+		}
+	}
+	return irregular, nil
+}
+
+// LastPaydayAmount - сколько пришло в последнюю выплату: у нерегулярного дохода - последнее поступление
+func (s *FinanceService) LastPaydayAmount(ctx context.Context, income models.Income) models.Money {
+	if !income.IsIrregular() {
+		return income.PaydayAmount(income.NextPayDate)
+	}
+
+	entry, err := s.processingLogRepo.GetLastProcessingLog(ctx, income.ID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Failed to get last receipt for income %d: %v", income.ID, err)
+		}
+		return 0
+	}
+	return entry.Received()
+}
+
+// плановая сумма дохода за месяц: по расписанию, а у нерегулярного - среднее за последние месяцы.
+// Месяцы считаются в зоне пользователя loc: поступления записаны его календарным днем
+func (s *FinanceService) plannedIncomeForMonth(ctx context.Context, income models.Income, year int, month time.Month, loc *time.Location) (models.Money, error) {
+	if !income.IsIrregular() {
+		return incomeAmountForMonth(income, year, month)
+	}
+
+	monthStart := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	created := income.CreatedAt.In(loc)
+	created = time.Date(created.Year(), created.Month(), 1, 0, 0, 0, 0, loc)
+
+	// месяцы до появления дохода в среднее не идут
+	months := max(s.irregularWindow, 1)
+	for months > 0 && monthStart.AddDate(0, -months, 0).Before(created) {
+		months--
+	}
+	if months == 0 {
+		// истории еще нет - берем оценку пользователя
+		return income.Amount, nil
+	}
+
+	// processed_date - дата без зоны, границы окна тоже передаем датами
+	from := models.DayStart(monthStart.AddDate(0, -months, 0))
+	total, err := s.processingLogRepo.SumActualByIncomePeriod(ctx, income.ID, from, models.DayStart(monthStart))
+	if err != nil {
+		return 0, err
+	}
+	return total / models.Money(months), nil
+}
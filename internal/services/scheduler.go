@@ -2,39 +2,70 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"github.com/Lina3386/telegram-bot/internal/repository"
 	"log"
 	"time"
 
 	"github.com/Lina3386/telegram-bot/internal/models"
+	"github.com/Lina3386/telegram-bot/internal/sender"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// часы уведомлений у пользователей в разных зонах наступают не только в начале часа сервера
+const schedulerInterval = 15 * time.Minute
+
+const (
+	// уведомление, ушедшее позже этого срока, помечается как пропущенное
+	missedPaydayGrace = time.Hour
+	// более старые пропущенные выплаты не догоняем
+	missedPaydayMaxAge = 7 * 24 * time.Hour
+)
+
+const (
+	notificationRetryInterval = time.Minute
+	notificationRetryBatch    = 100
+	// на сколько уведомление забирается одним экземпляром бота, пока он пытается его отправить
+	notificationClaimLease = 10 * time.Minute
+	// пауза перед повтором растет вдвое с каждой попыткой, но не больше часа
+	notificationRetryBaseDelay = time.Minute
+	notificationRetryMaxDelay  = time.Hour
+	// уведомление, которое не удалось доставить за сутки, уже неактуально
+	notificationMaxAge = 24 * time.Hour
+)
+
 type Scheduler struct {
-	bot              *tgbotapi.BotAPI
+	bot              BotAPI
 	financeService   *FinanceService
 	userRepo         *repository.UserRepository
 	contributionRepo *repository.MonthlyContributionsRepository
+	notificationRepo *repository.NotificationRepository
 }
 
-func NewScheduler(bot *tgbotapi.BotAPI, financeService *FinanceService, userRepo *repository.UserRepository, contributionRepo *repository.MonthlyContributionsRepository) *Scheduler {
+func NewScheduler(bot BotAPI, financeService *FinanceService, userRepo *repository.UserRepository, contributionRepo *repository.MonthlyContributionsRepository, notificationRepo *repository.NotificationRepository) *Scheduler {
 	return &Scheduler{
 		bot:              bot,
 		financeService:   financeService,
 		userRepo:         userRepo,
 		contributionRepo: contributionRepo,
+		notificationRepo: notificationRepo,
 	}
 }
 
 func (s *Scheduler) Start(ctx context.Context) error {
-	log.Println("Scheduler started, checking every hour...")
+	log.Printf("Scheduler started, checking every %s...", schedulerInterval)
 
 	s.checkPayDates(ctx)
+	s.checkCategoryBudgets(ctx)
 
-	ticker := time.NewTicker(1 * time.Hour)
+	ticker := time.NewTicker(schedulerInterval)
 	defer ticker.Stop()
 
+	retryTicker := time.NewTicker(notificationRetryInterval)
+	defer retryTicker.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -42,153 +73,202 @@ func (s *Scheduler) Start(ctx context.Context) error {
 			return nil
 
 		case <-ticker.C:
-			now := time.Now()
-			if now.Minute() < 5 {
-				currentHour := now.Hour()
-				log.Printf("⏰ %02d:00 - Checking for payday notifications...", currentHour)
-				s.checkPayDatesForHour(ctx, currentHour)
-			}
+			s.checkPayDates(ctx)
+			s.checkCategoryBudgets(ctx)
+			log.Printf("[SENDER] %s", sender.Summary())
+
+		case <-retryTicker.C:
+			s.retryNotifications(ctx)
 		}
 	}
 }
 
-func (s *Scheduler) checkPayDatesForHour(ctx context.Context, hour int) {
-	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	log.Printf("Checking pay dates for today at hour %d: %s", hour, today.Format("02.01.2006"))
+// день выплаты и час уведомления сравниваются со временем в зоне пользователя;
+// выплаты, уведомление о которых не ушло вовремя (бот лежал), доставляются с пометкой "пропущено"
+func (s *Scheduler) checkPayDates(ctx context.Context) {
+	// зоны восточнее UTC уже могут жить завтрашним днем
+	horizon := models.DayStart(time.Now().UTC()).AddDate(0, 0, 2)
 
-	incomes, err := s.financeService.GetIncomesByPayDateAndHour(ctx, today, hour)
+	incomes, err := s.financeService.GetIncomesDueBefore(ctx, horizon)
 	if err != nil {
-		log.Printf("Failed to get incomes by pay date and hour: %v", err)
-		return
-	}
-
-	if len(incomes) == 0 {
-		log.Printf("No pay dates today at hour %d", hour)
+		log.Printf("Failed to get due incomes: %v", err)
 		return
 	}
 
-	log.Printf("Found %d income(s) for today at hour %d", len(incomes), hour)
-
 	for _, income := range incomes {
-		processed, err := s.financeService.IsIncomeProcessedOnDate(ctx, income.ID, today)
+		user, err := s.userRepo.GetUserByID(ctx, income.UserID)
 		if err != nil {
-			log.Printf("Failed to check if income %d was processed: %v", income.ID, err)
-		} else if processed {
-			log.Printf("Income %d already processed for %s, skipping", income.ID, today.Format("02.01.2006"))
+			log.Printf("Failed to get user %d: %v", income.UserID, err)
 			continue
 		}
 
-		user, err := s.userRepo.GetUserByID(ctx, income.UserID)
+		now := user.Now()
+		payDate := models.DayStart(income.NextPayDate)
+		dueAt := paydayDueAt(income, payDate, now.Location())
+		if now.Before(dueAt) {
+			continue
+		}
+
+		if late := now.Sub(dueAt); late > missedPaydayMaxAge {
+			// слишком старые выплаты не напоминаем, переходим к первой, которую еще можно догнать
+			log.Printf("[SCHEDULER] Payday %s for income %d is %s old, skipping", payDate.Format("02.01.2006"), income.ID, late.Round(time.Hour))
+			next := income.NextPayDate
+			for now.Sub(dueAt) > missedPaydayMaxAge {
+				next = s.financeService.calculateNextPayDateForIncome(&income, next)
+				payDate = models.DayStart(next)
+				dueAt = paydayDueAt(income, payDate, now.Location())
+			}
+			s.advancePayDate(ctx, income, next)
+			income.NextPayDate = next
+			if now.Before(dueAt) {
+				continue
+			}
+		}
+
+		today := models.DayStart(now)
+
+		processed, err := s.financeService.IsIncomeProcessedOnDate(ctx, income.ID, payDate)
 		if err != nil {
-			log.Printf("Failed to get user %d: %v", income.UserID, err)
+			log.Printf("Failed to check if income %d was processed: %v", income.ID, err)
+			continue
+		}
+		if processed {
+			// уведомление ушло, но "Завершить" так и не нажали - не теряем следующую выплату
+			next := s.financeService.calculateNextPayDateForIncome(&income, payDate)
+			if !models.DayStart(next).After(today) {
+				s.advancePayDate(ctx, income, next)
+			}
 			continue
 		}
 
-		s.sendPaydayNotification(ctx, income, user.TelegramID)
+		late := now.Sub(dueAt)
+		missed := late > missedPaydayGrace
+		if missed {
+			log.Printf("[SCHEDULER] Catching up missed payday %s for income %d (%s late)", payDate.Format("02.01.2006"), income.ID, late.Round(time.Minute))
+		} else {
+			log.Printf("⏰ Payday for income %d at %s (%s)", income.ID, now.Format("02.01.2006 15:04"), user.Timezone)
+		}
+		incomeAmount := income.PaydayAmount(income.NextPayDate)
+		s.sendPaydayNotification(ctx, income, incomeAmount, user.TelegramID, missed)
 
-		_, err = s.financeService.LogIncomeProcessing(ctx, income.ID, income.UserID, today, income.Amount)
+		_, err = s.financeService.LogIncomeProcessing(ctx, income.ID, income.UserID, payDate, incomeAmount)
 		if err != nil {
 			log.Printf("Failed to log income processing for income %d: %v", income.ID, err)
 		}
 	}
 }
 
-func (s *Scheduler) checkPayDates(ctx context.Context) {
-	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	log.Printf("Checking pay dates for today: %s", today.Format("02.01.2006"))
-
-	incomes, err := s.financeService.GetIncomesByPayDate(ctx, today)
-	if err != nil {
-		log.Printf("Failed to get incomes by pay date: %v", err)
-		return
-	}
+// момент уведомления о выплате payDate: час уведомления дохода в зоне пользователя
+func paydayDueAt(income models.Income, payDate time.Time, loc *time.Location) time.Time {
+	return time.Date(payDate.Year(), payDate.Month(), payDate.Day(), income.NotificationHour, 0, 0, 0, loc)
+}
 
-	if len(incomes) == 0 {
-		log.Println("No pay dates today")
+func (s *Scheduler) advancePayDate(ctx context.Context, income models.Income, next time.Time) {
+	if err := s.financeService.UpdateIncomeNextPayDate(ctx, income.ID, next); err != nil {
+		log.Printf("Failed to advance pay date for income %d: %v", income.ID, err)
 		return
 	}
+	log.Printf("[SCHEDULER] Income %d: next pay date %s -> %s", income.ID, income.NextPayDate.Format("02.01.2006"), next.Format("02.01.2006"))
+}
 
-	log.Printf("Found %d income(s) for today", len(incomes))
-
-	for _, income := range incomes {
-		user, err := s.userRepo.GetUserByID(ctx, income.UserID)
-		if err != nil {
-			log.Printf("Failed to get user %d: %v", income.UserID, err)
-			continue
-		}
-
-		s.sendPaydayNotification(ctx, income, user.TelegramID)
-
-		nextPayDate := s.calculateNextPayDate(income.Frequency, income.RecurringDay)
-		err = s.financeService.UpdateIncomeNextPayDate(ctx, income.ID, nextPayDate)
-		if err != nil {
-			log.Printf("Failed to update next pay date for income %d: %v", income.ID, err)
-		}
-	}
+// NotifyIncomeReceived - то же меню с рекомендациями, что и в день выплаты, для поступления,
+// записанного вручную (нерегулярный доход)
+func (s *Scheduler) NotifyIncomeReceived(ctx context.Context, income models.Income, amount models.Money, telegramID int64) {
+	s.sendPaydayNotification(ctx, income, amount, telegramID, false)
 }
 
-func (s *Scheduler) sendPaydayNotification(ctx context.Context, income models.Income, telegramID int64) {
+func (s *Scheduler) sendPaydayNotification(ctx context.Context, income models.Income, incomeAmount models.Money, telegramID int64, missed bool) {
 	goals, err := s.financeService.GetUserActiveGoalsByTelegramID(ctx, telegramID)
 	if err != nil {
 		log.Printf("Failed to get goals for user %d: %v", telegramID, err)
 		return
 	}
 
-	now := time.Now()
+	now := s.financeService.UserNow(ctx, telegramID)
 	dateStr := now.Format("02.01.2006")
 
+	// у дохода частями пишем, какая часть пришла
+	incomeName := income.Name
+	if k := income.InstallmentOn(income.NextPayDate); k >= 0 {
+		incomeName = fmt.Sprintf("%s (часть %d из %d)", income.Name, k+1, len(income.Installments))
+	}
+
+	var missedText string
+	if missed {
+		missedText = fmt.Sprintf("⏳ Пропущено: уведомление о выплате %s не удалось отправить вовремя\n\n", income.NextPayDate.Format("02.01.2006"))
+	}
+
 	if len(goals) == 0 {
-		msg := fmt.Sprintf(
+		msg := missedText + fmt.Sprintf(
 			"💰 Сегодня: %s\n\n"+
 				"🎯 День дохода: %s\n"+
-				"Сумма: %d₽\n\n"+
+				"Сумма: %s\n\n"+
 				"ℹ️ У вас нет активных целей для накопления",
-			dateStr, income.Name, income.Amount,
+			dateStr, incomeName, models.FormatAmount(incomeAmount, income.Currency),
 		)
 
-		s.sendNotification(telegramID, msg, nil)
+		var keyboard *tgbotapi.InlineKeyboardMarkup
+		if !income.IsIrregular() {
+			markup := tgbotapi.NewInlineKeyboardMarkup(paydayConfirmButtons(income))
+			keyboard = &markup
+		}
+		s.sendNotification(ctx, telegramID, msg, keyboard)
 		return
 	}
 
-	currentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	currentMonth := models.MonthStart(now)
 	monthlyContributions, err := s.financeService.GetMonthlyContributions(ctx, income.UserID, currentMonth)
 	if err != nil {
 		log.Printf("Failed to get monthly contributions: %v", err)
 		monthlyContributions = make([]models.MonthlyContribution, 0)
 	}
 
-	contributedMap := make(map[int64]int64)
+	contributedMap := make(map[int64]models.Money)
 	for _, contrib := range monthlyContributions {
 		contributedMap[contrib.GoalID] += contrib.AmountContributed
 	}
 
-	recommendedMap := s.calculateSmartPaydayRecommendations(income.Amount, goals, contributedMap)
+	recommendedMap, err := s.financeService.paydayRecommendations(ctx, income, income.NextPayDate, incomeAmount, goals, contributedMap)
+	if err != nil {
+		log.Printf("Failed to calculate payday recommendations for user %d: %v", telegramID, err)
+		recommendedMap = make(map[int64]models.Money)
+	}
 
-	totalRecommended := int64(0)
-	for _, amount := range recommendedMap {
-		totalRecommended += amount
+	user, err := s.userRepo.GetUserByID(ctx, income.UserID)
+	if err != nil {
+		log.Printf("Failed to get user %d: %v", income.UserID, err)
+		return
+	}
+	converter, err := s.financeService.CurrencyConverter(ctx)
+	if err != nil {
+		log.Printf("Failed to load exchange rates: %v", err)
+		return
 	}
 
-	totalMonthlyPlan := int64(0)
-	totalAlreadyContributed := int64(0)
+	// итоги по целям в разных валютах приводим к базовой
+	totalRecommended := models.Money(0)
+	totalMonthlyPlan := models.Money(0)
+	totalAlreadyContributed := models.Money(0)
 	for _, goal := range goals {
-		totalMonthlyPlan += goal.MonthlyContrib
-		totalAlreadyContributed += contributedMap[goal.ID]
+		recommended, _ := converter.Convert(recommendedMap[goal.ID], goal.Currency, user.BaseCurrency)
+		plan, _ := converter.Convert(goal.MonthlyContrib, goal.Currency, user.BaseCurrency)
+		contributed, _ := converter.Convert(contributedMap[goal.ID], goal.Currency, user.BaseCurrency)
+		totalRecommended += recommended
+		totalMonthlyPlan += plan
+		totalAlreadyContributed += contributed
 	}
 
-	text := fmt.Sprintf(
+	text := missedText + fmt.Sprintf(
 		"💰 Сегодня: %s\n\n"+
 			"🎯 День поступления: %s\n"+
-			"Сумма: %d₽\n\n"+
-			"📈 Рекомендуем отложить: %d₽\n"+
+			"Сумма: %s\n\n"+
+			"📈 Рекомендуем отложить: %s\n"+
 			"(из этого поступления)\n\n"+
-			"📊 Месячный план: %d/%d₽\n"+
+			"📊 Месячный план: %s/%s\n"+
 			"(уже отложено / нужно)\n\n",
-		dateStr, income.Name, income.Amount,
-		totalRecommended,
-		totalAlreadyContributed, totalMonthlyPlan,
+		dateStr, incomeName, models.FormatAmount(incomeAmount, income.Currency),
+		models.FormatAmount(totalRecommended, user.BaseCurrency),
+		totalAlreadyContributed, models.FormatAmount(totalMonthlyPlan, user.BaseCurrency),
 	)
 
 	for i, goal := range goals {
@@ -197,152 +277,195 @@ func (s *Scheduler) sendPaydayNotification(ctx context.Context, income models.In
 			remaining = 0
 		}
 
-		progress := int64(0)
-		if goal.TargetAmount > 0 {
-			progress = (goal.CurrentAmount * 100) / goal.TargetAmount
-		}
+		progress := goal.CurrentAmount.Percent(goal.TargetAmount)
 
 		recommended := recommendedMap[goal.ID]
 
 		text += fmt.Sprintf(
 			"%d. %s (%d)\n"+
-				" Накоплено: %d/%d₽ (%d%%)\n"+
-				" Отложить 💰: %d₽ (рекомендация)\n"+
-				" До цели: %d₽\n\n",
+				" Накоплено: %s/%s (%d%%)\n"+
+				" Отложить 💰: %s (рекомендация)\n"+
+				" До цели: %s\n\n",
 			i+1, goal.GoalName, goal.Priority,
-			goal.CurrentAmount, goal.TargetAmount, progress,
-			recommended, remaining,
+			goal.CurrentAmount, models.FormatAmount(goal.TargetAmount, goal.Currency), progress,
+			models.FormatAmount(recommended, goal.Currency), models.FormatAmount(remaining, goal.Currency),
 		)
 	}
 
+	// поступление нерегулярного дохода уже записано с фактической суммой
 	var buttons [][]tgbotapi.InlineKeyboardButton
+	if !income.IsIrregular() {
+		buttons = append(buttons, paydayConfirmButtons(income))
+	}
 	for _, goal := range goals {
 		btn := tgbotapi.NewInlineKeyboardButtonData(
 			fmt.Sprintf("💰 %s (%d)", goal.GoalName, goal.Priority),
-			fmt.Sprintf("payday_goal_%d_%d", income.ID, goal.ID),
+			fmt.Sprintf("payday/%d/goal/%d", income.ID, goal.ID),
 		)
 		buttons = append(buttons, []tgbotapi.InlineKeyboardButton{btn})
 	}
 
 	completeBtn := tgbotapi.NewInlineKeyboardButtonData(
 		"✅ Завершить",
-		fmt.Sprintf("payday_complete_%d", income.ID),
+		fmt.Sprintf("payday/%d/complete", income.ID),
 	)
 	buttons = append(buttons, []tgbotapi.InlineKeyboardButton{completeBtn})
 
-	msg := tgbotapi.NewMessage(telegramID, text)
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons...)
-
-	_, err = s.bot.Send(msg)
-	if err != nil {
-		log.Printf("Failed to send payday notification to %d: %v", telegramID, err)
-	} else {
-		log.Printf("Sent payday notification to user %d for income: %s (%d₽)", telegramID, income.Name, income.Amount)
+	markup := tgbotapi.NewInlineKeyboardMarkup(buttons...)
+	if err := s.sendNotification(ctx, telegramID, text, &markup); err == nil {
+		log.Printf("Sent payday notification to user %d for income: %s (%s)", telegramID, incomeName, models.FormatAmount(incomeAmount, income.Currency))
 	}
 }
 
-func (s *Scheduler) calculateSmartPaydayRecommendations(incomeAmount int64, goals []models.SavingsGoal, contributedMap map[int64]int64) map[int64]int64 {
-	recommendations := make(map[int64]int64)
-	remainingIncome := incomeAmount
-
-	sortedGoals := make([]models.SavingsGoal, len(goals))
-	copy(sortedGoals, goals)
+// подтвердить, что выплата пришла по плану, или указать фактическую сумму и дату
+func paydayConfirmButtons(income models.Income) []tgbotapi.InlineKeyboardButton {
+	payDate := income.NextPayDate.Format("20060102")
+	return tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("👍 Пришло по плану", fmt.Sprintf("payday/%d/confirm/%s", income.ID, payDate)),
+		tgbotapi.NewInlineKeyboardButtonData("✏️ Пришло иначе", fmt.Sprintf("payday/%d/correct/%s", income.ID, payDate)),
+	)
+}
 
-	for i := 0; i < len(sortedGoals)-1; i++ {
-		for j := i + 1; j < len(sortedGoals); j++ {
-			if sortedGoals[i].Priority > sortedGoals[j].Priority {
-				sortedGoals[i], sortedGoals[j] = sortedGoals[j], sortedGoals[i]
-			}
-		}
+func (s *Scheduler) checkCategoryBudgets(ctx context.Context) {
+	alerts, err := s.financeService.CheckCategoryBudgets(ctx)
+	if err != nil {
+		log.Printf("Failed to check category budgets: %v", err)
+		return
 	}
 
-	for _, goal := range sortedGoals {
-		if remainingIncome <= 0 {
-			break
-		}
-
-		alreadyContributed := contributedMap[goal.ID]
-
-		remainingForGoal := goal.MonthlyBudgetLimit - alreadyContributed
-		if remainingForGoal < 0 {
-			remainingForGoal = 0
-		}
-
-		remainingToTarget := goal.TargetAmount - goal.CurrentAmount
-		if remainingToTarget < 0 {
-			remainingToTarget = 0
-		}
-
-		maxRecommend := remainingToTarget
-		if maxRecommend > remainingForGoal {
-			maxRecommend = remainingForGoal
-		}
-		if maxRecommend > remainingIncome {
-			maxRecommend = remainingIncome
-		}
-
-		if maxRecommend > incomeAmount/2 {
-			maxRecommend = incomeAmount / 2
-		}
-
-		if remainingToTarget <= 5000 {
-			maxRecommend = remainingToTarget
+	for _, alert := range alerts {
+		var text string
+		if alert.Threshold >= 100 {
+			text = fmt.Sprintf(
+				"🚨 Лимит превышен!\n\n"+
+					"🏷 Категория: %s\n"+
+					"Потрачено: %s из %s\n"+
+					"Перерасход: %s",
+				alert.CategoryName,
+				models.FormatAmount(alert.Spent, alert.Currency), models.FormatAmount(alert.Limit, alert.Currency),
+				models.FormatAmount(alert.Spent-alert.Limit, alert.Currency),
+			)
+		} else {
+			text = fmt.Sprintf(
+				"⚠️ Лимит почти исчерпан\n\n"+
+					"🏷 Категория: %s\n"+
+					"Потрачено: %s из %s (%d%%)\n"+
+					"Осталось: %s",
+				alert.CategoryName,
+				models.FormatAmount(alert.Spent, alert.Currency), models.FormatAmount(alert.Limit, alert.Currency),
+				alert.Spent.Percent(alert.Limit), models.FormatAmount(alert.Limit-alert.Spent, alert.Currency),
+			)
 		}
 
-		recommendations[goal.ID] = maxRecommend
-		remainingIncome -= maxRecommend
+		s.sendNotification(ctx, alert.TelegramID, text, nil)
+		log.Printf("[CATEGORY] Budget alert (%d%%) sent to user %d for '%s'", alert.Threshold, alert.TelegramID, alert.CategoryName)
 	}
-
-	return recommendations
 }
 
-func (s *Scheduler) sendNotification(chatID int64, text string, buttons *tgbotapi.InlineKeyboardMarkup) {
+// sendNotification отправляет уведомление, а если Telegram или сеть временно недоступны - сохраняет
+// его, и retryNotifications доставит позже
+func (s *Scheduler) sendNotification(ctx context.Context, chatID int64, text string, buttons *tgbotapi.InlineKeyboardMarkup) error {
 	msg := tgbotapi.NewMessage(chatID, text)
 	if buttons != nil {
 		msg.ReplyMarkup = buttons
 	}
 
-	_, err := s.bot.Send(msg)
-	if err != nil {
-		log.Printf("Failed to send notification to %d: %v", chatID, err)
+	_, err := s.bot.Send(ctx, msg)
+	if err == nil {
+		return nil
+	}
+	if sender.IsPermanent(err) {
+		sender.CountDropped()
+		log.Printf("[NOTIFY] Dropped notification to %d: %v", chatID, err)
+		return err
 	}
-}
-
-func (s *Scheduler) calculateNextPayDate(frequency string, recurringDay int) time.Time {
-	now := time.Now()
 
-	switch frequency {
-	case "monthly":
-		next := time.Date(now.Year(), now.Month()+1, recurringDay, 9, 0, 0, 0, now.Location())
-		maxDay := daysInMonth(next.Year(), next.Month())
-		if recurringDay > maxDay {
-			next = time.Date(next.Year(), next.Month(), maxDay, 9, 0, 0, 0, now.Location())
+	now := time.Now().UTC()
+	pending := &models.PendingNotification{
+		ChatID:        chatID,
+		Text:          text,
+		Attempts:      1,
+		LastError:     err.Error(),
+		NextAttemptAt: now.Add(notificationRetryDelay(1, err)),
+		CreatedAt:     now,
+	}
+	if buttons != nil {
+		markup, errJSON := json.Marshal(buttons)
+		if errJSON != nil {
+			log.Printf("Failed to marshal notification buttons: %v", errJSON)
+		} else {
+			pending.ReplyMarkup = sql.NullString{String: string(markup), Valid: true}
 		}
-		return next
+	}
 
-	case "weekly":
-		daysUntil := (recurringDay - int(now.Weekday()) + 7) % 7
-		if daysUntil <= 0 {
-			daysUntil = 7
-		}
-		next := now.AddDate(0, 0, daysUntil)
-		return time.Date(next.Year(), next.Month(), next.Day(), 9, 0, 0, 0, next.Location())
+	if errQueue := s.notificationRepo.EnqueueNotification(ctx, pending); errQueue != nil {
+		sender.CountDropped()
+		log.Printf("[NOTIFY] Dropped notification to %d: %v (send: %v)", chatID, errQueue, err)
+		return err
+	}
+	sender.CountQueued()
+	log.Printf("[NOTIFY] Failed to send notification to %d, retry at %s: %v", chatID, pending.NextAttemptAt.Format("15:04:05"), err)
+	return err
+}
 
-	case "biweekly":
-		daysUntil := (recurringDay - int(now.Weekday()) + 7) % 7
+// retryNotifications повторяет отправку сохраненных уведомлений, у которых подошло время
+func (s *Scheduler) retryNotifications(ctx context.Context) {
+	now := time.Now().UTC()
+	pending, err := s.notificationRepo.ClaimDueNotifications(ctx, now, now.Add(notificationClaimLease), notificationRetryBatch)
+	if err != nil {
+		log.Printf("Failed to get pending notifications: %v", err)
+		return
+	}
 
-		if daysUntil == 0 {
-			daysUntil = 14
-		} else if daysUntil < 0 {
-			daysUntil = (7 - int(now.Weekday()) + recurringDay + 7)
-		} else {
-			daysUntil = daysUntil + 7
+	for _, n := range pending {
+		msg := tgbotapi.NewMessage(n.ChatID, n.Text)
+		if n.ReplyMarkup.Valid {
+			var markup tgbotapi.InlineKeyboardMarkup
+			if err := json.Unmarshal([]byte(n.ReplyMarkup.String), &markup); err != nil {
+				log.Printf("Failed to unmarshal buttons of notification %d: %v", n.ID, err)
+			} else {
+				msg.ReplyMarkup = markup
+			}
+		}
+
+		_, err := s.bot.Send(ctx, msg)
+		switch {
+		case err == nil:
+			log.Printf("[NOTIFY] Delivered notification %d to %d after %d failed attempt(s)", n.ID, n.ChatID, n.Attempts)
+			s.deleteNotification(ctx, n.ID)
+
+		case sender.IsPermanent(err) || now.Sub(n.CreatedAt) > notificationMaxAge:
+			sender.CountDropped()
+			log.Printf("[NOTIFY] Dropped notification %d to %d after %d attempt(s): %v", n.ID, n.ChatID, n.Attempts+1, err)
+			s.deleteNotification(ctx, n.ID)
+
+		default:
+			attempts := n.Attempts + 1
+			next := time.Now().UTC().Add(notificationRetryDelay(attempts, err))
+			if errRepo := s.notificationRepo.RescheduleNotification(ctx, n.ID, attempts, err.Error(), next); errRepo != nil {
+				log.Printf("Failed to reschedule notification %d: %v", n.ID, errRepo)
+			}
 		}
+	}
+}
 
-		next := now.AddDate(0, 0, daysUntil)
-		return time.Date(next.Year(), next.Month(), next.Day(), 9, 0, 0, 0, next.Location())
+func (s *Scheduler) deleteNotification(ctx context.Context, id int64) {
+	if err := s.notificationRepo.DeleteNotification(ctx, id); err != nil {
+		log.Printf("Failed to delete notification %d: %v", id, err)
+	}
+}
 
-	default:
-		return now.AddDate(0, 0, 1)
+// пауза перед попыткой номер attempts+1; если Telegram сам назвал срок (retry_after), ждем не меньше
+func notificationRetryDelay(attempts int, err error) time.Duration {
+	delay := notificationRetryBaseDelay
+	for i := 1; i < attempts && delay < notificationRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > notificationRetryMaxDelay {
+		delay = notificationRetryMaxDelay
+	}
+	if retryAfter := sender.RetryAfter(err); retryAfter > delay {
+		delay = retryAfter
 	}
+	return delay
 }
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+)
+
+func (s *FinanceService) SetTimezone(ctx context.Context, telegramID int64, timezone string) (string, error) {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return "", fmt.Errorf("user not found: %w", err)
+	}
+
+	name, err := models.NormalizeTimezone(timezone)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.userRepo.UpdateTimezone(ctx, user.ID, name); err != nil {
+		return "", fmt.Errorf("failed to update timezone: %w", err)
+	}
+
+	log.Printf("[TIMEZONE] User %d switched to %s", user.ID, name)
+
+	// при смене зоны может смениться текущий месяц
+	_, err = s.DistributeFundsToGoals(ctx, telegramID)
+	if err != nil {
+		log.Printf("Failed to distribute funds after changing timezone: %v", err)
+	}
+	return name, nil
+}
+
+// UserNow - текущее время в зоне пользователя; если пользователя нет - время сервера
+func (s *FinanceService) UserNow(ctx context.Context, telegramID int64) time.Time {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return time.Now()
+	}
+	return user.Now()
+}
+
+// то же по внутреннему id пользователя
+func (s *FinanceService) userNowByID(ctx context.Context, userID int64) time.Time {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return time.Now()
+	}
+	return user.Now()
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/models"
+)
+
+var ErrUndoExpired = errors.New("undo window has passed")
+
+// UndoWindow - сколько после удаления работает кнопка отмены
+func (s *FinanceService) UndoWindow() time.Duration {
+	return s.undoWindow
+}
+
+func (s *FinanceService) undoDeadline() time.Time {
+	return time.Now().UTC().Add(-s.undoWindow)
+}
+
+func (s *FinanceService) RestoreIncome(ctx context.Context, telegramID int64, incomeID int64) (*models.Income, error) {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	restored, err := s.incomeRepo.RestoreIncome(ctx, incomeID, user.ID, s.undoDeadline())
+	if err != nil {
+		return nil, err
+	}
+	if !restored {
+		return nil, ErrUndoExpired
+	}
+
+	s.redistributeAfterEdit(ctx, telegramID)
+	log.Printf("[UNDO] Income %d restored", incomeID)
+	return s.incomeRepo.GetIncomeByID(ctx, incomeID)
+}
+
+func (s *FinanceService) RestoreExpense(ctx context.Context, telegramID int64, expenseID int64) (*models.Expense, error) {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	restored, err := s.expenseRepo.RestoreExpense(ctx, expenseID, user.ID, s.undoDeadline())
+	if err != nil {
+		return nil, err
+	}
+	if !restored {
+		return nil, ErrUndoExpired
+	}
+
+	s.redistributeAfterEdit(ctx, telegramID)
+	log.Printf("[UNDO] Expense %d restored", expenseID)
+	return s.expenseRepo.GetExpenseByID(ctx, expenseID)
+}
+
+// RestoreGoal возвращает цель на ее прежний приоритет, сдвигая остальные; взносы и история при удалении не трогались
+func (s *FinanceService) RestoreGoal(ctx context.Context, telegramID int64, goalID int64) (*models.SavingsGoal, error) {
+	user, err := s.userRepo.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	err = s.txManager.ReadCommitted(ctx, func(ctx context.Context) error {
+		goal, errTx := s.goalRepo.GetDeletedGoal(ctx, goalID, user.ID, s.undoDeadline())
+		if errors.Is(errTx, sql.ErrNoRows) {
+			return ErrUndoExpired
+		}
+		if errTx != nil {
+			return fmt.Errorf("failed to get deleted goal: %w", errTx)
+		}
+
+		priority := goal.Priority
+		if goal.HoldsPriority() {
+			goals, errTx := s.goalRepo.GetUserGoals(ctx, user.ID)
+			if errTx != nil {
+				return errTx
+			}
+
+			// пока цель была удалена, целей могло стать меньше - встаем не дальше конца очереди
+			holding := 0
+			for _, other := range goals {
+				if other.HoldsPriority() {
+					holding++
+				}
+			}
+			priority = min(priority, holding+1)
+
+			for i := range goals {
+				if goals[i].HoldsPriority() && goals[i].Priority >= priority {
+					goals[i].Priority++
+					if errTx = s.goalRepo.UpdateGoal(ctx, &goals[i]); errTx != nil {
+						return fmt.Errorf("failed to shift goal priority: %w", errTx)
+					}
+				}
+			}
+		}
+
+		if errTx = s.goalRepo.RestoreGoal(ctx, goal.ID, priority); errTx != nil {
+			return errTx
+		}
+
+		_, errTx = s.DistributeFundsToGoals(ctx, telegramID)
+		return errTx
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[UNDO] Goal %d restored", goalID)
+	return s.goalRepo.GetGoalByID(ctx, goalID)
+}
+
+// PurgeDeleted удаляет насовсем все, что удалено раньше чем retention назад
+func (s *FinanceService) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	before := time.Now().UTC().Add(-retention)
+
+	incomes, err := s.incomeRepo.PurgeDeletedIncomes(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+	expenses, err := s.expenseRepo.PurgeDeletedExpenses(ctx, before)
+	if err != nil {
+		return incomes, err
+	}
+	goals, err := s.goalRepo.PurgeDeletedGoals(ctx, before)
+	if err != nil {
+		return incomes + expenses, err
+	}
+
+	return incomes + expenses + goals, nil
+}
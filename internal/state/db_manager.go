@@ -4,28 +4,163 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Lina3386/telegram-bot/internal/client/db"
 )
 
+// запас времени на один запрос к таблице сессий
+const dbStateQueryTimeout = 5 * time.Second
+
+// DBStateManager хранит сессии в таблице sessions, чтобы диалоги переживали перезапуск
 type DBStateManager struct {
-	db *sql.DB
+	db  db.QueryExecer
+	ttl time.Duration
+}
+
+func NewDBStateManager(db db.QueryExecer, ttl time.Duration) *DBStateManager {
+	return &DBStateManager{
+		db:  db,
+		ttl: ttl,
+	}
+}
+
+func (m *DBStateManager) expiresAt(now time.Time) time.Time {
+	if m.ttl <= 0 {
+		// без TTL сессия не истекает
+		return now.AddDate(100, 0, 0)
+	}
+	return now.Add(m.ttl)
 }
 
 func (m *DBStateManager) GetSession(ctx context.Context, userID int64) (*UserSession, error) {
-	var session UserSession
+	ctx, cancel := context.WithTimeout(ctx, dbStateQueryTimeout)
+	defer cancel()
+
+	session := &UserSession{
+		UserID:   userID,
+		State:    StateIdle,
+		TempData: make(map[string]string),
+	}
+
 	var tempDataJSON []byte
+	err := m.db.QueryRowContext(ctx,
+		`SELECT state, temp_data, updated_at FROM sessions WHERE user_id = $1 AND expires_at > $2`,
+		userID, time.Now().UTC(),
+	).Scan(&session.State, &tempDataJSON, &session.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return session, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
 
+	if err := json.Unmarshal(tempDataJSON, &session.TempData); err != nil {
+		return nil, fmt.Errorf("failed to decode temp data: %w", err)
+	}
+	return session, nil
+}
+
+func (m *DBStateManager) SetState(ctx context.Context, userID int64, state DialogState) error {
+	ctx, cancel := context.WithTimeout(ctx, dbStateQueryTimeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	// у истекшей сессии временные данные не переносятся
+	_, err := m.db.ExecContext(ctx,
+		`INSERT INTO sessions (user_id, state, temp_data, updated_at, expires_at)
+		 VALUES ($1, $2, '{}'::jsonb, $3, $4)
+		 ON CONFLICT (user_id) DO UPDATE
+		 SET state = EXCLUDED.state,
+		     temp_data = CASE WHEN sessions.expires_at > $3 THEN sessions.temp_data ELSE '{}'::jsonb END,
+		     updated_at = EXCLUDED.updated_at,
+		     expires_at = EXCLUDED.expires_at`,
+		userID, string(state), now, m.expiresAt(now),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set state %s: %w", state, err)
+	}
+	return nil
+}
+
+func (m *DBStateManager) GetState(ctx context.Context, userID int64) (DialogState, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbStateQueryTimeout)
+	defer cancel()
+
+	var state string
 	err := m.db.QueryRowContext(ctx,
-		"SELECT user_id, state, temp_data FROM sessions WHERE user_id = $1",
-		userID,
-	).Scan(&session.UserID, &session.State, &tempDataJSON)
+		`SELECT state FROM sessions WHERE user_id = $1 AND expires_at > $2`,
+		userID, time.Now().UTC(),
+	).Scan(&state)
+	if err == sql.ErrNoRows {
+		return StateIdle, nil
+	}
+	if err != nil {
+		return StateIdle, fmt.Errorf("failed to get state: %w", err)
+	}
+	return DialogState(state), nil
+}
+
+func (m *DBStateManager) SetTempData(ctx context.Context, userID int64, key, value string) error {
+	ctx, cancel := context.WithTimeout(ctx, dbStateQueryTimeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	_, err := m.db.ExecContext(ctx,
+		`INSERT INTO sessions (user_id, state, temp_data, updated_at, expires_at)
+		 VALUES ($1, $2, jsonb_build_object($3::text, $4::text), $5, $6)
+		 ON CONFLICT (user_id) DO UPDATE
+		 SET state = CASE WHEN sessions.expires_at > $5 THEN sessions.state ELSE EXCLUDED.state END,
+		     temp_data = CASE WHEN sessions.expires_at > $5 THEN sessions.temp_data || EXCLUDED.temp_data ELSE EXCLUDED.temp_data END,
+		     updated_at = EXCLUDED.updated_at,
+		     expires_at = EXCLUDED.expires_at`,
+		userID, string(StateIdle), key, value, now, m.expiresAt(now),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set temp data %s: %w", key, err)
+	}
+	return nil
+}
+
+func (m *DBStateManager) GetTempData(ctx context.Context, userID int64, key string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbStateQueryTimeout)
+	defer cancel()
 
+	var value sql.NullString
+	err := m.db.QueryRowContext(ctx,
+		`SELECT temp_data ->> $2 FROM sessions WHERE user_id = $1 AND expires_at > $3`,
+		userID, key, time.Now().UTC(),
+	).Scan(&value)
 	if err == sql.ErrNoRows {
-		session.UserID = userID
-		session.State = StateIdle
-		session.TempData = make(map[string]string)
-		return &session, nil
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get temp data %s: %w", key, err)
+	}
+	return value.String, nil
+}
+
+func (m *DBStateManager) ClearSession(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, dbStateQueryTimeout)
+	defer cancel()
+
+	if _, err := m.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to clear session: %w", err)
 	}
+	return nil
+}
 
-	json.Unmarshal(tempDataJSON, &session.TempData)
-	return &session, nil
+// сессия в состоянии idle без данных эквивалентна отсутствующей
+func (m *DBStateManager) ClearState(ctx context.Context, userID int64) error {
+	return m.ClearSession(ctx, userID)
+}
+
+func (m *DBStateManager) PurgeExpired(ctx context.Context) (int64, error) {
+	res, err := m.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= $1`, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
 }
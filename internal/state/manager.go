@@ -1,133 +1,211 @@
 package state
 
 import (
+	"context"
 	"sync"
+	"time"
 )
 
 type DialogState string
 
 const (
-	StateChangingGoalPriority  DialogState = "changing_goal_priority"
-	StatePaydayEnteringAmount              = "payday_entering_amount"
-	StateIdle                  DialogState = "idle"
-	StateAddingIncome          DialogState = "adding_income"
-	StateAddingIncomeAmount    DialogState = "adding_income_amount"
-	StateAddingIncomeFrequency DialogState = "adding_income_frequency"
-	StateAddingIncomeDay       DialogState = "adding_income_day"
-	StateAddingIncomeHour      DialogState = "adding_income_hour"
-	StateAddingExpense         DialogState = "adding_expense"
-	StateAddingExpenseAmount   DialogState = "adding_expense_amount"
-	StateAddingContribution    DialogState = "adding_contribution"
-	StateCreatingGoal          DialogState = "creating_goal"
-	StateCreatingGoalTarget    DialogState = "creating_goal_target"
-	StateWithdrawingFromGoal   DialogState = "withdrawing_from_goal"
+	StateChangingGoalPriority   DialogState = "changing_goal_priority"
+	StatePaydayEnteringAmount   DialogState = "payday_entering_amount"
+	StatePaydayEnteringActual   DialogState = "payday_entering_actual"
+	StateIdle                   DialogState = "idle"
+	StateAddingIncome           DialogState = "adding_income"
+	StateAddingIncomeAmount     DialogState = "adding_income_amount"
+	StateAddingIncomeFrequency  DialogState = "adding_income_frequency"
+	StateAddingIncomeDay        DialogState = "adding_income_day"
+	StateAddingIncomeShift      DialogState = "adding_income_shift"
+	StateAddingIncomeHour       DialogState = "adding_income_hour"
+	StateAddingExpense          DialogState = "adding_expense"
+	StateAddingExpenseAmount    DialogState = "adding_expense_amount"
+	StateAddingExpenseKind      DialogState = "adding_expense_kind"
+	StateAddingExpenseFrequency DialogState = "adding_expense_frequency"
+	StateAddingExpenseDay       DialogState = "adding_expense_day"
+	StateAddingExpenseDate      DialogState = "adding_expense_date"
+	StateAddingExpenseCategory  DialogState = "adding_expense_category"
+	StateSettingCategoryLimit   DialogState = "setting_category_limit"
+	StateAddingContribution     DialogState = "adding_contribution"
+	StateCreatingGoal           DialogState = "creating_goal"
+	StateCreatingGoalTarget     DialogState = "creating_goal_target"
+	StateCreatingGoalDeadline   DialogState = "creating_goal_deadline"
+	StateWithdrawingFromGoal    DialogState = "withdrawing_from_goal"
+	StateSettingGoalPercent     DialogState = "setting_goal_percent"
+	StateSettingTimezone        DialogState = "setting_timezone"
+	StateEditingName            DialogState = "editing_name"
+	StateEditingAmount          DialogState = "editing_amount"
+	StateEditingIncomeHour      DialogState = "editing_income_hour"
 )
 
+// StateStore хранит состояние многошаговых диалогов пользователей
+type StateStore interface {
+	GetSession(ctx context.Context, userID int64) (*UserSession, error)
+	SetState(ctx context.Context, userID int64, state DialogState) error
+	GetState(ctx context.Context, userID int64) (DialogState, error)
+	SetTempData(ctx context.Context, userID int64, key, value string) error
+	GetTempData(ctx context.Context, userID int64, key string) (string, error)
+	ClearSession(ctx context.Context, userID int64) error
+	ClearState(ctx context.Context, userID int64) error
+	// PurgeExpired удаляет сессии, к которым не обращались дольше TTL
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
 type UserSession struct {
-	UserID   int64
-	State    DialogState
-	TempData map[string]string
-	mu       sync.RWMutex
+	UserID    int64
+	State     DialogState
+	TempData  map[string]string
+	UpdatedAt time.Time
+	mu        sync.RWMutex
+}
+
+// Get - значение из временных данных диалога или пустая строка
+func (s *UserSession) Get(key string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.TempData[key]
 }
 
+// StateManager хранит сессии в памяти процесса
 type StateManager struct {
 	sessions map[int64]*UserSession
+	ttl      time.Duration
 	mu       sync.RWMutex
 }
 
-func NewStateManager() *StateManager {
+func NewStateManager(ttl time.Duration) *StateManager {
 	return &StateManager{
 		sessions: make(map[int64]*UserSession),
+		ttl:      ttl,
 	}
 }
 
-func (sm *StateManager) GetSession(userID int64) *UserSession {
+func (sm *StateManager) expired(session *UserSession) bool {
+	return sm.ttl > 0 && time.Since(session.UpdatedAt) > sm.ttl
+}
+
+// возвращает живую сессию, вызывать под sm.mu
+func (sm *StateManager) liveSession(userID int64) (*UserSession, bool) {
+	session, exists := sm.sessions[userID]
+	if !exists {
+		return nil, false
+	}
+	if sm.expired(session) {
+		delete(sm.sessions, userID)
+		return nil, false
+	}
+	return session, true
+}
+
+func (sm *StateManager) GetSession(_ context.Context, userID int64) (*UserSession, error) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	if session, exists := sm.sessions[userID]; exists {
-		return session
+	if session, exists := sm.liveSession(userID); exists {
+		return session, nil
 	}
 
 	session := &UserSession{
-		UserID:   userID,
-		State:    StateIdle,
-		TempData: make(map[string]string),
+		UserID:    userID,
+		State:     StateIdle,
+		TempData:  make(map[string]string),
+		UpdatedAt: time.Now(),
 	}
 	sm.sessions[userID] = session
-	return session
+	return session, nil
 }
 
-func (sm *StateManager) SetState(userID int64, state DialogState) {
+func (sm *StateManager) SetState(_ context.Context, userID int64, state DialogState) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	if session, exists := sm.sessions[userID]; exists {
+	if session, exists := sm.liveSession(userID); exists {
 		session.State = state
+		session.UpdatedAt = time.Now()
 	} else {
 		sm.sessions[userID] = &UserSession{
-			UserID:   userID,
-			State:    state,
-			TempData: make(map[string]string),
+			UserID:    userID,
+			State:     state,
+			TempData:  make(map[string]string),
+			UpdatedAt: time.Now(),
 		}
 	}
+	return nil
 }
 
-func (sm *StateManager) GetState(userID int64) DialogState {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
+func (sm *StateManager) GetState(_ context.Context, userID int64) (DialogState, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
 
-	if session, exists := sm.sessions[userID]; exists {
-		return session.State
+	if session, exists := sm.liveSession(userID); exists {
+		return session.State, nil
 	}
-	return StateIdle
+	return StateIdle, nil
 }
 
-func (sm *StateManager) SetTempData(userID int64, key, value string) {
+func (sm *StateManager) SetTempData(_ context.Context, userID int64, key, value string) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	if session, exists := sm.sessions[userID]; exists {
+	if session, exists := sm.liveSession(userID); exists {
 		session.mu.Lock()
 		defer session.mu.Unlock()
 		session.TempData[key] = value
+		session.UpdatedAt = time.Now()
 	} else {
 		session := &UserSession{
-			UserID:   userID,
-			State:    StateIdle,
-			TempData: map[string]string{key: value},
+			UserID:    userID,
+			State:     StateIdle,
+			TempData:  map[string]string{key: value},
+			UpdatedAt: time.Now(),
 		}
 		sm.sessions[userID] = session
 	}
+	return nil
 }
 
-func (sm *StateManager) GetTempData(userID int64, key string) string {
-	sm.mu.RLock()
-	session, exists := sm.sessions[userID]
-	sm.mu.RUnlock()
+func (sm *StateManager) GetTempData(_ context.Context, userID int64, key string) (string, error) {
+	sm.mu.Lock()
+	session, exists := sm.liveSession(userID)
+	sm.mu.Unlock()
 
 	if !exists {
-		return ""
+		return "", nil
 	}
-
-	session.mu.RLock()
-	defer session.mu.RUnlock()
-	return session.TempData[key]
+	return session.Get(key), nil
 }
 
-func (sm *StateManager) ClearSession(userID int64) {
+func (sm *StateManager) ClearSession(_ context.Context, userID int64) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
 	delete(sm.sessions, userID)
+	return nil
 }
 
-func (sm *StateManager) ClearState(userID int64) {
+func (sm *StateManager) ClearState(_ context.Context, userID int64) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
 	if session, exists := sm.sessions[userID]; exists {
 		session.State = StateIdle
 		session.TempData = make(map[string]string)
+		session.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (sm *StateManager) PurgeExpired(_ context.Context) (int64, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	var purged int64
+	for userID, session := range sm.sessions {
+		if sm.expired(session) {
+			delete(sm.sessions, userID)
+			purged++
+		}
 	}
+	return purged, nil
 }
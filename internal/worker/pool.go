@@ -0,0 +1,145 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"runtime/debug"
+	"sync"
+)
+
+var ErrPoolClosed = errors.New("worker pool is closed")
+
+type task struct {
+	key int64
+	fn  func()
+}
+
+// Pool выполняет задачи параллельно на нескольких воркерах, но задачи с одним ключом -
+// строго по очереди и в порядке Submit. Ключ - обычно telegram id пользователя.
+// Очередь ограничена: когда она заполнена, Submit ждет, пока освободится место
+type Pool struct {
+	tasks chan task
+	slots chan struct{} // занятые места в очереди, включая выполняющиеся задачи
+
+	mu      sync.Mutex
+	pending map[int64][]task // ключи, по которым сейчас работает воркер, и их ожидающие задачи
+	closed  bool
+
+	inFlight sync.WaitGroup
+	workers  sync.WaitGroup
+}
+
+func NewPool(workers int, queueSize int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < workers {
+		queueSize = workers
+	}
+
+	p := &Pool{
+		tasks:   make(chan task, queueSize),
+		slots:   make(chan struct{}, queueSize),
+		pending: make(map[int64][]task),
+	}
+	p.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Submit ставит задачу в очередь. Если очередь заполнена, ждет освобождения места или отмены ctx
+func (p *Pool) Submit(ctx context.Context, key int64, fn func()) error {
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		log.Printf("[WORKER] Queue is full (%d tasks), waiting for a free slot", cap(p.slots))
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.slots
+		return ErrPoolClosed
+	}
+	p.inFlight.Add(1)
+
+	t := task{key: key, fn: fn}
+	if queue, busy := p.pending[key]; busy {
+		// по этому ключу уже работает воркер - он заберет задачу, когда закончит предыдущие
+		p.pending[key] = append(queue, t)
+		p.mu.Unlock()
+		return nil
+	}
+	p.pending[key] = nil
+	p.mu.Unlock()
+
+	// мест в канале столько же, сколько слотов, поэтому отправка не блокируется
+	p.tasks <- t
+	return nil
+}
+
+func (p *Pool) work() {
+	defer p.workers.Done()
+
+	for t := range p.tasks {
+		for {
+			p.run(t)
+
+			p.mu.Lock()
+			queue := p.pending[t.key]
+			if len(queue) == 0 {
+				delete(p.pending, t.key)
+				p.mu.Unlock()
+				break
+			}
+			t = queue[0]
+			p.pending[t.key] = queue[1:]
+			p.mu.Unlock()
+		}
+	}
+}
+
+func (p *Pool) run(t task) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[WORKER] Task for key %d panicked: %v\n%s", t.key, r, debug.Stack())
+		}
+		<-p.slots
+		p.inFlight.Done()
+	}()
+	t.fn()
+}
+
+// Shutdown перестает принимать задачи и ждет, пока выполнятся уже принятые, или отмены ctx
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		p.inFlight.Wait()
+		close(p.tasks)
+		p.workers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
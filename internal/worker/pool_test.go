@@ -0,0 +1,125 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolKeepsOrderPerKey(t *testing.T) {
+	p := NewPool(4, 16)
+
+	var mu sync.Mutex
+	got := make(map[int64][]int)
+	var running [3]atomic.Int32
+
+	for i := 0; i < 50; i++ {
+		for key := int64(0); key < 3; key++ {
+			i, key := i, key
+			err := p.Submit(context.Background(), key, func() {
+				if running[key].Add(1) > 1 {
+					t.Errorf("two tasks for key %d run at once", key)
+				}
+				time.Sleep(100 * time.Microsecond)
+				mu.Lock()
+				got[key] = append(got[key], i)
+				mu.Unlock()
+				running[key].Add(-1)
+			})
+			if err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+		}
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	for key := int64(0); key < 3; key++ {
+		if len(got[key]) != 50 {
+			t.Fatalf("key %d: ran %d tasks, want 50", key, len(got[key]))
+		}
+		for i, v := range got[key] {
+			if v != i {
+				t.Fatalf("key %d: task %d ran at position %d", key, v, i)
+			}
+		}
+	}
+}
+
+func TestPoolRunsKeysConcurrently(t *testing.T) {
+	p := NewPool(2, 4)
+	defer p.Shutdown(context.Background())
+
+	release := make(chan struct{})
+	p.Submit(context.Background(), 1, func() { <-release })
+
+	done := make(chan struct{})
+	p.Submit(context.Background(), 2, func() { close(done) })
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("task for another key is blocked by a slow task")
+	}
+	close(release)
+}
+
+func TestPoolBackpressure(t *testing.T) {
+	p := NewPool(1, 1)
+
+	release := make(chan struct{})
+	p.Submit(context.Background(), 1, func() { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Submit(ctx, 2, func() {}); err != context.DeadlineExceeded {
+		t.Fatalf("Submit() to full queue error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	if err := p.Submit(context.Background(), 2, func() {}); err != nil {
+		t.Fatalf("Submit() after release error = %v", err)
+	}
+	p.Shutdown(context.Background())
+}
+
+func TestPoolShutdownDrains(t *testing.T) {
+	p := NewPool(2, 8)
+
+	var done atomic.Int32
+	for i := 0; i < 8; i++ {
+		p.Submit(context.Background(), int64(i%3), func() {
+			time.Sleep(time.Millisecond)
+			done.Add(1)
+		})
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if done.Load() != 8 {
+		t.Errorf("completed %d tasks before Shutdown returned, want 8", done.Load())
+	}
+	if err := p.Submit(context.Background(), 1, func() {}); err != ErrPoolClosed {
+		t.Errorf("Submit() after Shutdown error = %v, want %v", err, ErrPoolClosed)
+	}
+}
+
+func TestPoolSurvivesPanic(t *testing.T) {
+	p := NewPool(1, 2)
+
+	p.Submit(context.Background(), 1, func() { panic("boom") })
+	done := make(chan struct{})
+	p.Submit(context.Background(), 1, func() { close(done) })
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker died after panic")
+	}
+	p.Shutdown(context.Background())
+}
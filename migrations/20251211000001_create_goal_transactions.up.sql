@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS goal_transactions (
+    id BIGSERIAL PRIMARY KEY,
+    goal_id BIGINT NOT NULL REFERENCES savings_goals(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(30) NOT NULL CHECK (kind IN ('deposit', 'withdrawal', 'payday_allocation', 'correction')),
+    amount BIGINT NOT NULL, -- Положительная сумма для пополнений, отрицательная для снятий
+    income_id BIGINT REFERENCES incomes(id) ON DELETE SET NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_goal_transactions_goal_created ON goal_transactions(goal_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_goal_transactions_user ON goal_transactions(user_id);
+
+-- Перенос истории: месячные взносы становятся пополнениями,
+-- а разница с текущим балансом записывается как корректировка
+INSERT INTO goal_transactions (goal_id, user_id, kind, amount, comment, created_at)
+SELECT goal_id, user_id, 'deposit', amount_contributed, 'Перенос месячных взносов', month
+FROM monthly_contributions
+WHERE amount_contributed <> 0;
+
+INSERT INTO goal_transactions (goal_id, user_id, kind, amount, comment, created_at)
+SELECT g.id, g.user_id, 'correction',
+       COALESCE(g.current_amount, 0) - COALESCE((SELECT SUM(mc.amount_contributed) FROM monthly_contributions mc WHERE mc.goal_id = g.id), 0),
+       'Начальный баланс', COALESCE(g.created_at, CURRENT_TIMESTAMP)
+FROM savings_goals g
+WHERE COALESCE(g.current_amount, 0) - COALESCE((SELECT SUM(mc.amount_contributed) FROM monthly_contributions mc WHERE mc.goal_id = g.id), 0) <> 0;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_goal_transactions_user;
+DROP INDEX IF EXISTS idx_goal_transactions_goal_created;
+DROP TABLE IF EXISTS goal_transactions CASCADE;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS sessions (
+    user_id BIGINT PRIMARY KEY, -- Telegram ID пользователя
+    state VARCHAR(64) NOT NULL DEFAULT 'idle',
+    temp_data JSONB NOT NULL DEFAULT '{}'::jsonb,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_sessions_expires_at;
+DROP TABLE IF EXISTS sessions CASCADE;
@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS expense_categories (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    monthly_limit BIGINT NOT NULL DEFAULT 0, -- 0 означает отсутствие лимита
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_categories_user_id ON expense_categories(user_id);
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS category_id BIGINT REFERENCES expense_categories(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_category_id ON expenses(category_id);
+
+-- Отправленные предупреждения о лимитах, чтобы не повторять их в течение месяца
+CREATE TABLE IF NOT EXISTS category_budget_alerts (
+    id BIGSERIAL PRIMARY KEY,
+    category_id BIGINT NOT NULL REFERENCES expense_categories(id) ON DELETE CASCADE,
+    month DATE NOT NULL,
+    threshold INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(category_id, month, threshold)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS category_budget_alerts CASCADE;
+DROP INDEX IF EXISTS idx_expenses_category_id;
+ALTER TABLE expenses DROP COLUMN IF EXISTS category_id;
+DROP INDEX IF EXISTS idx_expense_categories_user_id;
+DROP TABLE IF EXISTS expense_categories CASCADE;
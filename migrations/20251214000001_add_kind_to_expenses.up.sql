@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'recurring';
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS frequency VARCHAR(20) NOT NULL DEFAULT 'monthly';
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS recurring_day INT NOT NULL DEFAULT 1;
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS spent_at DATE;
+
+ALTER TABLE expenses ADD CONSTRAINT expenses_kind_check CHECK (kind IN ('recurring', 'one_off'));
+ALTER TABLE expenses ADD CONSTRAINT expenses_one_off_date_check CHECK (kind <> 'one_off' OR spent_at IS NOT NULL);
+
+CREATE INDEX IF NOT EXISTS idx_expenses_user_kind ON expenses(user_id, kind);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_expenses_user_kind;
+ALTER TABLE expenses DROP CONSTRAINT IF EXISTS expenses_one_off_date_check;
+ALTER TABLE expenses DROP CONSTRAINT IF EXISTS expenses_kind_check;
+ALTER TABLE expenses DROP COLUMN IF EXISTS spent_at;
+ALTER TABLE expenses DROP COLUMN IF EXISTS recurring_day;
+ALTER TABLE expenses DROP COLUMN IF EXISTS frequency;
+ALTER TABLE expenses DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS base_currency CHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE savings_goals ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB';
+
+-- Курсы валют в рублях за единицу; RUB = 1 подразумевается и не хранится
+CREATE TABLE IF NOT EXISTS rates (
+    currency CHAR(3) PRIMARY KEY,
+    rate NUMERIC(18, 6) NOT NULL CHECK (rate > 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS rates CASCADE;
+ALTER TABLE savings_goals DROP COLUMN IF EXISTS currency;
+ALTER TABLE expenses DROP COLUMN IF EXISTS currency;
+ALTER TABLE incomes DROP COLUMN IF EXISTS currency;
+ALTER TABLE users DROP COLUMN IF EXISTS base_currency;
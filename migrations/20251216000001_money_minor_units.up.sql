@@ -0,0 +1,35 @@
+-- +goose Up
+-- Суммы хранятся в минимальных единицах валюты (копейках, центах)
+UPDATE users SET monthly_expense = monthly_expense * 100;
+UPDATE incomes SET amount = amount * 100;
+UPDATE expenses SET amount = amount * 100;
+UPDATE expense_categories SET monthly_limit = monthly_limit * 100;
+UPDATE savings_goals
+SET target_amount = target_amount * 100,
+    current_amount = current_amount * 100,
+    monthly_contrib = monthly_contrib * 100,
+    monthly_budget_limit = monthly_budget_limit * 100,
+    monthly_accumulated = monthly_accumulated * 100;
+UPDATE monthly_contributions SET amount_contributed = amount_contributed * 100;
+UPDATE goal_transactions SET amount = amount * 100;
+UPDATE income_processing_log SET income_amount = income_amount * 100;
+
+-- Незавершенные диалоги хранят суммы в рублях - сбрасываем их
+UPDATE sessions SET state = 'idle', temp_data = '{}'::jsonb;
+
+-- +goose Down
+UPDATE sessions SET state = 'idle', temp_data = '{}'::jsonb;
+
+UPDATE income_processing_log SET income_amount = income_amount / 100;
+UPDATE goal_transactions SET amount = amount / 100;
+UPDATE monthly_contributions SET amount_contributed = amount_contributed / 100;
+UPDATE savings_goals
+SET target_amount = target_amount / 100,
+    current_amount = current_amount / 100,
+    monthly_contrib = monthly_contrib / 100,
+    monthly_budget_limit = monthly_budget_limit / 100,
+    monthly_accumulated = monthly_accumulated / 100;
+UPDATE expense_categories SET monthly_limit = monthly_limit / 100;
+UPDATE expenses SET amount = amount / 100;
+UPDATE incomes SET amount = amount / 100;
+UPDATE users SET monthly_expense = monthly_expense / 100;
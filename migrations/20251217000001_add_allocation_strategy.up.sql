@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS allocation_strategy VARCHAR(32) NOT NULL DEFAULT 'priority_weighted';
+ALTER TABLE users ADD CONSTRAINT users_allocation_strategy_check
+    CHECK (allocation_strategy IN ('priority_weighted', 'waterfall', 'equal', 'deadline', 'fixed_percent'));
+
+-- Доля свободных средств для стратегии фиксированных процентов
+ALTER TABLE savings_goals ADD COLUMN IF NOT EXISTS allocation_percent INT NOT NULL DEFAULT 0;
+ALTER TABLE savings_goals ADD CONSTRAINT savings_goals_allocation_percent_check
+    CHECK (allocation_percent BETWEEN 0 AND 100);
+
+-- +goose Down
+ALTER TABLE savings_goals DROP CONSTRAINT IF EXISTS savings_goals_allocation_percent_check;
+ALTER TABLE savings_goals DROP COLUMN IF EXISTS allocation_percent;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_allocation_strategy_check;
+ALTER TABLE users DROP COLUMN IF EXISTS allocation_strategy;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Срок, заданный пользователем; target_date остается расчетной датой достижения
+ALTER TABLE savings_goals ADD COLUMN IF NOT EXISTS deadline DATE;
+
+-- +goose Down
+ALTER TABLE savings_goals DROP COLUMN IF EXISTS deadline;
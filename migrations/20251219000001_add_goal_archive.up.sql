@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE savings_goals DROP CONSTRAINT IF EXISTS savings_goals_status_check;
+ALTER TABLE savings_goals ADD CONSTRAINT savings_goals_status_check
+    CHECK (status IN ('active', 'completed', 'paused', 'archived'));
+
+-- Когда цель достигнута; для архива и расчета затраченного времени
+ALTER TABLE savings_goals ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP;
+UPDATE savings_goals SET completed_at = updated_at WHERE status = 'completed';
+
+-- +goose Down
+UPDATE savings_goals SET status = CASE WHEN completed_at IS NOT NULL THEN 'completed' ELSE 'paused' END WHERE status = 'archived';
+ALTER TABLE savings_goals DROP COLUMN IF EXISTS completed_at;
+ALTER TABLE savings_goals DROP CONSTRAINT IF EXISTS savings_goals_status_check;
+ALTER TABLE savings_goals ADD CONSTRAINT savings_goals_status_check
+    CHECK (status IN ('active', 'completed', 'paused'));
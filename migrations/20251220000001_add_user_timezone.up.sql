@@ -0,0 +1,6 @@
+-- +goose Up
+-- IANA-зона пользователя: часы уведомлений, дни выплат и границы месяцев считаются в ней
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'Europe/Moscow';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Второй день месяца для выплат два раза в месяц
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS second_day INT NOT NULL DEFAULT 0;
+-- Опорная дата: для выплат раз в две недели и раз в квартал
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS anchor_date DATE;
+UPDATE incomes SET anchor_date = next_pay_date::date WHERE frequency = 'biweekly';
+
+-- +goose Down
+ALTER TABLE incomes DROP COLUMN IF EXISTS anchor_date;
+ALTER TABLE incomes DROP COLUMN IF EXISTS second_day;
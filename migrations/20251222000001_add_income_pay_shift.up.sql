@@ -0,0 +1,8 @@
+-- +goose Up
+-- Перенос выплаты, выпавшей на выходной или праздник
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS pay_shift VARCHAR(10) NOT NULL DEFAULT 'none';
+ALTER TABLE incomes ADD CONSTRAINT incomes_pay_shift_check CHECK (pay_shift IN ('none', 'previous', 'next'));
+
+-- +goose Down
+ALTER TABLE incomes DROP CONSTRAINT IF EXISTS incomes_pay_shift_check;
+ALTER TABLE incomes DROP COLUMN IF EXISTS pay_shift;
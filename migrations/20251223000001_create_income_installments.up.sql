@@ -0,0 +1,16 @@
+-- +goose Up
+-- Части дохода, приходящего несколькими выплатами (аванс и зарплата)
+CREATE TABLE IF NOT EXISTS income_installments (
+    id BIGSERIAL PRIMARY KEY,
+    income_id BIGINT NOT NULL REFERENCES incomes(id) ON DELETE CASCADE,
+    day INT NOT NULL CHECK (day BETWEEN 1 AND 31),
+    amount BIGINT NOT NULL DEFAULT 0, -- используется, если percent = 0
+    percent INT NOT NULL DEFAULT 0 CHECK (percent BETWEEN 0 AND 100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_income_installments_income_id ON income_installments(income_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_income_installments_income_id;
+DROP TABLE IF EXISTS income_installments CASCADE;
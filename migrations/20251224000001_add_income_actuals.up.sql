@@ -0,0 +1,8 @@
+-- +goose Up
+-- Сколько и когда пришло на самом деле; received_date NULL - пользователь еще не подтвердил поступление
+ALTER TABLE income_processing_log ADD COLUMN IF NOT EXISTS actual_amount BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE income_processing_log ADD COLUMN IF NOT EXISTS received_date DATE;
+
+-- +goose Down
+ALTER TABLE income_processing_log DROP COLUMN IF EXISTS received_date;
+ALTER TABLE income_processing_log DROP COLUMN IF EXISTS actual_amount;
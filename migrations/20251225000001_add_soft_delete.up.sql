@@ -0,0 +1,20 @@
+-- +goose Up
+-- Мягкое удаление: запись скрыта, пока ее можно вернуть, и удаляется фоновой очисткой после срока хранения
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE savings_goals ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_incomes_deleted_at ON incomes(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_expenses_deleted_at ON expenses(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_savings_goals_deleted_at ON savings_goals(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_savings_goals_deleted_at;
+DROP INDEX IF EXISTS idx_expenses_deleted_at;
+DROP INDEX IF EXISTS idx_incomes_deleted_at;
+DELETE FROM savings_goals WHERE deleted_at IS NOT NULL;
+DELETE FROM expenses WHERE deleted_at IS NOT NULL;
+DELETE FROM incomes WHERE deleted_at IS NOT NULL;
+ALTER TABLE savings_goals DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE expenses DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE incomes DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Перенос накопленного с цели на цель (например, перед удалением цели)
+ALTER TABLE goal_transactions DROP CONSTRAINT IF EXISTS goal_transactions_kind_check;
+ALTER TABLE goal_transactions ADD CONSTRAINT goal_transactions_kind_check
+    CHECK (kind IN ('deposit', 'withdrawal', 'payday_allocation', 'correction', 'transfer'));
+
+-- +goose Down
+UPDATE goal_transactions SET kind = CASE WHEN amount < 0 THEN 'withdrawal' ELSE 'deposit' END WHERE kind = 'transfer';
+ALTER TABLE goal_transactions DROP CONSTRAINT IF EXISTS goal_transactions_kind_check;
+ALTER TABLE goal_transactions ADD CONSTRAINT goal_transactions_kind_check
+    CHECK (kind IN ('deposit', 'withdrawal', 'payday_allocation', 'correction'));
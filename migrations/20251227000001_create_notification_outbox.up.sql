@@ -0,0 +1,18 @@
+-- +goose Up
+-- Уведомления, которые не удалось отправить (лимиты Telegram, сеть); планировщик повторяет их с нарастающей паузой
+CREATE TABLE IF NOT EXISTS notification_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL, -- Telegram ID чата
+    text TEXT NOT NULL,
+    reply_markup JSONB, -- inline-кнопки как в Telegram API
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_next_attempt ON notification_outbox(next_attempt_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_notification_outbox_next_attempt;
+DROP TABLE IF EXISTS notification_outbox CASCADE;